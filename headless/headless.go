@@ -0,0 +1,280 @@
+package headless
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"httpDebugger/pkg/api"
+	"httpDebugger/pkg/certs"
+	"httpDebugger/pkg/fingerprintDB"
+	"httpDebugger/pkg/logging"
+	"httpDebugger/pkg/passthrough"
+	"httpDebugger/pkg/proxy"
+	"httpDebugger/pkg/proxy/upstream"
+	"httpDebugger/pkg/rewrite"
+	"httpDebugger/pkg/session"
+	"httpDebugger/pkg/sessiondata"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+// Options configures a proxy run without the TUI
+type Options struct {
+	Listen     string
+	CACertFile string
+	CAKeyFile  string
+	StoreSize  int
+	CaptureDir string
+	RulesFile  string
+	LogDir     string
+	// SOCKSListen, when set, also accepts SOCKS5 clients on this address
+	SOCKSListen string
+	// TransparentListen, when set, accepts connections redirected by the firewall
+	TransparentListen string
+	// Reverse, when set, also serves a reverse proxy in front of this upstream
+	// base URL on ReverseListen, DefaultReverseListen by default
+	Reverse       *url.URL
+	ReverseListen string
+	// APIListen, when set, serves the control API on this address
+	APIListen string
+	// Chain routes upstream connections through other proxies; nil connects directly
+	Chain *upstream.Chain
+	// Passthrough decides which TLS hosts are relayed without interception;
+	// nil keeps the default automatic passthrough of pinned hosts
+	Passthrough *passthrough.Policy
+	// Fingerprints identifies the client of each session; nil uses the
+	// built-in database
+	Fingerprints *fingerprintDB.Database
+
+	// Output receives every finished session as one JSON object per line
+	Output io.Writer
+	// Status receives human readable progress messages, e.g. os.Stderr
+	Status io.Writer
+}
+
+// Run serves the proxy until ctx is done, then shuts it down gracefully
+func Run(ctx context.Context, opts Options) error {
+	if opts.Listen == "" {
+		opts.Listen = proxy.DefaultListen
+	}
+	if opts.CACertFile == "" {
+		opts.CACertFile = certs.DefaultCACertFile
+	}
+	if opts.CAKeyFile == "" {
+		opts.CAKeyFile = certs.DefaultCAKeyFile
+	}
+	if opts.Reverse != nil && opts.ReverseListen == "" {
+		opts.ReverseListen = proxy.DefaultReverseListen
+	}
+	if opts.Output == nil {
+		opts.Output = io.Discard
+	}
+	if opts.Status == nil {
+		opts.Status = io.Discard
+	}
+
+	logger, err := logging.NewLogger(opts.LogDir, true)
+	if err != nil {
+		return err
+	}
+	defer logger.Close()
+
+	store, err := session.Open(opts.CaptureDir, opts.StoreSize)
+	if err != nil {
+		return err
+	}
+	if closer, ok := store.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	caCache, err := certs.LoadCA(opts.CACertFile, opts.CAKeyFile)
+	if err != nil {
+		return err
+	}
+
+	var rewriteEngine *rewrite.Engine
+	if opts.RulesFile != "" {
+		rewriteEngine, err = rewrite.LoadFile(opts.RulesFile)
+		if err != nil {
+			return err
+		}
+		defer rewriteEngine.Close()
+
+		rewriteEngine.Watch(time.Second, func(err error) {
+			if err != nil {
+				logger.LogError(err, "reloading rewrite rules")
+				fmt.Fprintf(opts.Status, "Error reloading rewrite rules: %v\n", err)
+				return
+			}
+			logger.LogInfo(fmt.Sprintf("Reloaded %d rewrite rules from %s", len(rewriteEngine.Rules()), rewriteEngine.Path()))
+		})
+	}
+
+	p := proxy.NewProxy(newSessionWriter(store, opts.Output), logger, caCache)
+	p.SetRewrite(rewriteEngine)
+	p.SetChain(opts.Chain)
+	if opts.Passthrough != nil {
+		p.SetPassthrough(opts.Passthrough)
+	}
+	if opts.Fingerprints != nil {
+		p.SetFingerprints(opts.Fingerprints)
+	}
+
+	listener, err := net.Listen("tcp", opts.Listen)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Handler:  p,
+		ErrorLog: log.New(io.Discard, "", 0),
+	}
+
+	logger.LogInfo(fmt.Sprintf("Proxy listening on %s", listener.Addr()))
+	fmt.Fprintf(opts.Status, "Proxy listening on %s\n", listener.Addr())
+	if opts.Chain != nil {
+		logger.LogInfo(fmt.Sprintf("Upstream proxies: %s", opts.Chain))
+		fmt.Fprintf(opts.Status, "Upstream proxies: %s\n", opts.Chain)
+	}
+	if opts.Passthrough != nil {
+		logger.LogInfo(fmt.Sprintf("TLS interception: %s", opts.Passthrough))
+		fmt.Fprintf(opts.Status, "TLS interception: %s\n", opts.Passthrough)
+	}
+	if opts.Fingerprints != nil && opts.Fingerprints.Path() != "" {
+		logger.LogInfo(fmt.Sprintf("Loaded %d client fingerprints, labels saved to %s", len(opts.Fingerprints.Entries()), opts.Fingerprints.Path()))
+		fmt.Fprintf(opts.Status, "Loaded %d client fingerprints, labels saved to %s\n", len(opts.Fingerprints.Entries()), opts.Fingerprints.Path())
+	}
+
+	listenTransparent := func(addr string) (net.Listener, error) {
+		listener, tproxy, err := proxy.ListenTransparent(addr)
+		if err == nil && !tproxy {
+			logger.LogInfo(proxy.NoTPROXYMessage)
+			fmt.Fprintln(opts.Status, proxy.NoTPROXYMessage)
+		}
+		return listener, err
+	}
+
+	type frontend struct {
+		name   string
+		addr   string
+		serve  func(net.Listener) error
+		listen func(string) (net.Listener, error)
+	}
+	frontends := []frontend{
+		{"SOCKS5 proxy", opts.SOCKSListen, p.ServeSOCKS, nil},
+		{"Transparent proxy", opts.TransparentListen, p.ServeTransparent, listenTransparent},
+	}
+	if opts.Reverse != nil {
+		frontends = append(frontends, frontend{"Reverse proxy to " + opts.Reverse.String(), opts.ReverseListen, func(listener net.Listener) error {
+			return p.ServeReverse(listener, opts.Reverse)
+		}, nil})
+	}
+	for _, frontend := range frontends {
+		if frontend.addr == "" {
+			continue
+		}
+		listen := frontend.listen
+		if listen == nil {
+			listen = func(addr string) (net.Listener, error) { return net.Listen("tcp", addr) }
+		}
+		frontendListener, err := listen(frontend.addr)
+		if err != nil {
+			listener.Close()
+			return err
+		}
+		defer frontendListener.Close()
+		go frontend.serve(frontendListener)
+
+		logger.LogInfo(fmt.Sprintf("%s listening on %s", frontend.name, frontendListener.Addr()))
+		fmt.Fprintf(opts.Status, "%s listening on %s\n", frontend.name, frontendListener.Addr())
+	}
+
+	var apiServer *http.Server
+	if opts.APIListen != "" {
+		apiServer, err = api.ListenAndServe(opts.APIListen, api.Config{
+			Store:       store,
+			Breakpoints: p.Breakpoints(),
+			Mappings:    p.Mappings(),
+			Rewrite:     rewriteEngine,
+			CACertFile:  opts.CACertFile,
+			ProxyAddr:   listener.Addr().String(),
+		})
+		if err != nil {
+			listener.Close()
+			return err
+		}
+		// Event streams never finish on their own, so close rather than shut down
+		defer apiServer.Close()
+		logger.LogInfo(fmt.Sprintf("API listening on %s", apiServer.Addr))
+		fmt.Fprintf(opts.Status, "API listening on %s\n", apiServer.Addr)
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	fmt.Fprintln(opts.Status, "Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return nil
+}
+
+// sessionWriter stores sessions and writes each finished one to out as a JSON line
+type sessionWriter struct {
+	store session.Store
+
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func newSessionWriter(store session.Store, out io.Writer) *sessionWriter {
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	return &sessionWriter{store: store, enc: enc}
+}
+
+func (w *sessionWriter) Store(s *sessiondata.Session) error {
+	if err := w.store.Store(s); err != nil {
+		return err
+	}
+
+	// WebSocket and tunnel sessions are stored when they open and again when they end
+	if s.WebSocket != nil && (s.WebSocket.State == sessiondata.WSConnecting || s.WebSocket.State == sessiondata.WSOpen) {
+		return nil
+	}
+	if s.Tunnel != nil && s.Tunnel.Open() {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.enc.Encode(s)
+}
+
+func (w *sessionWriter) GetAll() []*sessiondata.Session {
+	return w.store.GetAll()
+}
+
+func (w *sessionWriter) Get(id string) (*sessiondata.Session, error) {
+	return w.store.Get(id)
+}
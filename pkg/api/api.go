@@ -0,0 +1,227 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+
+	"httpDebugger/pkg/breakpoints"
+	"httpDebugger/pkg/mapping"
+	"httpDebugger/pkg/rewrite"
+	"httpDebugger/pkg/session"
+	"httpDebugger/pkg/sessiondata"
+)
+
+// Config holds what the API exposes. Nil managers are reported as having no rules
+type Config struct {
+	Store       session.Store
+	Breakpoints *breakpoints.Manager
+	Mappings    *mapping.Manager
+	Rewrite     *rewrite.Engine
+	// CACertFile is served at /api/ca.crt
+	CACertFile string
+	// ProxyAddr is the address of the proxy replayed requests are sent through
+	ProxyAddr string
+}
+
+// Server is a REST/JSON API controlling a running proxy, with session events
+// pushed over server-sent events at /api/events
+type Server struct {
+	config Config
+	mux    *http.ServeMux
+
+	mu      sync.Mutex
+	clients map[chan *sessiondata.Session]struct{}
+}
+
+func NewServer(config Config) *Server {
+	s := &Server{
+		config:  config,
+		mux:     http.NewServeMux(),
+		clients: make(map[chan *sessiondata.Session]struct{}),
+	}
+
+	s.mux.HandleFunc("GET /api/sessions", s.listSessions)
+	s.mux.HandleFunc("DELETE /api/sessions", s.clearSessions)
+	s.mux.HandleFunc("GET /api/sessions/search", s.searchSessions)
+	s.mux.HandleFunc("GET /api/sessions/{id}", s.getSession)
+	s.mux.HandleFunc("POST /api/sessions/{id}/replay", s.replaySession)
+	s.mux.HandleFunc("GET /api/events", s.events)
+
+	s.mux.HandleFunc("GET /api/breakpoints", s.listBreakpoints)
+	s.mux.HandleFunc("POST /api/breakpoints", s.addBreakpoint)
+	s.mux.HandleFunc("PATCH /api/breakpoints/{id}", s.updateBreakpoint)
+	s.mux.HandleFunc("DELETE /api/breakpoints/{id}", s.removeBreakpoint)
+	s.mux.HandleFunc("GET /api/breakpoints/pending", s.listPending)
+	s.mux.HandleFunc("POST /api/breakpoints/pending/{id}", s.resolvePending)
+
+	s.mux.HandleFunc("GET /api/mappings", s.listMappings)
+	s.mux.HandleFunc("POST /api/mappings", s.addMapping)
+	s.mux.HandleFunc("PATCH /api/mappings/{id}", s.updateMapping)
+	s.mux.HandleFunc("DELETE /api/mappings/{id}", s.removeMapping)
+
+	s.mux.HandleFunc("GET /api/rewrite", s.listRewriteRules)
+	s.mux.HandleFunc("PATCH /api/rewrite/{name}", s.updateRewriteRule)
+
+	s.mux.HandleFunc("GET /api/ca.crt", s.caCert)
+
+	config.Store.SubscribeSessions(s.broadcast)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// ListenAndServe starts the API on addr in the background. The returned
+// server's Addr is the address actually listened on
+func ListenAndServe(addr string, config Config) (*http.Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	server := &http.Server{
+		Addr:     listener.Addr().String(),
+		Handler:  NewServer(config),
+		ErrorLog: log.New(io.Discard, "", 0),
+	}
+	go server.Serve(listener)
+	return server, nil
+}
+
+type sessionList struct {
+	Total    int                    `json:"total"`
+	Sessions []*sessiondata.Session `json:"sessions"`
+}
+
+// listSessions returns session summaries, paged with ?offset= and ?limit=
+func (s *Server) listSessions(w http.ResponseWriter, r *http.Request) {
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	limit, err := queryInt(r, "limit", -1)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	store := s.config.Store
+	writeJSON(w, http.StatusOK, sessionList{
+		Total:    store.Len(),
+		Sessions: summaries(store.GetPage(offset, limit)),
+	})
+}
+
+func (s *Server) clearSessions(w http.ResponseWriter, r *http.Request) {
+	if err := s.config.Store.Clear(); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// searchSessions maps ?url=, ?header=, ?header_value=, ?cookie=, ?cookie_value=,
+// ?body= and ?fingerprint= onto session.SearchOptions
+func (s *Server) searchSessions(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	results, err := s.config.Store.Search(session.SearchOptions{
+		URL:         query.Get("url"),
+		HeadersKey:  query.Get("header"),
+		HeadersVal:  query.Get("header_value"),
+		CookiesKey:  query.Get("cookie"),
+		CookiesVal:  query.Get("cookie_value"),
+		Body:        query.Get("body"),
+		Fingerprint: query.Get("fingerprint"),
+	})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, sessionList{Total: len(results), Sessions: summaries(results)})
+}
+
+func (s *Server) getSession(w http.ResponseWriter, r *http.Request) {
+	found, err := s.config.Store.Get(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, found)
+}
+
+// replaySession sends the session's request through the proxy again and
+// returns the new session, as stored by the proxy when it can be found
+func (s *Server) replaySession(w http.ResponseWriter, r *http.Request) {
+	found, err := s.config.Store.Get(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	replay, err := found.Replay(s.config.ProxyAddr)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	if stored, err := s.config.Store.Get(replay.ID); err == nil {
+		replay = stored
+	}
+	writeJSON(w, http.StatusOK, replay)
+}
+
+func (s *Server) caCert(w http.ResponseWriter, r *http.Request) {
+	data, err := os.ReadFile(s.config.CACertFile)
+	if err != nil {
+		writeError(w, http.StatusNotFound, errors.New("CA certificate not generated yet"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-pem-file")
+	w.Write(data)
+}
+
+func summaries(sessions []*sessiondata.Session) []*sessiondata.Session {
+	result := make([]*sessiondata.Session, len(sessions))
+	for i, s := range sessions {
+		result[i] = s.Summary()
+	}
+	return result
+}
+
+func queryInt(r *http.Request, name string, fallback int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errors.New("invalid " + name)
+	}
+	return n, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func readJSON(r *http.Request, v any) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return errors.New("invalid JSON body: " + err.Error())
+	}
+	return nil
+}
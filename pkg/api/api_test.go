@@ -0,0 +1,223 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"httpDebugger/pkg/breakpoints"
+	"httpDebugger/pkg/mapping"
+	"httpDebugger/pkg/rewrite"
+	"httpDebugger/pkg/session"
+	"httpDebugger/pkg/sessiondata"
+	"httpDebugger/pkg/sortedMap"
+)
+
+func newSession(id, rawURL, body string) *sessiondata.Session {
+	return &sessiondata.Session{
+		ID:        id,
+		Timestamp: time.Now(),
+		Request: &sessiondata.RequestData{
+			Method:  "GET",
+			URL:     rawURL,
+			Headers: sortedMap.New(),
+			Body:    body,
+		},
+	}
+}
+
+func newTestServer(t *testing.T) (*httptest.Server, Config) {
+	t.Helper()
+
+	rules, err := rewrite.ParseRules([]byte(`
+rules:
+  - name: tag
+    actions:
+      - type: set-header
+        name: X-Tag
+        value: "1"
+`), "rules.yaml")
+	if err != nil {
+		t.Fatalf("ParseRules() failed: %v", err)
+	}
+	engine := rewrite.NewEngine(rules)
+
+	config := Config{
+		Store:       session.NewInMemoryStore(10),
+		Breakpoints: breakpoints.NewManager(),
+		Mappings:    mapping.NewManager(),
+		Rewrite:     engine,
+	}
+	server := httptest.NewServer(NewServer(config))
+	t.Cleanup(server.Close)
+	return server, config
+}
+
+func do(t *testing.T, method, url, body string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("NewRequest() failed: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, url, err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func decode(t *testing.T, resp *http.Response, v any) {
+	t.Helper()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("decoding response failed: %v", err)
+	}
+}
+
+func TestSessions(t *testing.T) {
+	server, config := newTestServer(t)
+	config.Store.Store(newSession("1", "https://example.com/a", "first"))
+	config.Store.Store(newSession("2", "https://example.com/b", "second"))
+
+	var list sessionList
+	decode(t, do(t, "GET", server.URL+"/api/sessions?limit=1", ""), &list)
+	if list.Total != 2 || len(list.Sessions) != 1 {
+		t.Fatalf("list = %d of %d sessions, want 1 of 2", len(list.Sessions), list.Total)
+	}
+	if list.Sessions[0].Request.Body != "" {
+		t.Errorf("listed sessions should not include bodies")
+	}
+
+	var found sessiondata.Session
+	decode(t, do(t, "GET", server.URL+"/api/sessions/2", ""), &found)
+	if found.Request.Body != "second" {
+		t.Errorf("Body = %q, want second", found.Request.Body)
+	}
+
+	if resp := do(t, "GET", server.URL+"/api/sessions/missing", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("missing session status = %d, want 404", resp.StatusCode)
+	}
+
+	decode(t, do(t, "GET", server.URL+"/api/sessions/search?url=example.com/a", ""), &list)
+	if list.Total != 1 || list.Sessions[0].ID != "1" {
+		t.Errorf("search returned %+v, want session 1", list.Sessions)
+	}
+
+	if resp := do(t, "DELETE", server.URL+"/api/sessions", ""); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("clear status = %d, want 204", resp.StatusCode)
+	}
+	if config.Store.Len() != 0 {
+		t.Errorf("store has %d sessions after clear", config.Store.Len())
+	}
+}
+
+func TestBreakpointRules(t *testing.T) {
+	server, config := newTestServer(t)
+
+	resp := do(t, "POST", server.URL+"/api/breakpoints", `{"rule": "method=POST url=/login"}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("add status = %d, want 201", resp.StatusCode)
+	}
+	var added ruleView
+	decode(t, resp, &added)
+	if !added.Enabled || added.ID == "" {
+		t.Errorf("unexpected rule %+v", added)
+	}
+
+	if resp := do(t, "POST", server.URL+"/api/breakpoints", `{"rule": "url=("}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid rule status = %d, want 400", resp.StatusCode)
+	}
+
+	resp = do(t, "PATCH", server.URL+"/api/breakpoints/"+added.ID, `{"enabled": false}`)
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("disable status = %d, want 204", resp.StatusCode)
+	}
+	if rules := config.Breakpoints.Rules(); len(rules) != 1 || rules[0].Enabled {
+		t.Errorf("rule should be disabled")
+	}
+
+	if resp := do(t, "DELETE", server.URL+"/api/breakpoints/"+added.ID, ""); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("remove status = %d, want 204", resp.StatusCode)
+	}
+	if resp := do(t, "DELETE", server.URL+"/api/breakpoints/"+added.ID, ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("second remove status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestMappingAndRewriteRules(t *testing.T) {
+	server, config := newTestServer(t)
+
+	var added ruleView
+	decode(t, do(t, "POST", server.URL+"/api/mappings", `{"rule": "remote ^https://a\\.test https://b.test"}`), &added)
+	if resp := do(t, "PATCH", server.URL+"/api/mappings/"+added.ID, `{"enabled": false}`); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("disable mapping status = %d, want 204", resp.StatusCode)
+	}
+	if rules := config.Mappings.Rules(); len(rules) != 1 || rules[0].Enabled {
+		t.Errorf("mapping should be disabled")
+	}
+
+	var rules []*rewrite.Rule
+	decode(t, do(t, "GET", server.URL+"/api/rewrite", ""), &rules)
+	if len(rules) != 1 || rules[0].Name != "tag" {
+		t.Fatalf("rewrite rules = %+v, want the tag rule", rules)
+	}
+
+	if resp := do(t, "PATCH", server.URL+"/api/rewrite/tag", `{"enabled": false}`); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("disable rewrite status = %d, want 204", resp.StatusCode)
+	}
+	if !config.Rewrite.Rules()[0].Disabled {
+		t.Errorf("rewrite rule should be disabled")
+	}
+	if resp := do(t, "PATCH", server.URL+"/api/rewrite/missing", `{"enabled": false}`); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("missing rewrite rule status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	server, config := newTestServer(t)
+
+	resp := do(t, "GET", server.URL+"/api/events", "")
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	config.Store.Store(newSession("1", "https://example.com/", "body"))
+
+	lines := make(chan string, 16)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatal("event stream closed")
+			}
+			data, found := strings.CutPrefix(line, "data: ")
+			if !found {
+				continue
+			}
+			var event sessiondata.Session
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				t.Fatalf("decoding event failed: %v", err)
+			}
+			if event.ID != "1" {
+				t.Errorf("event ID = %q, want 1", event.ID)
+			}
+			return
+		case <-timeout:
+			t.Fatal("no session event received")
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"httpDebugger/pkg/sessiondata"
+)
+
+const (
+	eventBufferSize   = 64
+	keepAliveInterval = 15 * time.Second
+)
+
+// broadcast hands a stored session to every connected event stream. Slow
+// clients miss events rather than blocking the proxy
+func (s *Server) broadcast(summary *sessiondata.Session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for client := range s.clients {
+		select {
+		case client <- summary:
+		default:
+		}
+	}
+}
+
+func (s *Server) subscribe() chan *sessiondata.Session {
+	client := make(chan *sessiondata.Session, eventBufferSize)
+	s.mu.Lock()
+	s.clients[client] = struct{}{}
+	s.mu.Unlock()
+	return client
+}
+
+func (s *Server) unsubscribe(client chan *sessiondata.Session) {
+	s.mu.Lock()
+	delete(s.clients, client)
+	s.mu.Unlock()
+}
+
+// events streams a "session" event with the summary of every session stored
+// from now on. Sessions stored more than once, like WebSockets, send one event
+// per update
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	client := s.subscribe()
+	defer s.unsubscribe(client)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case summary := <-client:
+			data, err := json.Marshal(summary)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: session\nid: %s\ndata: %s\n\n", summary.ID, data)
+			flusher.Flush()
+		}
+	}
+}
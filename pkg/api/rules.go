@@ -0,0 +1,243 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"httpDebugger/pkg/breakpoints"
+	"httpDebugger/pkg/mapping"
+	"httpDebugger/pkg/rewrite"
+	"httpDebugger/pkg/sessiondata"
+)
+
+var errNoManager = errors.New("not available in this proxy")
+
+// ruleView is how breakpoint and mapping rules are listed; Rule uses the same
+// syntax as the TUI prompt
+type ruleView struct {
+	ID      string `json:"id"`
+	Rule    string `json:"rule"`
+	Enabled bool   `json:"enabled"`
+}
+
+type ruleRequest struct {
+	Rule string `json:"rule"`
+}
+
+type enabledRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+type pendingView struct {
+	ID      string               `json:"id"`
+	Stage   string               `json:"stage"`
+	Rule    string               `json:"rule"`
+	Session *sessiondata.Session `json:"session"`
+}
+
+type resolveRequest struct {
+	// Action is "forward" or "drop"
+	Action string `json:"action"`
+}
+
+func breakpointView(rule *breakpoints.Rule) ruleView {
+	return ruleView{ID: rule.ID, Rule: rule.String(), Enabled: rule.Enabled}
+}
+
+func mappingView(rule *mapping.Rule) ruleView {
+	return ruleView{ID: rule.ID, Rule: rule.String(), Enabled: rule.Enabled}
+}
+
+func (s *Server) listBreakpoints(w http.ResponseWriter, r *http.Request) {
+	views := []ruleView{}
+	if s.config.Breakpoints != nil {
+		for _, rule := range s.config.Breakpoints.Rules() {
+			views = append(views, breakpointView(rule))
+		}
+	}
+	writeJSON(w, http.StatusOK, views)
+}
+
+func (s *Server) addBreakpoint(w http.ResponseWriter, r *http.Request) {
+	if s.config.Breakpoints == nil {
+		writeError(w, http.StatusNotImplemented, errNoManager)
+		return
+	}
+
+	var req ruleRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	rule, err := breakpoints.ParseRule(req.Rule)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.config.Breakpoints.AddRule(rule)
+	writeJSON(w, http.StatusCreated, breakpointView(rule))
+}
+
+func (s *Server) updateBreakpoint(w http.ResponseWriter, r *http.Request) {
+	if s.config.Breakpoints == nil {
+		writeError(w, http.StatusNotFound, breakpoints.ErrNotFound)
+		return
+	}
+
+	var req enabledRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := s.config.Breakpoints.SetRuleEnabled(r.PathValue("id"), req.Enabled); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) removeBreakpoint(w http.ResponseWriter, r *http.Request) {
+	if s.config.Breakpoints == nil {
+		writeError(w, http.StatusNotFound, breakpoints.ErrNotFound)
+		return
+	}
+	if err := s.config.Breakpoints.RemoveRule(r.PathValue("id")); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listPending returns the sessions currently held by breakpoints
+func (s *Server) listPending(w http.ResponseWriter, r *http.Request) {
+	views := []pendingView{}
+	if s.config.Breakpoints != nil {
+		for _, p := range s.config.Breakpoints.Pending() {
+			views = append(views, pendingView{
+				ID:      p.ID,
+				Stage:   p.Stage.String(),
+				Rule:    p.Rule.String(),
+				Session: p.Session.Summary(),
+			})
+		}
+	}
+	writeJSON(w, http.StatusOK, views)
+}
+
+// resolvePending forwards a held session unchanged or drops it
+func (s *Server) resolvePending(w http.ResponseWriter, r *http.Request) {
+	if s.config.Breakpoints == nil {
+		writeError(w, http.StatusNotFound, breakpoints.ErrNotFound)
+		return
+	}
+
+	var req resolveRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var decision breakpoints.Decision
+	switch req.Action {
+	case "forward":
+		decision.Action = breakpoints.ActionForward
+	case "drop":
+		decision.Action = breakpoints.ActionDrop
+	default:
+		writeError(w, http.StatusBadRequest, errors.New(`action must be "forward" or "drop"`))
+		return
+	}
+
+	if err := s.config.Breakpoints.Resolve(r.PathValue("id"), decision); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listMappings(w http.ResponseWriter, r *http.Request) {
+	views := []ruleView{}
+	if s.config.Mappings != nil {
+		for _, rule := range s.config.Mappings.Rules() {
+			views = append(views, mappingView(rule))
+		}
+	}
+	writeJSON(w, http.StatusOK, views)
+}
+
+func (s *Server) addMapping(w http.ResponseWriter, r *http.Request) {
+	if s.config.Mappings == nil {
+		writeError(w, http.StatusNotImplemented, errNoManager)
+		return
+	}
+
+	var req ruleRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	rule, err := mapping.ParseRule(req.Rule)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.config.Mappings.Add(rule)
+	writeJSON(w, http.StatusCreated, mappingView(rule))
+}
+
+func (s *Server) updateMapping(w http.ResponseWriter, r *http.Request) {
+	if s.config.Mappings == nil {
+		writeError(w, http.StatusNotFound, mapping.ErrNotFound)
+		return
+	}
+
+	var req enabledRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := s.config.Mappings.SetRuleEnabled(r.PathValue("id"), req.Enabled); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) removeMapping(w http.ResponseWriter, r *http.Request) {
+	if s.config.Mappings == nil {
+		writeError(w, http.StatusNotFound, mapping.ErrNotFound)
+		return
+	}
+	if err := s.config.Mappings.Remove(r.PathValue("id")); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listRewriteRules(w http.ResponseWriter, r *http.Request) {
+	rules := []*rewrite.Rule{}
+	if s.config.Rewrite != nil {
+		rules = append(rules, s.config.Rewrite.Rules()...)
+	}
+	writeJSON(w, http.StatusOK, rules)
+}
+
+// updateRewriteRule turns a rewrite rule on or off until its file is reloaded
+func (s *Server) updateRewriteRule(w http.ResponseWriter, r *http.Request) {
+	if s.config.Rewrite == nil {
+		writeError(w, http.StatusNotFound, rewrite.ErrNotFound)
+		return
+	}
+
+	var req enabledRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := s.config.Rewrite.SetRuleEnabled(r.PathValue("name"), req.Enabled); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
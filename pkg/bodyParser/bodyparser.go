@@ -52,6 +52,63 @@ func Parse(body string, options BodyParserOptions) (string, error) {
 	return decompressedBody, nil
 }
 
+// Decompress decodes body according to a Content-Encoding value
+func Decompress(body, compression string) (string, error) {
+	return decompress(body, compression)
+}
+
+// Compress encodes body according to a Content-Encoding value, the inverse of Decompress
+func Compress(body, compression string) (string, error) {
+	var buf bytes.Buffer
+
+	switch compression {
+	case CompressionGzip:
+		writer := gzip.NewWriter(&buf)
+		if _, err := writer.Write([]byte(body)); err != nil {
+			return "", fmt.Errorf("gzip compression error: %w", err)
+		}
+		if err := writer.Close(); err != nil {
+			return "", fmt.Errorf("gzip compression error: %w", err)
+		}
+	case CompressionZstd:
+		writer, err := zstd.NewWriter(&buf)
+		if err != nil {
+			return "", fmt.Errorf("zstd compression error: %w", err)
+		}
+		if _, err := writer.Write([]byte(body)); err != nil {
+			return "", fmt.Errorf("zstd compression error: %w", err)
+		}
+		if err := writer.Close(); err != nil {
+			return "", fmt.Errorf("zstd compression error: %w", err)
+		}
+	case CompressionDeflate:
+		writer, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		if err != nil {
+			return "", fmt.Errorf("deflate compression error: %w", err)
+		}
+		if _, err := writer.Write([]byte(body)); err != nil {
+			return "", fmt.Errorf("deflate compression error: %w", err)
+		}
+		if err := writer.Close(); err != nil {
+			return "", fmt.Errorf("deflate compression error: %w", err)
+		}
+	case CompressionBrotli:
+		writer := brotli.NewWriter(&buf)
+		if _, err := writer.Write([]byte(body)); err != nil {
+			return "", fmt.Errorf("brotli compression error: %w", err)
+		}
+		if err := writer.Close(); err != nil {
+			return "", fmt.Errorf("brotli compression error: %w", err)
+		}
+	case "":
+		return body, nil
+	default:
+		return "", fmt.Errorf("unsupported compression method: %s", compression)
+	}
+
+	return buf.String(), nil
+}
+
 func decompress(body, compression string) (string, error) {
 	switch compression {
 	case CompressionGzip:
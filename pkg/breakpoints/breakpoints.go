@@ -0,0 +1,396 @@
+package breakpoints
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"httpDebugger/pkg/sessiondata"
+
+	"github.com/google/uuid"
+)
+
+// Stage tells whether a held session is waiting before its request is sent
+// upstream or before its response is returned to the client
+type Stage int
+
+const (
+	StageRequest Stage = iota
+	StageResponse
+)
+
+func (s Stage) String() string {
+	if s == StageResponse {
+		return "response"
+	}
+	return "request"
+}
+
+type Action int
+
+const (
+	// ActionForward lets the session continue, using any edits made to it
+	ActionForward Action = iota
+	// ActionDrop aborts the exchange without answering the client
+	ActionDrop
+	// ActionRespond answers the client with Decision.Response instead
+	ActionRespond
+)
+
+// Decision is how the user resolved a held session
+type Decision struct {
+	Action   Action
+	Response *sessiondata.ResponseData
+	// Edited reports that the held request or response was modified and must be
+	// rebuilt from the session instead of sending the original bytes
+	Edited bool
+}
+
+var ErrNotFound = errors.New("breakpoint not found")
+
+// Pending is a session held by a breakpoint until Resolve is called
+type Pending struct {
+	ID      string
+	Session *sessiondata.Session
+	Stage   Stage
+	Rule    *Rule
+
+	decision chan Decision
+}
+
+// Manager holds the breakpoint rules and the sessions currently paused by them
+type Manager struct {
+	mu          sync.Mutex
+	rules       []*Rule
+	pending     []*Pending
+	subscribers []func()
+}
+
+func NewManager() *Manager {
+	return &Manager{}
+}
+
+// Subscribe registers a callback run whenever rules or held sessions change
+func (m *Manager) Subscribe(callback func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subscribers = append(m.subscribers, callback)
+}
+
+func (m *Manager) notify() {
+	m.mu.Lock()
+	subs := make([]func(), len(m.subscribers))
+	copy(subs, m.subscribers)
+	m.mu.Unlock()
+
+	for _, callback := range subs {
+		go callback()
+	}
+}
+
+func (m *Manager) AddRule(rule *Rule) {
+	m.mu.Lock()
+	m.rules = append(m.rules, rule)
+	m.mu.Unlock()
+	m.notify()
+}
+
+func (m *Manager) RemoveRule(id string) error {
+	m.mu.Lock()
+	for i, rule := range m.rules {
+		if rule.ID == id {
+			m.rules = append(m.rules[:i], m.rules[i+1:]...)
+			m.mu.Unlock()
+			m.notify()
+			return nil
+		}
+	}
+	m.mu.Unlock()
+	return ErrNotFound
+}
+
+// SetRuleEnabled turns a rule on or off without removing it
+func (m *Manager) SetRuleEnabled(id string, enabled bool) error {
+	m.mu.Lock()
+	for _, rule := range m.rules {
+		if rule.ID == id {
+			rule.Enabled = enabled
+			m.mu.Unlock()
+			m.notify()
+			return nil
+		}
+	}
+	m.mu.Unlock()
+	return ErrNotFound
+}
+
+// ClearRules removes every rule; sessions already held stay held
+func (m *Manager) ClearRules() {
+	m.mu.Lock()
+	m.rules = nil
+	m.mu.Unlock()
+	m.notify()
+}
+
+func (m *Manager) Rules() []*Rule {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rules := make([]*Rule, len(m.rules))
+	copy(rules, m.rules)
+	return rules
+}
+
+// Match returns the first enabled rule matching the session's request
+func (m *Manager) Match(session *sessiondata.Session) *Rule {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, rule := range m.rules {
+		if rule.Enabled && rule.Matches(session) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// Hold pauses the caller until the user resolves the session or ctx is done,
+// in which case the session is dropped
+func (m *Manager) Hold(ctx context.Context, session *sessiondata.Session, stage Stage, rule *Rule) Decision {
+	p := &Pending{
+		ID:       uuid.New().String(),
+		Session:  session,
+		Stage:    stage,
+		Rule:     rule,
+		decision: make(chan Decision, 1),
+	}
+
+	m.mu.Lock()
+	m.pending = append(m.pending, p)
+	m.mu.Unlock()
+	m.notify()
+
+	var decision Decision
+	select {
+	case decision = <-p.decision:
+	case <-ctx.Done():
+		decision = Decision{Action: ActionDrop}
+	}
+
+	m.remove(p.ID)
+	return decision
+}
+
+// Pending returns the held sessions, oldest first
+func (m *Manager) Pending() []*Pending {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pending := make([]*Pending, len(m.pending))
+	copy(pending, m.pending)
+	return pending
+}
+
+// Resolve releases a held session with the given decision
+func (m *Manager) Resolve(id string, decision Decision) error {
+	if decision.Action == ActionRespond && decision.Response == nil {
+		decision.Response = &sessiondata.ResponseData{StatusCode: http.StatusOK, Status: "200 OK"}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, p := range m.pending {
+		if p.ID == id {
+			select {
+			case p.decision <- decision:
+			default:
+			}
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// ResolveAll forwards every held session unchanged
+func (m *Manager) ResolveAll() {
+	for _, p := range m.Pending() {
+		m.Resolve(p.ID, Decision{Action: ActionForward})
+	}
+}
+
+func (m *Manager) remove(id string) {
+	m.mu.Lock()
+	for i, p := range m.pending {
+		if p.ID == id {
+			m.pending = append(m.pending[:i], m.pending[i+1:]...)
+			break
+		}
+	}
+	m.mu.Unlock()
+	m.notify()
+}
+
+// Rule selects requests to hold. Empty fields match anything; all set fields must match
+type Rule struct {
+	ID     string
+	URL    string
+	Method string
+	Host   string
+	// Header is a header name, optionally followed by ":" and a value regex
+	Header string
+	// Response also holds the response of matching requests
+	Response bool
+	Enabled  bool
+
+	urlRe       *regexp.Regexp
+	headerName  string
+	headerValue *regexp.Regexp
+}
+
+// ParseRule parses a rule written as space separated key=value terms, e.g.
+//
+//	method=POST host=api.example.com url=/v1/login header=X-Debug:1 response
+//
+// A bare term without "=" is used as the URL regex
+func ParseRule(spec string) (*Rule, error) {
+	rule := &Rule{ID: uuid.New().String(), Enabled: true}
+
+	for _, term := range strings.Fields(spec) {
+		name, value, found := strings.Cut(term, "=")
+		if !found {
+			if strings.EqualFold(term, "response") {
+				rule.Response = true
+				continue
+			}
+			name, value = "url", term
+		}
+
+		switch strings.ToLower(name) {
+		case "url":
+			rule.URL = value
+		case "method":
+			rule.Method = strings.ToUpper(value)
+		case "host":
+			rule.Host = strings.ToLower(value)
+		case "header":
+			rule.Header = value
+		default:
+			return nil, fmt.Errorf("unknown breakpoint term %q", name)
+		}
+	}
+
+	if err := rule.compile(); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (r *Rule) compile() error {
+	if r.URL != "" {
+		re, err := regexp.Compile(r.URL)
+		if err != nil {
+			return fmt.Errorf("invalid url regex: %w", err)
+		}
+		r.urlRe = re
+	}
+
+	if r.Header != "" {
+		name, value, found := strings.Cut(r.Header, ":")
+		r.headerName = strings.TrimSpace(name)
+		if found {
+			re, err := regexp.Compile(strings.TrimSpace(value))
+			if err != nil {
+				return fmt.Errorf("invalid header regex: %w", err)
+			}
+			r.headerValue = re
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the session's request satisfies every condition of the rule
+func (r *Rule) Matches(session *sessiondata.Session) bool {
+	req := session.Request
+	if req == nil {
+		return false
+	}
+
+	if r.Method != "" && !strings.EqualFold(req.Method, r.Method) {
+		return false
+	}
+
+	if r.urlRe != nil && !r.urlRe.MatchString(req.URL) {
+		return false
+	}
+
+	if r.Host != "" {
+		parsed, err := url.Parse(req.URL)
+		if err != nil || !strings.EqualFold(parsed.Hostname(), r.Host) {
+			return false
+		}
+	}
+
+	if r.headerName != "" && !r.matchesHeader(req) {
+		return false
+	}
+
+	return true
+}
+
+func (r *Rule) matchesHeader(req *sessiondata.RequestData) bool {
+	if req.Headers == nil {
+		return false
+	}
+	for _, key := range req.Headers.Order {
+		if !strings.EqualFold(key, r.headerName) {
+			continue
+		}
+		if r.headerValue == nil {
+			return true
+		}
+		value, _ := req.Headers.Get(key)
+		if values, ok := value.([]string); ok {
+			for _, v := range values {
+				if r.headerValue.MatchString(v) {
+					return true
+				}
+			}
+		} else if r.headerValue.MatchString(fmt.Sprintf("%v", value)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) String() string {
+	var terms []string
+	if r.Method != "" {
+		terms = append(terms, "method="+r.Method)
+	}
+	if r.Host != "" {
+		terms = append(terms, "host="+r.Host)
+	}
+	if r.URL != "" {
+		terms = append(terms, "url="+r.URL)
+	}
+	if r.Header != "" {
+		terms = append(terms, "header="+r.Header)
+	}
+	if r.Response {
+		terms = append(terms, "response")
+	}
+	if len(terms) == 0 {
+		return "*"
+	}
+	return strings.Join(terms, " ")
+}
@@ -0,0 +1,118 @@
+package breakpoints
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"httpDebugger/pkg/sessiondata"
+	"httpDebugger/pkg/sortedMap"
+)
+
+func createTestSession(method, url string, headers map[string]string) *sessiondata.Session {
+	sm := sortedMap.New()
+	for k, v := range headers {
+		sm.Put(k, v)
+	}
+	return &sessiondata.Session{
+		ID:      "test-id",
+		Request: &sessiondata.RequestData{Method: method, URL: url, Headers: sm},
+	}
+}
+
+func TestRuleMatches(t *testing.T) {
+	session := createTestSession("POST", "https://api.example.com/v1/login?next=/home", map[string]string{"X-Debug": "on"})
+
+	tests := []struct {
+		spec    string
+		matches bool
+	}{
+		{"", true},
+		{"/v1/login", true},
+		{"method=post", true},
+		{"method=GET", false},
+		{"host=API.example.com", true},
+		{"host=example.com", false},
+		{"header=x-debug", true},
+		{"header=X-Debug:^on$", true},
+		{"header=X-Debug:off", false},
+		{"method=POST host=api.example.com url=login$", false},
+		{"method=POST host=api.example.com url=login response", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			rule, err := ParseRule(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseRule(%q) failed: %v", tt.spec, err)
+			}
+			if got := rule.Matches(session); got != tt.matches {
+				t.Errorf("Matches() = %v, want %v", got, tt.matches)
+			}
+		})
+	}
+}
+
+func TestParseRuleErrors(t *testing.T) {
+	for _, spec := range []string{"url=([", "header=X:([", "status=200"} {
+		if _, err := ParseRule(spec); err == nil {
+			t.Errorf("ParseRule(%q) should fail", spec)
+		}
+	}
+
+	rule, _ := ParseRule("method=POST response")
+	if !rule.Response || rule.String() != "method=POST response" {
+		t.Errorf("unexpected rule %q", rule.String())
+	}
+}
+
+func TestHoldAndResolve(t *testing.T) {
+	manager := NewManager()
+	rule, _ := ParseRule("method=POST")
+	manager.AddRule(rule)
+
+	session := createTestSession("POST", "https://example.com/", nil)
+	if manager.Match(session) != rule {
+		t.Fatal("Match() did not return the rule")
+	}
+
+	done := make(chan Decision)
+	go func() {
+		done <- manager.Hold(context.Background(), session, StageRequest, rule)
+	}()
+
+	var pending []*Pending
+	for i := 0; i < 100 && len(pending) == 0; i++ {
+		time.Sleep(5 * time.Millisecond)
+		pending = manager.Pending()
+	}
+	if len(pending) != 1 || pending[0].Session != session {
+		t.Fatalf("expected the session to be held, got %d pending", len(pending))
+	}
+
+	if err := manager.Resolve(pending[0].ID, Decision{Action: ActionRespond}); err != nil {
+		t.Fatalf("Resolve() failed: %v", err)
+	}
+
+	decision := <-done
+	if decision.Action != ActionRespond || decision.Response == nil || decision.Response.StatusCode != 200 {
+		t.Errorf("unexpected decision %+v", decision)
+	}
+	if len(manager.Pending()) != 0 {
+		t.Errorf("resolved session should no longer be pending")
+	}
+	if err := manager.Resolve(pending[0].ID, Decision{}); err != ErrNotFound {
+		t.Errorf("resolving twice should return ErrNotFound, got %v", err)
+	}
+}
+
+func TestHoldDropsOnCancel(t *testing.T) {
+	manager := NewManager()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	decision := manager.Hold(ctx, createTestSession("GET", "https://example.com/", nil), StageResponse, nil)
+	if decision.Action != ActionDrop {
+		t.Errorf("cancelled hold should drop, got %v", decision.Action)
+	}
+}
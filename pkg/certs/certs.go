@@ -11,6 +11,7 @@ import (
 	"math/big"
 	"net"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -24,6 +25,12 @@ const (
 	bitSize              = 2048
 )
 
+// Default locations of the CA used to sign intercepted hosts
+const (
+	DefaultCACertFile = "certs/httpCA.crt"
+	DefaultCAKeyFile  = "certs/httpCA.key"
+)
+
 type CertCache struct {
 	CACert tls.Certificate
 	Cache  map[string]tls.Certificate
@@ -36,6 +43,31 @@ func NewCertCache() *CertCache {
 	}
 }
 
+// LoadCA returns a cache signing with the CA stored in certFile and keyFile. A new
+// CA is generated and saved there when neither file exists yet
+func LoadCA(certFile, keyFile string) (*CertCache, error) {
+	_, certErr := os.Stat(certFile)
+	_, keyErr := os.Stat(keyFile)
+	if !os.IsNotExist(certErr) || !os.IsNotExist(keyErr) {
+		// Never overwrite an existing CA that fails to load
+		if _, err := tls.LoadX509KeyPair(certFile, keyFile); err != nil {
+			return nil, fmt.Errorf("loading CA: %w", err)
+		}
+	}
+
+	for _, dir := range []string{filepath.Dir(certFile), filepath.Dir(keyFile)} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create folder %s: %w", dir, err)
+		}
+	}
+
+	cache := NewCertCache()
+	if err := cache.LoadOrGenerateCA(filepath.Dir(certFile), certFile, keyFile); err != nil {
+		return nil, err
+	}
+	return cache, nil
+}
+
 func (c *CertCache) GenerateCA() error {
 	privateKey, err := rsa.GenerateKey(rand.Reader, bitSize)
 	if err != nil {
@@ -31,6 +31,13 @@ func ParseClientHelloFull(rawClientHello []byte) (*TLSFingerprint, error) {
 
 	fp := &TLSFingerprint{}
 
+	recordLen := int(binary.BigEndian.Uint16(rawClientHello[3:5])) + 5
+	if recordLen > len(rawClientHello) {
+		recordLen = len(rawClientHello)
+	}
+	fp.Raw = make([]byte, recordLen)
+	copy(fp.Raw, rawClientHello[:recordLen])
+
 	fingerprinter := &utls.Fingerprinter{}
 	spec, err := fingerprinter.FingerprintClientHello(rawClientHello)
 	if err == nil && spec != nil {
@@ -41,6 +48,7 @@ func ParseClientHelloFull(rawClientHello []byte) (*TLSFingerprint, error) {
 	extractFromRaw(fp, rawClientHello)
 
 	fp.ComputeJA3()
+	fp.ComputeJA4()
 	return fp, nil
 }
 
@@ -143,6 +151,10 @@ func extractFromRaw(fp *TLSFingerprint, rawClientHello []byte) {
 		extData := data[offset : offset+extLen]
 
 		switch extType {
+		case ExtensionSNI:
+			if fp.ServerName == "" {
+				fp.ServerName = parseServerName(extData)
+			}
 		case ExtensionALPN:
 			if len(fp.ALPNProtocols) == 0 {
 				fp.ALPNProtocols = parseALPN(extData)
@@ -181,6 +193,17 @@ func extractFromRaw(fp *TLSFingerprint, rawClientHello []byte) {
 	}
 }
 
+// ParseServerName returns the SNI of a raw ClientHello record, or "" when it
+// has none
+func ParseServerName(rawClientHello []byte) string {
+	if len(rawClientHello) < 44 || rawClientHello[0] != 0x16 || rawClientHello[5] != 0x01 {
+		return ""
+	}
+	fp := &TLSFingerprint{}
+	extractFromRaw(fp, rawClientHello)
+	return fp.ServerName
+}
+
 func GenerateClientHelloHash(data []byte) string {
 	if len(data) < 200 {
 		h := fnv.New64a()
@@ -233,6 +256,28 @@ func parseSignatureAlgorithms(data []byte) []uint16 {
 	return algs
 }
 
+// parseServerName returns the first host_name entry of a server_name extension
+func parseServerName(data []byte) string {
+	if len(data) < 2 {
+		return ""
+	}
+	listLen := int(binary.BigEndian.Uint16(data[0:2]))
+	offset := 2
+	for offset+3 <= listLen+2 && offset+3 <= len(data) {
+		nameType := data[offset]
+		nameLen := int(binary.BigEndian.Uint16(data[offset+1 : offset+3]))
+		offset += 3
+		if offset+nameLen > len(data) {
+			break
+		}
+		if nameType == 0 {
+			return string(data[offset : offset+nameLen])
+		}
+		offset += nameLen
+	}
+	return ""
+}
+
 func parseALPN(data []byte) []string {
 	if len(data) < 2 {
 		return nil
@@ -0,0 +1,198 @@
+package clientHello
+
+import (
+	"crypto/tls"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+)
+
+// captureClientHello returns the first record a TLS client sends for serverName
+func captureClientHello(t *testing.T, serverName string) []byte {
+	t.Helper()
+
+	client, server := net.Pipe()
+	defer server.Close()
+
+	go func() {
+		tlsConn := tls.Client(client, &tls.Config{ServerName: serverName, NextProtos: []string{"h2", "http/1.1"}})
+		tlsConn.SetDeadline(time.Now().Add(5 * time.Second))
+		tlsConn.Handshake()
+		client.Close()
+	}()
+
+	buffer := make([]byte, 16384)
+	n, err := server.Read(buffer)
+	if err != nil {
+		t.Fatalf("reading ClientHello failed: %v", err)
+	}
+	return buffer[:n]
+}
+
+func TestParseClientHelloFull(t *testing.T) {
+	raw := captureClientHello(t, "api.example.com")
+
+	fp, err := ParseClientHelloFull(raw)
+	if err != nil {
+		t.Fatalf("ParseClientHelloFull() failed: %v", err)
+	}
+	if fp.ServerName != "api.example.com" {
+		t.Errorf("ServerName = %q, want api.example.com", fp.ServerName)
+	}
+	if len(fp.ALPNProtocols) != 2 || fp.ALPNProtocols[0] != "h2" {
+		t.Errorf("ALPNProtocols = %v, want [h2 http/1.1]", fp.ALPNProtocols)
+	}
+	if fp.JA3Hash == "" {
+		t.Errorf("JA3Hash should be computed")
+	}
+}
+
+func TestParseServerName(t *testing.T) {
+	if got := ParseServerName(captureClientHello(t, "www.example.org")); got != "www.example.org" {
+		t.Errorf("ParseServerName() = %q, want www.example.org", got)
+	}
+	if got := ParseServerName([]byte("GET / HTTP/1.1\r\nHost: example.org\r\n\r\n")); got != "" {
+		t.Errorf("ParseServerName() of plaintext = %q, want empty", got)
+	}
+}
+
+func TestComputeJA4(t *testing.T) {
+	fp, err := ParseClientHelloFull(captureClientHello(t, "api.example.com"))
+	if err != nil {
+		t.Fatalf("ParseClientHelloFull() failed: %v", err)
+	}
+
+	parts := strings.Split(fp.JA4, "_")
+	if len(parts) != 3 || len(parts[1]) != 12 || len(parts[2]) != 12 {
+		t.Fatalf("JA4 = %q, want three parts with 12 character hashes", fp.JA4)
+	}
+	if !strings.HasPrefix(parts[0], "t13d") || !strings.HasSuffix(parts[0], "h2") {
+		t.Errorf("JA4 prefix = %q, want t13d...h2", parts[0])
+	}
+
+	// the order of cipher suites and extensions does not change JA4
+	shuffled := *fp
+	shuffled.CipherSuites = slices.Clone(fp.CipherSuites)
+	slices.Reverse(shuffled.CipherSuites)
+	shuffled.Extensions = slices.Clone(fp.Extensions)
+	slices.Reverse(shuffled.Extensions)
+	shuffled.ComputeJA4()
+	if shuffled.JA4 != fp.JA4 {
+		t.Errorf("JA4 changed with the order: %q != %q", shuffled.JA4, fp.JA4)
+	}
+
+	noSNI := &TLSFingerprint{TLSVersion: 0x0303, CipherSuites: []uint16{0x0a0a, 0xc02f}, Extensions: []uint16{ExtensionALPN}}
+	noSNI.ComputeJA4()
+	if want := "t12i010100_" + ja4Hash("c02f") + "_" + emptyJA4Hash; noSNI.JA4 != want {
+		t.Errorf("JA4 = %q, want %q", noSNI.JA4, want)
+	}
+}
+
+func TestParseServerHello(t *testing.T) {
+	hello := []byte{0x03, 0x03}
+	hello = append(hello, make([]byte, 32)...) // random
+	hello = append(hello, 0x00)                // session ID
+	hello = append(hello, 0x13, 0x01, 0x00)    // cipher suite, compression
+	extensions := []byte{
+		0x00, 0x2b, 0x00, 0x02, 0x03, 0x04, // supported_versions: TLS 1.3
+		0x00, 0x10, 0x00, 0x05, 0x00, 0x03, 0x02, 'h', '2', // ALPN: h2
+		0x00, 0x33, 0x00, 0x00, // key_share
+	}
+	hello = append(hello, byte(len(extensions)>>8), byte(len(extensions)))
+	hello = append(hello, extensions...)
+
+	handshake := append([]byte{0x02, 0x00, byte(len(hello) >> 8), byte(len(hello))}, hello...)
+	record := append([]byte{0x16, 0x03, 0x03, byte(len(handshake) >> 8), byte(len(handshake))}, handshake...)
+
+	fp, err := ParseServerHello(record)
+	if err != nil {
+		t.Fatalf("ParseServerHello() failed: %v", err)
+	}
+	if fp.JA3S != "771,4865,43-16-51" {
+		t.Errorf("JA3S = %q, want 771,4865,43-16-51", fp.JA3S)
+	}
+	if want := "t1303h2_1301_" + ja4Hash("002b,0010,0033"); fp.JA4S != want {
+		t.Errorf("JA4S = %q, want %q", fp.JA4S, want)
+	}
+	if fp.ALPN != "h2" || fp.SupportedVersion != 0x0304 {
+		t.Errorf("ALPN = %q, SupportedVersion = 0x%04x", fp.ALPN, fp.SupportedVersion)
+	}
+
+	if _, err := ParseServerHello(record[:len(record)-1]); err == nil {
+		t.Errorf("ParseServerHello() should reject a truncated record")
+	}
+}
+
+func TestGoSpec(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	go func() {
+		uconn := utls.UClient(client, &utls.Config{ServerName: "api.example.com"}, utls.HelloChrome_120)
+		uconn.SetDeadline(time.Now().Add(5 * time.Second))
+		uconn.Handshake()
+		client.Close()
+	}()
+	buffer := make([]byte, 16384)
+	n, err := server.Read(buffer)
+	if err != nil {
+		t.Fatalf("reading ClientHello failed: %v", err)
+	}
+
+	fp, err := ParseClientHelloFull(buffer[:n])
+	if err != nil {
+		t.Fatalf("ParseClientHelloFull() failed: %v", err)
+	}
+	source, err := fp.GoSpec()
+	if err != nil {
+		t.Fatalf("GoSpec() failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"func clientHelloSpec() *utls.ClientHelloSpec",
+		"utls.GREASE_PLACEHOLDER,",
+		"0x1301, // TLS_AES_128_GCM_SHA256",
+		"&utls.SNIExtension{}",
+		`&utls.ALPNExtension{AlpnProtocols: []string{"h2", "http/1.1"}}`,
+		"{Group: utls.CurveID(0x001d)}, // X25519",
+		"utls.BoringGREASEECH()",
+		fp.JA4,
+	} {
+		if !strings.Contains(source, want) {
+			t.Errorf("GoSpec() is missing %q:\n%s", want, source)
+		}
+	}
+
+	if _, err := (&TLSFingerprint{}).GoSpec(); err == nil {
+		t.Errorf("GoSpec() without a raw ClientHello should fail")
+	}
+}
+
+func TestSpecKeyIgnoresExtensionOrder(t *testing.T) {
+	fingerprint := func(extensions ...uint16) *TLSFingerprint {
+		fp := &TLSFingerprint{
+			TLSVersion:     0x0303,
+			CipherSuites:   []uint16{0x0a0a, 0x1301, 0x1302},
+			Extensions:     extensions,
+			EllipticCurves: []uint16{0x001d, 0x0017},
+			ALPNProtocols:  []string{"h2", "http/1.1"},
+		}
+		fp.ComputeJA3()
+		return fp
+	}
+
+	first := fingerprint(0x2a2a, 0, 10, 11, 13, 16, 43)
+	permuted := fingerprint(0x4a4a, 16, 43, 0, 13, 11, 10)
+	if first.JA3 == permuted.JA3 {
+		t.Fatal("test fingerprints should differ in JA3")
+	}
+	if first.SpecKey() != permuted.SpecKey() {
+		t.Error("SpecKey() should not depend on extension order or GREASE values")
+	}
+	if first.SpecKey() == fingerprint(0, 10, 11, 13, 16).SpecKey() {
+		t.Error("SpecKey() should change when an extension is missing")
+	}
+}
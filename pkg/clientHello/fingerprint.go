@@ -3,7 +3,11 @@ package clientHello
 import (
 	"crypto/md5"
 	"crypto/tls"
+	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"slices"
+	"strconv"
 	"strings"
 
 	utls "github.com/refraction-networking/utls"
@@ -23,7 +27,13 @@ type TLSFingerprint struct {
 	RecordSizeLimit   uint16                `json:"record_size_limit,omitempty"`
 	JA3               string                `json:"ja3"`
 	JA3Hash           string                `json:"ja3_hash"`
+	JA4               string                `json:"ja4"`
+	JA4Raw            string                `json:"ja4_raw,omitempty"`
+	Raw               []byte                `json:"raw,omitempty"`
 	Spec              *utls.ClientHelloSpec `json:"-"`
+
+	// ServerName is the SNI the client asked for; it is not part of the fingerprint
+	ServerName string `json:"server_name,omitempty"`
 }
 
 func (f *TLSFingerprint) ComputeJA3() {
@@ -66,6 +76,81 @@ func (f *TLSFingerprint) ComputeJA3() {
 	f.JA3Hash = fmt.Sprintf("%x", hash)
 }
 
+// UnmarshalJSON restores a fingerprint and rebuilds its utls spec from the raw
+// ClientHello, which is not serialized itself
+func (f *TLSFingerprint) UnmarshalJSON(data []byte) error {
+	type fingerprintAlias TLSFingerprint
+	if err := json.Unmarshal(data, (*fingerprintAlias)(f)); err != nil {
+		return err
+	}
+
+	// sessions saved before JA4 existed still get one
+	if f.JA4 == "" && len(f.CipherSuites) > 0 {
+		f.ComputeJA4()
+	}
+
+	f.Spec = nil
+	if len(f.Raw) > 0 {
+		fingerprinter := &utls.Fingerprinter{}
+		if spec, err := fingerprinter.FingerprintClientHello(f.Raw); err == nil {
+			f.Spec = spec
+		}
+	}
+	return nil
+}
+
+// NewSpec re-fingerprints the raw ClientHello into a fresh utls spec.
+// Specs hold per-connection state (key shares, GREASE seeds) and must not be
+// shared between handshakes, so every upstream dial needs its own copy.
+func (f *TLSFingerprint) NewSpec() (*utls.ClientHelloSpec, error) {
+	if len(f.Raw) == 0 {
+		return nil, fmt.Errorf("no raw client hello available")
+	}
+
+	fingerprinter := &utls.Fingerprinter{AllowBluntMimicry: true}
+	spec, err := fingerprinter.FingerprintClientHello(f.Raw)
+	if err != nil {
+		return nil, fmt.Errorf("fingerprinting client hello: %w", err)
+	}
+
+	for _, ext := range spec.Extensions {
+		if sni, ok := ext.(*utls.SNIExtension); ok {
+			sni.ServerName = ""
+		}
+	}
+	return spec, nil
+}
+
+// SpecKey identifies fingerprints that produce the same ClientHello shape,
+// ignoring per-connection values such as the random, session ID and SNI.
+// Like JA4 it sorts ciphers, extensions and curves, so clients that permute
+// their extensions on every connection (Chrome) keep one key
+func (f *TLSFingerprint) SpecKey() string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d|%v|%v|%v|%v|%v|%v|%v|%v|%v|%d",
+		f.TLSVersion, sortedWithoutGREASE(f.CipherSuites), sortedWithoutGREASE(f.Extensions),
+		sortedWithoutGREASE(f.EllipticCurves), f.ECPointFormats,
+		f.ALPNProtocols, f.SignatureAlgs, withoutGREASE(f.SupportedVersions),
+		withoutGREASE(f.KeyShareCurves), f.CertCompAlgs, f.RecordSizeLimit)
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+func sortedWithoutGREASE(values []uint16) []uint16 {
+	filtered := withoutGREASE(values)
+	slices.Sort(filtered)
+	return filtered
+}
+
+func withoutGREASE(values []uint16) []uint16 {
+	filtered := make([]uint16, 0, len(values))
+	for _, v := range values {
+		if !isGREASE(v) {
+			filtered = append(filtered, v)
+		}
+	}
+	return filtered
+}
+
 func (f *TLSFingerprint) ToTLSConfig() *tls.Config {
 	config := &tls.Config{}
 
@@ -0,0 +1,256 @@
+package clientHello
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"go/format"
+	"strings"
+
+	utls "github.com/refraction-networking/utls"
+)
+
+var tlsVersionNames = map[uint16]string{
+	utls.VersionTLS10: "utls.VersionTLS10",
+	utls.VersionTLS11: "utls.VersionTLS11",
+	utls.VersionTLS12: "utls.VersionTLS12",
+	utls.VersionTLS13: "utls.VersionTLS13",
+}
+
+var certCompressionNames = map[uint16]string{
+	1: "utls.CertCompressionZlib",
+	2: "utls.CertCompressionBrotli",
+	3: "utls.CertCompressionZstd",
+}
+
+// GoSpec returns a Go source file with a function building a utls.ClientHelloSpec
+// that sends this ClientHello, with every list spelled out
+func (f *TLSFingerprint) GoSpec() (string, error) {
+	spec, err := f.NewSpec()
+	if err != nil {
+		return "", err
+	}
+	extensions := rawExtensions(f.Raw)
+	if len(extensions) != len(spec.Extensions) {
+		return "", fmt.Errorf("client hello has %d extensions, utls read %d", len(extensions), len(spec.Extensions))
+	}
+
+	var b strings.Builder
+	b.WriteString("package main\n\n")
+	b.WriteString("import (\n\t\"net\"\n\n\tutls \"github.com/refraction-networking/utls\"\n)\n\n")
+
+	fmt.Fprintf(&b, "// clientHelloSpec reproduces the ClientHello with JA3 %s and JA4 %s.\n", f.JA3Hash, f.JA4)
+	b.WriteString("// Key shares and GREASE values are generated again on every handshake.\n")
+	b.WriteString("func clientHelloSpec() *utls.ClientHelloSpec {\n")
+	b.WriteString("return &utls.ClientHelloSpec{\n")
+	if spec.TLSVersMin != 0 {
+		fmt.Fprintf(&b, "TLSVersMin: %s,\n", goVersion(spec.TLSVersMin))
+	}
+	if spec.TLSVersMax != 0 {
+		fmt.Fprintf(&b, "TLSVersMax: %s,\n", goVersion(spec.TLSVersMax))
+	}
+
+	b.WriteString("CipherSuites: []uint16{\n")
+	for _, suite := range spec.CipherSuites {
+		if isGREASE(suite) {
+			b.WriteString("utls.GREASE_PLACEHOLDER,\n")
+		} else {
+			fmt.Fprintf(&b, "0x%04x, // %s\n", suite, tls.CipherSuiteName(suite))
+		}
+	}
+	b.WriteString("},\n")
+	fmt.Fprintf(&b, "CompressionMethods: %s,\n", goBytes("[]uint8", spec.CompressionMethods))
+
+	b.WriteString("Extensions: []utls.TLSExtension{\n")
+	for i, ext := range spec.Extensions {
+		b.WriteString(goExtension(ext, extensions[i]))
+		b.WriteString(",\n")
+	}
+	b.WriteString("},\n}\n}\n\n")
+
+	b.WriteString("// dialTLS opens a TLS connection to addr that sends clientHelloSpec.\n")
+	b.WriteString("func dialTLS(addr, serverName string) (*utls.UConn, error) {\n")
+	b.WriteString("conn, err := net.Dial(\"tcp\", addr)\nif err != nil {\nreturn nil, err\n}\n")
+	b.WriteString("uconn := utls.UClient(conn, &utls.Config{ServerName: serverName}, utls.HelloCustom)\n")
+	b.WriteString("if err := uconn.ApplyPreset(clientHelloSpec()); err != nil {\nconn.Close()\nreturn nil, err\n}\n")
+	b.WriteString("if err := uconn.Handshake(); err != nil {\nconn.Close()\nreturn nil, err\n}\n")
+	b.WriteString("return uconn, nil\n}\n")
+
+	source, err := format.Source([]byte(b.String()))
+	if err != nil {
+		return "", fmt.Errorf("formatting generated code: %w", err)
+	}
+	return string(source), nil
+}
+
+type rawExtension struct {
+	id   uint16
+	data []byte
+}
+
+// rawExtensions lists the extensions of a ClientHello record in order
+func rawExtensions(raw []byte) []rawExtension {
+	if len(raw) < 5+38 {
+		return nil
+	}
+	data := raw[5:]
+	offset := 38
+	offset += 1 + int(data[offset])
+	if offset+2 > len(data) {
+		return nil
+	}
+	offset += 2 + int(binary.BigEndian.Uint16(data[offset:]))
+	if offset >= len(data) {
+		return nil
+	}
+	offset += 1 + int(data[offset])
+	if offset+2 > len(data) {
+		return nil
+	}
+	end := min(offset+2+int(binary.BigEndian.Uint16(data[offset:])), len(data))
+	offset += 2
+
+	var extensions []rawExtension
+	for offset+4 <= end {
+		id := binary.BigEndian.Uint16(data[offset:])
+		length := int(binary.BigEndian.Uint16(data[offset+2:]))
+		offset += 4
+		if offset+length > end {
+			break
+		}
+		extensions = append(extensions, rawExtension{id: id, data: data[offset : offset+length]})
+		offset += length
+	}
+	return extensions
+}
+
+// goExtension returns the Go expression of one extension of a spec
+func goExtension(ext utls.TLSExtension, raw rawExtension) string {
+	switch e := ext.(type) {
+	case *utls.UtlsGREASEExtension:
+		if len(e.Body) > 0 {
+			return fmt.Sprintf("&utls.UtlsGREASEExtension{Body: %s}", goBytes("[]byte", e.Body))
+		}
+		return "&utls.UtlsGREASEExtension{}"
+	case *utls.SNIExtension:
+		return "&utls.SNIExtension{}"
+	case *utls.StatusRequestExtension:
+		return "&utls.StatusRequestExtension{}"
+	case *utls.SupportedCurvesExtension:
+		return "&utls.SupportedCurvesExtension{Curves: []utls.CurveID{\n" + goCurves(e.Curves) + "}}"
+	case *utls.SupportedPointsExtension:
+		return fmt.Sprintf("&utls.SupportedPointsExtension{SupportedPoints: %s}", goBytes("[]uint8", e.SupportedPoints))
+	case *utls.SignatureAlgorithmsExtension:
+		return "&utls.SignatureAlgorithmsExtension{SupportedSignatureAlgorithms: []utls.SignatureScheme{\n" +
+			goSignatureSchemes(e.SupportedSignatureAlgorithms) + "}}"
+	case *utls.SignatureAlgorithmsCertExtension:
+		return "&utls.SignatureAlgorithmsCertExtension{SupportedSignatureAlgorithms: []utls.SignatureScheme{\n" +
+			goSignatureSchemes(e.SupportedSignatureAlgorithms) + "}}"
+	case *utls.FakeDelegatedCredentialsExtension:
+		return "&utls.FakeDelegatedCredentialsExtension{SupportedSignatureAlgorithms: []utls.SignatureScheme{\n" +
+			goSignatureSchemes(e.SupportedSignatureAlgorithms) + "}}"
+	case *utls.ALPNExtension:
+		return fmt.Sprintf("&utls.ALPNExtension{AlpnProtocols: %s}", goStrings(e.AlpnProtocols))
+	case *utls.ApplicationSettingsExtension:
+		return fmt.Sprintf("&utls.ApplicationSettingsExtension{SupportedProtocols: %s}", goStrings(e.SupportedProtocols))
+	case *utls.ApplicationSettingsExtensionNew:
+		return fmt.Sprintf("&utls.ApplicationSettingsExtensionNew{SupportedProtocols: %s}", goStrings(e.SupportedProtocols))
+	case *utls.NPNExtension:
+		return fmt.Sprintf("&utls.NPNExtension{NextProtos: %s}", goStrings(e.NextProtos))
+	case *utls.SCTExtension:
+		return "&utls.SCTExtension{}"
+	case *utls.ExtendedMasterSecretExtension:
+		return "&utls.ExtendedMasterSecretExtension{}"
+	case *utls.SessionTicketExtension:
+		return "&utls.SessionTicketExtension{}"
+	case *utls.UtlsPaddingExtension:
+		return "&utls.UtlsPaddingExtension{GetPaddingLen: utls.BoringPaddingStyle}"
+	case *utls.UtlsCompressCertExtension:
+		algorithms := make([]string, 0, len(e.Algorithms))
+		for _, alg := range e.Algorithms {
+			name, ok := certCompressionNames[uint16(alg)]
+			if !ok {
+				name = fmt.Sprintf("utls.CertCompressionAlgo(0x%04x)", uint16(alg))
+			}
+			algorithms = append(algorithms, name)
+		}
+		return fmt.Sprintf("&utls.UtlsCompressCertExtension{Algorithms: []utls.CertCompressionAlgo{%s}}", strings.Join(algorithms, ", "))
+	case *utls.FakeRecordSizeLimitExtension:
+		return fmt.Sprintf("&utls.FakeRecordSizeLimitExtension{Limit: 0x%04x}", e.Limit)
+	case *utls.SupportedVersionsExtension:
+		versions := make([]string, 0, len(e.Versions))
+		for _, v := range e.Versions {
+			versions = append(versions, goVersion(v))
+		}
+		return fmt.Sprintf("&utls.SupportedVersionsExtension{Versions: []uint16{%s}}", strings.Join(versions, ", "))
+	case *utls.PSKKeyExchangeModesExtension:
+		return fmt.Sprintf("&utls.PSKKeyExchangeModesExtension{Modes: %s}", goBytes("[]uint8", e.Modes))
+	case *utls.KeyShareExtension:
+		var b strings.Builder
+		b.WriteString("&utls.KeyShareExtension{KeyShares: []utls.KeyShare{\n")
+		for _, share := range e.KeyShares {
+			if isGREASE(uint16(share.Group)) {
+				b.WriteString("{Group: utls.CurveID(utls.GREASE_PLACEHOLDER), Data: []byte{0}},\n")
+			} else {
+				fmt.Fprintf(&b, "{Group: utls.CurveID(0x%04x)}, // %s\n", uint16(share.Group), tls.CurveID(share.Group))
+			}
+		}
+		b.WriteString("}}")
+		return b.String()
+	case *utls.RenegotiationInfoExtension:
+		return "&utls.RenegotiationInfoExtension{Renegotiation: utls.RenegotiateOnceAsClient}"
+	case *utls.GREASEEncryptedClientHelloExtension:
+		return "utls.BoringGREASEECH()"
+	case utls.PreSharedKeyExtension:
+		return "&utls.UtlsPreSharedKeyExtension{}"
+	case *utls.FakeChannelIDExtension:
+		return fmt.Sprintf("&utls.FakeChannelIDExtension{OldExtensionID: %t}", e.OldExtensionID)
+	}
+	return fmt.Sprintf("&utls.GenericExtension{Id: 0x%04x, Data: %s}", raw.id, goBytes("[]byte", raw.data))
+}
+
+func goVersion(version uint16) string {
+	if isGREASE(version) {
+		return "utls.GREASE_PLACEHOLDER"
+	}
+	if name, ok := tlsVersionNames[version]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", version)
+}
+
+func goCurves(curves []utls.CurveID) string {
+	var b strings.Builder
+	for _, curve := range curves {
+		if isGREASE(uint16(curve)) {
+			b.WriteString("utls.GREASE_PLACEHOLDER,\n")
+		} else {
+			fmt.Fprintf(&b, "0x%04x, // %s\n", uint16(curve), tls.CurveID(curve))
+		}
+	}
+	return b.String()
+}
+
+func goSignatureSchemes(schemes []utls.SignatureScheme) string {
+	var b strings.Builder
+	for _, scheme := range schemes {
+		fmt.Fprintf(&b, "0x%04x, // %s\n", uint16(scheme), tls.SignatureScheme(scheme))
+	}
+	return b.String()
+}
+
+func goStrings(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = fmt.Sprintf("%q", v)
+	}
+	return "[]string{" + strings.Join(quoted, ", ") + "}"
+}
+
+func goBytes(typ string, values []byte) string {
+	hex := make([]string, len(values))
+	for i, v := range values {
+		hex[i] = fmt.Sprintf("0x%02x", v)
+	}
+	return typ + "{" + strings.Join(hex, ", ") + "}"
+}
@@ -0,0 +1,124 @@
+package clientHello
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// emptyJA4Hash stands in for the hash of an empty list
+const emptyJA4Hash = "000000000000"
+
+// ComputeJA4 sets the JA4 fingerprint, which sorts the cipher suites and
+// extensions so that clients shuffling them still match
+func (f *TLSFingerprint) ComputeJA4() {
+	ciphers := withoutGREASE(f.CipherSuites)
+	extensions := withoutGREASE(f.Extensions)
+
+	sni := "i"
+	if slices.Contains(extensions, ExtensionSNI) {
+		sni = "d"
+	}
+	alpn := ""
+	if len(f.ALPNProtocols) > 0 {
+		alpn = f.ALPNProtocols[0]
+	}
+
+	version := f.TLSVersion
+	if supported := withoutGREASE(f.SupportedVersions); len(supported) > 0 {
+		version = slices.Max(supported)
+	}
+
+	a := fmt.Sprintf("t%s%s%s%s%s", ja4Version(version), sni,
+		ja4Count(len(ciphers)), ja4Count(len(extensions)), ja4ALPN(alpn))
+
+	sortedCiphers := slices.Clone(ciphers)
+	slices.Sort(sortedCiphers)
+	cipherList := hexList(sortedCiphers)
+
+	// SNI and ALPN are already covered by the first part
+	sortedExtensions := make([]uint16, 0, len(extensions))
+	for _, e := range extensions {
+		if e != ExtensionSNI && e != ExtensionALPN {
+			sortedExtensions = append(sortedExtensions, e)
+		}
+	}
+	slices.Sort(sortedExtensions)
+	extensionList := hexList(sortedExtensions)
+	if algs := hexList(f.SignatureAlgs); algs != "" {
+		extensionList += "_" + algs
+	}
+
+	f.JA4 = a + "_" + ja4Hash(cipherList) + "_" + ja4Hash(extensionList)
+	f.JA4Raw = a + "_" + cipherList + "_" + extensionList
+}
+
+// ja4Version names a TLS version the way JA4 and JA4S do
+func ja4Version(version uint16) string {
+	switch version {
+	case 0x0304:
+		return "13"
+	case 0x0303:
+		return "12"
+	case 0x0302:
+		return "11"
+	case 0x0301:
+		return "10"
+	case 0x0300:
+		return "s3"
+	case 0x0002:
+		return "s2"
+	case 0xfeff:
+		return "d1"
+	case 0xfefd:
+		return "d2"
+	case 0xfefc:
+		return "d3"
+	default:
+		return "00"
+	}
+}
+
+// ja4Count writes a list length as two digits, capped at 99
+func ja4Count(n int) string {
+	return fmt.Sprintf("%02d", min(n, 99))
+}
+
+// ja4ALPN keeps the first and last character of an ALPN protocol, falling back
+// to its hex form when either is not alphanumeric
+func ja4ALPN(protocol string) string {
+	if protocol == "" {
+		return "00"
+	}
+	first, last := protocol[0], protocol[len(protocol)-1]
+	if !isAlphanumeric(first) || !isAlphanumeric(last) {
+		encoded := fmt.Sprintf("%x", protocol)
+		return encoded[:1] + encoded[len(encoded)-1:]
+	}
+	return string([]byte{first, last})
+}
+
+func isAlphanumeric(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// hexList writes values as comma separated four digit hex, skipping GREASE
+func hexList(values []uint16) string {
+	parts := make([]string, 0, len(values))
+	for _, v := range values {
+		if !isGREASE(v) {
+			parts = append(parts, fmt.Sprintf("%04x", v))
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// ja4Hash returns the first 12 hex characters of the SHA-256 of list
+func ja4Hash(list string) string {
+	if list == "" {
+		return emptyJA4Hash
+	}
+	sum := sha256.Sum256([]byte(list))
+	return fmt.Sprintf("%x", sum[:6])
+}
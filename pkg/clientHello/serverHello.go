@@ -0,0 +1,114 @@
+package clientHello
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ServerHelloFingerprint describes the ServerHello a server answered with
+type ServerHelloFingerprint struct {
+	// Version is the legacy version field, 0x0303 for TLS 1.2 and 1.3
+	Version     uint16   `json:"version"`
+	CipherSuite uint16   `json:"cipher_suite"`
+	Extensions  []uint16 `json:"extensions"`
+	// SupportedVersion is the version picked through supported_versions,
+	// zero before TLS 1.3
+	SupportedVersion uint16 `json:"supported_version,omitempty"`
+	ALPN             string `json:"alpn,omitempty"`
+
+	JA3S     string `json:"ja3s"`
+	JA3SHash string `json:"ja3s_hash"`
+	JA4S     string `json:"ja4s"`
+}
+
+// ParseServerHello fingerprints the first handshake record a server sent
+func ParseServerHello(record []byte) (*ServerHelloFingerprint, error) {
+	if len(record) < 5 || record[0] != 0x16 {
+		return nil, errors.New("not a valid server hello: invalid record type")
+	}
+	length := int(binary.BigEndian.Uint16(record[3:5]))
+	if length+5 > len(record) {
+		return nil, errors.New("not a valid server hello: truncated record")
+	}
+	data := record[5 : length+5]
+	if len(data) < 4 || data[0] != 0x02 {
+		return nil, errors.New("not a valid server hello: invalid handshake type")
+	}
+	helloLen := int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+	if helloLen+4 > len(data) {
+		return nil, errors.New("not a valid server hello: truncated message")
+	}
+	data = data[4 : helloLen+4]
+
+	// version, random and the session ID length
+	if len(data) < 35 {
+		return nil, errors.New("not a valid server hello: too short")
+	}
+	fp := &ServerHelloFingerprint{Version: binary.BigEndian.Uint16(data[0:2])}
+	offset := 35 + int(data[34])
+
+	// cipher suite and compression method
+	if offset+3 > len(data) {
+		return nil, errors.New("not a valid server hello: too short")
+	}
+	fp.CipherSuite = binary.BigEndian.Uint16(data[offset : offset+2])
+	offset += 3
+
+	if offset+2 <= len(data) {
+		extensionsEnd := min(offset+2+int(binary.BigEndian.Uint16(data[offset:offset+2])), len(data))
+		offset += 2
+		for offset+4 <= extensionsEnd {
+			extType := binary.BigEndian.Uint16(data[offset : offset+2])
+			extLen := int(binary.BigEndian.Uint16(data[offset+2 : offset+4]))
+			offset += 4
+			if offset+extLen > extensionsEnd {
+				break
+			}
+			fp.Extensions = append(fp.Extensions, extType)
+			extData := data[offset : offset+extLen]
+
+			switch extType {
+			case ExtensionSupportedVersions:
+				if len(extData) == 2 {
+					fp.SupportedVersion = binary.BigEndian.Uint16(extData)
+				}
+			case ExtensionALPN:
+				if protocols := parseALPN(extData); len(protocols) > 0 {
+					fp.ALPN = protocols[0]
+				}
+			}
+			offset += extLen
+		}
+	}
+
+	fp.ComputeJA3S()
+	fp.ComputeJA4S()
+	return fp, nil
+}
+
+// ComputeJA3S sets the JA3S fingerprint: version, cipher suite and extensions
+// in the order the server sent them
+func (f *ServerHelloFingerprint) ComputeJA3S() {
+	exts := make([]string, 0, len(f.Extensions))
+	for _, e := range f.Extensions {
+		exts = append(exts, fmt.Sprintf("%d", e))
+	}
+
+	f.JA3S = fmt.Sprintf("%d,%d,%s", f.Version, f.CipherSuite, strings.Join(exts, "-"))
+	hash := md5.Sum([]byte(f.JA3S))
+	f.JA3SHash = fmt.Sprintf("%x", hash)
+}
+
+// ComputeJA4S sets the JA4S fingerprint, the server side counterpart of JA4
+func (f *ServerHelloFingerprint) ComputeJA4S() {
+	version := f.Version
+	if f.SupportedVersion != 0 {
+		version = f.SupportedVersion
+	}
+
+	f.JA4S = fmt.Sprintf("t%s%s%s_%04x_%s", ja4Version(version), ja4Count(len(f.Extensions)),
+		ja4ALPN(f.ALPN), f.CipherSuite, ja4Hash(hexList(f.Extensions)))
+}
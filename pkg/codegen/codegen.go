@@ -0,0 +1,199 @@
+package codegen
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"httpDebugger/pkg/sessiondata"
+)
+
+// Format names a language or tool to generate code for
+type Format string
+
+const (
+	FormatCurl           Format = "curl"
+	FormatGo             Format = "go"
+	FormatPythonRequests Format = "python-requests"
+	FormatPythonHTTPX    Format = "python-httpx"
+	FormatFetch          Format = "fetch"
+	FormatRaw            Format = "raw"
+)
+
+// Formats lists every format, the default first
+var Formats = []Format{FormatCurl, FormatGo, FormatPythonRequests, FormatPythonHTTPX, FormatFetch, FormatRaw}
+
+// DefaultBodyFile is where a cURL command reads a binary body from
+const DefaultBodyFile = "request-body.bin"
+
+// Options tunes the generated code
+type Options struct {
+	// BodyFile is the file a cURL command sends a binary body from, with
+	// --data-binary @file. The caller writes the body there
+	BodyFile string
+}
+
+type generator func(req *request, opts Options) (string, error)
+
+var generators = map[Format]generator{
+	FormatCurl:           curl,
+	FormatGo:             goHTTP,
+	FormatPythonRequests: pythonRequests,
+	FormatPythonHTTPX:    pythonHTTPX,
+	FormatFetch:          fetch,
+	FormatRaw:            raw,
+}
+
+// ParseFormat returns the format called name
+func ParseFormat(name string) (Format, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, format := range Formats {
+		if string(format) == name {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown format %q, use one of %s", name, FormatNames())
+}
+
+// FormatNames lists the formats for prompts and errors
+func FormatNames() string {
+	names := make([]string, len(Formats))
+	for i, format := range Formats {
+		names[i] = string(format)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Generate returns code that sends the request of session again, with its
+// headers in their original order where the target allows it
+func Generate(session *sessiondata.Session, format Format, opts Options) (string, error) {
+	if session == nil || session.Request == nil {
+		return "", errors.New("no request to generate code for")
+	}
+	switch session.Type {
+	case sessiondata.WebSocketSession:
+		return "", errors.New("WebSocket sessions cannot be converted to code")
+	case sessiondata.TunnelSession:
+		return "", errors.New("TCP tunnel sessions cannot be converted to code")
+	}
+
+	generate, ok := generators[format]
+	if !ok {
+		return "", fmt.Errorf("unknown format %q, use one of %s", format, FormatNames())
+	}
+	if opts.BodyFile == "" {
+		opts.BodyFile = DefaultBodyFile
+	}
+	return generate(newRequest(session), opts)
+}
+
+// IsBinary reports whether a body cannot be written as text
+func IsBinary(body string) bool {
+	if !utf8.ValidString(body) {
+		return true
+	}
+	for _, r := range body {
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' {
+			return true
+		}
+	}
+	return false
+}
+
+type header struct {
+	name  string
+	value string
+}
+
+// request is the part of a session the generators need
+type request struct {
+	method  string
+	url     string
+	http2   bool
+	headers []header
+	body    string
+	binary  bool
+}
+
+// hopByHopHeaders belong to the connection to the proxy and are not sent again
+var hopByHopHeaders = map[string]bool{
+	"connection":          true,
+	"keep-alive":          true,
+	"proxy-connection":    true,
+	"proxy-authorization": true,
+	"transfer-encoding":   true,
+	"upgrade":             true,
+	"te":                  true,
+}
+
+func newRequest(session *sessiondata.Session) *request {
+	data := session.Request
+	req := &request{
+		method: data.Method,
+		url:    data.URL,
+		http2:  session.Protocol == sessiondata.HTTP2Protocol,
+		body:   data.Body,
+		binary: IsBinary(data.Body),
+	}
+	if req.method == "" {
+		req.method = "GET"
+	}
+
+	if data.Headers == nil {
+		return req
+	}
+	for _, name := range data.Headers.Keys() {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(name, ":") || lower == "host" || lower == "content-length" || hopByHopHeaders[lower] {
+			continue
+		}
+		value, _ := data.Headers.Get(name)
+		switch v := value.(type) {
+		case []string:
+			for _, s := range v {
+				req.headers = append(req.headers, header{name, s})
+			}
+		case []interface{}:
+			for _, s := range v {
+				req.headers = append(req.headers, header{name, fmt.Sprintf("%v", s)})
+			}
+		default:
+			req.headers = append(req.headers, header{name, fmt.Sprintf("%v", v)})
+		}
+	}
+	return req
+}
+
+// joinedHeaders merges repeated headers for targets that take a map, joining
+// cookies with "; " and other values with ", "
+func (r *request) joinedHeaders() []header {
+	var joined []header
+	index := make(map[string]int)
+	for _, h := range r.headers {
+		lower := strings.ToLower(h.name)
+		i, seen := index[lower]
+		if !seen {
+			index[lower] = len(joined)
+			joined = append(joined, h)
+			continue
+		}
+		separator := ", "
+		if lower == "cookie" {
+			separator = "; "
+		}
+		joined[i].value += separator + h.value
+	}
+	return joined
+}
+
+// target returns the path, query and host of the request URL
+func (r *request) target() (path, host string) {
+	u, err := url.Parse(r.url)
+	if err != nil {
+		return r.url, ""
+	}
+	path = u.RequestURI()
+	return path, u.Host
+}
@@ -0,0 +1,221 @@
+package codegen
+
+import (
+	"go/parser"
+	"go/token"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"httpDebugger/pkg/sessiondata"
+	"httpDebugger/pkg/sortedMap"
+)
+
+// newSession builds a session whose headers are given as name, value pairs in order
+func newSession(method, url, body string, headers ...any) *sessiondata.Session {
+	order := sortedMap.New()
+	for i := 0; i+1 < len(headers); i += 2 {
+		order.Put(headers[i].(string), headers[i+1])
+	}
+	return &sessiondata.Session{
+		ID:       "test-id",
+		Protocol: sessiondata.HTTP11Protocol,
+		Request: &sessiondata.RequestData{
+			Method:  method,
+			URL:     url,
+			Body:    body,
+			Headers: order,
+			Cookies: map[string]string{},
+		},
+	}
+}
+
+func generate(t *testing.T, session *sessiondata.Session, format Format) string {
+	t.Helper()
+	code, err := Generate(session, format, Options{})
+	if err != nil {
+		t.Fatalf("Generate(%s) failed: %v", format, err)
+	}
+	return code
+}
+
+func TestCurl(t *testing.T) {
+	tests := []struct {
+		name     string
+		session  *sessiondata.Session
+		expected string
+	}{
+		{
+			name: "Simple GET request",
+			session: newSession("GET", "https://api.example.com/users", "",
+				"Accept", "application/json", "User-Agent", "TestAgent/1.0"),
+			expected: "curl -X GET 'https://api.example.com/users' -H 'Accept: application/json' -H 'User-Agent: TestAgent/1.0'",
+		},
+		{
+			name: "POST request with body",
+			session: newSession("POST", "https://api.example.com/users", `{"name":"John","email":"john@example.com"}`,
+				"Content-Type", "application/json", "Authorization", "Bearer token123"),
+			expected: `curl -X POST 'https://api.example.com/users' -H 'Content-Type: application/json' -H 'Authorization: Bearer token123' --data-raw '{"name":"John","email":"john@example.com"}'`,
+		},
+		{
+			name: "Host and Content-Length are skipped",
+			session: newSession("GET", "https://api.example.com/data", "",
+				"Host", "api.example.com", "Accept", "application/json", "Content-Length", "123"),
+			expected: "curl -X GET 'https://api.example.com/data' -H 'Accept: application/json'",
+		},
+		{
+			name:     "Request with empty headers",
+			session:  newSession("GET", "https://api.example.com/simple", ""),
+			expected: "curl -X GET 'https://api.example.com/simple'",
+		},
+		{
+			name: "Body with single quotes",
+			session: newSession("POST", "https://api.example.com/test", `{"message":"It's a test"}`,
+				"Content-Type", "application/json"),
+			expected: `curl -X POST 'https://api.example.com/test' -H 'Content-Type: application/json' --data-raw '{"message":"It'\''s a test"}'`,
+		},
+		{
+			name:     "Header with single quotes",
+			session:  newSession("GET", "https://api.example.com/test", "", "Custom-Header", "Value with 'quotes'"),
+			expected: `curl -X GET 'https://api.example.com/test' -H 'Custom-Header: Value with '\''quotes'\'''`,
+		},
+		{
+			name:     "Body with newlines",
+			session:  newSession("POST", "https://api.example.com/test", "{\n  \"name\": \"test\"\n}"),
+			expected: "curl -X POST 'https://api.example.com/test' --data-raw '{\n  \"name\": \"test\"\n}'",
+		},
+		{
+			name:     "Body starting with @ is not a file",
+			session:  newSession("POST", "https://api.example.com/test", "@/etc/passwd"),
+			expected: "curl -X POST 'https://api.example.com/test' --data-raw '@/etc/passwd'",
+		},
+		{
+			name: "Repeated and non-string headers",
+			session: newSession("POST", "https://api.example.com/data", "test data",
+				"Rate-Limit", []string{"100", "per-hour"}, "Custom-Bool", true, "Content-Length", 9),
+			expected: "curl -X POST 'https://api.example.com/data' -H 'Rate-Limit: 100' -H 'Rate-Limit: per-hour' -H 'Custom-Bool: true' --data-raw 'test data'",
+		},
+		{
+			name: "Cookies and compression",
+			session: newSession("GET", "https://example.com/", "",
+				"Accept-Encoding", "gzip, br", "Cookie", "a=1; b='2'", "Connection", "keep-alive"),
+			expected: `curl -X GET 'https://example.com/' -H 'Accept-Encoding: gzip, br' -b 'a=1; b='\''2'\''' --compressed`,
+		},
+		{
+			name:     "Binary body",
+			session:  newSession("PUT", "https://example.com/upload", "\x89PNG\r\n\x1a\n\x00"),
+			expected: "curl -X PUT 'https://example.com/upload' --data-binary '@request-body.bin'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := generate(t, tt.session, FormatCurl); got != tt.expected {
+				t.Errorf("curl =\n%s\nwant\n%s", got, tt.expected)
+			}
+		})
+	}
+
+	h2 := newSession("GET", "https://example.com/", "")
+	h2.Protocol = sessiondata.HTTP2Protocol
+	if got := generate(t, h2, FormatCurl); !strings.HasPrefix(got, "curl --http2 ") {
+		t.Errorf("HTTP/2 curl = %s, want --http2", got)
+	}
+	if got, _ := Generate(newSession("PUT", "https://example.com/", "\x00"), FormatCurl, Options{BodyFile: "it's.bin"}); !strings.HasSuffix(got, `--data-binary '@it'\''s.bin'`) {
+		t.Errorf("curl with a body file = %s", got)
+	}
+}
+
+func TestCurlSingleQuotes(t *testing.T) {
+	session := newSession("POST", "https://example.com/", `{"note":"it's 'quoted'"}`, "X-Note", "don't 'panic'")
+	code := generate(t, session, FormatCurl)
+
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no shell to run the command with")
+	}
+	// The shell must hand curl the body and header unchanged
+	out, err := exec.Command(sh, "-c", `printf '%s\n'`+strings.TrimPrefix(code, "curl")).Output()
+	if err != nil {
+		t.Fatalf("running %s failed: %v", code, err)
+	}
+	want := []string{"-X", "POST", "https://example.com/", "-H", "X-Note: don't 'panic'", "--data-raw", `{"note":"it's 'quoted'"}`}
+	if got := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n"); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("shell arguments = %q, want %q", got, want)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	session := newSession("POST", "https://api.example.com/items?q=1", "{\"name\": \"It's \\\"quoted\\\"\"}\n",
+		":authority", "api.example.com",
+		"Content-Type", "application/json",
+		"X-Multi", []string{"a", "b"},
+		"Accept-Encoding", "gzip",
+		"Cookie", "a=1",
+	)
+
+	source := generate(t, session, FormatGo)
+	if _, err := parser.ParseFile(token.NewFileSet(), "main.go", source, 0); err != nil {
+		t.Fatalf("Go code does not parse: %v\n%s", err, source)
+	}
+	if strings.Contains(source, "Accept-Encoding") || strings.Contains(source, ":authority") {
+		t.Errorf("Go code should leave out Accept-Encoding and pseudo headers:\n%s", source)
+	}
+	if strings.Index(source, `"Content-Type"`) > strings.Index(source, `"X-Multi", "b"`) {
+		t.Errorf("Go code lost the header order:\n%s", source)
+	}
+
+	requests := generate(t, session, FormatPythonRequests)
+	for _, want := range []string{
+		`"X-Multi": "a, b",`,
+		`data = "{\"name\": \"It's \\\"quoted\\\"\"}\n".encode()`,
+		`requests.request("POST", url, headers=headers, data=data)`,
+	} {
+		if !strings.Contains(requests, want) {
+			t.Errorf("python-requests is missing %s:\n%s", want, requests)
+		}
+	}
+
+	httpx := generate(t, newSession("PUT", "https://example.com/", "\x00\xff\"", "X-Multi", []string{"a", "b"}), FormatPythonHTTPX)
+	for _, want := range []string{`("X-Multi", "a"),`, `("X-Multi", "b"),`, `content = b"\x00\xff\""`, "httpx.Client(http2=False)"} {
+		if !strings.Contains(httpx, want) {
+			t.Errorf("python-httpx is missing %s:\n%s", want, httpx)
+		}
+	}
+
+	js := generate(t, session, FormatFetch)
+	for _, want := range []string{`"Cookie": "a=1",`, `body: "{\"name\": \"It's \\\"quoted\\\"\"}\n",`, `method: "POST",`} {
+		if !strings.Contains(js, want) {
+			t.Errorf("fetch is missing %s:\n%s", want, js)
+		}
+	}
+
+	raw := generate(t, session, FormatRaw)
+	if !strings.HasPrefix(raw, "POST /items?q=1 HTTP/1.1\r\nHost: api.example.com\r\nContent-Type: application/json\r\n") ||
+		!strings.Contains(raw, "X-Multi: a\r\nX-Multi: b\r\n") ||
+		!strings.HasSuffix(raw, "Content-Length: 28\r\n\r\n"+session.Request.Body) {
+		t.Errorf("raw =\n%q", raw)
+	}
+
+	ws := newSession("GET", "wss://example.com/socket", "")
+	ws.Type = sessiondata.WebSocketSession
+	if _, err := Generate(ws, FormatCurl, Options{}); err == nil {
+		t.Errorf("Generate() of a WebSocket session should fail")
+	}
+	if _, err := ParseFormat("cobol"); err == nil {
+		t.Errorf("ParseFormat() should reject unknown formats")
+	}
+}
+
+func BenchmarkCurl(b *testing.B) {
+	session := newSession("POST", "https://api.example.com/benchmark", `{"data":"benchmark test"}`,
+		"Content-Type", "application/json",
+		"Authorization", "Bearer token123",
+		"Accept", "application/json",
+		"User-Agent", "BenchmarkAgent/1.0")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Generate(session, FormatCurl, Options{})
+	}
+}
@@ -0,0 +1,64 @@
+package codegen
+
+import (
+	"strings"
+)
+
+// curl writes a single line shell command. Cookies go to -b, a compressed
+// response is decoded with --compressed and binary bodies are read from a file
+func curl(req *request, opts Options) (string, error) {
+	args := []string{"curl"}
+	if req.http2 {
+		args = append(args, "--http2")
+	}
+	method := req.method
+	if !isToken(method) {
+		method = shellQuote(method)
+	}
+	args = append(args, "-X", method, shellQuote(req.url))
+
+	compressed := false
+	var cookies []string
+	for _, h := range req.headers {
+		switch strings.ToLower(h.name) {
+		case "cookie":
+			cookies = append(cookies, h.value)
+			continue
+		case "accept-encoding":
+			compressed = true
+		}
+		args = append(args, "-H", shellQuote(h.name+": "+h.value))
+	}
+	if len(cookies) > 0 {
+		args = append(args, "-b", shellQuote(strings.Join(cookies, "; ")))
+	}
+	if compressed {
+		args = append(args, "--compressed")
+	}
+
+	switch {
+	case req.body == "":
+	case req.binary:
+		args = append(args, "--data-binary", shellQuote("@"+opts.BodyFile))
+	default:
+		args = append(args, "--data-raw", shellQuote(req.body))
+	}
+
+	return strings.Join(args, " "), nil
+}
+
+// shellQuote quotes s for POSIX shells. Single quotes are closed, escaped and
+// reopened, so 'It'\”s' reads as It's
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// isToken reports whether s is safe unquoted, like the usual HTTP methods
+func isToken(s string) bool {
+	for _, r := range s {
+		if !(r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,53 @@
+package codegen
+
+import (
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+)
+
+// goHTTP writes a Go program using net/http. Go sends headers sorted by name
+// and handles Accept-Encoding itself, so the order is only kept in the source
+func goHTTP(req *request, opts Options) (string, error) {
+	var b strings.Builder
+	b.WriteString("package main\n\nimport (\n\t\"fmt\"\n\t\"io\"\n\t\"net/http\"\n")
+	if req.body != "" {
+		b.WriteString("\t\"strings\"\n")
+	}
+	b.WriteString(")\n\nfunc main() {\n")
+
+	body := "nil"
+	if req.body != "" {
+		fmt.Fprintf(&b, "body := strings.NewReader(%s)\n", goString(req.body))
+		body = "body"
+	}
+	fmt.Fprintf(&b, "req, err := http.NewRequest(%s, %s, %s)\n", strconv.Quote(req.method), strconv.Quote(req.url), body)
+	b.WriteString("if err != nil {\npanic(err)\n}\n")
+
+	for _, h := range req.headers {
+		if strings.EqualFold(h.name, "Accept-Encoding") {
+			continue
+		}
+		fmt.Fprintf(&b, "req.Header.Add(%s, %s)\n", strconv.Quote(h.name), strconv.Quote(h.value))
+	}
+
+	b.WriteString("\nresp, err := http.DefaultClient.Do(req)\nif err != nil {\npanic(err)\n}\n")
+	b.WriteString("defer resp.Body.Close()\n\n")
+	b.WriteString("data, err := io.ReadAll(resp.Body)\nif err != nil {\npanic(err)\n}\n")
+	b.WriteString("fmt.Println(resp.Status)\nfmt.Println(string(data))\n}\n")
+
+	source, err := format.Source([]byte(b.String()))
+	if err != nil {
+		return "", fmt.Errorf("formatting generated code: %w", err)
+	}
+	return string(source), nil
+}
+
+// goString quotes s, as a raw string when that keeps it readable
+func goString(s string) string {
+	if strconv.CanBackquote(s) && strings.ContainsAny(s, "\"\n") {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
@@ -0,0 +1,47 @@
+package codegen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// fetch writes JavaScript for the Fetch API, runnable as an ES module in Node
+// 18+ or a browser console. Browsers refuse to set some headers, like Cookie
+func fetch(req *request, opts Options) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "const response = await fetch(%s, {\n", jsString(req.url))
+	fmt.Fprintf(&b, "  method: %s,\n", jsString(req.method))
+	b.WriteString("  headers: {\n")
+	for _, h := range req.joinedHeaders() {
+		fmt.Fprintf(&b, "    %s: %s,\n", jsString(h.name), jsString(h.value))
+	}
+	b.WriteString("  },\n")
+
+	switch {
+	case req.body == "":
+	case req.binary:
+		values := make([]string, len(req.body))
+		for i := 0; i < len(req.body); i++ {
+			values[i] = strconv.Itoa(int(req.body[i]))
+		}
+		fmt.Fprintf(&b, "  body: new Uint8Array([%s]),\n", strings.Join(values, ", "))
+	default:
+		fmt.Fprintf(&b, "  body: %s,\n", jsString(req.body))
+	}
+
+	b.WriteString("});\n\nconsole.log(response.status);\nconsole.log(await response.text());\n")
+	return b.String(), nil
+}
+
+// jsString returns a JavaScript string literal. JSON strings are valid
+// JavaScript since ES2019
+func jsString(s string) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,125 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+)
+
+// pythonRequests writes a script using requests, which only speaks HTTP/1.1
+// and takes headers as a dict
+func pythonRequests(req *request, opts Options) (string, error) {
+	var b strings.Builder
+	b.WriteString("import requests\n\n")
+	fmt.Fprintf(&b, "url = %s\n", pyString(req.url))
+	b.WriteString("headers = {\n")
+	for _, h := range req.joinedHeaders() {
+		fmt.Fprintf(&b, "    %s: %s,\n", pyString(h.name), pyString(h.value))
+	}
+	b.WriteString("}\n")
+
+	args := "headers=headers"
+	if req.body != "" {
+		fmt.Fprintf(&b, "data = %s\n", pyBody(req))
+		args += ", data=data"
+	}
+
+	if req.http2 {
+		b.WriteString("\n# requests speaks HTTP/1.1 only, use httpx to send this over HTTP/2\n")
+	} else {
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "response = requests.request(%s, url, %s)\n", pyString(req.method), args)
+	b.WriteString("print(response.status_code)\nprint(response.text)\n")
+	return b.String(), nil
+}
+
+// pythonHTTPX writes a script using httpx, whose header list keeps the order
+// and repeated headers
+func pythonHTTPX(req *request, opts Options) (string, error) {
+	var b strings.Builder
+	if req.http2 {
+		b.WriteString("# HTTP/2 needs the h2 package: pip install 'httpx[http2]'\n")
+	}
+	b.WriteString("import httpx\n\n")
+	fmt.Fprintf(&b, "url = %s\n", pyString(req.url))
+	b.WriteString("headers = [\n")
+	for _, h := range req.headers {
+		fmt.Fprintf(&b, "    (%s, %s),\n", pyString(h.name), pyString(h.value))
+	}
+	b.WriteString("]\n")
+
+	args := "headers=headers"
+	if req.body != "" {
+		fmt.Fprintf(&b, "content = %s\n", pyBody(req))
+		args += ", content=content"
+	}
+
+	http2 := "False"
+	if req.http2 {
+		http2 = "True"
+	}
+	fmt.Fprintf(&b, "\nwith httpx.Client(http2=%s) as client:\n", http2)
+	fmt.Fprintf(&b, "    response = client.request(%s, url, %s)\n", pyString(req.method), args)
+	b.WriteString("print(response.http_version, response.status_code)\nprint(response.text)\n")
+	return b.String(), nil
+}
+
+// pyBody returns the body as bytes, so that requests does not encode it as
+// Latin-1
+func pyBody(req *request) string {
+	if req.binary {
+		return pyBytes(req.body)
+	}
+	return pyString(req.body) + ".encode()"
+}
+
+// pyString returns a double quoted Python string literal
+func pyString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '"':
+			b.WriteString(`\"`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			switch {
+			case r < 0x20 || r == 0x7f:
+				fmt.Fprintf(&b, `\x%02x`, r)
+			case r == 0x2028 || r == 0x2029:
+				fmt.Fprintf(&b, `\u%04x`, r)
+			default:
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// pyBytes returns a Python bytes literal holding s byte for byte
+func pyBytes(s string) string {
+	var b strings.Builder
+	b.WriteString(`b"`)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' || c == '"':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c >= 0x20 && c < 0x7f:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, `\x%02x`, c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,29 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+
+	"httpDebugger/pkg/sessiondata"
+)
+
+// raw writes the request as HTTP/1.1 text with CRLF line endings, ready for
+// netcat or openssl s_client
+func raw(req *request, opts Options) (string, error) {
+	path, host := req.target()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s\r\n", req.method, path, sessiondata.HTTP11Protocol)
+	if host != "" {
+		fmt.Fprintf(&b, "Host: %s\r\n", host)
+	}
+	for _, h := range req.headers {
+		fmt.Fprintf(&b, "%s: %s\r\n", h.name, h.value)
+	}
+	if req.body != "" {
+		fmt.Fprintf(&b, "Content-Length: %d\r\n", len(req.body))
+	}
+	b.WriteString("\r\n")
+	b.WriteString(req.body)
+	return b.String(), nil
+}
@@ -0,0 +1,366 @@
+package fingerprintDB
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"httpDebugger/pkg/sessiondata"
+)
+
+// MinConfidence is the lowest confidence a match needs to be reported
+const MinConfidence = 0.25
+
+// How much each fingerprint counts towards a match. JA4 ignores the extension
+// shuffling of recent browsers, so it weighs more than JA3
+const (
+	weightJA4         = 0.35
+	weightJA3         = 0.25
+	weightHTTP2       = 0.25
+	weightHeaderOrder = 0.15
+
+	// minEvidence keeps entries that define little, such as a header order
+	// alone, from ever reaching a high confidence
+	minEvidence = 0.5
+)
+
+//go:embed builtin.json
+var builtinEntries []byte
+
+// ErrNoFingerprint is returned when labeling a session that carries nothing
+// to identify its client by
+var ErrNoFingerprint = errors.New("session has no fingerprint to label")
+
+// Entry maps the fingerprints of one client to its name. Empty fields are
+// not compared
+type Entry struct {
+	Name string `json:"name"`
+	// JA3 is the JA3 hash
+	JA3 string `json:"ja3,omitempty"`
+	JA4 string `json:"ja4,omitempty"`
+	// HTTP2 is the Akamai HTTP/2 fingerprint string
+	HTTP2 string `json:"http2,omitempty"`
+	// HeaderOrder lists lower case header names in the order the client sends
+	// them, without Host and HTTP/2 pseudo headers
+	HeaderOrder []string `json:"header_order,omitempty"`
+
+	builtin bool
+}
+
+func (e *Entry) empty() bool {
+	return e.JA3 == "" && e.JA4 == "" && e.HTTP2 == "" && len(e.HeaderOrder) == 0
+}
+
+// sameFingerprints reports whether both entries describe the same client
+func (e *Entry) sameFingerprints(other *Entry) bool {
+	return e.JA3 == other.JA3 && e.JA4 == other.JA4 && e.HTTP2 == other.HTTP2 &&
+		strings.Join(e.HeaderOrder, ",") == strings.Join(other.HeaderOrder, ",")
+}
+
+// Database maps known fingerprints to client names. Entries added by the
+// user come before the built-in ones and win ties
+type Database struct {
+	mu      sync.RWMutex
+	entries []*Entry
+	path    string
+}
+
+// New returns a database holding only entries
+func New(entries ...Entry) *Database {
+	db := &Database{}
+	for _, entry := range entries {
+		db.Add(entry)
+	}
+	return db
+}
+
+// Builtin returns a database of common browsers, HTTP libraries and tools
+func Builtin() *Database {
+	var entries []*Entry
+	if err := json.Unmarshal(builtinEntries, &entries); err != nil {
+		panic(fmt.Sprintf("parsing built-in fingerprints: %v", err))
+	}
+	for _, entry := range entries {
+		entry.builtin = true
+	}
+	return &Database{entries: entries}
+}
+
+// Open returns the built-in database extended with the entries stored in
+// path. Labels are saved back to path, which is created on the first one
+func Open(path string) (*Database, error) {
+	db := Builtin()
+	db.path = path
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return db, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if _, err := db.Import(f); err != nil {
+		return nil, fmt.Errorf("loading %s: %w", path, err)
+	}
+	return db, nil
+}
+
+// Path returns the file labels are saved to, empty when they are kept in memory
+func (db *Database) Path() string {
+	return db.path
+}
+
+// Add stores a user entry, replacing one with the same fingerprints
+func (db *Database) Add(entry Entry) error {
+	entry.Name = strings.TrimSpace(entry.Name)
+	if entry.Name == "" {
+		return errors.New("fingerprint entry needs a name")
+	}
+	if entry.empty() {
+		return fmt.Errorf("fingerprint entry %q has no fingerprint", entry.Name)
+	}
+	for i, name := range entry.HeaderOrder {
+		entry.HeaderOrder[i] = strings.ToLower(name)
+	}
+	entry.builtin = false
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i, existing := range db.entries {
+		if existing.sameFingerprints(&entry) {
+			db.entries = append(db.entries[:i], db.entries[i+1:]...)
+			break
+		}
+	}
+	db.entries = append([]*Entry{&entry}, db.entries...)
+	return nil
+}
+
+// Entries returns a copy of every entry, user entries first
+func (db *Database) Entries() []Entry {
+	if db == nil {
+		return nil
+	}
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	entries := make([]Entry, len(db.entries))
+	for i, entry := range db.entries {
+		entries[i] = *entry
+	}
+	return entries
+}
+
+// Label names the client that produced session and saves the database when it
+// has a path
+func (db *Database) Label(name string, session *sessiondata.Session) (*Entry, error) {
+	entry := EntryFromSession(session)
+	if entry.empty() {
+		return nil, ErrNoFingerprint
+	}
+	entry.Name = name
+	if err := db.Add(entry); err != nil {
+		return nil, err
+	}
+	if err := db.Save(); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Import adds the entries of a JSON array as user entries and returns how
+// many were read
+func (db *Database) Import(r io.Reader) (int, error) {
+	var entries []Entry
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return 0, fmt.Errorf("parsing fingerprints: %w", err)
+	}
+	// Add puts each entry first, so go backwards to keep the file's order
+	for i := len(entries) - 1; i >= 0; i-- {
+		if err := db.Add(entries[i]); err != nil {
+			return 0, err
+		}
+	}
+	return len(entries), nil
+}
+
+// Export writes every entry as a JSON array that Import reads back
+func (db *Database) Export(w io.Writer) error {
+	return writeEntries(w, db.Entries())
+}
+
+// Save writes the user entries to the database's path, if any
+func (db *Database) Save() error {
+	if db.path == "" {
+		return nil
+	}
+
+	var entries []Entry
+	for _, entry := range db.Entries() {
+		if !entry.builtin {
+			entries = append(entries, entry)
+		}
+	}
+
+	f, err := os.Create(db.path)
+	if err != nil {
+		return err
+	}
+	if err := writeEntries(f, entries); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeEntries(w io.Writer, entries []Entry) error {
+	if entries == nil {
+		entries = []Entry{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(entries)
+}
+
+// Identify returns the entry that best matches session, or nil when none
+// reaches MinConfidence
+func (db *Database) Identify(session *sessiondata.Session) *sessiondata.ClientMatch {
+	if db == nil || session == nil {
+		return nil
+	}
+	observed := EntryFromSession(session)
+	if observed.empty() {
+		return nil
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var best *sessiondata.ClientMatch
+	for _, entry := range db.entries {
+		match := score(entry, &observed)
+		if match.Confidence >= MinConfidence && (best == nil || match.Confidence > best.Confidence) {
+			best = match
+		}
+	}
+	return best
+}
+
+// Annotate sets the session's client to its best match
+func (db *Database) Annotate(session *sessiondata.Session) {
+	if db == nil || session == nil {
+		return
+	}
+	session.Client = db.Identify(session)
+}
+
+// score compares what an entry expects with what a session showed. Matched
+// fingerprints add their weight, partially for similar JA4s and header orders
+func score(entry, observed *Entry) *sessiondata.ClientMatch {
+	match := &sessiondata.ClientMatch{Name: entry.Name}
+	var matched, defined float64
+
+	if entry.JA4 != "" {
+		defined += weightJA4
+		if entry.JA4 == observed.JA4 {
+			matched += weightJA4
+			match.MatchedOn = append(match.MatchedOn, "JA4")
+		} else if observed.JA4 != "" && ja4Hashes(entry.JA4) == ja4Hashes(observed.JA4) {
+			// same cipher suites and extensions, e.g. with another ALPN
+			matched += weightJA4 * 0.8
+			match.MatchedOn = append(match.MatchedOn, "JA4 ciphers and extensions")
+		}
+	}
+	if entry.JA3 != "" {
+		defined += weightJA3
+		if entry.JA3 == observed.JA3 {
+			matched += weightJA3
+			match.MatchedOn = append(match.MatchedOn, "JA3")
+		}
+	}
+	if entry.HTTP2 != "" {
+		defined += weightHTTP2
+		if entry.HTTP2 == observed.HTTP2 {
+			matched += weightHTTP2
+			match.MatchedOn = append(match.MatchedOn, "HTTP/2")
+		}
+	}
+	if len(entry.HeaderOrder) > 0 {
+		defined += weightHeaderOrder
+		similarity := float64(commonSubsequence(entry.HeaderOrder, observed.HeaderOrder)) / float64(len(entry.HeaderOrder))
+		if similarity > 0 {
+			matched += weightHeaderOrder * similarity
+			match.MatchedOn = append(match.MatchedOn, "header order")
+		}
+	}
+
+	match.Confidence = matched / max(defined, minEvidence)
+	return match
+}
+
+// ja4Hashes drops the first part of a JA4, which holds the counts and ALPN
+func ja4Hashes(ja4 string) string {
+	_, hashes, _ := strings.Cut(ja4, "_")
+	return hashes
+}
+
+// commonSubsequence returns the length of the longest common subsequence of a
+// and b, which rewards headers sent in the same relative order
+func commonSubsequence(a, b []string) int {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(cur[j], prev[j+1])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// ignoredHeaders depend on the request rather than on the client
+var ignoredHeaders = map[string]bool{
+	"host":           true,
+	"cookie":         true,
+	"referer":        true,
+	"content-length": true,
+	"content-type":   true,
+	"authorization":  true,
+	"origin":         true,
+}
+
+// EntryFromSession collects the fingerprints a session carries, unnamed
+func EntryFromSession(session *sessiondata.Session) Entry {
+	var entry Entry
+	if fp := session.TLSFingerprint; fp != nil {
+		entry.JA3 = fp.JA3Hash
+		entry.JA4 = fp.JA4
+	}
+	if h2 := session.HTTP2Fingerprint; h2 != nil {
+		entry.HTTP2 = h2.Akamai
+	}
+	if session.Request != nil && session.Request.Headers != nil {
+		for _, name := range session.Request.Headers.Keys() {
+			name = strings.ToLower(name)
+			if !strings.HasPrefix(name, ":") && !ignoredHeaders[name] {
+				entry.HeaderOrder = append(entry.HeaderOrder, name)
+			}
+		}
+	}
+	return entry
+}
@@ -0,0 +1,115 @@
+package fingerprintDB
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"httpDebugger/pkg/clientHello"
+	"httpDebugger/pkg/http2Fingerprint"
+	"httpDebugger/pkg/sessiondata"
+	"httpDebugger/pkg/sortedMap"
+)
+
+func newSession(ja3, ja4, http2 string, headers ...string) *sessiondata.Session {
+	order := sortedMap.New()
+	for _, name := range headers {
+		order.Put(name, "value")
+	}
+	session := &sessiondata.Session{Request: &sessiondata.RequestData{Headers: order}}
+	if ja3 != "" || ja4 != "" {
+		session.TLSFingerprint = &clientHello.TLSFingerprint{JA3Hash: ja3, JA4: ja4}
+	}
+	if http2 != "" {
+		session.HTTP2Fingerprint = &http2Fingerprint.HTTP2Fingerprint{Akamai: http2}
+	}
+	return session
+}
+
+func TestBuiltinIdentify(t *testing.T) {
+	db := Builtin()
+	if len(db.Entries()) == 0 {
+		t.Fatalf("Builtin() has no entries")
+	}
+
+	chrome := newSession("e46fdad0be2bc335cc5f6ff30cff7e67", "t13d1516h2_8daaf6152771_02713d6af862",
+		"1:65536;2:0;4:6291456;6:262144|15663105|0|m,a,s,p",
+		"sec-ch-ua", "sec-ch-ua-mobile", "sec-ch-ua-platform", "upgrade-insecure-requests", "user-agent",
+		"accept", "sec-fetch-site", "sec-fetch-mode", "sec-fetch-user", "sec-fetch-dest", "accept-encoding", "accept-language")
+	match := db.Identify(chrome)
+	if match == nil || match.Name != "Chrome 120-131" || match.Confidence < 0.99 {
+		t.Fatalf("Identify() = %+v, want Chrome 120-131 with full confidence", match)
+	}
+
+	// curl asked for HTTP/1.1 only changes the ALPN part of its JA4
+	curl := newSession("0149f47eabf9a20d0893e2a44e5a6323", "t13d3112h1_e8f1e7e78f70_b26ce05bbdd6", "", "Host", "User-Agent", "Accept")
+	match = db.Identify(curl)
+	if match == nil || match.Name != "curl 7.88 / OpenSSL 3.0" || match.Confidence >= 1 {
+		t.Errorf("Identify() = %+v, want a partial curl match", match)
+	}
+
+	// a header order alone is weak evidence
+	requests := newSession("", "", "", "Host", "User-Agent", "Accept-Encoding", "Accept", "Connection")
+	match = db.Identify(requests)
+	if match == nil || match.Name != "python-requests" || match.Confidence > minEvidence {
+		t.Errorf("Identify() = %+v, want a low confidence python-requests match", match)
+	}
+
+	if match := db.Identify(newSession("unknown", "t13d0000h2_000000000000_000000000000", "")); match != nil {
+		t.Errorf("Identify() of an unknown client = %+v, want nil", match)
+	}
+
+	var nilDB *Database
+	nilDB.Annotate(chrome)
+}
+
+func TestLabelAndExport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fingerprints.json")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() of a missing file failed: %v", err)
+	}
+
+	app := newSession("0123456789abcdef0123456789abcdef", "t13d0000h2_000000000000_000000000000", "", "user-agent", "x-app-version")
+	if _, err := db.Label("Example app 2.1", app); err != nil {
+		t.Fatalf("Label() failed: %v", err)
+	}
+	db.Annotate(app)
+	if app.Client == nil || app.Client.Name != "Example app 2.1" || app.Client.Confidence < 0.99 {
+		t.Fatalf("Annotate() after Label() = %+v", app.Client)
+	}
+
+	if _, err := db.Label("Nothing", &sessiondata.Session{}); err != ErrNoFingerprint {
+		t.Errorf("Label() of a session without fingerprints = %v, want ErrNoFingerprint", err)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	if match := reopened.Identify(app); match == nil || match.Name != "Example app 2.1" {
+		t.Errorf("labels were not saved, Identify() = %+v", match)
+	}
+
+	var exported bytes.Buffer
+	if err := db.Export(&exported); err != nil {
+		t.Fatalf("Export() failed: %v", err)
+	}
+	imported := New()
+	n, err := imported.Import(&exported)
+	if err != nil {
+		t.Fatalf("Import() failed: %v", err)
+	}
+	if n != len(db.Entries()) || len(imported.Entries()) != n {
+		t.Errorf("Import() read %d of %d entries", n, len(db.Entries()))
+	}
+	if imported.Entries()[0].Name != "Example app 2.1" {
+		t.Errorf("Import() should keep the exported order, first entry %q", imported.Entries()[0].Name)
+	}
+
+	os.WriteFile(path, []byte("not json"), 0o644)
+	if _, err := Open(path); err == nil {
+		t.Errorf("Open() should reject an invalid file")
+	}
+}
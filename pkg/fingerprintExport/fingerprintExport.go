@@ -0,0 +1,307 @@
+package fingerprintExport
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"httpDebugger/pkg/clientHello"
+	"httpDebugger/pkg/http2Fingerprint"
+	"httpDebugger/pkg/sessiondata"
+)
+
+// Format names a way to write a session's fingerprint for another client
+type Format string
+
+const (
+	// FormatGo is Go source building an equivalent utls.ClientHelloSpec
+	FormatGo Format = "go"
+	// FormatTLSClient is the custom client profile of bogdanfinn/tls-client and its API
+	FormatTLSClient Format = "tls-client"
+	// FormatCycleTLS holds the options of CycleTLS
+	FormatCycleTLS Format = "cycletls"
+	// FormatCurlCffi holds the ja3, akamai and extra_fp arguments of curl_cffi
+	FormatCurlCffi Format = "curl_cffi"
+)
+
+// Formats lists every format, the default first
+var Formats = []Format{FormatGo, FormatTLSClient, FormatCycleTLS, FormatCurlCffi}
+
+// ErrNoFingerprint is returned for sessions without a captured ClientHello
+var ErrNoFingerprint = errors.New("session has no TLS fingerprint")
+
+// ParseFormat returns the format called name
+func ParseFormat(name string) (Format, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, format := range Formats {
+		if string(format) == name {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown fingerprint format %q, use one of %s", name, formatNames())
+}
+
+func formatNames() string {
+	names := make([]string, len(Formats))
+	for i, format := range Formats {
+		names[i] = string(format)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Export writes the TLS and HTTP/2 fingerprints of session in format
+func Export(session *sessiondata.Session, format Format) (string, error) {
+	if session == nil || session.TLSFingerprint == nil {
+		return "", ErrNoFingerprint
+	}
+	fp := session.TLSFingerprint
+
+	var value any
+	switch format {
+	case FormatGo:
+		return fp.GoSpec()
+	case FormatTLSClient:
+		value = tlsClientProfile(fp, session.HTTP2Fingerprint)
+	case FormatCycleTLS:
+		value = cycleTLSOptions(session)
+	case FormatCurlCffi:
+		value = curlCffiOptions(fp, session.HTTP2Fingerprint)
+	default:
+		return "", fmt.Errorf("unknown fingerprint format %q, use one of %s", format, formatNames())
+	}
+
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+type tlsClientPriority struct {
+	StreamDep uint32 `json:"streamDep"`
+	Exclusive bool   `json:"exclusive"`
+	Weight    int    `json:"weight"`
+}
+
+type tlsClientPriorityFrame struct {
+	StreamID      uint32            `json:"streamID"`
+	PriorityParam tlsClientPriority `json:"priorityParam"`
+}
+
+type tlsClient struct {
+	JA3String                 string                   `json:"ja3String"`
+	SupportedSignatureAlgs    []string                 `json:"supportedSignatureAlgorithms,omitempty"`
+	SupportedVersions         []string                 `json:"supportedVersions,omitempty"`
+	KeyShareCurves            []string                 `json:"keyShareCurves,omitempty"`
+	CertCompressionAlgorithms []string                 `json:"certCompressionAlgos,omitempty"`
+	ALPNProtocols             []string                 `json:"alpnProtocols,omitempty"`
+	RecordSizeLimit           uint16                   `json:"recordSizeLimit,omitempty"`
+	H2Settings                map[string]uint32        `json:"h2Settings,omitempty"`
+	H2SettingsOrder           []string                 `json:"h2SettingsOrder,omitempty"`
+	ConnectionFlow            uint32                   `json:"connectionFlow,omitempty"`
+	PriorityFrames            []tlsClientPriorityFrame `json:"priorityFrames,omitempty"`
+	HeaderPriority            *tlsClientPriority       `json:"headerPriority,omitempty"`
+	PseudoHeaderOrder         []string                 `json:"pseudoHeaderOrder,omitempty"`
+}
+
+var h2SettingNames = map[uint16]string{
+	http2Fingerprint.SettingHeaderTableSize:      "HEADER_TABLE_SIZE",
+	http2Fingerprint.SettingEnablePush:           "ENABLE_PUSH",
+	http2Fingerprint.SettingMaxConcurrentStreams: "MAX_CONCURRENT_STREAMS",
+	http2Fingerprint.SettingInitialWindowSize:    "INITIAL_WINDOW_SIZE",
+	http2Fingerprint.SettingMaxFrameSize:         "MAX_FRAME_SIZE",
+	http2Fingerprint.SettingMaxHeaderListSize:    "MAX_HEADER_LIST_SIZE",
+}
+
+var certCompressionNames = map[uint16]string{1: "zlib", 2: "brotli", 3: "zstd"}
+
+const (
+	extensionALPS    = 17513
+	extensionALPSNew = 17613
+)
+
+func tlsClientProfile(fp *clientHello.TLSFingerprint, h2 *http2Fingerprint.HTTP2Fingerprint) tlsClient {
+	profile := tlsClient{
+		JA3String:       fp.JA3,
+		ALPNProtocols:   fp.ALPNProtocols,
+		RecordSizeLimit: fp.RecordSizeLimit,
+	}
+	for _, alg := range fp.SignatureAlgs {
+		profile.SupportedSignatureAlgs = append(profile.SupportedSignatureAlgs, tls.SignatureScheme(alg).String())
+	}
+	for _, version := range fp.SupportedVersions {
+		profile.SupportedVersions = append(profile.SupportedVersions, versionName(version))
+	}
+	for _, curve := range fp.KeyShareCurves {
+		profile.KeyShareCurves = append(profile.KeyShareCurves, curveName(curve))
+	}
+	for _, alg := range fp.CertCompAlgs {
+		profile.CertCompressionAlgorithms = append(profile.CertCompressionAlgorithms, certCompressionName(alg))
+	}
+
+	if h2 == nil {
+		return profile
+	}
+	profile.H2Settings = make(map[string]uint32, len(h2.Settings))
+	for _, setting := range h2.Settings {
+		name, ok := h2SettingNames[setting.ID]
+		if !ok {
+			name = fmt.Sprintf("UNKNOWN_SETTING_%d", setting.ID)
+		}
+		profile.H2Settings[name] = setting.Value
+		profile.H2SettingsOrder = append(profile.H2SettingsOrder, name)
+	}
+	profile.ConnectionFlow = h2.WindowUpdate
+	for _, p := range h2.Priorities {
+		profile.PriorityFrames = append(profile.PriorityFrames, tlsClientPriorityFrame{
+			StreamID:      p.StreamID,
+			PriorityParam: tlsClientPriority{StreamDep: p.DependsOn, Exclusive: p.Exclusive, Weight: int(p.Weight) + 1},
+		})
+	}
+	if p := h2.HeaderPriority; p != nil {
+		profile.HeaderPriority = &tlsClientPriority{StreamDep: p.DependsOn, Exclusive: p.Exclusive, Weight: int(p.Weight) + 1}
+	}
+	profile.PseudoHeaderOrder = h2.PseudoHeaderOrder
+	return profile
+}
+
+type cycleTLS struct {
+	JA3              string `json:"ja3"`
+	JA4R             string `json:"ja4r,omitempty"`
+	HTTP2Fingerprint string `json:"http2Fingerprint,omitempty"`
+	UserAgent        string `json:"userAgent,omitempty"`
+}
+
+func cycleTLSOptions(session *sessiondata.Session) cycleTLS {
+	options := cycleTLS{
+		JA3:  session.TLSFingerprint.JA3,
+		JA4R: session.TLSFingerprint.JA4Raw,
+	}
+	if h2 := session.HTTP2Fingerprint; h2 != nil {
+		options.HTTP2Fingerprint = h2.Akamai
+	}
+	if session.Request != nil && session.Request.Headers != nil {
+		options.UserAgent = sessiondata.HTTPHeader(session.Request.Headers).Get("User-Agent")
+	}
+	return options
+}
+
+type curlCffi struct {
+	JA3     string        `json:"ja3"`
+	Akamai  string        `json:"akamai,omitempty"`
+	ExtraFP curlCffiExtra `json:"extra_fp"`
+}
+
+type curlCffiExtra struct {
+	SignatureAlgorithms []string `json:"tls_signature_algorithms,omitempty"`
+	GREASE              bool     `json:"tls_grease"`
+	PermuteExtensions   bool     `json:"tls_permute_extensions"`
+	CertCompression     string   `json:"tls_cert_compression,omitempty"`
+	StreamWeight        int      `json:"http2_stream_weight,omitempty"`
+	StreamExclusive     int      `json:"http2_stream_exclusive,omitempty"`
+	NoPriority          bool     `json:"http2_no_priority,omitempty"`
+	RecordSizeLimit     uint16   `json:"tls_record_size_limit,omitempty"`
+}
+
+// opensslSignatureNames are the names OpenSSL and BoringSSL give signature schemes
+var opensslSignatureNames = map[uint16]string{
+	0x0201: "rsa_pkcs1_sha1",
+	0x0203: "ecdsa_sha1",
+	0x0401: "rsa_pkcs1_sha256",
+	0x0403: "ecdsa_secp256r1_sha256",
+	0x0501: "rsa_pkcs1_sha384",
+	0x0503: "ecdsa_secp384r1_sha384",
+	0x0601: "rsa_pkcs1_sha512",
+	0x0603: "ecdsa_secp521r1_sha512",
+	0x0804: "rsa_pss_rsae_sha256",
+	0x0805: "rsa_pss_rsae_sha384",
+	0x0806: "rsa_pss_rsae_sha512",
+	0x0807: "ed25519",
+	0x0808: "ed448",
+	0x0809: "rsa_pss_pss_sha256",
+	0x080a: "rsa_pss_pss_sha384",
+	0x080b: "rsa_pss_pss_sha512",
+}
+
+func curlCffiOptions(fp *clientHello.TLSFingerprint, h2 *http2Fingerprint.HTTP2Fingerprint) curlCffi {
+	options := curlCffi{JA3: fp.JA3}
+	options.ExtraFP.SignatureAlgorithms = opensslSignatures(fp.SignatureAlgs)
+	options.ExtraFP.RecordSizeLimit = fp.RecordSizeLimit
+	for _, suite := range fp.CipherSuites {
+		if isGREASE(suite) {
+			options.ExtraFP.GREASE = true
+		}
+	}
+	// Only Chromium sends ALPS, and it shuffles its extensions on every connection
+	for _, ext := range fp.Extensions {
+		if ext == extensionALPS || ext == extensionALPSNew {
+			options.ExtraFP.PermuteExtensions = true
+		}
+	}
+	if len(fp.CertCompAlgs) > 0 {
+		options.ExtraFP.CertCompression = certCompressionName(fp.CertCompAlgs[0])
+	}
+
+	if h2 == nil {
+		return options
+	}
+	options.Akamai = h2.Akamai
+	if p := h2.HeaderPriority; p != nil {
+		options.ExtraFP.StreamWeight = int(p.Weight) + 1
+		if p.Exclusive {
+			options.ExtraFP.StreamExclusive = 1
+		}
+	} else {
+		options.ExtraFP.NoPriority = true
+	}
+	return options
+}
+
+func opensslSignatures(schemes []uint16) []string {
+	names := make([]string, 0, len(schemes))
+	for _, scheme := range schemes {
+		name, ok := opensslSignatureNames[scheme]
+		if !ok {
+			name = fmt.Sprintf("0x%04x", scheme)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func versionName(version uint16) string {
+	if isGREASE(version) {
+		return "GREASE"
+	}
+	switch version {
+	case tls.VersionTLS13:
+		return "1.3"
+	case tls.VersionTLS12:
+		return "1.2"
+	case tls.VersionTLS11:
+		return "1.1"
+	case tls.VersionTLS10:
+		return "1.0"
+	}
+	return fmt.Sprintf("0x%04x", version)
+}
+
+func curveName(curve uint16) string {
+	if isGREASE(curve) {
+		return "GREASE"
+	}
+	return strings.TrimPrefix(tls.CurveID(curve).String(), "Curve")
+}
+
+func certCompressionName(alg uint16) string {
+	if name, ok := certCompressionNames[alg]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", alg)
+}
+
+func isGREASE(value uint16) bool {
+	return value&0x0f0f == 0x0a0a && value>>8 == value&0xff
+}
@@ -0,0 +1,111 @@
+package fingerprintExport
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"httpDebugger/pkg/clientHello"
+	"httpDebugger/pkg/http2Fingerprint"
+	"httpDebugger/pkg/sessiondata"
+	"httpDebugger/pkg/sortedMap"
+)
+
+func newSession() *sessiondata.Session {
+	headers := sortedMap.New()
+	headers.Put("User-Agent", "Mozilla/5.0 Chrome/120.0")
+
+	h2 := &http2Fingerprint.HTTP2Fingerprint{
+		Settings: []http2Fingerprint.Setting{
+			{ID: http2Fingerprint.SettingHeaderTableSize, Value: 65536},
+			{ID: http2Fingerprint.SettingEnablePush, Value: 0},
+			{ID: http2Fingerprint.SettingInitialWindowSize, Value: 6291456},
+			{ID: http2Fingerprint.SettingMaxHeaderListSize, Value: 262144},
+		},
+		WindowUpdate:      15663105,
+		HeaderPriority:    &http2Fingerprint.Priority{Exclusive: true, Weight: 255},
+		PseudoHeaderOrder: []string{":method", ":authority", ":scheme", ":path"},
+	}
+	h2.ComputeAkamai()
+
+	return &sessiondata.Session{
+		Request: &sessiondata.RequestData{Headers: headers},
+		TLSFingerprint: &clientHello.TLSFingerprint{
+			TLSVersion:        0x0303,
+			CipherSuites:      []uint16{0x2a2a, 0x1301, 0xc02b},
+			Extensions:        []uint16{0x2a2a, 0, 17513, 43, 51},
+			SignatureAlgs:     []uint16{0x0403, 0x0804},
+			SupportedVersions: []uint16{0x2a2a, 0x0304, 0x0303},
+			KeyShareCurves:    []uint16{0x2a2a, 29},
+			CertCompAlgs:      []uint16{2},
+			ALPNProtocols:     []string{"h2", "http/1.1"},
+			JA3:               "771,4865-49195,0-17513-43-51,,",
+		},
+		HTTP2Fingerprint: h2,
+	}
+}
+
+func TestExportJSON(t *testing.T) {
+	session := newSession()
+
+	var profile map[string]any
+	data, err := Export(session, FormatTLSClient)
+	if err != nil {
+		t.Fatalf("Export(tls-client) failed: %v", err)
+	}
+	if err := json.Unmarshal([]byte(data), &profile); err != nil {
+		t.Fatalf("Export(tls-client) is not JSON: %v", err)
+	}
+	if got := profile["supportedVersions"]; strings.Join(toStrings(got), ",") != "GREASE,1.3,1.2" {
+		t.Errorf("supportedVersions = %v", got)
+	}
+	if got := profile["keyShareCurves"]; strings.Join(toStrings(got), ",") != "GREASE,X25519" {
+		t.Errorf("keyShareCurves = %v", got)
+	}
+	if got := profile["supportedSignatureAlgorithms"]; strings.Join(toStrings(got), ",") != "ECDSAWithP256AndSHA256,PSSWithSHA256" {
+		t.Errorf("supportedSignatureAlgorithms = %v", got)
+	}
+	if got := profile["h2SettingsOrder"]; strings.Join(toStrings(got), ",") != "HEADER_TABLE_SIZE,ENABLE_PUSH,INITIAL_WINDOW_SIZE,MAX_HEADER_LIST_SIZE" {
+		t.Errorf("h2SettingsOrder = %v", got)
+	}
+
+	var cycle map[string]any
+	data, _ = Export(session, FormatCycleTLS)
+	json.Unmarshal([]byte(data), &cycle)
+	if cycle["ja3"] != session.TLSFingerprint.JA3 || cycle["userAgent"] != "Mozilla/5.0 Chrome/120.0" ||
+		cycle["http2Fingerprint"] != "1:65536;2:0;4:6291456;6:262144|15663105|0|m,a,s,p" {
+		t.Errorf("Export(cycletls) = %s", data)
+	}
+
+	var cffi struct {
+		JA3     string         `json:"ja3"`
+		Akamai  string         `json:"akamai"`
+		ExtraFP map[string]any `json:"extra_fp"`
+	}
+	data, _ = Export(session, FormatCurlCffi)
+	json.Unmarshal([]byte(data), &cffi)
+	if cffi.Akamai != session.HTTP2Fingerprint.Akamai || cffi.ExtraFP["tls_grease"] != true ||
+		cffi.ExtraFP["tls_permute_extensions"] != true || cffi.ExtraFP["tls_cert_compression"] != "brotli" ||
+		cffi.ExtraFP["http2_stream_weight"] != float64(256) {
+		t.Errorf("Export(curl_cffi) = %s", data)
+	}
+	if got := strings.Join(toStrings(cffi.ExtraFP["tls_signature_algorithms"]), ","); got != "ecdsa_secp256r1_sha256,rsa_pss_rsae_sha256" {
+		t.Errorf("tls_signature_algorithms = %s", got)
+	}
+
+	if _, err := Export(&sessiondata.Session{}, FormatCycleTLS); err != ErrNoFingerprint {
+		t.Errorf("Export() without a fingerprint = %v, want ErrNoFingerprint", err)
+	}
+	if _, err := ParseFormat("java"); err == nil {
+		t.Errorf("ParseFormat() should reject unknown formats")
+	}
+}
+
+func toStrings(value any) []string {
+	values, _ := value.([]any)
+	strs := make([]string, len(values))
+	for i, v := range values {
+		strs[i], _ = v.(string)
+	}
+	return strs
+}
@@ -0,0 +1,327 @@
+package fuzzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"httpDebugger/pkg/replayRunner"
+	"httpDebugger/pkg/sessiondata"
+)
+
+// Options configures an attack
+type Options struct {
+	Mode Mode
+	// Payloads are payload sources, see LoadPayloads
+	Payloads []string
+	// Concurrency is how many requests may be in flight at once
+	Concurrency int
+	// Rate caps the requests started per second; 0 is unlimited
+	Rate float64
+	// Match counts its matches in every response
+	Match *regexp.Regexp
+}
+
+// ParseSpec parses space separated terms such as
+// "mode=pitchfork payloads=file:users.txt payloads=range:1-100 concurrency=4 rate=10 match=error"
+func ParseSpec(spec string) (Options, error) {
+	opts := Options{Mode: ModeSniper, Concurrency: 1}
+
+	for _, term := range strings.Fields(spec) {
+		name, value, found := strings.Cut(term, "=")
+		if !found {
+			return opts, fmt.Errorf("expected name=value, got %q", term)
+		}
+
+		var err error
+		switch strings.ToLower(name) {
+		case "mode":
+			opts.Mode, err = ParseMode(value)
+		case "payloads":
+			opts.Payloads = append(opts.Payloads, value)
+		case "concurrency":
+			opts.Concurrency, err = strconv.Atoi(value)
+			if err == nil && opts.Concurrency < 1 {
+				err = fmt.Errorf("must be at least 1")
+			}
+		case "rate":
+			opts.Rate, err = strconv.ParseFloat(value, 64)
+			if err == nil && opts.Rate < 0 {
+				err = fmt.Errorf("must not be negative")
+			}
+		case "match":
+			opts.Match, err = regexp.Compile(value)
+		default:
+			return opts, fmt.Errorf("unknown fuzzer term %q", name)
+		}
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s %q: %w", name, value, err)
+		}
+	}
+
+	if len(opts.Payloads) == 0 {
+		return opts, errors.New("no payloads, add payloads=list:a,b, payloads=file:words.txt, payloads=range:1-100 or payloads=random:count:length")
+	}
+	return opts, nil
+}
+
+// Result is the outcome of one attempt
+type Result struct {
+	Attempt Attempt
+	// Session is the linked session the attempt produced
+	Session  *sessiondata.Session
+	Done     bool
+	Status   int
+	Length   int
+	Duration time.Duration
+	Matches  int
+	Error    string
+}
+
+// Attack sends every attempt of a template, each as a replay of the original
+// session, and records what came back
+type Attack struct {
+	opts     Options
+	attempts []Attempt
+	sessions []*sessiondata.Session
+	index    map[*sessiondata.Session]int
+	runner   *replayRunner.Runner
+
+	mu      sync.Mutex
+	results []Result
+}
+
+// New prepares an attack on text, the raw request of original with insertion
+// points between Marker pairs
+func New(original *sessiondata.Session, text string, opts Options) (*Attack, error) {
+	template, err := ParseTemplate(text)
+	if err != nil {
+		return nil, err
+	}
+
+	var sets [][]string
+	for _, source := range opts.Payloads {
+		payloads, err := LoadPayloads(source)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, payloads)
+	}
+
+	attempts, err := template.Attempts(opts.Mode, sets)
+	if err != nil {
+		return nil, err
+	}
+	if len(attempts) == 0 {
+		return nil, errors.New("the attack has no requests")
+	}
+
+	a := &Attack{
+		opts:     opts,
+		attempts: attempts,
+		index:    make(map[*sessiondata.Session]int, len(attempts)),
+		results:  make([]Result, len(attempts)),
+	}
+	for i, attempt := range attempts {
+		req, err := sessiondata.ParseRawRequest(template.Render(attempt.Values))
+		if err != nil {
+			return nil, fmt.Errorf("request %d: %w", i+1, err)
+		}
+
+		// Replays link to the session they were made from through its ID
+		session := &sessiondata.Session{
+			ID:             original.ID,
+			Timestamp:      original.Timestamp,
+			Type:           sessiondata.HTTPSession,
+			Protocol:       original.Protocol,
+			TLSFingerprint: original.TLSFingerprint,
+			Request:        req,
+		}
+		a.sessions = append(a.sessions, session)
+		a.index[session] = i
+		a.results[i] = Result{Attempt: attempt}
+	}
+	return a, nil
+}
+
+// Start sends the attempts in the background with send, in order
+func (a *Attack) Start(ctx context.Context, send replayRunner.Sender) {
+	runnerOpts := replayRunner.Options{
+		Concurrency: a.opts.Concurrency,
+		Rate:        a.opts.Rate,
+		Repeat:      1,
+		Timing:      replayRunner.TimingFast,
+	}
+	a.runner = replayRunner.New(runnerOpts, func(ctx context.Context, session *sessiondata.Session) (*sessiondata.Session, error) {
+		replay, err := send(ctx, session)
+		a.record(a.index[session], replay, err)
+		return replay, err
+	})
+	a.runner.Start(ctx, a.sessions)
+}
+
+func (a *Attack) record(i int, replay *sessiondata.Session, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	result := &a.results[i]
+	result.Done = true
+	result.Session = replay
+	if err != nil {
+		result.Error = err.Error()
+	}
+	if replay == nil {
+		return
+	}
+	result.Duration = replay.Duration
+	if replay.Error != nil && result.Error == "" {
+		result.Error = replay.Error.Error()
+	}
+	if replay.Response != nil {
+		result.Status = replay.Response.StatusCode
+		result.Length = len(replay.Response.Body)
+		if a.opts.Match != nil {
+			result.Matches = len(a.opts.Match.FindAllStringIndex(sessiondata.FormatRawResponse(replay.Response), -1))
+		}
+	}
+}
+
+// Stop starts no further attempts; the ones in flight still complete and are
+// recorded like any other
+func (a *Attack) Stop() {
+	if a.runner != nil {
+		a.runner.Stop()
+	}
+}
+
+// Wait blocks until the attack has finished or was stopped
+func (a *Attack) Wait() {
+	if a.runner != nil {
+		a.runner.Wait()
+	}
+}
+
+// Done reports whether the attack has finished
+func (a *Attack) Done() bool {
+	return a.runner == nil || a.runner.Done()
+}
+
+// Summary returns the progress and latency statistics of the attack
+func (a *Attack) Summary() replayRunner.Summary {
+	if a.runner == nil {
+		return replayRunner.Summary{Total: len(a.attempts)}
+	}
+	return a.runner.Summary()
+}
+
+// Options returns the options the attack was created with
+func (a *Attack) Options() Options {
+	return a.opts
+}
+
+// Results returns a copy of the results in attempt order
+func (a *Attack) Results() []Result {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]Result(nil), a.results...)
+}
+
+// SortKey is a column results can be sorted by
+type SortKey int
+
+const (
+	SortIndex SortKey = iota
+	SortStatus
+	SortLength
+	SortDuration
+	SortMatches
+)
+
+func (k SortKey) String() string {
+	switch k {
+	case SortStatus:
+		return "status"
+	case SortLength:
+		return "length"
+	case SortDuration:
+		return "duration"
+	case SortMatches:
+		return "matches"
+	}
+	return "request"
+}
+
+// Next returns the key after k, wrapping around
+func (k SortKey) Next() SortKey {
+	return (k + 1) % (SortMatches + 1)
+}
+
+// SortResults orders results by key, keeping the attempt order among equal
+// values. Attempts still in flight go last either way
+func SortResults(results []Result, key SortKey, descending bool) {
+	value := func(r Result) int64 {
+		switch key {
+		case SortStatus:
+			return int64(r.Status)
+		case SortLength:
+			return int64(r.Length)
+		case SortDuration:
+			return int64(r.Duration)
+		case SortMatches:
+			return int64(r.Matches)
+		}
+		return int64(r.Attempt.Index)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Done != results[j].Done {
+			return results[i].Done
+		}
+		vi, vj := value(results[i]), value(results[j])
+		if vi == vj {
+			return results[i].Attempt.Index < results[j].Attempt.Index
+		}
+		if descending {
+			return vi > vj
+		}
+		return vi < vj
+	})
+}
+
+// Anomalies reports the attempts, by index, whose status or length differs
+// from the most common one among the finished results
+func Anomalies(results []Result) map[int]bool {
+	statuses := make(map[int]int)
+	lengths := make(map[int]int)
+	for _, r := range results {
+		if r.Done {
+			statuses[r.Status]++
+			lengths[r.Length]++
+		}
+	}
+	status, length := mostCommon(statuses), mostCommon(lengths)
+
+	anomalies := make(map[int]bool)
+	for _, r := range results {
+		if r.Done && (r.Status != status || r.Length != length || r.Error != "") {
+			anomalies[r.Attempt.Index] = true
+		}
+	}
+	return anomalies
+}
+
+// mostCommon returns the value counted most often, the smallest among ties
+func mostCommon(counts map[int]int) int {
+	best, bestCount := 0, 0
+	for value, count := range counts {
+		if count > bestCount || (count == bestCount && value < best) {
+			best, bestCount = value, count
+		}
+	}
+	return best
+}
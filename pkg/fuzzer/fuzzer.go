@@ -0,0 +1,313 @@
+package fuzzer
+
+import (
+	"bufio"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Marker surrounds an insertion point in a request template, around the
+// value it has when it is not being fuzzed
+const Marker = "§"
+
+// MaxAttempts bounds the requests a single attack may produce
+const MaxAttempts = 100000
+
+// Mode decides how payloads are placed into the insertion points
+type Mode string
+
+const (
+	// ModeSniper puts each payload into one position at a time, leaving the others unchanged
+	ModeSniper Mode = "sniper"
+	// ModeBatteringRam puts the same payload into every position at once
+	ModeBatteringRam Mode = "battering-ram"
+	// ModePitchfork takes one payload set per position and steps through them together
+	ModePitchfork Mode = "pitchfork"
+	// ModeClusterBomb tries every combination of one payload set per position
+	ModeClusterBomb Mode = "cluster-bomb"
+)
+
+// Modes lists every mode, the default first
+var Modes = []Mode{ModeSniper, ModeBatteringRam, ModePitchfork, ModeClusterBomb}
+
+// ParseMode returns the mode called name
+func ParseMode(name string) (Mode, error) {
+	for _, mode := range Modes {
+		if string(mode) == strings.ToLower(name) {
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("unknown attack mode %q, use sniper, battering-ram, pitchfork or cluster-bomb", name)
+}
+
+// Template is a raw HTTP request with marked insertion points
+type Template struct {
+	// literals surround the positions: literals[i] comes before position i
+	literals []string
+	defaults []string
+}
+
+// ParseTemplate splits text at pairs of Marker
+func ParseTemplate(text string) (*Template, error) {
+	parts := strings.Split(text, Marker)
+	if len(parts)%2 == 0 {
+		return nil, fmt.Errorf("unpaired %s marker", Marker)
+	}
+	if len(parts) == 1 {
+		return nil, fmt.Errorf("no insertion points; surround the values to fuzz with %s", Marker)
+	}
+
+	t := &Template{}
+	for i, part := range parts {
+		if i%2 == 0 {
+			t.literals = append(t.literals, part)
+		} else {
+			t.defaults = append(t.defaults, part)
+		}
+	}
+	return t, nil
+}
+
+// Positions returns the number of insertion points
+func (t *Template) Positions() int {
+	return len(t.defaults)
+}
+
+// Defaults returns the values between the markers
+func (t *Template) Defaults() []string {
+	return append([]string(nil), t.defaults...)
+}
+
+// Render fills the positions with values, one per position
+func (t *Template) Render(values []string) string {
+	var sb strings.Builder
+	for i, literal := range t.literals {
+		sb.WriteString(literal)
+		if i < len(values) {
+			sb.WriteString(values[i])
+		}
+	}
+	return sb.String()
+}
+
+// Attempt is one request of an attack
+type Attempt struct {
+	Index int
+	// Values holds the value of every position
+	Values []string
+	// Payloads are the values that came from payload sets, for display
+	Payloads []string
+}
+
+// Attempts lists the requests of an attack on t. Sniper and battering-ram
+// use the first payload set, pitchfork and cluster-bomb one set per position
+func (t *Template) Attempts(mode Mode, sets [][]string) ([]Attempt, error) {
+	if len(sets) == 0 {
+		return nil, errors.New("no payloads")
+	}
+	positions := t.Positions()
+
+	var attempts []Attempt
+	add := func(values, payloads []string) error {
+		if len(attempts) >= MaxAttempts {
+			return fmt.Errorf("the attack would send more than %d requests", MaxAttempts)
+		}
+		attempts = append(attempts, Attempt{Index: len(attempts), Values: values, Payloads: payloads})
+		return nil
+	}
+
+	switch mode {
+	case ModeSniper:
+		for position := 0; position < positions; position++ {
+			for _, payload := range sets[0] {
+				values := t.Defaults()
+				values[position] = payload
+				if err := add(values, []string{payload}); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+	case ModeBatteringRam:
+		for _, payload := range sets[0] {
+			values := make([]string, positions)
+			for i := range values {
+				values[i] = payload
+			}
+			if err := add(values, []string{payload}); err != nil {
+				return nil, err
+			}
+		}
+
+	case ModePitchfork, ModeClusterBomb:
+		if len(sets) != positions {
+			return nil, fmt.Errorf("%s needs one payload set per position: %d positions, %d sets", mode, positions, len(sets))
+		}
+		if mode == ModePitchfork {
+			n := len(sets[0])
+			for _, set := range sets {
+				n = min(n, len(set))
+			}
+			for i := 0; i < n; i++ {
+				values := make([]string, positions)
+				for position, set := range sets {
+					values[position] = set[i]
+				}
+				if err := add(values, values); err != nil {
+					return nil, err
+				}
+			}
+			break
+		}
+
+		// Count like an odometer, the last position changing fastest
+		indexes := make([]int, positions)
+		for _, set := range sets {
+			if len(set) == 0 {
+				return nil, nil
+			}
+		}
+		for {
+			values := make([]string, positions)
+			for position, set := range sets {
+				values[position] = set[indexes[position]]
+			}
+			if err := add(values, values); err != nil {
+				return nil, err
+			}
+
+			position := positions - 1
+			for ; position >= 0; position-- {
+				indexes[position]++
+				if indexes[position] < len(sets[position]) {
+					break
+				}
+				indexes[position] = 0
+			}
+			if position < 0 {
+				break
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown attack mode %q", mode)
+	}
+	return attempts, nil
+}
+
+// LoadPayloads resolves a payload source:
+//
+//	list:a,b,c           the given values
+//	file:words.txt       one value per line of a wordlist
+//	range:1-100[:step]   numbers, optionally zero-padded like range:001-100
+//	random:count:length  random alphanumeric strings
+func LoadPayloads(source string) ([]string, error) {
+	kind, arg, _ := strings.Cut(source, ":")
+	switch strings.ToLower(kind) {
+	case "list":
+		return strings.Split(arg, ","), nil
+	case "file":
+		return readWordlist(arg)
+	case "range":
+		return numberRange(arg)
+	case "random":
+		return randomStrings(arg)
+	}
+	return nil, fmt.Errorf("unknown payload source %q, use list:, file:, range: or random:", source)
+}
+
+func readWordlist(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if word := strings.TrimRight(scanner.Text(), "\r"); word != "" {
+			words = append(words, word)
+		}
+		if len(words) > MaxAttempts {
+			return nil, fmt.Errorf("%s has more than %d payloads", path, MaxAttempts)
+		}
+	}
+	return words, scanner.Err()
+}
+
+var rangePattern = regexp.MustCompile(`^(-?\d+)-(-?\d+)(?::(\d+))?$`)
+
+func numberRange(spec string) ([]string, error) {
+	match := rangePattern.FindStringSubmatch(spec)
+	if match == nil {
+		return nil, fmt.Errorf("invalid range %q, use from-to or from-to:step", spec)
+	}
+	from, _ := strconv.Atoi(match[1])
+	to, _ := strconv.Atoi(match[2])
+	step := 1
+	if match[3] != "" {
+		step, _ = strconv.Atoi(match[3])
+	}
+	if step < 1 {
+		return nil, fmt.Errorf("invalid range step %q", match[3])
+	}
+	if (max(from, to)-min(from, to))/step >= MaxAttempts {
+		return nil, fmt.Errorf("range %q has more than %d payloads", spec, MaxAttempts)
+	}
+
+	// A leading zero pads every number to the width of the bound
+	width := 0
+	if strings.HasPrefix(match[1], "0") && len(match[1]) > 1 {
+		width = len(match[1])
+	}
+
+	var numbers []string
+	if from <= to {
+		for n := from; n <= to; n += step {
+			numbers = append(numbers, fmt.Sprintf("%0*d", width, n))
+		}
+	} else {
+		for n := from; n >= to; n -= step {
+			numbers = append(numbers, fmt.Sprintf("%0*d", width, n))
+		}
+	}
+	return numbers, nil
+}
+
+const alphanumeric = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+func randomStrings(spec string) ([]string, error) {
+	countText, lengthText, _ := strings.Cut(spec, ":")
+	count, err := strconv.Atoi(countText)
+	if err != nil || count < 1 || count > MaxAttempts {
+		return nil, fmt.Errorf("invalid random count %q", countText)
+	}
+	length := 8
+	if lengthText != "" {
+		if length, err = strconv.Atoi(lengthText); err != nil || length < 1 || length > 4096 {
+			return nil, fmt.Errorf("invalid random length %q", lengthText)
+		}
+	}
+
+	values := make([]string, count)
+	for i := range values {
+		b := make([]byte, length)
+		for j := range b {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphanumeric))))
+			if err != nil {
+				return nil, err
+			}
+			b[j] = alphanumeric[n.Int64()]
+		}
+		values[i] = string(b)
+	}
+	return values, nil
+}
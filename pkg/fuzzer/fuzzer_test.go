@@ -0,0 +1,203 @@
+package fuzzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"httpDebugger/pkg/sessiondata"
+)
+
+func TestTemplate(t *testing.T) {
+	template, err := ParseTemplate("GET /users/§1§?sort=§name§ HTTP/1.1\n")
+	if err != nil {
+		t.Fatalf("ParseTemplate() failed: %v", err)
+	}
+	if template.Positions() != 2 || !reflect.DeepEqual(template.Defaults(), []string{"1", "name"}) {
+		t.Errorf("positions %d, defaults %q", template.Positions(), template.Defaults())
+	}
+	if got := template.Render([]string{"42", "id"}); got != "GET /users/42?sort=id HTTP/1.1\n" {
+		t.Errorf("Render() = %q", got)
+	}
+
+	for _, text := range []string{"GET / HTTP/1.1", "GET /§a HTTP/1.1"} {
+		if _, err := ParseTemplate(text); err == nil {
+			t.Errorf("ParseTemplate(%q) should fail", text)
+		}
+	}
+}
+
+func TestAttempts(t *testing.T) {
+	template, _ := ParseTemplate("§a§-§b§")
+	render := func(attempts []Attempt) string {
+		var rendered []string
+		for _, attempt := range attempts {
+			rendered = append(rendered, template.Render(attempt.Values))
+		}
+		return strings.Join(rendered, " ")
+	}
+
+	tests := []struct {
+		mode Mode
+		sets [][]string
+		want string
+	}{
+		{ModeSniper, [][]string{{"1", "2"}}, "1-b 2-b a-1 a-2"},
+		{ModeBatteringRam, [][]string{{"1", "2"}}, "1-1 2-2"},
+		{ModePitchfork, [][]string{{"1", "2", "3"}, {"x", "y"}}, "1-x 2-y"},
+		{ModeClusterBomb, [][]string{{"1", "2"}, {"x", "y"}}, "1-x 1-y 2-x 2-y"},
+	}
+	for _, tt := range tests {
+		attempts, err := template.Attempts(tt.mode, tt.sets)
+		if err != nil {
+			t.Errorf("%s: %v", tt.mode, err)
+			continue
+		}
+		if got := render(attempts); got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.mode, got, tt.want)
+		}
+		for i, attempt := range attempts {
+			if attempt.Index != i {
+				t.Errorf("%s: attempt %d has index %d", tt.mode, i, attempt.Index)
+			}
+		}
+	}
+
+	if _, err := template.Attempts(ModeClusterBomb, [][]string{{"1"}}); err == nil {
+		t.Error("cluster-bomb with one set for two positions should fail")
+	}
+	big := make([]string, 1000)
+	if _, err := template.Attempts(ModeClusterBomb, [][]string{big, big}); err == nil {
+		t.Errorf("an attack of more than %d requests should fail", MaxAttempts)
+	}
+}
+
+func TestLoadPayloads(t *testing.T) {
+	wordlist := filepath.Join(t.TempDir(), "words.txt")
+	os.WriteFile(wordlist, []byte("admin\r\n\nroot\n"), 0o644)
+
+	tests := []struct {
+		source string
+		want   []string
+	}{
+		{"list:a,b,c", []string{"a", "b", "c"}},
+		{"file:" + wordlist, []string{"admin", "root"}},
+		{"range:1-3", []string{"1", "2", "3"}},
+		{"range:10-0:5", []string{"10", "5", "0"}},
+		{"range:008-010", []string{"008", "009", "010"}},
+	}
+	for _, tt := range tests {
+		got, err := LoadPayloads(tt.source)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("LoadPayloads(%q) = %q, %v, want %q", tt.source, got, err, tt.want)
+		}
+	}
+
+	random, err := LoadPayloads("random:3:12")
+	if err != nil || len(random) != 3 || len(random[0]) != 12 {
+		t.Errorf("LoadPayloads(random:3:12) = %q, %v", random, err)
+	}
+
+	for _, source := range []string{"words.txt", "range:1", "range:1-2:0", "range:0-1000000", "random:0", "file:/does/not/exist"} {
+		if _, err := LoadPayloads(source); err == nil {
+			t.Errorf("LoadPayloads(%q) should fail", source)
+		}
+	}
+}
+
+func TestParseSpec(t *testing.T) {
+	opts, err := ParseSpec("mode=cluster-bomb payloads=list:a payloads=range:1-2 concurrency=4 rate=2 match=err(or)?")
+	if err != nil {
+		t.Fatalf("ParseSpec() failed: %v", err)
+	}
+	if opts.Mode != ModeClusterBomb || len(opts.Payloads) != 2 || opts.Concurrency != 4 || opts.Rate != 2 || opts.Match.String() != "err(or)?" {
+		t.Errorf("unexpected options: %+v", opts)
+	}
+
+	for _, spec := range []string{"", "mode=sniper", "mode=spray payloads=list:a", "payloads=list:a match=(", "payloads=list:a concurrency=0"} {
+		if _, err := ParseSpec(spec); err == nil {
+			t.Errorf("ParseSpec(%q) should fail", spec)
+		}
+	}
+}
+
+func TestAttack(t *testing.T) {
+	original := &sessiondata.Session{ID: "original", Type: sessiondata.HTTPSession, Timestamp: time.Now()}
+	opts, _ := ParseSpec("payloads=list:guest,admin,nobody concurrency=2 match=welcome")
+	attack, err := New(original, "POST https://example.com/login HTTP/1.1\nContent-Type: text/plain\n\nuser=§x§", opts)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	attack.Start(context.Background(), func(ctx context.Context, session *sessiondata.Session) (*sessiondata.Session, error) {
+		if session.ID != original.ID {
+			t.Errorf("attempts should replay the original session, got ID %q", session.ID)
+		}
+		replay := &sessiondata.Session{ParentID: session.ID, Request: session.Request, Duration: time.Millisecond}
+		switch session.Request.Body {
+		case "user=admin":
+			replay.Response = &sessiondata.ResponseData{StatusCode: 200, Body: "welcome, welcome back"}
+		default:
+			replay.Response = &sessiondata.ResponseData{StatusCode: 401, Body: "denied"}
+		}
+		return replay, nil
+	})
+	attack.Wait()
+
+	results := attack.Results()
+	if len(results) != 3 || !results[0].Done || results[1].Status != 200 || results[1].Matches != 2 || results[1].Length != 21 {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	if results[1].Session.ParentID != "original" || results[1].Attempt.Payloads[0] != "admin" {
+		t.Errorf("result 1 is not linked to its attempt: %+v", results[1])
+	}
+
+	if anomalies := Anomalies(results); len(anomalies) != 1 || !anomalies[1] {
+		t.Errorf("Anomalies() = %v, want only the admin attempt", anomalies)
+	}
+
+	SortResults(results, SortMatches, true)
+	if results[0].Attempt.Index != 1 || results[1].Attempt.Index != 0 || results[2].Attempt.Index != 2 {
+		t.Errorf("sorted by matches: %d %d %d", results[0].Attempt.Index, results[1].Attempt.Index, results[2].Attempt.Index)
+	}
+	SortResults(results, SortIndex, false)
+	if results[0].Attempt.Index != 0 || results[2].Attempt.Index != 2 {
+		t.Error("sorting by request should restore the attempt order")
+	}
+}
+
+func TestAttackStop(t *testing.T) {
+	original := &sessiondata.Session{ID: "original", Type: sessiondata.HTTPSession, Timestamp: time.Now()}
+	opts, _ := ParseSpec("payloads=list:a,b,c concurrency=1")
+	attack, err := New(original, "GET https://example.com/§x§ HTTP/1.1\n\n", opts)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	sent := make(chan struct{}, 3)
+	release := make(chan struct{})
+	attack.Start(context.Background(), func(ctx context.Context, session *sessiondata.Session) (*sessiondata.Session, error) {
+		sent <- struct{}{}
+		<-release
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return &sessiondata.Session{Response: &sessiondata.ResponseData{StatusCode: 200}}, nil
+	})
+	<-sent
+	attack.Stop()
+	close(release)
+	attack.Wait()
+
+	results := attack.Results()
+	if !results[0].Done || results[0].Status != 200 || results[0].Error != "" {
+		t.Errorf("the attempt in flight should complete after Stop(): %+v", results[0])
+	}
+	if results[1].Done || results[2].Done {
+		t.Errorf("no attempt should start after Stop(): %+v", results[1:])
+	}
+}
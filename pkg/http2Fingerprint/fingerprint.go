@@ -0,0 +1,107 @@
+package http2Fingerprint
+
+import (
+	"crypto/md5"
+	"fmt"
+	"strings"
+)
+
+const (
+	SettingHeaderTableSize      = 0x1
+	SettingEnablePush           = 0x2
+	SettingMaxConcurrentStreams = 0x3
+	SettingInitialWindowSize    = 0x4
+	SettingMaxFrameSize         = 0x5
+	SettingMaxHeaderListSize    = 0x6
+	SettingNoRFC7540Priorities  = 0x9
+)
+
+type Setting struct {
+	ID    uint16 `json:"id"`
+	Value uint32 `json:"value"`
+}
+
+type Priority struct {
+	StreamID  uint32 `json:"stream_id"`
+	Exclusive bool   `json:"exclusive"`
+	DependsOn uint32 `json:"depends_on"`
+	Weight    uint8  `json:"weight"`
+}
+
+// HTTP2Fingerprint describes how a client opens an HTTP/2 connection: the order
+// and values of its SETTINGS, the connection WINDOW_UPDATE, the PRIORITY frames
+// sent before the first request and the order of the pseudo-headers
+type HTTP2Fingerprint struct {
+	Settings          []Setting  `json:"settings"`
+	WindowUpdate      uint32     `json:"window_update"`
+	Priorities        []Priority `json:"priorities,omitempty"`
+	HeaderPriority    *Priority  `json:"header_priority,omitempty"`
+	PseudoHeaderOrder []string   `json:"pseudo_header_order"`
+	Akamai            string     `json:"akamai"`
+	AkamaiHash        string     `json:"akamai_hash"`
+}
+
+// ComputeAkamai builds the Akamai-style fingerprint string
+// SETTINGS|WINDOW_UPDATE|PRIORITY|PSEUDO_HEADER_ORDER
+func (f *HTTP2Fingerprint) ComputeAkamai() {
+	parts := make([]string, 4)
+
+	settings := make([]string, 0, len(f.Settings))
+	for _, s := range f.Settings {
+		settings = append(settings, fmt.Sprintf("%d:%d", s.ID, s.Value))
+	}
+	parts[0] = strings.Join(settings, ";")
+
+	parts[1] = "00"
+	if f.WindowUpdate > 0 {
+		parts[1] = fmt.Sprintf("%d", f.WindowUpdate)
+	}
+
+	parts[2] = "0"
+	if len(f.Priorities) > 0 {
+		priorities := make([]string, 0, len(f.Priorities))
+		for _, p := range f.Priorities {
+			exclusive := 0
+			if p.Exclusive {
+				exclusive = 1
+			}
+			priorities = append(priorities, fmt.Sprintf("%d:%d:%d:%d", p.StreamID, exclusive, p.DependsOn, int(p.Weight)+1))
+		}
+		parts[2] = strings.Join(priorities, ",")
+	}
+
+	pseudo := make([]string, 0, len(f.PseudoHeaderOrder))
+	for _, name := range f.PseudoHeaderOrder {
+		if trimmed := strings.TrimPrefix(name, ":"); trimmed != "" {
+			pseudo = append(pseudo, trimmed[:1])
+		}
+	}
+	parts[3] = strings.Join(pseudo, ",")
+
+	f.Akamai = strings.Join(parts, "|")
+	hash := md5.Sum([]byte(f.Akamai))
+	f.AkamaiHash = fmt.Sprintf("%x", hash)
+}
+
+// Setting returns the value the client advertised for id
+func (f *HTTP2Fingerprint) Setting(id uint16) (uint32, bool) {
+	for _, s := range f.Settings {
+		if s.ID == id {
+			return s.Value, true
+		}
+	}
+	return 0, false
+}
+
+// Clone returns a deep copy safe to hand out while the original keeps being filled
+func (f *HTTP2Fingerprint) Clone() *HTTP2Fingerprint {
+	c := *f
+	c.Settings = append([]Setting(nil), f.Settings...)
+	c.Priorities = append([]Priority(nil), f.Priorities...)
+	c.PseudoHeaderOrder = append([]string(nil), f.PseudoHeaderOrder...)
+	if f.HeaderPriority != nil {
+		hp := *f.HeaderPriority
+		c.HeaderPriority = &hp
+	}
+	return &c
+}
@@ -1,4 +1,4 @@
-package tui
+package logging
 
 import (
 	"fmt"
@@ -18,8 +18,14 @@ type Logger struct {
 	mu      sync.Mutex
 }
 
-func NewLogger(verbose bool) (*Logger, error) {
-	logsDir := "logs"
+// DefaultDir is where log files are written unless another directory is given
+const DefaultDir = "logs"
+
+// NewLogger creates a log file named after the current time in logsDir
+func NewLogger(logsDir string, verbose bool) (*Logger, error) {
+	if logsDir == "" {
+		logsDir = DefaultDir
+	}
 	if err := os.MkdirAll(logsDir, 0o755); err != nil {
 		return nil, fmt.Errorf("failed to create logs directory: %v", err)
 	}
@@ -51,6 +57,13 @@ func NewLogger(verbose bool) (*Logger, error) {
 	return l, nil
 }
 
+// SetVerbose turns logging of individual requests and responses on or off
+func (l *Logger) SetVerbose(verbose bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.verbose = verbose
+}
+
 func (l *Logger) LogRequest(session *sessiondata.Session) {
 	if !l.verbose || session == nil || session.Request == nil {
 		return
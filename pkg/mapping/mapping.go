@@ -0,0 +1,306 @@
+package mapping
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"httpDebugger/pkg/sessiondata"
+	"httpDebugger/pkg/sortedMap"
+
+	"github.com/google/uuid"
+)
+
+// Kind tells whether a rule answers requests itself or sends them to another origin
+type Kind int
+
+const (
+	// KindLocal answers matching requests with a local file or inline body
+	KindLocal Kind = iota
+	// KindRemote forwards matching requests to another URL
+	KindRemote
+)
+
+func (k Kind) String() string {
+	if k == KindRemote {
+		return "remote"
+	}
+	return "local"
+}
+
+var ErrNotFound = errors.New("mapping not found")
+
+// Manager holds the Map Local and Map Remote rules
+type Manager struct {
+	mu       sync.Mutex
+	rules    []*Rule
+	disabled bool
+}
+
+func NewManager() *Manager {
+	return &Manager{}
+}
+
+func (m *Manager) Add(rule *Rule) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rules = append(m.rules, rule)
+}
+
+func (m *Manager) Remove(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, rule := range m.rules {
+		if rule.ID == id {
+			m.rules = append(m.rules[:i], m.rules[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// SetRuleEnabled turns a single rule on or off without removing it
+func (m *Manager) SetRuleEnabled(id string, enabled bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, rule := range m.rules {
+		if rule.ID == id {
+			rule.Enabled = enabled
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (m *Manager) Clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rules = nil
+}
+
+func (m *Manager) Rules() []*Rule {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rules := make([]*Rule, len(m.rules))
+	copy(rules, m.rules)
+	return rules
+}
+
+// Enabled reports whether rules are applied at all
+func (m *Manager) Enabled() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return !m.disabled
+}
+
+// SetEnabled turns every rule on or off without removing them
+func (m *Manager) SetEnabled(enabled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.disabled = !enabled
+}
+
+// Match returns the first enabled rule matching the session's request URL
+func (m *Manager) Match(session *sessiondata.Session) *Rule {
+	if m == nil || session.Request == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.disabled {
+		return nil
+	}
+	for _, rule := range m.rules {
+		if rule.Enabled && rule.urlRe.MatchString(session.Request.URL) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// Rule maps requests whose URL matches a regex to a local response or another origin
+type Rule struct {
+	ID   string
+	Kind Kind
+	URL  string
+
+	// File, or Body when File is empty, is the Map Local response body
+	File        string
+	Body        string
+	StatusCode  int
+	ContentType string
+
+	// Target replaces the matched part of the URL for Map Remote and may refer to
+	// regex groups as $1
+	Target string
+
+	Enabled bool
+
+	urlRe *regexp.Regexp
+}
+
+// ParseRule parses a rule written as
+//
+//	local <url-regex> <file>
+//	local <url-regex> [status=404] [type=application/json] body=<inline body>
+//	remote <url-regex> <target>
+//
+// body= takes the rest of the line
+func ParseRule(spec string) (*Rule, error) {
+	spec = strings.TrimSpace(spec)
+	rule := &Rule{ID: uuid.New().String(), Enabled: true, StatusCode: http.StatusOK}
+
+	var body string
+	hasBody := false
+	if i := strings.Index(spec, " body="); i >= 0 {
+		body = spec[i+len(" body="):]
+		spec = spec[:i]
+		hasBody = true
+	}
+
+	terms := strings.Fields(spec)
+	if len(terms) < 2 {
+		return nil, fmt.Errorf("expected \"local <url-regex> <file>\" or \"remote <url-regex> <target>\"")
+	}
+
+	switch strings.ToLower(terms[0]) {
+	case "local":
+		rule.Kind = KindLocal
+	case "remote":
+		rule.Kind = KindRemote
+	default:
+		return nil, fmt.Errorf("unknown mapping kind %q", terms[0])
+	}
+	rule.URL = terms[1]
+
+	for _, term := range terms[2:] {
+		name, value, found := strings.Cut(term, "=")
+		if !found {
+			if rule.Kind == KindRemote {
+				rule.Target = term
+			} else {
+				rule.File = term
+			}
+			continue
+		}
+
+		switch strings.ToLower(name) {
+		case "status":
+			code, err := strconv.Atoi(value)
+			if err != nil || code < 100 || code > 999 {
+				return nil, fmt.Errorf("invalid status %q", value)
+			}
+			rule.StatusCode = code
+		case "type":
+			rule.ContentType = value
+		default:
+			return nil, fmt.Errorf("unknown mapping term %q", name)
+		}
+	}
+
+	if hasBody {
+		rule.Body = body
+	}
+
+	if err := rule.compile(); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (r *Rule) compile() error {
+	re, err := regexp.Compile(r.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url regex: %w", err)
+	}
+	r.urlRe = re
+
+	if r.Kind == KindRemote {
+		if r.Target == "" {
+			return fmt.Errorf("map remote needs a target")
+		}
+		return nil
+	}
+
+	if r.File != "" {
+		if _, err := os.Stat(r.File); err != nil {
+			return fmt.Errorf("map local file: %w", err)
+		}
+	}
+	return nil
+}
+
+// LocalResponse builds the Map Local response. Files are read on every call so
+// edits show up without re-adding the rule
+func (r *Rule) LocalResponse() (*sessiondata.ResponseData, error) {
+	body := r.Body
+	contentType := r.ContentType
+
+	if r.File != "" {
+		data, err := os.ReadFile(r.File)
+		if err != nil {
+			return nil, fmt.Errorf("map local: %w", err)
+		}
+		body = string(data)
+		if contentType == "" {
+			contentType = mime.TypeByExtension(filepath.Ext(r.File))
+		}
+	}
+	if contentType == "" {
+		contentType = http.DetectContentType([]byte(body))
+	}
+
+	headers := sortedMap.New()
+	headers.Put("Content-Type", contentType)
+	headers.Put("Content-Length", strconv.Itoa(len(body)))
+
+	return &sessiondata.ResponseData{
+		StatusCode:  r.StatusCode,
+		Status:      strings.TrimSpace(fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode))),
+		Headers:     headers,
+		Cookies:     make(map[string]string),
+		Body:        body,
+		ContentType: contentType,
+	}, nil
+}
+
+// RemoteURL returns the URL a Map Remote rule sends rawURL to
+func (r *Rule) RemoteURL(rawURL string) (*url.URL, error) {
+	target, err := url.Parse(r.urlRe.ReplaceAllString(rawURL, r.Target))
+	if err != nil {
+		return nil, fmt.Errorf("map remote: %w", err)
+	}
+	if target.Scheme == "" || target.Host == "" {
+		return nil, fmt.Errorf("map remote: URL must be absolute: %s", target)
+	}
+	return target, nil
+}
+
+// Source describes where the rule sends or answers requests from
+func (r *Rule) Source() string {
+	switch {
+	case r.Kind == KindRemote:
+		return r.Target
+	case r.File != "":
+		return r.File
+	default:
+		return "inline body"
+	}
+}
+
+func (r *Rule) String() string {
+	return fmt.Sprintf("%s %s -> %s", r.Kind, r.URL, r.Source())
+}
@@ -0,0 +1,114 @@
+package mapping
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"httpDebugger/pkg/sessiondata"
+)
+
+func newSession(rawURL string) *sessiondata.Session {
+	return &sessiondata.Session{Request: &sessiondata.RequestData{Method: "GET", URL: rawURL}}
+}
+
+func TestParseRule(t *testing.T) {
+	rule, err := ParseRule(`local ^https://api\.example\.com/v1/me status=201 type=application/json body={"name": "mock user"}`)
+	if err != nil {
+		t.Fatalf("ParseRule() failed: %v", err)
+	}
+	if rule.Kind != KindLocal || rule.StatusCode != 201 || rule.ContentType != "application/json" {
+		t.Errorf("unexpected rule: %+v", rule)
+	}
+	if rule.Body != `{"name": "mock user"}` {
+		t.Errorf("Body = %q, want the rest of the line", rule.Body)
+	}
+
+	invalid := []string{
+		"local",
+		"proxy ^https://example.com",
+		"local ( body=x",
+		"local ^https://example.com /does/not/exist",
+		"remote ^https://example.com",
+		"local ^https://example.com status=abc",
+	}
+	for _, spec := range invalid {
+		if _, err := ParseRule(spec); err == nil {
+			t.Errorf("ParseRule(%q) should fail", spec)
+		}
+	}
+}
+
+func TestLocalResponseFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "user.json")
+	if err := os.WriteFile(path, []byte(`{"id":1}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rule, err := ParseRule("local /users/1$ " + path)
+	if err != nil {
+		t.Fatalf("ParseRule() failed: %v", err)
+	}
+
+	resp, err := rule.LocalResponse()
+	if err != nil {
+		t.Fatalf("LocalResponse() failed: %v", err)
+	}
+	if resp.StatusCode != 200 || resp.Status != "200 OK" || resp.Body != `{"id":1}` {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if resp.ContentType != "application/json" {
+		t.Errorf("ContentType = %q, want application/json", resp.ContentType)
+	}
+
+	// Edits to the file are served without re-adding the rule
+	os.WriteFile(path, []byte(`{"id":2}`), 0o644)
+	if resp, _ := rule.LocalResponse(); resp.Body != `{"id":2}` {
+		t.Errorf("file should be read on every request, got %q", resp.Body)
+	}
+}
+
+func TestManagerMatch(t *testing.T) {
+	m := NewManager()
+	remote, err := ParseRule(`remote ^https://prod\.example\.com/(.*) https://staging.example.com/api/$1`)
+	if err != nil {
+		t.Fatalf("ParseRule() failed: %v", err)
+	}
+	m.Add(remote)
+
+	session := newSession("https://prod.example.com/users?id=1")
+	rule := m.Match(session)
+	if rule != remote {
+		t.Fatalf("Match() = %v, want the remote rule", rule)
+	}
+
+	target, err := rule.RemoteURL(session.Request.URL)
+	if err != nil {
+		t.Fatalf("RemoteURL() failed: %v", err)
+	}
+	if target.String() != "https://staging.example.com/api/users?id=1" {
+		t.Errorf("RemoteURL() = %s", target)
+	}
+
+	if m.Match(newSession("https://other.example.com/")) != nil {
+		t.Errorf("non-matching URL should not be mapped")
+	}
+
+	m.SetEnabled(false)
+	if m.Match(session) != nil {
+		t.Errorf("disabled manager should not map anything")
+	}
+	m.SetEnabled(true)
+
+	if err := m.Remove(remote.ID); err != nil || m.Match(session) != nil {
+		t.Errorf("Remove() = %v, rule should be gone", err)
+	}
+	if err := m.Remove(remote.ID); err != ErrNotFound {
+		t.Errorf("Remove() twice = %v, want ErrNotFound", err)
+	}
+
+	var nilManager *Manager
+	if nilManager.Match(session) != nil {
+		t.Errorf("nil manager should not match")
+	}
+}
@@ -0,0 +1,205 @@
+package passthrough
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	"httpDebugger/pkg/proxy/upstream"
+)
+
+// DefaultFailureThreshold is how many TLS handshakes in a row a client may
+// fail against the proxy's certificate before its host is passed through
+const DefaultFailureThreshold = 3
+
+// Reasons a TLS connection is tunneled instead of intercepted
+const (
+	// ReasonRule means the host matched a passthrough pattern, or missed the
+	// intercept list
+	ReasonRule = "rule"
+	// ReasonHandshakeFailures means clients kept rejecting the proxy's
+	// certificate for the host, as certificate-pinned apps do
+	ReasonHandshakeFailures = "handshake failures"
+)
+
+// Policy decides which TLS connections are intercepted and which are relayed
+// untouched. A nil Policy intercepts everything
+type Policy struct {
+	intercept   []string
+	passthrough []string
+	threshold   int
+
+	mu       sync.Mutex
+	failures map[string]int
+	auto     map[string]bool
+}
+
+// NewPolicy builds a policy from host patterns, as accepted by
+// upstream.MatchesHost. Hosts matching passthrough are never intercepted; when
+// intercept is not empty, only hosts matching it are. After threshold failed
+// handshakes in a row a host is passed through automatically; 0 disables that
+func NewPolicy(intercept, passthrough []string, threshold int) (*Policy, error) {
+	if threshold < 0 {
+		return nil, fmt.Errorf("invalid passthrough threshold %d", threshold)
+	}
+
+	policy := Automatic(threshold)
+	var err error
+	if policy.intercept, err = cleanPatterns(intercept); err != nil {
+		return nil, err
+	}
+	if policy.passthrough, err = cleanPatterns(passthrough); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// Automatic returns a policy that intercepts every host until threshold
+// handshakes with it failed in a row
+func Automatic(threshold int) *Policy {
+	return &Policy{
+		threshold: max(threshold, 0),
+		failures:  make(map[string]int),
+		auto:      make(map[string]bool),
+	}
+}
+
+func cleanPatterns(patterns []string) ([]string, error) {
+	var clean []string
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if strings.Contains(pattern, "/") {
+			if _, _, err := net.ParseCIDR(pattern); err != nil {
+				return nil, fmt.Errorf("invalid host pattern %q: %w", pattern, err)
+			}
+		}
+		clean = append(clean, pattern)
+	}
+	return clean, nil
+}
+
+// Passthrough reports whether connections to host are tunneled untouched,
+// and why
+func (p *Policy) Passthrough(host string) (bool, string) {
+	if p == nil {
+		return false, ""
+	}
+	host = normalize(host)
+
+	if matchesAny(host, p.passthrough) {
+		return true, ReasonRule
+	}
+	if len(p.intercept) > 0 && !matchesAny(host, p.intercept) {
+		return true, ReasonRule
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.auto[host] {
+		return true, ReasonHandshakeFailures
+	}
+	return false, ""
+}
+
+// HandshakeFailed records a client rejecting the proxy's certificate for host.
+// It reports whether host has just been switched to passthrough
+func (p *Policy) HandshakeFailed(host string) bool {
+	if p == nil || p.threshold == 0 {
+		return false
+	}
+	host = normalize(host)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.auto[host] {
+		return false
+	}
+	p.failures[host]++
+	if p.failures[host] < p.threshold {
+		return false
+	}
+	delete(p.failures, host)
+	p.auto[host] = true
+	return true
+}
+
+// HandshakeSucceeded resets the failure count of host
+func (p *Policy) HandshakeSucceeded(host string) {
+	if p == nil {
+		return
+	}
+	host = normalize(host)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.failures, host)
+}
+
+// AutoHosts returns the hosts passed through after repeated handshake failures
+func (p *Policy) AutoHosts() []string {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	hosts := make([]string, 0, len(p.auto))
+	for host := range p.auto {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// Forget stops passing host through automatically, e.g. once the client has
+// been set up to trust the proxy's CA
+func (p *Policy) Forget(host string) {
+	if p == nil {
+		return
+	}
+	host = normalize(host)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.auto, host)
+	delete(p.failures, host)
+}
+
+// String describes the configured patterns for status messages
+func (p *Policy) String() string {
+	if p == nil {
+		return "intercept everything"
+	}
+	var parts []string
+	if len(p.intercept) > 0 {
+		parts = append(parts, "intercept only "+strings.Join(p.intercept, ", "))
+	}
+	if len(p.passthrough) > 0 {
+		parts = append(parts, "pass through "+strings.Join(p.passthrough, ", "))
+	}
+	if p.threshold > 0 {
+		parts = append(parts, fmt.Sprintf("pass through after %d failed handshakes", p.threshold))
+	}
+	if len(parts) == 0 {
+		return "intercept everything"
+	}
+	return strings.Join(parts, "; ")
+}
+
+func matchesAny(host string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if upstream.MatchesHost(host, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalize(host string) string {
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
@@ -0,0 +1,97 @@
+package passthrough
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPolicyPatterns(t *testing.T) {
+	policy, err := NewPolicy(nil, []string{"*.apple.com", "10.0.0.0/8"}, 0)
+	if err != nil {
+		t.Fatalf("NewPolicy() failed: %v", err)
+	}
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"apple.com", true},
+		{"itunes.apple.com", true},
+		{"10.1.2.3", true},
+		{"example.com", false},
+		{"Itunes.Apple.com.", true},
+	}
+	for _, tt := range tests {
+		got, reason := policy.Passthrough(tt.host)
+		if got != tt.want {
+			t.Errorf("Passthrough(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+		if got && reason != ReasonRule {
+			t.Errorf("Passthrough(%q) reason = %q, want %q", tt.host, reason, ReasonRule)
+		}
+	}
+
+	allowList, err := NewPolicy([]string{"api.example.com"}, []string{"*.example.com"}, 0)
+	if err != nil {
+		t.Fatalf("NewPolicy() failed: %v", err)
+	}
+	if got, _ := allowList.Passthrough("other.com"); !got {
+		t.Errorf("hosts missing from the intercept list should be passed through")
+	}
+	if got, _ := allowList.Passthrough("api.example.com"); !got {
+		t.Errorf("the passthrough list should win over the intercept list")
+	}
+
+	if _, err := NewPolicy(nil, []string{"10.0.0.0/33"}, 0); err == nil {
+		t.Errorf("NewPolicy() should reject an invalid IP range")
+	}
+	if _, err := NewPolicy(nil, nil, -1); err == nil {
+		t.Errorf("NewPolicy() should reject a negative threshold")
+	}
+
+	var nilPolicy *Policy
+	if got, _ := nilPolicy.Passthrough("example.com"); got {
+		t.Errorf("a nil policy should intercept everything")
+	}
+}
+
+func TestPolicyHandshakeFailures(t *testing.T) {
+	policy := Automatic(3)
+
+	policy.HandshakeFailed("pinned.example.com")
+	policy.HandshakeFailed("pinned.example.com")
+	policy.HandshakeSucceeded("pinned.example.com")
+	policy.HandshakeFailed("pinned.example.com")
+	if got, _ := policy.Passthrough("pinned.example.com"); got {
+		t.Fatalf("a successful handshake should reset the failure count")
+	}
+
+	policy.HandshakeFailed("pinned.example.com")
+	if !policy.HandshakeFailed("pinned.example.com") {
+		t.Fatalf("HandshakeFailed() should report the switch on the third failure in a row")
+	}
+	if policy.HandshakeFailed("pinned.example.com") {
+		t.Errorf("HandshakeFailed() should report the switch only once")
+	}
+
+	got, reason := policy.Passthrough("pinned.example.com")
+	if !got || reason != ReasonHandshakeFailures {
+		t.Errorf("Passthrough() = %v, %q, want true, %q", got, reason, ReasonHandshakeFailures)
+	}
+	if hosts := policy.AutoHosts(); !reflect.DeepEqual(hosts, []string{"pinned.example.com"}) {
+		t.Errorf("AutoHosts() = %v", hosts)
+	}
+
+	policy.Forget("pinned.example.com")
+	if got, _ := policy.Passthrough("pinned.example.com"); got {
+		t.Errorf("Forget() should intercept the host again")
+	}
+
+	disabled := Automatic(0)
+	for range 10 {
+		disabled.HandshakeFailed("pinned.example.com")
+	}
+	if got, _ := disabled.Passthrough("pinned.example.com"); got {
+		t.Errorf("a zero threshold should never pass hosts through automatically")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"sync"
 
+	"httpDebugger/pkg/http2Fingerprint"
 	"httpDebugger/pkg/proxy/interfaces"
 	"httpDebugger/pkg/sortedMap"
 
@@ -21,6 +22,11 @@ type HTTP2FrameWrapper struct {
 	http2Started      bool
 	streams           map[uint32]*HTTP2StreamData
 	onHeadersCallback func(streamID uint32, headers *sortedMap.SortedMap)
+
+	fpMu          sync.Mutex
+	fingerprint   *http2Fingerprint.HTTP2Fingerprint
+	settingsSeen  bool
+	fingerprinted bool
 }
 
 type HTTP2StreamData struct {
@@ -50,7 +56,20 @@ func NewHTTP2FrameWrapper(conn net.Conn, logger interfaces.Logger) *HTTP2FrameWr
 		decoder:      hpackDecoderPool.Get().(*hpack.Decoder),
 		http2Started: false,
 		streams:      make(map[uint32]*HTTP2StreamData),
+		fingerprint:  &http2Fingerprint.HTTP2Fingerprint{},
+	}
+}
+
+// Fingerprint returns the client's HTTP/2 fingerprint once its first HEADERS frame
+// has been seen, or nil if the connection has not got that far yet
+func (w *HTTP2FrameWrapper) Fingerprint() *http2Fingerprint.HTTP2Fingerprint {
+	w.fpMu.Lock()
+	defer w.fpMu.Unlock()
+
+	if !w.fingerprinted {
+		return nil
 	}
+	return w.fingerprint.Clone()
 }
 
 func (w *HTTP2FrameWrapper) SetHeadersCallback(callback func(streamID uint32, headers *sortedMap.SortedMap)) {
@@ -180,6 +199,8 @@ func (w *HTTP2FrameWrapper) routeFrame(frameType, flags byte, streamID uint32, l
 		_ = headers
 	case 0x08:
 		w.processWindowUpdateFrame(streamID, payload)
+	case 0x02:
+		w.processPriorityFrame(streamID, payload)
 	}
 }
 
@@ -187,12 +208,6 @@ func (w *HTTP2FrameWrapper) routeFrame(frameType, flags byte, streamID uint32, l
 func (w *HTTP2FrameWrapper) processHeadersFrame(streamID uint32, flags byte, payload []byte) *sortedMap.SortedMap {
 	headerBlock := payload
 
-	if (flags & 0x20) != 0 {
-		if len(payload) >= 5 {
-			headerBlock = payload[5:]
-		}
-	}
-
 	if (flags & 0x08) != 0 {
 		if len(headerBlock) >= 1 {
 			padLength := headerBlock[0]
@@ -202,6 +217,14 @@ func (w *HTTP2FrameWrapper) processHeadersFrame(streamID uint32, flags byte, pay
 		}
 	}
 
+	var priority *http2Fingerprint.Priority
+	if (flags & 0x20) != 0 {
+		if len(headerBlock) >= 5 {
+			priority = parsePriority(streamID, headerBlock[:5])
+			headerBlock = headerBlock[5:]
+		}
+	}
+
 	if len(headerBlock) == 0 {
 		return nil
 	}
@@ -225,14 +248,18 @@ func (w *HTTP2FrameWrapper) processHeadersFrame(streamID uint32, flags byte, pay
 		}
 	}
 
+	var pseudoOrder []string
 	for _, hf := range headers {
 		// skip pseudo-header
 		if strings.HasPrefix(hf.Name, ":") {
+			pseudoOrder = append(pseudoOrder, hf.Name)
 			continue
 		}
 		w.streams[streamID].Headers.Put(hf.Name, hf.Value)
 	}
 
+	w.recordFirstHeaders(pseudoOrder, priority)
+
 	endHeaders := (flags & 0x04) != 0
 	if endHeaders {
 		w.streams[streamID].HeadersComplete = true
@@ -270,18 +297,65 @@ func (w *HTTP2FrameWrapper) processDataFrame(streamID uint32, flags byte, payloa
 	return data
 }
 
-// process SETTINGS frame, ignoring individual settings for now
+// process SETTINGS frame, recording the client's initial settings in order
 func (w *HTTP2FrameWrapper) processSettingsFrame(flags byte, payload []byte) {
-	if (flags & 0x01) == 0 {
-		for i := 0; i < len(payload); i += 6 {
-			if i+6 <= len(payload) {
-				settingID := uint16(payload[i])<<8 | uint16(payload[i+1])
-				value := uint32(payload[i+2])<<24 | uint32(payload[i+3])<<16 |
-					uint32(payload[i+4])<<8 | uint32(payload[i+5])
-				_ = settingID
-				_ = value
-			}
-		}
+	if (flags & 0x01) != 0 {
+		return
+	}
+
+	w.fpMu.Lock()
+	defer w.fpMu.Unlock()
+
+	if w.settingsSeen {
+		return
+	}
+	w.settingsSeen = true
+
+	for i := 0; i+6 <= len(payload); i += 6 {
+		settingID := uint16(payload[i])<<8 | uint16(payload[i+1])
+		value := uint32(payload[i+2])<<24 | uint32(payload[i+3])<<16 |
+			uint32(payload[i+4])<<8 | uint32(payload[i+5])
+		w.fingerprint.Settings = append(w.fingerprint.Settings, http2Fingerprint.Setting{ID: settingID, Value: value})
+	}
+}
+
+// process PRIORITY frame, recording the ones sent before the first request
+func (w *HTTP2FrameWrapper) processPriorityFrame(streamID uint32, payload []byte) {
+	if len(payload) < 5 {
+		return
+	}
+
+	w.fpMu.Lock()
+	defer w.fpMu.Unlock()
+
+	if w.fingerprinted {
+		return
+	}
+	w.fingerprint.Priorities = append(w.fingerprint.Priorities, *parsePriority(streamID, payload[:5]))
+}
+
+// recordFirstHeaders completes the fingerprint with the pseudo-header order and
+// priority of the first request on the connection
+func (w *HTTP2FrameWrapper) recordFirstHeaders(pseudoOrder []string, priority *http2Fingerprint.Priority) {
+	w.fpMu.Lock()
+	defer w.fpMu.Unlock()
+
+	if w.fingerprinted {
+		return
+	}
+	w.fingerprint.PseudoHeaderOrder = pseudoOrder
+	w.fingerprint.HeaderPriority = priority
+	w.fingerprint.ComputeAkamai()
+	w.fingerprinted = true
+}
+
+func parsePriority(streamID uint32, data []byte) *http2Fingerprint.Priority {
+	dependency := uint32(data[0])<<24 | uint32(data[1])<<16 | uint32(data[2])<<8 | uint32(data[3])
+	return &http2Fingerprint.Priority{
+		StreamID:  streamID,
+		Exclusive: dependency&0x80000000 != 0,
+		DependsOn: dependency & 0x7fffffff,
+		Weight:    data[4],
 	}
 }
 
@@ -348,7 +422,16 @@ func (w *HTTP2FrameWrapper) processWindowUpdateFrame(streamID uint32, payload []
 		increment := uint32(payload[0])<<24 | uint32(payload[1])<<16 |
 			uint32(payload[2])<<8 | uint32(payload[3])
 		increment = increment & 0x7fffffff
-		_ = increment
+
+		if streamID != 0 {
+			return
+		}
+
+		w.fpMu.Lock()
+		defer w.fpMu.Unlock()
+		if !w.fingerprinted && w.fingerprint.WindowUpdate == 0 {
+			w.fingerprint.WindowUpdate = increment
+		}
 	}
 }
 
@@ -0,0 +1,46 @@
+//go:build linux
+
+package connections
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// ListenTransparent listens on addr with IP_TRANSPARENT set, so connections
+// routed to it by iptables TPROXY are accepted even though they are addressed
+// to another host. That needs CAP_NET_ADMIN; without it the listener only
+// takes REDIRECT and DNAT connections and tproxy is false
+func ListenTransparent(addr string) (listener net.Listener, tproxy bool, err error) {
+	config := net.ListenConfig{Control: func(network, address string, c syscall.RawConn) error {
+		var sockErr error
+		err := c.Control(func(fd uintptr) {
+			// Both options set the same socket flag, which covers IPv4 on dual-stack sockets
+			if strings.HasSuffix(network, "6") {
+				sockErr = unix.SetsockoptInt(int(fd), unix.SOL_IPV6, unix.IPV6_TRANSPARENT, 1)
+			} else {
+				sockErr = unix.SetsockoptInt(int(fd), unix.SOL_IP, unix.IP_TRANSPARENT, 1)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		return sockErr
+	}}
+
+	listener, err = config.Listen(context.Background(), "tcp", addr)
+	if err == nil {
+		return listener, true, nil
+	}
+	if !errors.Is(err, unix.EPERM) {
+		return nil, false, err
+	}
+
+	listener, err = net.Listen("tcp", addr)
+	return listener, false, err
+}
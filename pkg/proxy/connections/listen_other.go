@@ -0,0 +1,12 @@
+//go:build !linux
+
+package connections
+
+import "net"
+
+// ListenTransparent listens on addr for redirected connections. TPROXY only
+// exists on Linux, so tproxy is always false
+func ListenTransparent(addr string) (listener net.Listener, tproxy bool, err error) {
+	listener, err = net.Listen("tcp", addr)
+	return listener, false, err
+}
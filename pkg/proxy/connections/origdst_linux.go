@@ -0,0 +1,73 @@
+//go:build linux
+
+package connections
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// soOriginalDst is SO_ORIGINAL_DST and IP6T_SO_ORIGINAL_DST from the netfilter headers
+const soOriginalDst = 80
+
+// OriginalDestination returns the host:port a connection redirected by
+// iptables REDIRECT or DNAT was originally sent to
+func OriginalDestination(conn net.Conn) (string, error) {
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return "", errors.New("original destination needs a TCP connection")
+	}
+	rawConn, err := tcpConn.SyscallConn()
+	if err != nil {
+		return "", err
+	}
+
+	isIPv6 := false
+	if local, ok := conn.LocalAddr().(*net.TCPAddr); ok && local.IP.To4() == nil {
+		isIPv6 = true
+	}
+
+	var addr string
+	var sockErr error
+	err = rawConn.Control(func(fd uintptr) {
+		if isIPv6 {
+			var sa unix.RawSockaddrInet6
+			if sockErr = getsockopt(fd, unix.SOL_IPV6, unsafe.Pointer(&sa), unix.SizeofSockaddrInet6); sockErr == nil {
+				addr = net.JoinHostPort(net.IP(sa.Addr[:]).String(), strconv.Itoa(int(networkPort(sa.Port))))
+			}
+			return
+		}
+
+		var sa unix.RawSockaddrInet4
+		if sockErr = getsockopt(fd, unix.SOL_IP, unsafe.Pointer(&sa), unix.SizeofSockaddrInet4); sockErr == nil {
+			addr = net.JoinHostPort(net.IP(sa.Addr[:]).String(), strconv.Itoa(int(networkPort(sa.Port))))
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+	if sockErr != nil {
+		return "", sockErr
+	}
+	return addr, nil
+}
+
+// getsockopt reads SO_ORIGINAL_DST into the sockaddr at sa, size bytes long
+func getsockopt(fd uintptr, level int, sa unsafe.Pointer, size uint32) error {
+	length := size
+	_, _, errno := unix.Syscall6(unix.SYS_GETSOCKOPT, fd, uintptr(level), soOriginalDst, uintptr(sa), uintptr(unsafe.Pointer(&length)), 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// networkPort converts a port the kernel keeps in network byte order
+func networkPort(port uint16) uint16 {
+	return binary.BigEndian.Uint16((*[2]byte)(unsafe.Pointer(&port))[:])
+}
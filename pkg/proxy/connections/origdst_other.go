@@ -0,0 +1,14 @@
+//go:build !linux
+
+package connections
+
+import (
+	"errors"
+	"net"
+)
+
+// OriginalDestination returns the host:port a redirected connection was
+// originally sent to. Only Linux exposes it, through SO_ORIGINAL_DST
+func OriginalDestination(conn net.Conn) (string, error) {
+	return "", errors.New("original destination lookup is only supported on Linux")
+}
@@ -30,11 +30,9 @@ func (bc *BufferedConn) Read(p []byte) (n int, err error) {
 			bc.buffer = nil
 			bc.bufIndex = 0
 		}
-		if n == len(p) {
-			return n, nil
-		}
-		m, err := bc.Conn.Read(p[n:])
-		return n + m, err
+		// Waiting for more would stall peers that expect a reply to the
+		// buffered data first, such as a TLS client after its ClientHello
+		return n, nil
 	}
 	return bc.Conn.Read(p)
 }
@@ -135,3 +133,52 @@ func (l *SingleConnListener) Close() error {
 func (l *SingleConnListener) Addr() net.Addr {
 	return l.conn.LocalAddr()
 }
+
+// maxRecordSize bounds a TLS record: a 5 byte header and up to 16KiB plus
+// expansion of payload
+const maxRecordSize = 5 + 16384 + 2048
+
+// HelloRecorder keeps the first TLS record read from a connection, which on
+// the client side of a handshake is the server's ServerHello
+type HelloRecorder struct {
+	net.Conn
+	mu     sync.Mutex
+	record []byte
+	done   bool
+}
+
+func NewHelloRecorder(conn net.Conn) *HelloRecorder {
+	return &HelloRecorder{Conn: conn}
+}
+
+func (r *HelloRecorder) Read(p []byte) (n int, err error) {
+	n, err = r.Conn.Read(p)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.done && n > 0 {
+		r.record = append(r.record, p[:n]...)
+		if len(r.record) >= 5 {
+			size := 5 + (int(r.record[3])<<8 | int(r.record[4]))
+			if len(r.record) >= size {
+				r.record = r.record[:size]
+				r.done = true
+			}
+		}
+		if len(r.record) > maxRecordSize {
+			r.record = nil
+			r.done = true
+		}
+	}
+	return n, err
+}
+
+// Record returns the first record once it was read completely, nil otherwise
+func (r *HelloRecorder) Record() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.done {
+		return nil
+	}
+	return r.record
+}
@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"net"
 	"net/http"
+	"net/url"
 
 	"httpDebugger/pkg/certs"
 
@@ -15,16 +17,21 @@ type Manager struct {
 	httpHandler *HTTPHandler
 	mitmHandler *MITMHandler
 	wsHandler   *WebSocketHandler
+	socks       *SOCKSHandler
+	transparent *TransparentHandler
 }
 
 // NewManager creates a new Manager instance
 func NewManager(config *types.Config, cache *certs.CertCache) *Manager {
+	mitmHandler := NewMITMHandler(config, cache)
 	return &Manager{
 		config:      config,
 		caCache:     cache,
 		httpHandler: NewHTTPHandler(config),
-		mitmHandler: NewMITMHandler(config, cache),
+		mitmHandler: mitmHandler,
 		wsHandler:   NewWebSocketHandler(config, cache),
+		socks:       NewSOCKSHandler(config, mitmHandler),
+		transparent: NewTransparentHandler(config, mitmHandler),
 	}
 }
 
@@ -36,6 +43,19 @@ func (m *Manager) HandleMITM(w http.ResponseWriter, r *http.Request) {
 	m.mitmHandler.Handle(w, r)
 }
 
+func (m *Manager) HandleSOCKS(conn net.Conn) {
+	m.socks.Handle(conn)
+}
+
+func (m *Manager) HandleTransparent(conn net.Conn) {
+	m.transparent.Handle(conn)
+}
+
+// Reverse returns a handler forwarding its clients to the upstream base URL
+func (m *Manager) Reverse(upstream *url.URL) *ReverseHandler {
+	return NewReverseHandler(m.config, m.mitmHandler, upstream)
+}
+
 func (m *Manager) GetWebSocketHandler() *WebSocketHandler {
 	return m.wsHandler
 }
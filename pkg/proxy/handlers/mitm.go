@@ -30,6 +30,7 @@ const (
 	HTTP10                   = "HTTP/1.0"
 	ConnectionHeaderClose    = "close"
 	HTTPSScheme              = "https"
+	HTTPScheme               = "http"
 	InitialBufferIndex       = 0
 )
 
@@ -49,12 +50,6 @@ func NewMITMHandler(config *types.Config, caCerts *certs.CertCache) *MITMHandler
 
 // Handle processes incoming CONNECT requests for HTTPS interception
 func (h *MITMHandler) Handle(w http.ResponseWriter, r *http.Request) {
-	// Extract host without port for certificate generation
-	hostWithoutPort, _, err := net.SplitHostPort(r.Host)
-	if err != nil {
-		hostWithoutPort = r.Host
-	}
-
 	// Hijack the connection to take over the TCP connection
 	hijacker, ok := w.(http.Hijacker)
 	if !ok {
@@ -94,24 +89,76 @@ func (h *MITMHandler) Handle(w http.ResponseWriter, r *http.Request) {
 		baseConn = connections.NewBufferedConn(clientConn, bufferedData)
 	}
 
-	// Parse the ClientHello message to extract TLS parameters
-	var clientHelloData []byte
-	var fingerprint *clientHello.TLSFingerprint
+	h.InterceptConn(baseConn, r.Host)
+}
 
-	if len(bufferedData) > 5 && bufferedData[0] == 0x16 && bufferedData[5] == 0x01 {
-		clientHelloData = bufferedData
-	} else {
-		buffer := make([]byte, 4096)
-		n, err := baseConn.Read(buffer)
-		if err != nil && !errors.Is(err, io.EOF) {
-			h.config.Logger.LogError(err, "reading HTTPS request")
-			return
+// InterceptConn takes over a client connection the client asked to tunnel to
+// target (host:port), as after a CONNECT or a SOCKS5 request. TLS is terminated
+// and intercepted, plaintext HTTP is proxied, and anything else is relayed as
+// is and recorded as a tunnel session. The caller closes conn
+func (h *MITMHandler) InterceptConn(conn net.Conn, target string) {
+	if _, _, err := net.SplitHostPort(target); err != nil {
+		target = net.JoinHostPort(target, "443")
+	}
+
+	first, err := sniff(conn)
+	if errors.Is(err, io.EOF) {
+		return
+	}
+	if err != nil {
+		h.config.Logger.LogError(err, "reading from tunneled connection to "+target)
+		return
+	}
+
+	switch {
+	case isClientHello(first):
+		h.interceptTLS(conn, target, first)
+	case isHTTPRequest(first):
+		h.handleHTTP1(connections.NewBufferedConn(conn, first), forwardTo(HTTPScheme, target), nil)
+	default:
+		h.relay(connections.NewBufferedConn(conn, first), target)
+	}
+}
+
+// interceptTLS terminates the client's TLS with a certificate for the target
+// host, then serves HTTP/1.1 or HTTP/2 depending on the negotiated protocol.
+// Hosts the passthrough policy excludes are relayed untouched instead
+func (h *MITMHandler) interceptTLS(conn net.Conn, target string, clientHelloData []byte) {
+	fingerprint := h.fingerprint(clientHelloData)
+
+	// Clients that resolved the name themselves only give us an IP to tunnel
+	// to, so the SNI tells which host they expect
+	target = hostFromName(target, fingerprint.ServerName)
+	host, _, _ := net.SplitHostPort(target)
+
+	if passthrough, reason := h.config.Passthrough.Passthrough(host); passthrough {
+		session := sessiondata.NewPassthroughSession(target, fingerprint, reason)
+		h.tunnel(connections.NewBufferedConn(conn, clientHelloData), session)
+		return
+	}
+
+	tlsConn, err := h.terminateTLS(conn, clientHelloData, fingerprint, func(string) string {
+		return host
+	})
+	if err != nil {
+		h.config.Logger.LogError(err, fmt.Sprintf("TLS handshake failed for %s", target))
+		if h.config.Passthrough.HandshakeFailed(host) {
+			h.config.Logger.LogInfo(fmt.Sprintf("Clients keep rejecting the certificate for %s, passing it through from now on", host))
 		}
-		clientHelloData = buffer[:n]
+		return
 	}
+	defer tlsConn.Close()
+	h.config.Passthrough.HandshakeSucceeded(host)
+
+	h.serveTLS(tlsConn, fingerprint, forwardTo(HTTPSScheme, target))
+}
 
+// fingerprint parses a ClientHello, reusing the result for clients that send
+// identical ones
+func (h *MITMHandler) fingerprint(clientHelloData []byte) *clientHello.TLSFingerprint {
 	fingerprint, found := h.tlsCache.Get(clientHelloData)
 	if !found {
+		var err error
 		fingerprint, err = clientHello.ParseClientHelloFull(clientHelloData)
 		if err != nil {
 			h.config.Logger.LogError(err, "reading client hello message")
@@ -122,37 +169,73 @@ func (h *MITMHandler) Handle(w http.ResponseWriter, r *http.Request) {
 		h.tlsCache.Set(clientHelloData, fingerprint)
 	}
 
-	// Generate a certificate for the requested host
-	cert, err := h.certsCache.GetHostCert(hostWithoutPort, h.config.CACert)
-	if err != nil {
-		h.config.Logger.LogError(err, "failed to generate certificate for host: "+r.Host)
-		http.Error(w, "Certificate Error", http.StatusInternalServerError)
-		return
+	// Cached fingerprints are shared by every client sending the same
+	// ClientHello, so the per-connection SNI goes on a copy
+	if serverName := clientHello.ParseServerName(clientHelloData); serverName != fingerprint.ServerName {
+		withName := *fingerprint
+		withName.ServerName = serverName
+		fingerprint = &withName
 	}
+	return fingerprint
+}
 
+// terminateTLS completes the TLS handshake of a client whose ClientHello was
+// already read, presenting a certificate for the host certHost returns for the
+// SNI the client sent
+func (h *MITMHandler) terminateTLS(conn net.Conn, clientHelloData []byte, fingerprint *clientHello.TLSFingerprint, certHost func(serverName string) string) (*tls.Conn, error) {
 	tlsConfig := fingerprint.ToTLSConfig()
-	tlsConfig.Certificates = []tls.Certificate{cert}
+	tlsConfig.GetCertificate = func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		host := certHost(hello.ServerName)
+		cert, err := h.certsCache.GetHostCert(host, h.config.CACert)
+		if err != nil {
+			h.config.Logger.LogError(err, "failed to generate certificate for host: "+host)
+			return nil, err
+		}
+		return &cert, nil
+	}
 
-	replayConn := connections.NewReplayConn(baseConn, clientHelloData)
+	replayConn := connections.NewReplayConn(conn, clientHelloData)
 	tlsConn := tls.Server(replayConn, tlsConfig)
-	defer tlsConn.Close()
-
-	err = tlsConn.Handshake()
-	if err != nil {
-		h.config.Logger.LogError(err, fmt.Sprintf("TLS handshake failed for %s", r.Host))
-		return
+	if err := tlsConn.Handshake(); err != nil {
+		tlsConn.Close()
+		return nil, err
 	}
+	return tlsConn, nil
+}
 
+// serveTLS serves HTTP/1.1 or HTTP/2 on a terminated TLS connection depending
+// on the negotiated protocol
+func (h *MITMHandler) serveTLS(tlsConn *tls.Conn, fingerprint *clientHello.TLSFingerprint, route route) {
 	switch tlsConn.ConnectionState().NegotiatedProtocol {
 	case "h2":
-		h.handleTLSHTTP2(tlsConn, r.Host, fingerprint)
+		h.handleTLSHTTP2(tlsConn, route, fingerprint)
 	case "http/1.1", "":
-		h.handleTLSHTTP1(tlsConn, r.Host, fingerprint)
+		h.handleHTTP1(tlsConn, route, fingerprint)
+	}
+}
+
+// route points a request read from an intercepted connection at the server it
+// is forwarded to
+type route func(req *http.Request)
+
+// forwardTo routes requests to target, the host:port the client connected to.
+// Over plain HTTP the Host header names the host when target is an IP
+func forwardTo(scheme, target string) route {
+	return func(req *http.Request) {
+		req.URL.Scheme = scheme
+		req.URL.Host = target
+		if scheme == HTTPScheme {
+			req.URL.Host = hostFromName(target, req.Host)
+		}
+		if req.Host == "" {
+			req.Host = target
+		}
 	}
 }
 
-// handleTLSHTTP1 processes HTTPS connections using HTTP/1.1
-func (h *MITMHandler) handleTLSHTTP1(clientConn *tls.Conn, originalHost string, fingerprint *clientHello.TLSFingerprint) {
+// handleHTTP1 processes HTTP/1.1 requests read from an intercepted connection,
+// after TLS termination for HTTPS
+func (h *MITMHandler) handleHTTP1(clientConn net.Conn, route route, fingerprint *clientHello.TLSFingerprint) {
 	// Initialize header parser
 	wsHandler := NewWebSocketHandler(h.config, h.certsCache)
 
@@ -184,11 +267,7 @@ func (h *MITMHandler) handleTLSHTTP1(clientConn *tls.Conn, originalHost string,
 		clientConn.SetDeadline(time.Time{})
 
 		// Update request URL and Host
-		req.URL.Scheme = HTTPSScheme
-		req.URL.Host = originalHost
-		if req.Host == "" {
-			req.Host = originalHost
-		}
+		route(req)
 
 		// Get the full request body
 		bodyBytes, err := io.ReadAll(req.Body)
@@ -216,7 +295,7 @@ func (h *MITMHandler) handleTLSHTTP1(clientConn *tls.Conn, originalHost string,
 }
 
 // handleTLSHTTP2 processes HTTPS connections using HTTP/2
-func (h *MITMHandler) handleTLSHTTP2(clientConn *tls.Conn, originalHost string, fingerprint *clientHello.TLSFingerprint) {
+func (h *MITMHandler) handleTLSHTTP2(clientConn *tls.Conn, route route, fingerprint *clientHello.TLSFingerprint) {
 	// Wrap the connection to capture HTTP/2 frames
 	wrappedConn := connections.NewHTTP2FrameWrapper(clientConn, h.config.Logger)
 	defer wrappedConn.Close()
@@ -242,11 +321,7 @@ func (h *MITMHandler) handleTLSHTTP2(clientConn *tls.Conn, originalHost string,
 	http2Server.ServeConn(wrappedConn, &http2.ServeConnOpts{
 		// Custom handler to process each request
 		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-			req.URL.Scheme = HTTPSScheme
-			req.URL.Host = originalHost
-			if req.Host == "" {
-				req.Host = originalHost
-			}
+			route(req)
 
 			var rawHeaders *sortedMap.SortedMap
 			streamMu.Lock()
@@ -272,6 +347,7 @@ func (h *MITMHandler) handleTLSHTTP2(clientConn *tls.Conn, originalHost string,
 
 			// Create session data with TLS fingerprint
 			session := sessiondata.NewSessionData(req, bodyBytes, rawHeaders, fingerprint, sessiondata.HTTP2Protocol)
+			session.HTTP2Fingerprint = wrappedConn.Fingerprint()
 
 			// Handle based on session type
 			switch session.Type {
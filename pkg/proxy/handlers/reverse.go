@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"httpDebugger/pkg/proxy/connections"
+	"httpDebugger/pkg/proxy/types"
+)
+
+// ReverseHandler serves clients that talk to the proxy as if it were the
+// server itself, forwarding every request to a fixed upstream base URL. Plain
+// HTTP and TLS are both accepted on the same listener
+type ReverseHandler struct {
+	config   *types.Config
+	mitm     *MITMHandler
+	upstream *url.URL
+}
+
+func NewReverseHandler(config *types.Config, mitm *MITMHandler, upstream *url.URL) *ReverseHandler {
+	return &ReverseHandler{config: config, mitm: mitm, upstream: upstream}
+}
+
+// ParseReverseTarget validates the upstream base URL of a reverse proxy, e.g.
+// https://api.example.com or http://localhost:3000/v1
+func ParseReverseTarget(raw string) (*url.URL, error) {
+	target, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid reverse proxy target %q: %w", raw, err)
+	}
+	if target.Scheme != HTTPScheme && target.Scheme != HTTPSScheme {
+		return nil, fmt.Errorf("reverse proxy target %q must be an http or https URL", raw)
+	}
+	if target.Host == "" {
+		return nil, fmt.Errorf("reverse proxy target %q has no host", raw)
+	}
+	target.RawQuery = ""
+	target.Fragment = ""
+	return target, nil
+}
+
+// Handle serves one client connection and closes it when done
+func (h *ReverseHandler) Handle(conn net.Conn) {
+	defer conn.Close()
+
+	first, err := sniff(conn)
+	if errors.Is(err, io.EOF) {
+		return
+	}
+	if err != nil {
+		h.config.Logger.LogError(err, "reading from reverse proxy client "+conn.RemoteAddr().String())
+		return
+	}
+
+	if !isClientHello(first) {
+		h.mitm.handleHTTP1(connections.NewBufferedConn(conn, first), h.route, nil)
+		return
+	}
+
+	fingerprint := h.mitm.fingerprint(first)
+	tlsConn, err := h.mitm.terminateTLS(conn, first, fingerprint, func(serverName string) string {
+		if serverName != "" {
+			return serverName
+		}
+		// Without SNI the client connected to an address, so the certificate
+		// is issued for the one it reached
+		host, _, _ := net.SplitHostPort(conn.LocalAddr().String())
+		return host
+	})
+	if err != nil {
+		h.config.Logger.LogError(err, "TLS handshake failed for reverse proxy client "+conn.RemoteAddr().String())
+		return
+	}
+	defer tlsConn.Close()
+
+	h.mitm.serveTLS(tlsConn, fingerprint, h.route)
+}
+
+// route points a request at the upstream, prefixing its path with the
+// upstream's base path. The Host header names the upstream as well, as a
+// client talking to it directly would send
+func (h *ReverseHandler) route(req *http.Request) {
+	req.URL.Scheme = h.upstream.Scheme
+	req.URL.Host = h.upstream.Host
+	if base := strings.TrimSuffix(h.upstream.Path, "/"); base != "" {
+		if req.URL.RawPath != "" {
+			req.URL.RawPath = strings.TrimSuffix(h.upstream.EscapedPath(), "/") + req.URL.RawPath
+		}
+		req.URL.Path = base + req.URL.Path
+	}
+	req.Host = h.upstream.Host
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"httpDebugger/pkg/proxy/types"
+)
+
+const (
+	socksVersion = 0x05
+
+	socksMethodNoAuth       = 0x00
+	socksMethodNoAcceptable = 0xff
+
+	socksCmdConnect = 0x01
+
+	socksAddrIPv4   = 0x01
+	socksAddrDomain = 0x03
+	socksAddrIPv6   = 0x04
+
+	socksReplySucceeded           = 0x00
+	socksReplyCommandNotSupported = 0x07
+	socksReplyAddrNotSupported    = 0x08
+
+	socksHandshakeTimeout = 10 * time.Second
+)
+
+// SOCKSHandler accepts SOCKS5 CONNECT requests and feeds the tunneled streams
+// into the same interception as HTTP CONNECT
+type SOCKSHandler struct {
+	config *types.Config
+	mitm   *MITMHandler
+}
+
+func NewSOCKSHandler(config *types.Config, mitm *MITMHandler) *SOCKSHandler {
+	return &SOCKSHandler{config: config, mitm: mitm}
+}
+
+// Handle serves one SOCKS5 client connection and closes it when done
+func (h *SOCKSHandler) Handle(conn net.Conn) {
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(socksHandshakeTimeout))
+	target, err := socksHandshake(conn)
+	if err != nil {
+		h.config.Logger.LogError(err, "SOCKS5 handshake with "+conn.RemoteAddr().String())
+		return
+	}
+	conn.SetDeadline(time.Time{})
+
+	h.mitm.InterceptConn(conn, target)
+}
+
+// socksHandshake negotiates a session without authentication and reads a
+// CONNECT request, returning its host:port target. Connections to the target
+// are reported as established right away, as with HTTP CONNECT
+func socksHandshake(conn net.Conn) (string, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return "", err
+	}
+	if header[0] != socksVersion {
+		return "", fmt.Errorf("unsupported SOCKS version %d", header[0])
+	}
+
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return "", err
+	}
+	method := byte(socksMethodNoAcceptable)
+	for _, m := range methods {
+		if m == socksMethodNoAuth {
+			method = socksMethodNoAuth
+			break
+		}
+	}
+	if _, err := conn.Write([]byte{socksVersion, method}); err != nil {
+		return "", err
+	}
+	if method == socksMethodNoAcceptable {
+		return "", errors.New("client offered no supported authentication method")
+	}
+
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(conn, request); err != nil {
+		return "", err
+	}
+	if request[0] != socksVersion {
+		return "", fmt.Errorf("unsupported SOCKS version %d", request[0])
+	}
+
+	var host string
+	switch request[3] {
+	case socksAddrIPv4, socksAddrIPv6:
+		size := net.IPv4len
+		if request[3] == socksAddrIPv6 {
+			size = net.IPv6len
+		}
+		ip := make([]byte, size)
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return "", err
+		}
+		host = net.IP(ip).String()
+	case socksAddrDomain:
+		size := make([]byte, 1)
+		if _, err := io.ReadFull(conn, size); err != nil {
+			return "", err
+		}
+		domain := make([]byte, size[0])
+		if _, err := io.ReadFull(conn, domain); err != nil {
+			return "", err
+		}
+		host = string(domain)
+	default:
+		writeSOCKSReply(conn, socksReplyAddrNotSupported)
+		return "", fmt.Errorf("unsupported SOCKS address type %d", request[3])
+	}
+
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(conn, port); err != nil {
+		return "", err
+	}
+
+	if request[1] != socksCmdConnect {
+		writeSOCKSReply(conn, socksReplyCommandNotSupported)
+		return "", fmt.Errorf("unsupported SOCKS command %d", request[1])
+	}
+	if err := writeSOCKSReply(conn, socksReplySucceeded); err != nil {
+		return "", err
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))), nil
+}
+
+// writeSOCKSReply answers a request with an unspecified bound address
+func writeSOCKSReply(conn net.Conn, reply byte) error {
+	_, err := conn.Write([]byte{socksVersion, reply, 0x00, socksAddrIPv4, 0, 0, 0, 0, 0, 0})
+	return err
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"errors"
+	"net"
+
+	"httpDebugger/pkg/proxy/connections"
+	"httpDebugger/pkg/proxy/types"
+)
+
+// TransparentHandler intercepts connections redirected to the proxy by the
+// firewall, from clients that are not configured to use a proxy at all
+type TransparentHandler struct {
+	config *types.Config
+	mitm   *MITMHandler
+}
+
+func NewTransparentHandler(config *types.Config, mitm *MITMHandler) *TransparentHandler {
+	return &TransparentHandler{config: config, mitm: mitm}
+}
+
+// Handle serves one redirected connection and closes it when done
+func (h *TransparentHandler) Handle(conn net.Conn) {
+	defer conn.Close()
+
+	target, err := originalDestination(conn)
+	if err != nil {
+		h.config.Logger.LogError(err, "finding the original destination of "+conn.RemoteAddr().String())
+		return
+	}
+
+	h.mitm.InterceptConn(conn, target)
+}
+
+// originalDestination asks netfilter where a REDIRECT or DNAT connection was
+// going. TPROXY keeps the original destination as the local address instead
+func originalDestination(conn net.Conn) (string, error) {
+	if target, err := connections.OriginalDestination(conn); err == nil && target != conn.LocalAddr().String() {
+		return target, nil
+	}
+
+	// A connection that was not redirected would loop back to the proxy
+	local, ok := conn.LocalAddr().(*net.TCPAddr)
+	if !ok || isLocalAddr(local.IP) {
+		return "", errors.New("connection was not redirected to the transparent proxy")
+	}
+	return local.String(), nil
+}
+
+func isLocalAddr(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsUnspecified() {
+		return true
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
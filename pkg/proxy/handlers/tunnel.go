@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"httpDebugger/pkg/proxy/connections"
+	"httpDebugger/pkg/sessiondata"
+)
+
+const (
+	// sniffTimeout is how long a tunneled client may stay silent before the
+	// stream is relayed as is, for protocols where the server speaks first
+	sniffTimeout = 2 * time.Second
+	// maxTLSRecord bounds how much of a fragmented ClientHello is collected
+	maxTLSRecord = 16384 + 5
+)
+
+var httpMethods = [][]byte{
+	[]byte("GET "), []byte("POST "), []byte("PUT "), []byte("DELETE "), []byte("HEAD "),
+	[]byte("OPTIONS "), []byte("PATCH "), []byte("TRACE "), []byte("CONNECT "),
+}
+
+// sniff reads the first bytes a tunneled client sends, collecting a whole TLS
+// record when they start a ClientHello. It returns no data when the client
+// waits for the server to speak first
+func sniff(conn net.Conn) ([]byte, error) {
+	conn.SetReadDeadline(time.Now().Add(sniffTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	buffer := make([]byte, 4096)
+	n, err := conn.Read(buffer)
+	if err != nil {
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return nil, nil
+		}
+		if !errors.Is(err, io.EOF) || n == 0 {
+			return nil, err
+		}
+	}
+	data := buffer[:n]
+
+	if isClientHello(data) {
+		want := 5 + int(binary.BigEndian.Uint16(data[3:5]))
+		for len(data) < want && len(data) < maxTLSRecord {
+			more := make([]byte, want-len(data))
+			n, err := conn.Read(more)
+			data = append(data, more[:n]...)
+			if err != nil {
+				break
+			}
+		}
+	}
+	return data, nil
+}
+
+func isClientHello(data []byte) bool {
+	return len(data) > 5 && data[0] == 0x16 && data[5] == 0x01
+}
+
+func isHTTPRequest(data []byte) bool {
+	for _, method := range httpMethods {
+		if bytes.HasPrefix(data, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostFromName replaces the host of target with name when target is an IP
+// address, keeping target's port. Clients that resolve names themselves only
+// tunnel to an IP, and the SNI or Host header tells which host they meant
+func hostFromName(target, name string) string {
+	host, port, err := net.SplitHostPort(target)
+	if err != nil || net.ParseIP(host) == nil || name == "" {
+		return target
+	}
+	if nameHost, _, err := net.SplitHostPort(name); err == nil {
+		name = nameHost
+	}
+	if net.ParseIP(name) != nil {
+		return target
+	}
+	return net.JoinHostPort(name, port)
+}
+
+// relay copies a stream that is neither TLS nor HTTP to target unchanged,
+// recording it as a tunnel session
+func (h *MITMHandler) relay(clientConn net.Conn, target string) {
+	h.tunnel(clientConn, sessiondata.NewTunnelSession(target))
+}
+
+// tunnel copies a stream to the target of a tunnel session unchanged in both
+// directions, storing the session when it opens and again when it closes
+func (h *MITMHandler) tunnel(clientConn net.Conn, session *sessiondata.Session) {
+	target := session.Tunnel.Target
+	h.config.Fingerprints.Annotate(session)
+	h.config.Logger.LogRequest(session)
+	h.config.SessionStore.Store(session)
+
+	backendConn, err := h.config.Chain.DialContext(context.Background(), "tcp", target)
+	if err != nil {
+		h.config.Logger.LogError(err, "dialing tunnel target "+target)
+		session.Error = err
+		session.Tunnel.ClosedAt = time.Now()
+		session.Duration = time.Since(session.Timestamp)
+		h.config.SessionStore.Store(session)
+		return
+	}
+	defer backendConn.Close()
+
+	sent := &previewWriter{}
+	received := &previewWriter{}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		io.Copy(io.MultiWriter(backendConn, sent), clientConn)
+		closeWrite(backendConn)
+	}()
+	go func() {
+		defer wg.Done()
+		io.Copy(io.MultiWriter(clientConn, received), backendConn)
+		closeWrite(clientConn)
+	}()
+	wg.Wait()
+
+	session.Tunnel.BytesSent = sent.total
+	session.Tunnel.BytesReceived = received.total
+	session.Tunnel.ClientPreview = sent.preview
+	session.Tunnel.ServerPreview = received.preview
+	session.Tunnel.ClosedAt = time.Now()
+	session.Duration = time.Since(session.Timestamp)
+	h.config.SessionStore.Store(session)
+
+	h.config.Logger.LogInfo(fmt.Sprintf("Tunnel to %s closed: %d bytes sent, %d bytes received",
+		target, sent.total, received.total))
+}
+
+// previewWriter counts what passes through and keeps its first bytes
+type previewWriter struct {
+	total   int64
+	preview []byte
+}
+
+func (w *previewWriter) Write(p []byte) (int, error) {
+	w.total += int64(len(p))
+	if room := sessiondata.TunnelPreviewSize - len(w.preview); room > 0 {
+		w.preview = append(w.preview, p[:min(room, len(p))]...)
+	}
+	return len(p), nil
+}
+
+// closeWrite signals the end of a stream to the peer while still reading its
+// reply, closing the whole connection when half-close is unsupported
+func closeWrite(conn net.Conn) {
+	if buffered, ok := conn.(*connections.BufferedConn); ok {
+		conn = buffered.Conn
+	}
+	if halfCloser, ok := conn.(interface{ CloseWrite() error }); ok {
+		halfCloser.CloseWrite()
+		return
+	}
+	conn.Close()
+}
@@ -2,20 +2,28 @@ package handlers
 
 import (
 	"bufio"
+	"context"
 	"crypto/rand"
 	"crypto/tls"
-	"encoding/binary"
+	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"httpDebugger/pkg/certs"
+	"httpDebugger/pkg/clientHello"
+	"httpDebugger/pkg/mapping"
 	"httpDebugger/pkg/sortedMap"
 
+	"httpDebugger/pkg/proxy/connections"
 	"httpDebugger/pkg/proxy/types"
+	"httpDebugger/pkg/proxy/utils"
 	"httpDebugger/pkg/sessiondata"
+	"httpDebugger/pkg/websocket"
 
 	"github.com/google/uuid"
 )
@@ -54,7 +62,25 @@ func (h *WebSocketHandler) Handle(r *http.Request, session *sessiondata.Session,
 		Messages:       []sessiondata.WebSocketMessage{},
 		UpgradeRequest: session.Request,
 	}
+	h.config.Fingerprints.Annotate(session)
 	h.config.SessionStore.Store(session)
+	// Store again once the connection is over so persistent stores keep the final state
+	defer func() { h.config.SessionStore.Store(h.snapshot(session)) }()
+
+	if rule := h.config.Mappings.Match(session); rule != nil {
+		if rule.Kind == mapping.KindLocal {
+			h.serveLocal(rule, session, clientConn)
+			return
+		}
+		if err := h.mapRemote(r, rule, session); err != nil {
+			h.config.Logger.LogError(err, "failed to map WebSocket")
+			session.WebSocket.State = sessiondata.WSFailed
+			session.Error = err
+			session.Duration = time.Since(session.Timestamp)
+			utils.SendErrorResponse(clientConn, http.StatusBadGateway, "Bad Gateway")
+			return
+		}
+	}
 
 	targetAddr := r.Host
 	if !strings.Contains(targetAddr, ":") {
@@ -79,15 +105,22 @@ func (h *WebSocketHandler) Handle(r *http.Request, session *sessiondata.Session,
 		return
 	}
 
-	useTLS := port == "443"
-
-	// Establish connection to the backend server
-	if useTLS {
-		backendConn, err = tls.Dial("tcp", targetAddr, &tls.Config{
-			ServerName: r.Host,
-		})
-	} else {
-		backendConn, err = net.Dial("tcp", targetAddr)
+	useTLS := port == "443" || r.URL.Scheme == HTTPSScheme
+
+	// Establish connection to the backend server, through the upstream proxy if any
+	backendConn, err = h.config.Chain.DialContext(r.Context(), "tcp", targetAddr)
+	if err == nil && useTLS {
+		host, _, _ := net.SplitHostPort(targetAddr)
+		recorder := connections.NewHelloRecorder(backendConn)
+		tlsConn := tls.Client(recorder, &tls.Config{ServerName: host})
+		if err = tlsConn.HandshakeContext(r.Context()); err != nil {
+			backendConn.Close()
+			session.UpstreamTLS = utils.UpstreamTLSFromError(err)
+		} else {
+			session.UpstreamTLS = utils.UpstreamTLSFromConn(tlsConn)
+			session.UpstreamTLS.ServerHello, _ = clientHello.ParseServerHello(recorder.Record())
+		}
+		backendConn = tlsConn
 	}
 
 	// Handle connection errors
@@ -108,8 +141,10 @@ func (h *WebSocketHandler) Handle(r *http.Request, session *sessiondata.Session,
 		return
 	}
 
-	// Read the handshake response from the backend
-	handshakeResp, err := http.ReadResponse(bufio.NewReader(backendConn), r)
+	// Read the handshake response from the backend; frames the server sends
+	// right after it may already be buffered in backendReader
+	backendReader := bufio.NewReader(backendConn)
+	handshakeResp, err := http.ReadResponse(backendReader, r)
 	if err != nil {
 		h.config.Logger.LogError(err, "failed to read handshake response")
 		session.WebSocket.State = sessiondata.WSFailed
@@ -142,34 +177,55 @@ func (h *WebSocketHandler) Handle(r *http.Request, session *sessiondata.Session,
 			UpgradeRequest:  session.Request,
 			UpgradeResponse: session.Response,
 			Subprotocol:     handshakeResp.Header.Get("Sec-WebSocket-Protocol"),
+			Extensions:      websocket.ParseExtensions(handshakeResp.Header.Values("Sec-WebSocket-Extensions")),
 		}
 
 		h.config.Logger.LogResponse(session)
 
+		// Compressed messages are recorded as sent when the parameters cannot be read
+		deflate, err := websocket.ParseDeflate(session.WebSocket.Extensions)
+		if err != nil {
+			h.config.Logger.LogError(err, "failed to parse WebSocket extensions")
+		}
+
+		// Messages held when the connection ends are dropped
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		conn := &wsConn{handler: h, session: session, client: clientConn, server: backendConn}
+		h.config.WebSockets.Register(session.ID, conn)
+		defer h.config.WebSockets.Unregister(session.ID)
+
 		errChan := make(chan error, 2)
 
 		// Start goroutines to handle bidirectional frame forwarding
 		go func() {
 			defer clientConn.Close()
 			defer backendConn.Close()
-			err := h.forwardWebSocketFrames(backendConn, clientConn, session, sessiondata.Inbound)
-			errChan <- err
+			stream := &wsStream{direction: sessiondata.Inbound, inflater: deflate.Inflater(sessiondata.Inbound)}
+			errChan <- conn.relay(ctx, backendReader, stream)
 		}()
 
 		go func() {
 			defer clientConn.Close()
 			defer backendConn.Close()
-			err := h.forwardWebSocketFrames(clientConn, backendConn, session, sessiondata.Outbound)
-			errChan <- err
+			stream := &wsStream{direction: sessiondata.Outbound, inflater: deflate.Inflater(sessiondata.Outbound)}
+			errChan <- conn.relay(ctx, clientConn, stream)
 		}()
 
+		// Either relay closes both connections when it ends, which ends the other;
+		// held messages must not keep it waiting
+		<-errChan
+		cancel()
 		<-errChan
 
 		// Update session state to closed
+		h.config.Mutex.Lock()
 		session.WebSocket.State = sessiondata.WSClosed
 		session.WebSocket.DisconnectedAt = time.Now()
 		session.WebSocket.ConnectionDuration = session.WebSocket.DisconnectedAt.Sub(session.WebSocket.ConnectedAt)
 		session.Duration = time.Since(session.Timestamp)
+		h.config.Mutex.Unlock()
 	} else {
 		session.WebSocket = &sessiondata.WebSocketData{
 			State:           sessiondata.WSFailed,
@@ -184,152 +240,367 @@ func (h *WebSocketHandler) Handle(r *http.Request, session *sessiondata.Session,
 	}
 }
 
-// forwardWebSocketFrames reads WebSocket frames from the 'from' connection, processes them, and writes them to the 'to' connection
-func (h *WebSocketHandler) forwardWebSocketFrames(from io.Reader, to io.Writer, session *sessiondata.Session, direction sessiondata.MessageDirection) error {
+// serveLocal answers the upgrade request with a Map Local response instead of
+// connecting to the server
+func (h *WebSocketHandler) serveLocal(rule *mapping.Rule, session *sessiondata.Session, clientConn net.Conn) {
+	session.WebSocket.State = sessiondata.WSFailed
+	session.Duration = time.Since(session.Timestamp)
+
+	data, err := rule.LocalResponse()
+	if err != nil {
+		h.config.Logger.LogError(err, "failed to map WebSocket")
+		session.Error = err
+		utils.SendErrorResponse(clientConn, http.StatusBadGateway, "Bad Gateway")
+		return
+	}
+
+	session.Mapping = &sessiondata.Mapping{Kind: rule.Kind.String(), Rule: rule.String(), Target: rule.Source()}
+	session.Response = data
+	session.WebSocket.UpgradeResponse = data
+	h.config.Logger.LogResponse(session)
+
+	if err := utils.WriteHTTPResponse(clientConn, utils.ResponseFromData(data)); err != nil {
+		h.config.Logger.LogError(err, "failed to write mapped response to client")
+	}
+}
+
+// mapRemote points the upgrade request at the target of a Map Remote rule; the
+// session keeps the original URL
+func (h *WebSocketHandler) mapRemote(r *http.Request, rule *mapping.Rule, session *sessiondata.Session) error {
+	target, err := rule.RemoteURL(session.Request.URL)
+	if err != nil {
+		return err
+	}
+
+	switch target.Scheme {
+	case "ws":
+		target.Scheme = "http"
+	case "wss":
+		target.Scheme = "https"
+	}
+
+	r.URL = target
+	r.Host = target.Host
+	session.Mapping = &sessiondata.Mapping{Kind: rule.Kind.String(), Rule: rule.String(), Target: target.String()}
+	return nil
+}
+
+// wsConn relays the frames of one WebSocket connection in both directions and
+// injects new messages into it
+type wsConn struct {
+	handler *WebSocketHandler
+	session *sessiondata.Session
+	client  io.Writer
+	server  io.Writer
+
+	// A fragmented message keeps its destination locked until its last
+	// fragment, so no other message is written in the middle of it
+	clientMu sync.Mutex
+	serverMu sync.Mutex
+}
+
+// destination returns where messages travelling in direction are written
+func (c *wsConn) destination(direction sessiondata.MessageDirection) (io.Writer, *sync.Mutex) {
+	if direction == sessiondata.Outbound {
+		return c.server, &c.serverMu
+	}
+	return c.client, &c.clientMu
+}
+
+// Inject sends a new message to the server (Outbound) or the client (Inbound)
+func (c *wsConn) Inject(direction sessiondata.MessageDirection, opcode byte, payload []byte) error {
+	switch opcode {
+	case websocket.OpText, websocket.OpBinary, websocket.OpPing, websocket.OpPong, websocket.OpClose:
+	default:
+		return fmt.Errorf("cannot inject a message with opcode %#x", opcode)
+	}
+
+	// Clients mask every frame they send, servers none
+	frames := websocket.Fragment(opcode, 0, payload, 0, direction == sessiondata.Outbound)
+	to, mu := c.destination(direction)
+	mu.Lock()
+	err := writeFrames(to, frames)
+	mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	msg := newWebSocketMessage(frames[0], direction)
+	msg.Injected = true
+	c.handler.addMessageToSession(c.session, msg)
+	return nil
+}
+
+// wsStream is one direction of a connection, read by a single relay goroutine
+type wsStream struct {
+	direction sessiondata.MessageDirection
+	// inflater decompresses the sender's messages; nil without permessage-deflate
+	inflater *websocket.Inflater
+	// decompress is set once a compressed message was dropped or edited while
+	// the sender keeps its compression context. The receiver's context no
+	// longer matches it, so compressed messages are forwarded inflated
+	decompress bool
+}
+
+// relay reads frames from one side and writes them to the other until the
+// connection closes. Data messages a rule may hold, or that have to be
+// forwarded inflated, are buffered whole first; others stream through and are
+// recorded once complete
+func (c *wsConn) relay(ctx context.Context, from io.Reader, stream *wsStream) error {
+	direction := stream.direction
+	to, mu := c.destination(direction)
+	interceptor := c.handler.config.WebSockets
+
+	// frames collects the data message being read; written counts the frames
+	// of it already forwarded
+	var frames []*websocket.Frame
+	var size, written int
+	holding, streaming, oversized := false, false, false
+	defer func() {
+		if streaming {
+			mu.Unlock()
+		}
+	}()
+
 	for {
-		// Read the frame header
-		header := make([]byte, 2)
-		if _, err := io.ReadFull(from, header); err != nil {
+		frame, err := websocket.ReadFrame(from)
+		if err != nil {
 			if err != io.EOF {
-				h.config.Logger.LogError(err, "reading frame header")
+				c.handler.config.Logger.LogError(err, "reading WebSocket frame")
 			}
 			return err
 		}
+		if direction == sessiondata.Outbound && !frame.Masked {
+			frame.Masked = true
+			rand.Read(frame.MaskKey[:])
+		}
 
-		fin := header[0]&0x80 != 0
-		opcode := header[0] & 0x0F
-		masked := header[1]&0x80 != 0
-		payloadLen := int64(header[1] & 0x7F)
+		// Control frames may come between the fragments of a message
+		if frame.IsControl() {
+			c.handler.addMessageToSession(c.session, newWebSocketMessage(frame, direction))
+			if frame.Opcode == websocket.OpClose {
+				c.handler.config.Mutex.Lock()
+				c.session.WebSocket.CloseCode = sessiondata.CloseNoStatusReceived
+				if code, reason, ok := websocket.CloseFrame(frame.Payload); ok {
+					c.session.WebSocket.CloseCode = code
+					c.session.WebSocket.CloseReason = reason
+				}
+				c.handler.config.Mutex.Unlock()
+			}
 
-		// Determine the actual payload length
-		if payloadLen == 126 {
-			ext := make([]byte, 2)
-			if _, err := io.ReadFull(from, ext); err != nil {
-				h.config.Logger.LogError(err, "reading extended length")
-				return err
+			if !streaming {
+				mu.Lock()
 			}
-			payloadLen = int64(binary.BigEndian.Uint16(ext))
-			header = append(header, ext...)
-		} else if payloadLen == 127 {
-			ext := make([]byte, 8)
-			if _, err := io.ReadFull(from, ext); err != nil {
-				h.config.Logger.LogError(err, "reading extended length")
-				return err
+			err := websocket.WriteFrame(to, frame)
+			if !streaming {
+				mu.Unlock()
 			}
-			payloadLen = int64(binary.BigEndian.Uint64(ext))
-			header = append(header, ext...)
-		}
-
-		var maskKey []byte
-		if masked {
-			maskKey = make([]byte, 4)
-			if _, err := io.ReadFull(from, maskKey); err != nil {
-				h.config.Logger.LogError(err, "reading mask key")
+			if err != nil || frame.Opcode == websocket.OpClose {
 				return err
 			}
+			continue
 		}
 
-		// Read the payload data
-		payload := make([]byte, payloadLen)
-		if payloadLen > 0 {
-			if _, err := io.ReadFull(from, payload); err != nil {
-				h.config.Logger.LogError(err, "reading payload")
+		if frame.Opcode != websocket.OpContinuation {
+			compressed := frame.RSV&websocket.RSV1 != 0
+			holding = (stream.decompress && compressed) || interceptor.Watches(c.session, direction)
+		}
+		frames = append(frames, frame)
+		size += len(frame.Payload)
+
+		if holding {
+			if frame.Fin {
+				err := c.intercept(ctx, frames, stream)
+				frames, size = nil, 0
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if size <= websocket.MaxFramePayload {
+				continue
+			}
+			if stream.decompress {
+				err := fmt.Errorf("message above %d bytes cannot be forwarded inflated", websocket.MaxFramePayload)
+				c.handler.config.Logger.LogError(err, "relaying WebSocket message")
 				return err
 			}
+			// Too large to hold: pass on what was buffered and stream the rest
+			holding = false
 		}
 
-		originalPayload := make([]byte, len(payload))
-		copy(originalPayload, payload)
-
-		if masked {
-			for i := range payload {
-				payload[i] ^= maskKey[i%4]
+		if !streaming {
+			mu.Lock()
+			streaming = true
+		}
+		err = writeFrames(to, frames[written:])
+		written = len(frames)
+
+		switch {
+		case oversized || size > websocket.MaxFramePayload:
+			// Too large to keep whole: record the fragments one by one
+			if !oversized && frames[0].RSV&websocket.RSV1 != 0 {
+				stream.inflater.Skip()
+			}
+			oversized = !frame.Fin
+			for _, f := range frames {
+				c.handler.addMessageToSession(c.session, newWebSocketMessage(f, direction))
 			}
+			frames, written = nil, 0
+		case frame.Fin:
+			msg, _ := c.assemble(frames, stream)
+			c.handler.addMessageToSession(c.session, newDataMessage(msg, direction))
+			frames, written = nil, 0
 		}
 
-		// Create and store the WebSocket message
-		id, _ := uuid.NewUUID()
-		msg := sessiondata.WebSocketMessage{
-			ID:         id.String(),
-			Timestamp:  time.Now(),
-			Direction:  direction,
-			Opcode:     opcode,
-			Payload:    payload,
-			IsMasked:   masked,
-			IsFragment: !fin,
-			Size:       int(payloadLen),
+		if frame.Fin {
+			size = 0
+			mu.Unlock()
+			streaming = false
 		}
-
-		// route based on opcode
-		switch opcode {
-		case 0x1:
-			msg.PayloadText = string(payload)
-			msg.Type = sessiondata.TextMessage
-		case 0x2:
-			msg.Type = sessiondata.BinaryMessage
-		case 0x8:
-			msg.Type = sessiondata.CloseMessage
-		case 0x9:
-			msg.Type = sessiondata.PingMessage
-		case 0xA:
-			msg.Type = sessiondata.PongMessage
-		default:
-			msg.Type = sessiondata.ContinuationMessage
+		if err != nil {
+			return err
 		}
+	}
+}
 
-		// Store the message in the session
-		h.addMessageToSession(session, msg)
+// assemble reassembles a complete data message, logging when it cannot be
+// inflated; the message then keeps its compressed payload
+func (c *wsConn) assemble(frames []*websocket.Frame, stream *wsStream) (*websocket.Message, error) {
+	msg, err := websocket.Assemble(frames, stream.inflater)
+	if err != nil {
+		c.handler.config.Logger.LogError(err, "failed to inflate WebSocket message")
+	}
+	return msg, err
+}
 
-		// Handle control frames and forwarding
-		switch opcode {
-		case 0x8:
-			closeCode := sessiondata.CloseNoStatusReceived
-			closeReason := ""
+// intercept forwards a complete data message, holding it first when a rule
+// matches and sending it as the user decided
+func (c *wsConn) intercept(ctx context.Context, frames []*websocket.Frame, stream *wsStream) error {
+	direction := stream.direction
+	msg, inflateErr := c.assemble(frames, stream)
 
-			if len(payload) >= 2 {
-				closeCode = int(binary.BigEndian.Uint16(payload[:2]))
-				if len(payload) > 2 {
-					closeReason = string(payload[2:])
-				}
+	interceptor := c.handler.config.WebSockets
+	var decision websocket.Decision
+	if rule := interceptor.Match(c.session, direction, msg.Opcode, msg.Payload); rule != nil {
+		decision = interceptor.Hold(ctx, c.session, direction, msg.Opcode, msg.Payload, rule)
+	}
+
+	// Keep the original fragment size so fragmented messages stay fragmented
+	fragment := 0
+	if len(frames) > 1 {
+		fragment = len(frames[0].Payload)
+	}
+	masked := direction == sessiondata.Outbound
+
+	switch {
+	case decision.Action == websocket.ActionDrop:
+		record := newDataMessage(msg, direction)
+		record.Dropped = true
+		c.handler.addMessageToSession(c.session, record)
+		c.diverge(msg, stream)
+		return nil
+
+	case decision.Edited:
+		opcode := msg.Opcode
+		if decision.Opcode != 0 {
+			opcode = decision.Opcode
+		}
+		frames = websocket.Fragment(opcode, 0, decision.Payload, fragment, masked)
+		edited := &websocket.Message{Opcode: opcode, Compressed: msg.Compressed, Payload: decision.Payload, Frames: msg.Frames}
+		record := newDataMessage(edited, direction)
+		record.Edited = true
+		record.OriginalPayload = msg.Payload
+		c.handler.addMessageToSession(c.session, record)
+		c.diverge(msg, stream)
+
+	default:
+		// An inflated message is sent uncompressed, which permessage-deflate allows
+		if stream.decompress && msg.Compressed {
+			if inflateErr != nil {
+				return inflateErr
 			}
+			frames = websocket.Fragment(msg.Opcode, 0, msg.Payload, fragment, masked)
+		}
+		c.handler.addMessageToSession(c.session, newDataMessage(msg, direction))
+	}
 
-			session.WebSocket.CloseCode = closeCode
-			session.WebSocket.CloseReason = closeReason
+	to, mu := c.destination(direction)
+	mu.Lock()
+	defer mu.Unlock()
+	return writeFrames(to, frames)
+}
 
-			h.forwardRawFrame(to, header, maskKey, originalPayload, direction)
-			return nil
+// diverge switches stream to forwarding inflated messages when the receiver
+// never got msg but the sender will keep compressing against it
+func (c *wsConn) diverge(msg *websocket.Message, stream *wsStream) {
+	if msg.Compressed && stream.inflater.ContextTakeover() && !stream.decompress {
+		stream.decompress = true
+		c.handler.config.Logger.LogInfo("WebSocket compression context changed, forwarding messages inflated on " + c.session.Request.URL)
+	}
+}
 
-		default:
-			h.forwardRawFrame(to, header, maskKey, originalPayload, direction)
+func writeFrames(to io.Writer, frames []*websocket.Frame) error {
+	for _, f := range frames {
+		if err := websocket.WriteFrame(to, f); err != nil {
+			return err
 		}
 	}
+	return nil
 }
 
-// forwardRawFrame writes the raw WebSocket frame to the destination, applying masking if necessary
-func (h *WebSocketHandler) forwardRawFrame(to io.Writer, header []byte, maskKey []byte, payload []byte, direction sessiondata.MessageDirection) {
-	// Write the frame header
-	to.Write(header)
+// newWebSocketMessage describes a frame for the session's message list
+func newWebSocketMessage(frame *websocket.Frame, direction sessiondata.MessageDirection) sessiondata.WebSocketMessage {
+	id, _ := uuid.NewUUID()
+	msg := sessiondata.WebSocketMessage{
+		ID:         id.String(),
+		Timestamp:  time.Now(),
+		Direction:  direction,
+		Opcode:     frame.Opcode,
+		Payload:    frame.Payload,
+		IsMasked:   frame.Masked,
+		IsFragment: !frame.Fin,
+		Size:       len(frame.Payload),
+	}
 
-	// Write the mask key and payload
-	if direction == sessiondata.Outbound {
-		if len(maskKey) == 0 {
-			maskKey = make([]byte, 4)
-			rand.Read(maskKey)
-			to.Write(maskKey)
-
-			maskedPayload := make([]byte, len(payload))
-			for i := range payload {
-				maskedPayload[i] = payload[i] ^ maskKey[i%4]
-			}
-			to.Write(maskedPayload)
-		} else {
-			to.Write(maskKey)
-			to.Write(payload)
+	switch frame.Opcode {
+	case websocket.OpText:
+		msg.PayloadText = string(frame.Payload)
+		msg.Type = sessiondata.TextMessage
+	case websocket.OpBinary:
+		msg.Type = sessiondata.BinaryMessage
+	case websocket.OpClose:
+		msg.Type = sessiondata.CloseMessage
+	case websocket.OpPing:
+		msg.Type = sessiondata.PingMessage
+	case websocket.OpPong:
+		msg.Type = sessiondata.PongMessage
+	default:
+		msg.Type = sessiondata.ContinuationMessage
+	}
+	return msg
+}
+
+// newDataMessage describes a reassembled data message, keeping its frames
+// when it was fragmented or compressed
+func newDataMessage(message *websocket.Message, direction sessiondata.MessageDirection) sessiondata.WebSocketMessage {
+	first := message.Frames[0]
+	msg := newWebSocketMessage(&websocket.Frame{Fin: true, Opcode: message.Opcode, Masked: first.Masked, Payload: message.Payload}, direction)
+	msg.Compressed = message.Compressed
+
+	if message.Compressed || len(message.Frames) > 1 {
+		msg.Frames = make([]sessiondata.WebSocketFrame, 0, len(message.Frames))
+		for _, f := range message.Frames {
+			msg.Frames = append(msg.Frames, sessiondata.WebSocketFrame{
+				Fin:     f.Fin,
+				RSV:     f.RSV,
+				Opcode:  f.Opcode,
+				Masked:  f.Masked,
+				Payload: f.Payload,
+			})
 		}
-	} else {
-		// Inbound messages are not masked
-		to.Write(payload)
 	}
+	return msg
 }
 
 // addMessageToSession updates the session with the new WebSocket message and updates statistics
@@ -362,6 +633,21 @@ func (h *WebSocketHandler) addMessageToSession(session *sessiondata.Session, msg
 	}
 }
 
+// snapshot copies the session and its WebSocket data under the config mutex,
+// so stores can encode it while messages are still being added to the original
+func (h *WebSocketHandler) snapshot(session *sessiondata.Session) *sessiondata.Session {
+	h.config.Mutex.Lock()
+	defer h.config.Mutex.Unlock()
+
+	copied := *session
+	if session.WebSocket != nil {
+		ws := *session.WebSocket
+		ws.Messages = slices.Clone(ws.Messages)
+		copied.WebSocket = &ws
+	}
+	return &copied
+}
+
 // extractResponseData extracts basic response data for WebSocket upgrade responses
 func (h *WebSocketHandler) extractResponseData(resp *http.Response) *sessiondata.ResponseData {
 	headers := sortedMap.New()
@@ -1,19 +1,42 @@
 package proxy
 
 import (
+	"context"
 	"crypto/tls"
+	"errors"
+	"net"
 	"net/http"
+	"net/url"
 	"time"
 
+	"httpDebugger/pkg/breakpoints"
 	"httpDebugger/pkg/certs"
+	"httpDebugger/pkg/clientHello"
+	"httpDebugger/pkg/fingerprintDB"
+	"httpDebugger/pkg/http2Fingerprint"
+	"httpDebugger/pkg/mapping"
+	"httpDebugger/pkg/passthrough"
 
+	"httpDebugger/pkg/proxy/connections"
 	"httpDebugger/pkg/proxy/handlers"
 	"httpDebugger/pkg/proxy/interfaces"
 	"httpDebugger/pkg/proxy/types"
+	"httpDebugger/pkg/proxy/upstream"
+	"httpDebugger/pkg/proxy/utils"
+	"httpDebugger/pkg/rewrite"
+	"httpDebugger/pkg/sessiondata"
+	"httpDebugger/pkg/websocket"
 )
 
+// DefaultListen is the address the proxy listens on unless told otherwise
+const DefaultListen = ":8080"
+
+// DefaultReverseListen is where a reverse proxy listens unless told otherwise
+const DefaultReverseListen = ":8443"
+
 type Proxy struct {
 	config     *types.Config
+	transport  *http.Transport
 	certsCache *certs.CertCache
 	handlers   *handlers.Manager
 }
@@ -42,16 +65,154 @@ func NewProxy(store interfaces.SessionStore, logger interfaces.Logger, caCache *
 		SessionStore: store,
 		Logger:       logger,
 		HTTPClient:   client,
+		Upstream:     upstream.NewPool(client),
+		Breakpoints:  breakpoints.NewManager(),
+		WebSockets:   websocket.NewInterceptor(),
+		Mappings:     mapping.NewManager(),
+		Passthrough:  passthrough.Automatic(passthrough.DefaultFailureThreshold),
+		Fingerprints: fingerprintDB.Builtin(),
 		CACert:       caCache.CACert,
 	}
 
 	return &Proxy{
 		config:     config,
+		transport:  transport,
 		handlers:   handlers.NewManager(config, caCache),
 		certsCache: caCache,
 	}
 }
 
+// Breakpoints returns the manager holding requests that match breakpoint rules
+func (p *Proxy) Breakpoints() *breakpoints.Manager {
+	return p.config.Breakpoints
+}
+
+// SetBreakpoints replaces the breakpoint manager; call it before serving
+func (p *Proxy) SetBreakpoints(manager *breakpoints.Manager) {
+	p.config.Breakpoints = manager
+}
+
+// WebSockets returns the interceptor holding WebSocket messages that match its
+// rules and injecting messages into live connections
+func (p *Proxy) WebSockets() *websocket.Interceptor {
+	return p.config.WebSockets
+}
+
+// SetWebSockets replaces the WebSocket interceptor; call it before serving
+func (p *Proxy) SetWebSockets(interceptor *websocket.Interceptor) {
+	p.config.WebSockets = interceptor
+}
+
+// Mappings returns the Map Local and Map Remote rules
+func (p *Proxy) Mappings() *mapping.Manager {
+	return p.config.Mappings
+}
+
+// SetMappings replaces the mapping rules; call it before serving
+func (p *Proxy) SetMappings(manager *mapping.Manager) {
+	p.config.Mappings = manager
+}
+
+// Passthrough returns the policy deciding which TLS hosts are not intercepted
+func (p *Proxy) Passthrough() *passthrough.Policy {
+	return p.config.Passthrough
+}
+
+// SetPassthrough replaces the policy deciding which TLS hosts are relayed
+// untouched; nil intercepts every host. Call it before serving
+func (p *Proxy) SetPassthrough(policy *passthrough.Policy) {
+	p.config.Passthrough = policy
+}
+
+// Fingerprints returns the database sessions' clients are identified with
+func (p *Proxy) Fingerprints() *fingerprintDB.Database {
+	return p.config.Fingerprints
+}
+
+// SetFingerprints replaces the database sessions' clients are identified
+// with; nil leaves them unidentified. Call it before serving
+func (p *Proxy) SetFingerprints(db *fingerprintDB.Database) {
+	p.config.Fingerprints = db
+}
+
+// SetChain sends upstream traffic through the proxies chosen by chain; nil
+// connects directly. Call it before serving
+func (p *Proxy) SetChain(chain *upstream.Chain) {
+	p.config.Chain = chain
+	p.transport.Proxy = chain.ProxyURL
+	p.config.Upstream.SetChain(chain)
+}
+
+// SetRewrite sets the rules applied to requests and responses passing through
+// the proxy; nil disables rewriting
+func (p *Proxy) SetRewrite(engine *rewrite.Engine) {
+	p.config.Rewrite = engine
+}
+
+// ServeSOCKS accepts SOCKS5 clients on listener until it is closed. Their
+// streams are intercepted like HTTP CONNECT tunnels
+func (p *Proxy) ServeSOCKS(listener net.Listener) error {
+	return serveConns(listener, p.handlers.HandleSOCKS)
+}
+
+// ServeTransparent accepts connections redirected to listener by iptables
+// REDIRECT or DNAT until it is closed, and intercepts them as if the client
+// had asked to CONNECT to their original destination. TPROXY connections are
+// only accepted by a listener opened with ListenTransparent
+func (p *Proxy) ServeTransparent(listener net.Listener) error {
+	return serveConns(listener, p.handlers.HandleTransparent)
+}
+
+// NoTPROXYMessage tells the user a transparent listener cannot take TPROXY connections
+const NoTPROXYMessage = "Transparent proxy has no CAP_NET_ADMIN for TPROXY, only REDIRECT and DNAT connections are accepted"
+
+// ListenTransparent opens a listener for ServeTransparent. On Linux it also
+// accepts TPROXY connections when the process has CAP_NET_ADMIN, which
+// tproxy reports; otherwise only REDIRECT and DNAT reach it
+func ListenTransparent(addr string) (listener net.Listener, tproxy bool, err error) {
+	return connections.ListenTransparent(addr)
+}
+
+// ServeReverse accepts clients on listener until it is closed and forwards
+// their requests to the upstream base URL, terminating TLS with a certificate
+// issued by the proxy's CA when they connect with it
+func (p *Proxy) ServeReverse(listener net.Listener, upstream *url.URL) error {
+	return serveConns(listener, p.handlers.Reverse(upstream).Handle)
+}
+
+// SendOptions chooses how a composed request goes out
+type SendOptions struct {
+	// ParentID links the new session to the session it was made from
+	ParentID string
+	// TLSFingerprint and HTTP2Fingerprint are mimicked on the upstream
+	// connection; nil sends it with Go's own
+	TLSFingerprint   *clientHello.TLSFingerprint
+	HTTP2Fingerprint *http2Fingerprint.HTTP2Fingerprint
+}
+
+// Send sends a request written or edited by the user and returns the new
+// session, stored like captured traffic, with its response or error
+func (p *Proxy) Send(ctx context.Context, req *sessiondata.RequestData, opts SendOptions) (*sessiondata.Session, error) {
+	session := sessiondata.NewComposedSession(req, opts.ParentID)
+	session.TLSFingerprint = opts.TLSFingerprint
+	session.HTTP2Fingerprint = opts.HTTP2Fingerprint
+	err := utils.SendRequest(ctx, session, p.config)
+	return session, err
+}
+
+func serveConns(listener net.Listener, handle func(net.Conn)) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go handle(conn)
+	}
+}
+
 func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodConnect {
 		p.handlers.HandleMITM(w, r)
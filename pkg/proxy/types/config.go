@@ -5,13 +5,38 @@ import (
 	"net/http"
 	"sync"
 
+	"httpDebugger/pkg/breakpoints"
+	"httpDebugger/pkg/fingerprintDB"
+	"httpDebugger/pkg/mapping"
+	"httpDebugger/pkg/passthrough"
 	"httpDebugger/pkg/proxy/interfaces"
+	"httpDebugger/pkg/proxy/upstream"
+	"httpDebugger/pkg/rewrite"
+	"httpDebugger/pkg/sessiondata"
+	"httpDebugger/pkg/websocket"
 )
 
 type Config struct {
 	SessionStore interfaces.SessionStore
 	Logger       interfaces.Logger
 	HTTPClient   *http.Client
+	Upstream     *upstream.Pool
+	Chain        *upstream.Chain
+	Breakpoints  *breakpoints.Manager
+	WebSockets   *websocket.Interceptor
+	Rewrite      *rewrite.Engine
+	Mappings     *mapping.Manager
+	Passthrough  *passthrough.Policy
+	Fingerprints *fingerprintDB.Database
 	CACert       tls.Certificate
 	Mutex        sync.Mutex
 }
+
+// UpstreamClient returns the client used to forward a session's request, mimicking
+// the client's TLS and HTTP/2 fingerprints when they were captured
+func (c *Config) UpstreamClient(session *sessiondata.Session) *http.Client {
+	if c.Upstream == nil {
+		return c.HTTPClient
+	}
+	return c.Upstream.Client(session.TLSFingerprint, session.HTTP2Fingerprint)
+}
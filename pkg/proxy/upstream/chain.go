@@ -0,0 +1,296 @@
+package upstream
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// Route sends connections to hosts matching Pattern through Proxy, or directly
+// when Proxy is nil
+type Route struct {
+	// Pattern is a host ("api.example.com"), a domain with its subdomains
+	// ("*.example.com"), an IP range ("10.0.0.0/8") or "*" for every host
+	Pattern string
+	Proxy   *url.URL
+}
+
+// Chain picks the upstream proxy for every outgoing connection, like a PAC file:
+// routes are tried in order and unmatched hosts go through the default proxy
+type Chain struct {
+	routes       []Route
+	defaultProxy *url.URL
+	dial         DialFunc
+}
+
+// ParseChain builds a chain from a default proxy URL ("" to connect directly),
+// a bypass list of host patterns that always connect directly, and per-host
+// routes written as "pattern=proxy-url" or "pattern=direct". Proxy URLs use
+// the http, https or socks5 scheme and may carry user:password credentials
+func ParseChain(defaultProxy string, bypass, routes []string) (*Chain, error) {
+	chain := &Chain{dial: defaultDial}
+
+	if defaultProxy != "" {
+		proxyURL, err := parseProxyURL(defaultProxy)
+		if err != nil {
+			return nil, err
+		}
+		chain.defaultProxy = proxyURL
+	}
+
+	for _, pattern := range bypass {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		chain.routes = append(chain.routes, Route{Pattern: pattern})
+	}
+
+	for _, spec := range routes {
+		pattern, target, found := strings.Cut(spec, "=")
+		pattern, target = strings.TrimSpace(pattern), strings.TrimSpace(target)
+		if !found || pattern == "" || target == "" {
+			return nil, fmt.Errorf("invalid upstream route %q, expected pattern=proxy-url or pattern=direct", spec)
+		}
+
+		route := Route{Pattern: pattern}
+		if !strings.EqualFold(target, "direct") {
+			proxyURL, err := parseProxyURL(target)
+			if err != nil {
+				return nil, err
+			}
+			route.Proxy = proxyURL
+		}
+		chain.routes = append(chain.routes, route)
+	}
+
+	return chain, nil
+}
+
+func parseProxyURL(raw string) (*url.URL, error) {
+	proxyURL, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upstream proxy %q: %w", raw, err)
+	}
+
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported upstream proxy scheme %q, expected http, https or socks5", proxyURL.Scheme)
+	}
+	if proxyURL.Hostname() == "" {
+		return nil, fmt.Errorf("upstream proxy %q has no host", raw)
+	}
+	return proxyURL, nil
+}
+
+// Routes returns the per-host routes, bypass entries first
+func (c *Chain) Routes() []Route {
+	if c == nil {
+		return nil
+	}
+	return append([]Route(nil), c.routes...)
+}
+
+// Default returns the proxy used for hosts no route matches, nil for direct
+func (c *Chain) Default() *url.URL {
+	if c == nil {
+		return nil
+	}
+	return c.defaultProxy
+}
+
+// ProxyFor returns the proxy connections to host go through, nil for direct
+func (c *Chain) ProxyFor(host string) *url.URL {
+	if c == nil {
+		return nil
+	}
+	for _, route := range c.routes {
+		if MatchesHost(host, route.Pattern) {
+			return route.Proxy
+		}
+	}
+	return c.defaultProxy
+}
+
+// ProxyURL can be used as http.Transport.Proxy
+func (c *Chain) ProxyURL(req *http.Request) (*url.URL, error) {
+	return c.ProxyFor(req.URL.Hostname()), nil
+}
+
+// DialContext opens a connection to addr, tunneled through the upstream proxy
+// chosen for its host. It can be used wherever a DialFunc is expected
+func (c *Chain) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	dial := defaultDial
+	if c != nil && c.dial != nil {
+		dial = c.dial
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upstream address %s: %w", addr, err)
+	}
+
+	proxyURL := c.ProxyFor(host)
+	if proxyURL == nil {
+		return dial(ctx, network, addr)
+	}
+
+	switch proxyURL.Scheme {
+	case "socks5", "socks5h":
+		return dialSOCKS5(ctx, dial, proxyURL, network, addr)
+	default:
+		return dialConnect(ctx, dial, proxyURL, addr)
+	}
+}
+
+func (c *Chain) String() string {
+	if c == nil {
+		return "direct"
+	}
+
+	parts := make([]string, 0, len(c.routes)+1)
+	for _, route := range c.routes {
+		parts = append(parts, route.Pattern+"="+proxyName(route.Proxy))
+	}
+	parts = append(parts, "*="+proxyName(c.defaultProxy))
+	return strings.Join(parts, " ")
+}
+
+// proxyName is a proxy URL without its password
+func proxyName(proxyURL *url.URL) string {
+	if proxyURL == nil {
+		return "direct"
+	}
+	return proxyURL.Redacted()
+}
+
+func proxyAddr(proxyURL *url.URL) string {
+	port := proxyURL.Port()
+	if port == "" {
+		switch proxyURL.Scheme {
+		case "https":
+			port = "443"
+		case "socks5", "socks5h":
+			port = "1080"
+		default:
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(proxyURL.Hostname(), port)
+}
+
+// dialConnect opens a tunnel to addr with an HTTP CONNECT request, sending
+// basic auth when the proxy URL has credentials
+func dialConnect(ctx context.Context, dial DialFunc, proxyURL *url.URL, addr string) (net.Conn, error) {
+	conn, err := dial(ctx, "tcp", proxyAddr(proxyURL))
+	if err != nil {
+		return nil, fmt.Errorf("dialing upstream proxy %s: %w", proxyURL.Redacted(), err)
+	}
+
+	if proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("TLS handshake with upstream proxy %s: %w", proxyURL.Redacted(), err)
+		}
+		conn = tlsConn
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(dialTimeout)
+	}
+	conn.SetDeadline(deadline)
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("sending CONNECT to upstream proxy %s: %w", proxyURL.Redacted(), err)
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("reading CONNECT response from upstream proxy %s: %w", proxyURL.Redacted(), err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("upstream proxy %s refused CONNECT to %s: %s", proxyURL.Redacted(), addr, resp.Status)
+	}
+
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// dialSOCKS5 opens a connection to addr through a SOCKS5 proxy, authenticating
+// with username and password when the proxy URL has credentials
+func dialSOCKS5(ctx context.Context, dial DialFunc, proxyURL *url.URL, network, addr string) (net.Conn, error) {
+	var auth *proxy.Auth
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		auth = &proxy.Auth{User: user.Username(), Password: password}
+	}
+
+	dialer, err := proxy.SOCKS5("tcp", proxyAddr(proxyURL), auth, contextDialer(dial))
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := dialer.(proxy.ContextDialer).DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("upstream SOCKS5 proxy %s: %w", proxyURL.Redacted(), err)
+	}
+	return conn, nil
+}
+
+// contextDialer adapts a DialFunc to the dialer interfaces of x/net/proxy
+type contextDialer DialFunc
+
+func (d contextDialer) Dial(network, addr string) (net.Conn, error) {
+	return d(context.Background(), network, addr)
+}
+
+func (d contextDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return d(ctx, network, addr)
+}
+
+// MatchesHost reports whether host matches a pattern: "*", an IP range such as
+// 10.0.0.0/8, "*.example.com" for a domain and its subdomains, or a host name
+func MatchesHost(host, pattern string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	pattern = strings.ToLower(pattern)
+
+	if pattern == "*" {
+		return true
+	}
+	if _, ipNet, err := net.ParseCIDR(pattern); err == nil {
+		ip := net.ParseIP(host)
+		return ip != nil && ipNet.Contains(ip)
+	}
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return host == suffix || strings.HasSuffix(host, "."+suffix)
+	}
+	return host == pattern
+}
@@ -0,0 +1,192 @@
+package upstream
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+)
+
+func TestChainRouting(t *testing.T) {
+	chain, err := ParseChain("http://proxy.corp:3128",
+		[]string{"localhost", "10.0.0.0/8"},
+		[]string{"*.partner.com=socks5://user:pw@socks.corp:1080", "*.internal=direct"})
+	if err != nil {
+		t.Fatalf("ParseChain() failed: %v", err)
+	}
+
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"localhost", ""},
+		{"10.1.2.3", ""},
+		{"api.partner.com", "socks.corp:1080"},
+		{"partner.com", "socks.corp:1080"},
+		{"wiki.internal", ""},
+		{"example.com", "proxy.corp:3128"},
+		{"11.0.0.1", "proxy.corp:3128"},
+	}
+	for _, tt := range tests {
+		got := ""
+		if proxyURL := chain.ProxyFor(tt.host); proxyURL != nil {
+			got = proxyURL.Host
+		}
+		if got != tt.want {
+			t.Errorf("ProxyFor(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+
+	var nilChain *Chain
+	if nilChain.ProxyFor("example.com") != nil {
+		t.Errorf("a nil chain should connect directly")
+	}
+
+	invalid := [][]string{
+		{"ftp://proxy:21"},
+		{"", "*.example.com"},
+		{"", "*.example.com=gopher://x"},
+	}
+	for _, args := range invalid {
+		var routes []string
+		if len(args) > 1 {
+			routes = args[1:]
+		}
+		if _, err := ParseChain(args[0], nil, routes); err == nil {
+			t.Errorf("ParseChain(%q, %q) should fail", args[0], routes)
+		}
+	}
+}
+
+// serveOnce accepts a single connection on a new listener and hands it to handle
+func serveOnce(t *testing.T, handle func(net.Conn)) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() failed: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		handle(conn)
+	}()
+	return listener.Addr().String()
+}
+
+// echo replies with whatever it reads until the connection closes
+func echo(conn io.ReadWriter) {
+	io.Copy(conn, conn)
+}
+
+func roundTrip(t *testing.T, conn net.Conn) {
+	t.Helper()
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "ping" {
+		t.Fatalf("read %q, %v through the tunnel, want ping", buf, err)
+	}
+}
+
+func TestDialHTTPConnect(t *testing.T) {
+	wantAuth := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:secret"))
+
+	proxyAddr := serveOnce(t, func(conn net.Conn) {
+		reader := bufio.NewReader(conn)
+		req, err := http.ReadRequest(reader)
+		if err != nil {
+			return
+		}
+		if req.Method != http.MethodConnect || req.Host != "target.test:443" || req.Header.Get("Proxy-Authorization") != wantAuth {
+			io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+			return
+		}
+		io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+		echo(struct {
+			io.Reader
+			io.Writer
+		}{reader, conn})
+	})
+
+	chain, err := ParseChain("http://user:secret@"+proxyAddr, nil, nil)
+	if err != nil {
+		t.Fatalf("ParseChain() failed: %v", err)
+	}
+	conn, err := chain.DialContext(context.Background(), "tcp", "target.test:443")
+	if err != nil {
+		t.Fatalf("DialContext() failed: %v", err)
+	}
+	roundTrip(t, conn)
+
+	refusing := serveOnce(t, func(conn net.Conn) {
+		http.ReadRequest(bufio.NewReader(conn))
+		io.WriteString(conn, "HTTP/1.1 403 Forbidden\r\n\r\n")
+	})
+	chain, _ = ParseChain("http://"+refusing, nil, nil)
+	if _, err := chain.DialContext(context.Background(), "tcp", "target.test:443"); err == nil {
+		t.Errorf("DialContext() should fail when the proxy refuses CONNECT")
+	}
+}
+
+func TestDialSOCKS5(t *testing.T) {
+	proxyAddr := serveOnce(t, func(conn net.Conn) {
+		buf := make([]byte, 512)
+
+		// Greeting: expect the username/password method
+		if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+			return
+		}
+		if _, err := io.ReadFull(conn, buf[:buf[1]]); err != nil {
+			return
+		}
+		conn.Write([]byte{5, 2})
+
+		// Username/password sub-negotiation
+		io.ReadFull(conn, buf[:2])
+		user := make([]byte, buf[1])
+		io.ReadFull(conn, user)
+		io.ReadFull(conn, buf[:1])
+		password := make([]byte, buf[0])
+		io.ReadFull(conn, password)
+		if string(user) != "user" || string(password) != "secret" {
+			conn.Write([]byte{1, 1})
+			return
+		}
+		conn.Write([]byte{1, 0})
+
+		// CONNECT request with a domain name
+		io.ReadFull(conn, buf[:5])
+		host := make([]byte, buf[4])
+		io.ReadFull(conn, host)
+		io.ReadFull(conn, buf[:2])
+		if string(host) != "target.test" || binary.BigEndian.Uint16(buf[:2]) != 443 {
+			conn.Write([]byte{5, 4, 0, 1, 0, 0, 0, 0, 0, 0})
+			return
+		}
+		conn.Write([]byte{5, 0, 0, 1, 127, 0, 0, 1, 0, 0})
+		echo(conn)
+	})
+
+	chain, err := ParseChain("", nil, []string{"*.test=socks5://user:secret@" + proxyAddr})
+	if err != nil {
+		t.Fatalf("ParseChain() failed: %v", err)
+	}
+	conn, err := chain.DialContext(context.Background(), "tcp", "target.test:443")
+	if err != nil {
+		t.Fatalf("DialContext() failed: %v", err)
+	}
+	roundTrip(t, conn)
+}
@@ -0,0 +1,862 @@
+package upstream
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"httpDebugger/pkg/http2Fingerprint"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+const (
+	defaultWindowSize   = 65535
+	defaultMaxFrameSize = 16384
+	// initialMaxConcurrentStreams limits streams until the server's SETTINGS arrive
+	initialMaxConcurrentStreams = 100
+)
+
+var (
+	errConnClosed  = errors.New("http2: upstream connection closed")
+	errBodyClosed  = errors.New("http2: response body closed")
+	errFlowControl = errors.New("http2: server exceeded the stream flow control window")
+	// errStreamUnprocessed fails streams a graceful GOAWAY left out; the server
+	// did not act on them, so they can be retried on a new connection
+	errStreamUnprocessed = errors.New("http2: stream not processed before GOAWAY")
+	// errStreamEnded stops writing a request body once the stream is over
+	errStreamEnded = errors.New("http2: stream ended")
+)
+
+// hopHeaders are connection-specific headers that are illegal in HTTP/2
+var hopHeaders = map[string]bool{
+	"connection":        true,
+	"keep-alive":        true,
+	"proxy-connection":  true,
+	"transfer-encoding": true,
+	"upgrade":           true,
+	"host":              true,
+}
+
+type headerOrderKey struct{}
+
+// WithHeaderOrder attaches the client's original header order to a request
+// context so the HTTP/2 client can replay it upstream
+func WithHeaderOrder(ctx context.Context, order []string) context.Context {
+	return context.WithValue(ctx, headerOrderKey{}, order)
+}
+
+func headerOrderFromContext(ctx context.Context) []string {
+	order, _ := ctx.Value(headerOrderKey{}).([]string)
+	return order
+}
+
+// http2Conn is a minimal HTTP/2 client connection that opens the connection with
+// the SETTINGS, WINDOW_UPDATE and PRIORITY frames captured from the client and
+// sends pseudo-headers and headers in the client's order
+type http2Conn struct {
+	conn        net.Conn
+	fingerprint *http2Fingerprint.HTTP2Fingerprint
+
+	wmu     sync.Mutex
+	framer  *http2.Framer
+	henc    *hpack.Encoder
+	headBuf bytes.Buffer
+
+	mu               sync.Mutex
+	cond             *sync.Cond
+	streams          map[uint32]*http2Stream
+	nextStreamID     uint32
+	peerMaxFrameSize uint32
+	peerInitialWin   int32
+	peerMaxStreams   uint32
+	connSendWindow   int32
+	// reserved counts requests waiting for the write lock to open their stream
+	reserved int
+	// localStreamWin and localConnWin are the receive windows the client
+	// advertised; connUnacked is connection window consumed but not yet returned
+	localStreamWin int32
+	localConnWin   int32
+	connUnacked    int32
+	closed         bool
+	goAway         bool
+	err            error
+	// idleTimer closes the connection once no stream used it for idleConnTimeout
+	idleTimer *time.Timer
+}
+
+type http2Stream struct {
+	id         uint32
+	sendWindow int32
+	respc      chan *http.Response
+	errc       chan error
+	body       *streamBody
+	// gotHeaders is guarded by http2Conn.mu
+	gotHeaders bool
+}
+
+func newHTTP2Conn(conn net.Conn, fingerprint *http2Fingerprint.HTTP2Fingerprint) (*http2Conn, error) {
+	cc := &http2Conn{
+		conn:             conn,
+		fingerprint:      fingerprint,
+		streams:          make(map[uint32]*http2Stream),
+		nextStreamID:     1,
+		peerMaxFrameSize: defaultMaxFrameSize,
+		peerInitialWin:   defaultWindowSize,
+		peerMaxStreams:   initialMaxConcurrentStreams,
+		connSendWindow:   defaultWindowSize,
+		localStreamWin:   defaultWindowSize,
+		localConnWin:     defaultWindowSize + int32(fingerprint.WindowUpdate),
+	}
+	cc.cond = sync.NewCond(&cc.mu)
+	cc.idleTimer = time.AfterFunc(idleConnTimeout, func() { cc.closeIfIdle() })
+	cc.framer = http2.NewFramer(conn, conn)
+	cc.framer.ReadMetaHeaders = hpack.NewDecoder(4096, nil)
+	cc.henc = hpack.NewEncoder(&cc.headBuf)
+
+	if maxFrame, ok := fingerprint.Setting(http2Fingerprint.SettingMaxFrameSize); ok {
+		cc.framer.SetMaxReadFrameSize(maxFrame)
+	}
+	if maxList, ok := fingerprint.Setting(http2Fingerprint.SettingMaxHeaderListSize); ok {
+		cc.framer.MaxHeaderListSize = maxList
+	}
+	if window, ok := fingerprint.Setting(http2Fingerprint.SettingInitialWindowSize); ok {
+		cc.localStreamWin = int32(window)
+	}
+
+	if err := cc.writePreface(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	go cc.readLoop()
+	return cc, nil
+}
+
+// writePreface sends the connection preface followed by the captured SETTINGS,
+// WINDOW_UPDATE and PRIORITY frames in their original order
+func (cc *http2Conn) writePreface() error {
+	cc.wmu.Lock()
+	defer cc.wmu.Unlock()
+
+	if _, err := io.WriteString(cc.conn, http2.ClientPreface); err != nil {
+		return err
+	}
+
+	settings := make([]http2.Setting, 0, len(cc.fingerprint.Settings))
+	for _, s := range cc.fingerprint.Settings {
+		settings = append(settings, http2.Setting{ID: http2.SettingID(s.ID), Val: s.Value})
+	}
+	if err := cc.framer.WriteSettings(settings...); err != nil {
+		return err
+	}
+
+	if cc.fingerprint.WindowUpdate > 0 {
+		if err := cc.framer.WriteWindowUpdate(0, cc.fingerprint.WindowUpdate); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range cc.fingerprint.Priorities {
+		err := cc.framer.WritePriority(p.StreamID, http2.PriorityParam{
+			StreamDep: p.DependsOn,
+			Exclusive: p.Exclusive,
+			Weight:    p.Weight,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// canTakeNewRequest reports whether the connection is still usable for new streams
+func (cc *http2Conn) canTakeNewRequest() bool {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return !cc.closed && !cc.goAway && cc.nextStreamID < 1<<31-1
+}
+
+func (cc *http2Conn) Close() error {
+	cc.closeWithError(errConnClosed)
+	return nil
+}
+
+// closeIfIdle closes the connection when no stream is open or about to be,
+// and reports whether it did
+func (cc *http2Conn) closeIfIdle() bool {
+	cc.mu.Lock()
+	idle := len(cc.streams) == 0 && cc.reserved == 0
+	if idle {
+		// Refuse new streams until the connection is closed below
+		cc.goAway = true
+	}
+	cc.mu.Unlock()
+	if idle {
+		cc.Close()
+	}
+	return idle
+}
+
+// streamDone wakes requests waiting for a stream and starts the idle timeout
+// once the last stream is gone, which closes a connection after GOAWAY right
+// away; callers must hold mu
+func (cc *http2Conn) streamDone() {
+	cc.cond.Broadcast()
+	if !cc.closed && len(cc.streams) == 0 && cc.reserved == 0 {
+		timeout := idleConnTimeout
+		if cc.goAway {
+			timeout = 0
+		}
+		cc.idleTimer.Reset(timeout)
+	}
+}
+
+func (cc *http2Conn) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading request body: %w", err)
+		}
+	}
+
+	if err := cc.reserveStream(req.Context()); err != nil {
+		return nil, err
+	}
+
+	// stream IDs must reach the wire in increasing order, so allocation and the
+	// HEADERS write happen under the same write lock
+	cc.wmu.Lock()
+	cc.mu.Lock()
+	cc.reserved--
+	if cc.closed || cc.goAway {
+		cc.cond.Broadcast()
+		cc.mu.Unlock()
+		cc.wmu.Unlock()
+		return nil, errConnClosed
+	}
+	stream := &http2Stream{
+		id:         cc.nextStreamID,
+		sendWindow: cc.peerInitialWin,
+		respc:      make(chan *http.Response, 1),
+		errc:       make(chan error, 1),
+	}
+	stream.body = newStreamBody(cc, stream.id)
+	cc.nextStreamID += 2
+	cc.streams[stream.id] = stream
+	maxFrame := int(cc.peerMaxFrameSize)
+	cc.mu.Unlock()
+
+	err := cc.writeHeaders(stream.id, req, len(body) == 0, len(body), maxFrame)
+	cc.wmu.Unlock()
+	if err != nil {
+		cc.closeWithError(err)
+		return nil, err
+	}
+
+	if len(body) > 0 {
+		// A stream ended by the server delivers its response or error below
+		if err := cc.writeBody(req.Context(), stream, body); err != nil && err != errStreamEnded {
+			cc.resetStream(stream.id, http2.ErrCodeCancel)
+			return nil, err
+		}
+	}
+
+	select {
+	case resp := <-stream.respc:
+		resp.Request = req
+		return resp, nil
+	case err := <-stream.errc:
+		return nil, err
+	case <-req.Context().Done():
+		cc.resetStream(stream.id, http2.ErrCodeCancel)
+		return nil, req.Context().Err()
+	}
+}
+
+// reserveStream waits until the server's SETTINGS_MAX_CONCURRENT_STREAMS allows
+// one more stream, then holds a place for it
+func (cc *http2Conn) reserveStream(ctx context.Context) error {
+	// Wake the wait below when the request is cancelled
+	stop := context.AfterFunc(ctx, func() {
+		cc.mu.Lock()
+		cc.cond.Broadcast()
+		cc.mu.Unlock()
+	})
+	defer stop()
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	for !cc.closed && !cc.goAway && uint32(len(cc.streams)+cc.reserved) >= cc.peerMaxStreams {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		cc.cond.Wait()
+	}
+	if cc.closed || cc.goAway {
+		return errConnClosed
+	}
+	cc.reserved++
+	return nil
+}
+
+// writeHeaders encodes and writes the request headers; callers must hold wmu
+func (cc *http2Conn) writeHeaders(streamID uint32, req *http.Request, endStream bool, contentLength int, maxFrame int) error {
+	cc.headBuf.Reset()
+	for _, name := range cc.pseudoHeaderOrder() {
+		cc.henc.WriteField(hpack.HeaderField{Name: name, Value: pseudoHeaderValue(name, req)})
+	}
+
+	hasContentLength := false
+	for _, name := range orderedHeaderNames(req.Header, headerOrderFromContext(req.Context())) {
+		lower := strings.ToLower(name)
+		if hopHeaders[lower] {
+			continue
+		}
+		if lower == "content-length" {
+			hasContentLength = true
+		}
+		for _, value := range req.Header[name] {
+			// TE is only allowed to ask for trailers
+			if lower == "te" && !strings.EqualFold(strings.TrimSpace(value), "trailers") {
+				continue
+			}
+			cc.henc.WriteField(hpack.HeaderField{Name: lower, Value: value})
+		}
+	}
+	if !hasContentLength && contentLength > 0 {
+		cc.henc.WriteField(hpack.HeaderField{Name: "content-length", Value: strconv.Itoa(contentLength)})
+	}
+
+	block := cc.headBuf.Bytes()
+
+	first := true
+	for len(block) > 0 || first {
+		chunk := block
+		if len(chunk) > maxFrame {
+			chunk = chunk[:maxFrame]
+		}
+		block = block[len(chunk):]
+		endHeaders := len(block) == 0
+
+		var err error
+		if first {
+			param := http2.HeadersFrameParam{
+				StreamID:      streamID,
+				BlockFragment: chunk,
+				EndStream:     endStream,
+				EndHeaders:    endHeaders,
+			}
+			if p := cc.fingerprint.HeaderPriority; p != nil {
+				param.Priority = http2.PriorityParam{StreamDep: p.DependsOn, Exclusive: p.Exclusive, Weight: p.Weight}
+			}
+			err = cc.framer.WriteHeaders(param)
+			first = false
+		} else {
+			err = cc.framer.WriteContinuation(streamID, endHeaders, chunk)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeBody sends the request body as DATA frames, respecting the peer's flow
+// control windows. It stops when ctx is cancelled or the stream ends
+func (cc *http2Conn) writeBody(ctx context.Context, stream *http2Stream, body []byte) error {
+	// Wake the wait below when the request is cancelled
+	stop := context.AfterFunc(ctx, func() {
+		cc.mu.Lock()
+		cc.cond.Broadcast()
+		cc.mu.Unlock()
+	})
+	defer stop()
+
+	for len(body) > 0 {
+		cc.mu.Lock()
+		for !cc.closed && cc.streams[stream.id] == stream && ctx.Err() == nil &&
+			(cc.connSendWindow <= 0 || stream.sendWindow <= 0) {
+			cc.cond.Wait()
+		}
+		if cc.closed {
+			err := cc.err
+			cc.mu.Unlock()
+			return err
+		}
+		if cc.streams[stream.id] != stream {
+			cc.mu.Unlock()
+			return errStreamEnded
+		}
+		if err := ctx.Err(); err != nil {
+			cc.mu.Unlock()
+			return err
+		}
+		n := int32(len(body))
+		n = min(n, cc.connSendWindow, stream.sendWindow, int32(cc.peerMaxFrameSize))
+		cc.connSendWindow -= n
+		stream.sendWindow -= n
+		cc.mu.Unlock()
+
+		chunk := body[:n]
+		body = body[n:]
+
+		cc.wmu.Lock()
+		err := cc.framer.WriteData(stream.id, len(body) == 0, chunk)
+		cc.wmu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resetStream cancels a stream that is still open; DATA arriving for it
+// afterwards is dropped
+func (cc *http2Conn) resetStream(streamID uint32, code http2.ErrCode) {
+	cc.mu.Lock()
+	stream, ok := cc.streams[streamID]
+	delete(cc.streams, streamID)
+	cc.streamDone()
+	cc.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	stream.body.closeWithError(errors.New("http2: stream reset"))
+
+	cc.wmu.Lock()
+	cc.framer.WriteRSTStream(streamID, code)
+	cc.wmu.Unlock()
+}
+
+func (cc *http2Conn) pseudoHeaderOrder() []string {
+	if len(cc.fingerprint.PseudoHeaderOrder) == 4 {
+		return cc.fingerprint.PseudoHeaderOrder
+	}
+	return []string{":method", ":authority", ":scheme", ":path"}
+}
+
+func pseudoHeaderValue(name string, req *http.Request) string {
+	switch name {
+	case ":method":
+		return req.Method
+	case ":authority":
+		if req.Host != "" {
+			return req.Host
+		}
+		return req.URL.Host
+	case ":scheme":
+		return req.URL.Scheme
+	case ":path":
+		return req.URL.RequestURI()
+	}
+	return ""
+}
+
+// orderedHeaderNames returns the request header names following the client's
+// original order, with any headers it did not send appended alphabetically
+func orderedHeaderNames(header http.Header, order []string) []string {
+	names := make([]string, 0, len(header))
+	seen := make(map[string]bool, len(header))
+
+	for _, name := range order {
+		canonical := http.CanonicalHeaderKey(name)
+		if _, ok := header[canonical]; ok && !seen[canonical] {
+			names = append(names, canonical)
+			seen[canonical] = true
+		}
+	}
+
+	var rest []string
+	for name := range header {
+		if !seen[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+
+	return append(names, rest...)
+}
+
+func (cc *http2Conn) readLoop() {
+	for {
+		frame, err := cc.framer.ReadFrame()
+		if err != nil {
+			cc.closeWithError(err)
+			return
+		}
+
+		switch f := frame.(type) {
+		case *http2.SettingsFrame:
+			cc.handleSettings(f)
+		case *http2.MetaHeadersFrame:
+			cc.handleHeaders(f)
+		case *http2.DataFrame:
+			cc.handleData(f)
+		case *http2.WindowUpdateFrame:
+			cc.handleWindowUpdate(f)
+		case *http2.PingFrame:
+			if !f.IsAck() {
+				cc.wmu.Lock()
+				cc.framer.WritePing(true, f.Data)
+				cc.wmu.Unlock()
+			}
+		case *http2.RSTStreamFrame:
+			cc.endStream(f.StreamID, fmt.Errorf("http2: stream reset by server: %v", f.ErrCode))
+		case *http2.GoAwayFrame:
+			if f.ErrCode != http2.ErrCodeNo {
+				cc.mu.Lock()
+				cc.goAway = true
+				cc.mu.Unlock()
+				cc.closeWithError(fmt.Errorf("http2: server sent GOAWAY: %v", f.ErrCode))
+				return
+			}
+			cc.handleGoAway(f.LastStreamID)
+		}
+	}
+}
+
+// handleGoAway stops new streams and fails the ones above lastStreamID, which
+// the server will not process. The others finish normally
+func (cc *http2Conn) handleGoAway(lastStreamID uint32) {
+	cc.mu.Lock()
+	cc.goAway = true
+	var unprocessed []uint32
+	for id := range cc.streams {
+		if id > lastStreamID {
+			unprocessed = append(unprocessed, id)
+		}
+	}
+	// Close right away when nothing is left to finish
+	cc.streamDone()
+	cc.mu.Unlock()
+
+	for _, id := range unprocessed {
+		cc.endStream(id, errStreamUnprocessed)
+	}
+}
+
+func (cc *http2Conn) handleSettings(f *http2.SettingsFrame) {
+	if f.IsAck() {
+		return
+	}
+
+	tableSize, hasTableSize := uint32(0), false
+
+	cc.mu.Lock()
+	f.ForeachSetting(func(s http2.Setting) error {
+		switch s.ID {
+		case http2.SettingMaxFrameSize:
+			cc.peerMaxFrameSize = s.Val
+		case http2.SettingInitialWindowSize:
+			delta := int32(s.Val) - cc.peerInitialWin
+			for _, stream := range cc.streams {
+				stream.sendWindow += delta
+			}
+			cc.peerInitialWin = int32(s.Val)
+		case http2.SettingHeaderTableSize:
+			tableSize, hasTableSize = s.Val, true
+		case http2.SettingMaxConcurrentStreams:
+			cc.peerMaxStreams = s.Val
+		}
+		return nil
+	})
+	cc.cond.Broadcast()
+	cc.mu.Unlock()
+
+	cc.wmu.Lock()
+	if hasTableSize {
+		cc.henc.SetMaxDynamicTableSizeLimit(tableSize)
+	}
+	cc.framer.WriteSettingsAck()
+	cc.wmu.Unlock()
+}
+
+func (cc *http2Conn) handleHeaders(f *http2.MetaHeadersFrame) {
+	cc.mu.Lock()
+	stream, ok := cc.streams[f.StreamID]
+	gotHeaders := ok && stream.gotHeaders
+	cc.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	if gotHeaders {
+		// trailers
+		if f.StreamEnded() {
+			cc.endStream(f.StreamID, nil)
+		}
+		return
+	}
+
+	status, err := strconv.Atoi(f.PseudoValue("status"))
+	if err != nil {
+		cc.endStream(f.StreamID, fmt.Errorf("http2: invalid :status %q", f.PseudoValue("status")))
+		return
+	}
+	if status >= 100 && status < 200 {
+		return
+	}
+	cc.mu.Lock()
+	stream.gotHeaders = true
+	cc.mu.Unlock()
+
+	header := make(http.Header)
+	for _, hf := range f.RegularFields() {
+		header.Add(http.CanonicalHeaderKey(hf.Name), hf.Value)
+	}
+
+	contentLength := int64(-1)
+	if cl := header.Get("Content-Length"); cl != "" {
+		if n, err := strconv.ParseInt(cl, 10, 64); err == nil {
+			contentLength = n
+		}
+	}
+
+	stream.respc <- &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/2.0",
+		ProtoMajor:    2,
+		Header:        header,
+		Body:          stream.body,
+		ContentLength: contentLength,
+	}
+
+	if f.StreamEnded() {
+		cc.endStream(f.StreamID, nil)
+	}
+}
+
+// handleData buffers the body of a stream. Its flow control windows are only
+// returned as the body is read, except for padding and data nobody will read
+func (cc *http2Conn) handleData(f *http2.DataFrame) {
+	cc.mu.Lock()
+	stream, ok := cc.streams[f.StreamID]
+	cc.mu.Unlock()
+
+	data := f.Data()
+	padding := int32(f.Length) - int32(len(data))
+	unread := padding
+
+	if ok {
+		if err := stream.body.write(data); err != nil {
+			unread += int32(len(data))
+			if err == errFlowControl {
+				cc.resetStream(f.StreamID, http2.ErrCodeFlowControl)
+				ok = false
+			}
+		}
+	} else {
+		unread += int32(len(data))
+	}
+
+	cc.consumed(unread)
+	if ok && padding > 0 && !f.StreamEnded() {
+		cc.writeWindowUpdate(f.StreamID, padding)
+	}
+
+	if ok && f.StreamEnded() {
+		cc.endStream(f.StreamID, nil)
+	}
+}
+
+// consumed returns n bytes to the connection receive window, sending a
+// WINDOW_UPDATE once a quarter of the window is waiting
+func (cc *http2Conn) consumed(n int32) {
+	if n <= 0 {
+		return
+	}
+	cc.mu.Lock()
+	cc.connUnacked += n
+	increment := int32(0)
+	if cc.connUnacked >= windowThreshold(cc.localConnWin) {
+		increment, cc.connUnacked = cc.connUnacked, 0
+	}
+	cc.mu.Unlock()
+
+	cc.writeWindowUpdate(0, increment)
+}
+
+func (cc *http2Conn) writeWindowUpdate(streamID uint32, increment int32) {
+	if increment <= 0 {
+		return
+	}
+	cc.wmu.Lock()
+	cc.framer.WriteWindowUpdate(streamID, uint32(increment))
+	cc.wmu.Unlock()
+}
+
+func windowThreshold(window int32) int32 {
+	return max(window/4, 1)
+}
+
+func (cc *http2Conn) handleWindowUpdate(f *http2.WindowUpdateFrame) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if f.StreamID == 0 {
+		cc.connSendWindow += int32(f.Increment)
+	} else if stream, ok := cc.streams[f.StreamID]; ok {
+		stream.sendWindow += int32(f.Increment)
+	}
+	cc.cond.Broadcast()
+}
+
+// endStream finishes a stream, failing it with err when the response never arrived
+func (cc *http2Conn) endStream(streamID uint32, err error) {
+	cc.mu.Lock()
+	stream, ok := cc.streams[streamID]
+	delete(cc.streams, streamID)
+	gotHeaders := ok && stream.gotHeaders
+	cc.streamDone()
+	cc.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	if !gotHeaders {
+		if err == nil {
+			err = errors.New("http2: stream ended without response headers")
+		}
+		stream.errc <- err
+	}
+
+	if err == nil {
+		err = io.EOF
+	}
+	stream.body.closeWithError(err)
+}
+
+func (cc *http2Conn) closeWithError(err error) {
+	cc.mu.Lock()
+	if cc.closed {
+		cc.mu.Unlock()
+		return
+	}
+	cc.closed = true
+	cc.err = err
+	cc.idleTimer.Stop()
+	streams := cc.streams
+	cc.streams = make(map[uint32]*http2Stream)
+	waiting := make(map[uint32]bool, len(streams))
+	for id, stream := range streams {
+		waiting[id] = !stream.gotHeaders
+	}
+	cc.cond.Broadcast()
+	cc.mu.Unlock()
+
+	for id, stream := range streams {
+		if waiting[id] {
+			stream.errc <- err
+		}
+		stream.body.closeWithError(err)
+	}
+	cc.conn.Close()
+}
+
+// streamBody buffers DATA frames so the read loop never blocks on a slow reader.
+// The stream window is returned as the body is read, so the server never sends
+// more than the window the client advertised
+type streamBody struct {
+	cc       *http2Conn
+	streamID uint32
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	buf  bytes.Buffer
+	// unacked counts bytes read but not yet returned to the stream window
+	unacked int32
+	err     error
+}
+
+func newStreamBody(cc *http2Conn, streamID uint32) *streamBody {
+	b := &streamBody{cc: cc, streamID: streamID}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+// write buffers p; it fails once the body is closed or when the server
+// overran the stream window
+func (b *streamBody) write(p []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err != nil {
+		return b.err
+	}
+	if int64(b.buf.Len())+int64(b.unacked)+int64(len(p)) > int64(b.cc.localStreamWin) {
+		return errFlowControl
+	}
+	b.buf.Write(p)
+	b.cond.Broadcast()
+	return nil
+}
+
+func (b *streamBody) closeWithError(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err == nil {
+		b.err = err
+	}
+	b.cond.Broadcast()
+}
+
+func (b *streamBody) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	for b.buf.Len() == 0 && b.err == nil {
+		b.cond.Wait()
+	}
+	if b.buf.Len() == 0 {
+		err := b.err
+		b.mu.Unlock()
+		return 0, err
+	}
+
+	n, _ := b.buf.Read(p)
+	// Once the stream ended the server sends nothing more on it
+	increment := int32(0)
+	if b.err == nil {
+		b.unacked += int32(n)
+		if b.unacked >= windowThreshold(b.cc.localStreamWin) {
+			increment, b.unacked = b.unacked, 0
+		}
+	}
+	b.mu.Unlock()
+
+	b.cc.consumed(int32(n))
+	b.cc.writeWindowUpdate(b.streamID, increment)
+	return n, nil
+}
+
+// Close cancels the stream if the response is still arriving and drops
+// whatever was buffered
+func (b *streamBody) Close() error {
+	b.mu.Lock()
+	if b.err == nil {
+		b.err = errBodyClosed
+	}
+	discarded := int32(b.buf.Len())
+	b.buf.Reset()
+	b.cond.Broadcast()
+	b.mu.Unlock()
+
+	b.cc.consumed(discarded)
+	b.cc.resetStream(b.streamID, http2.ErrCodeCancel)
+	return nil
+}
@@ -0,0 +1,435 @@
+package upstream
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"httpDebugger/pkg/http2Fingerprint"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// newTestHTTP2Conn connects the fingerprinted client to an HTTP/2 server
+// speaking cleartext over a local TCP connection
+func newTestHTTP2Conn(t *testing.T, server *http2.Server, handler http.HandlerFunc) *http2Conn {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go server.ServeConn(conn, &http2.ServeConnOpts{Handler: handler})
+		}
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fingerprint := &http2Fingerprint.HTTP2Fingerprint{
+		Settings: []http2Fingerprint.Setting{{ID: http2Fingerprint.SettingInitialWindowSize, Value: 65535}},
+	}
+	cc, err := newHTTP2Conn(conn, fingerprint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cc.Close() })
+	return cc
+}
+
+// waitForSettings waits until applied reports, under the connection lock,
+// that the server's SETTINGS were applied
+func waitForSettings(t *testing.T, cc *http2Conn, applied func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		cc.mu.Lock()
+		done := applied()
+		cc.mu.Unlock()
+		if done {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the server's SETTINGS never arrived")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func newTestRequest(t *testing.T, addr string) *http.Request {
+	req, err := http.NewRequest("GET", "http://"+addr+"/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func TestHTTP2MaxConcurrentStreams(t *testing.T) {
+	var active, peak atomic.Int32
+	cc := newTestHTTP2Conn(t, &http2.Server{MaxConcurrentStreams: 2}, func(w http.ResponseWriter, r *http.Request) {
+		n := active.Add(1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(20 * time.Millisecond)
+		active.Add(-1)
+		io.WriteString(w, "ok")
+	})
+
+	// Streams opened before the server's SETTINGS arrive are refused rather than queued
+	waitForSettings(t, cc, func() bool { return cc.peerMaxStreams == 2 })
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := cc.RoundTrip(newTestRequest(t, "example.com"))
+			if err != nil {
+				errs <- err
+				return
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("RoundTrip() failed: %v", err)
+	}
+	if peak.Load() > 2 {
+		t.Errorf("%d streams were open at once, the server allows 2", peak.Load())
+	}
+}
+
+func TestHTTP2FlowControl(t *testing.T) {
+	var written atomic.Int64
+	handlerDone := make(chan error, 1)
+	cc := newTestHTTP2Conn(t, &http2.Server{}, func(w http.ResponseWriter, r *http.Request) {
+		chunk := bytes.Repeat([]byte("x"), 4096)
+		for range 1024 {
+			if _, err := w.Write(chunk); err != nil {
+				handlerDone <- err
+				return
+			}
+			w.(http.Flusher).Flush()
+			written.Add(int64(len(chunk)))
+		}
+		handlerDone <- nil
+	})
+
+	resp, err := cc.RoundTrip(newTestRequest(t, "example.com"))
+	if err != nil {
+		t.Fatalf("RoundTrip() failed: %v", err)
+	}
+
+	// Without reading, the server can only send the advertised stream window
+	time.Sleep(100 * time.Millisecond)
+	if n := written.Load(); n > 2*defaultWindowSize {
+		t.Errorf("server sent %d bytes to a reader that read nothing", n)
+	}
+
+	if _, err := io.ReadFull(resp.Body, make([]byte, 1024)); err != nil {
+		t.Fatalf("reading the body failed: %v", err)
+	}
+	resp.Body.Close()
+
+	select {
+	case err := <-handlerDone:
+		if err == nil {
+			t.Error("the server kept sending after the body was closed")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("closing the body did not reset the stream")
+	}
+
+	cc.mu.Lock()
+	open := len(cc.streams)
+	cc.mu.Unlock()
+	if open != 0 {
+		t.Errorf("%d streams still open after Close()", open)
+	}
+
+	// The dropped data went back to the connection window, so it stays usable
+	resp, err = cc.RoundTrip(newTestRequest(t, "example.com"))
+	if err != nil {
+		t.Fatalf("RoundTrip() after Close() failed: %v", err)
+	}
+	if _, err := io.ReadFull(resp.Body, make([]byte, 256*1024)); err != nil {
+		t.Errorf("reading a second body failed: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestHTTP2ConnectionHeaders(t *testing.T) {
+	headers := make(chan http.Header, 1)
+	cc := newTestHTTP2Conn(t, &http2.Server{}, func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header
+	})
+
+	req := newTestRequest(t, "example.com")
+	req.Header.Set("TE", "gzip")
+	req.Header.Set("Connection", "keep-alive")
+	req.Header.Set("Keep-Alive", "timeout=5")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("X-Test", "1")
+
+	resp, err := cc.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip() failed: %v", err)
+	}
+	resp.Body.Close()
+
+	got := <-headers
+	for _, name := range []string{"Te", "Connection", "Keep-Alive", "Upgrade"} {
+		if _, ok := got[name]; ok {
+			t.Errorf("%s should not be sent over HTTP/2", name)
+		}
+	}
+	if got.Get("X-Test") != "1" {
+		t.Error("regular headers should be kept")
+	}
+
+	req = newTestRequest(t, "example.com")
+	req.Header.Set("TE", "trailers")
+	if resp, err := cc.RoundTrip(req); err != nil {
+		t.Fatalf("RoundTrip() with TE: trailers failed: %v", err)
+	} else {
+		resp.Body.Close()
+	}
+	if got := <-headers; got.Get("Te") != "trailers" {
+		t.Errorf("TE: trailers should be kept, got %q", got.Get("Te"))
+	}
+}
+
+func TestHTTP2CloseIdleConnections(t *testing.T) {
+	release := make(chan struct{})
+	cc := newTestHTTP2Conn(t, &http2.Server{}, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		io.WriteString(w, "ok")
+	})
+	transport := NewFingerprintTransport(nil, nil, defaultDial)
+	transport.h2Conns["example.com:443"] = cc
+
+	done := make(chan error, 1)
+	go func() {
+		resp, err := cc.RoundTrip(newTestRequest(t, "example.com"))
+		if err == nil {
+			_, err = io.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+		done <- err
+	}()
+
+	// A connection serving a request is not idle
+	time.Sleep(50 * time.Millisecond)
+	transport.CloseIdleConnections()
+	if !cc.canTakeNewRequest() || len(transport.h2Conns) != 1 {
+		t.Fatal("CloseIdleConnections() closed a busy connection")
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	transport.CloseIdleConnections()
+	if cc.canTakeNewRequest() || len(transport.h2Conns) != 0 {
+		t.Error("CloseIdleConnections() left an idle connection open")
+	}
+}
+
+// newRawHTTP2Conn connects the fingerprinted client to a server scripted frame
+// by frame by serve, which starts after the preface and SETTINGS exchange
+func newRawHTTP2Conn(t *testing.T, settings []http2.Setting, serve func(fr *http2.Framer)) *http2Conn {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		server, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer server.Close()
+		if _, err := io.ReadFull(server, make([]byte, len(http2.ClientPreface))); err != nil {
+			return
+		}
+		fr := http2.NewFramer(server, server)
+		fr.ReadMetaHeaders = hpack.NewDecoder(4096, nil)
+		if fr.WriteSettings(settings...) != nil {
+			return
+		}
+		serve(fr)
+		// Keep reading so the client never blocks on a write
+		for {
+			if _, err := fr.ReadFrame(); err != nil {
+				return
+			}
+		}
+	}()
+
+	client, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cc, err := newHTTP2Conn(client, &http2Fingerprint.HTTP2Fingerprint{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cc.Close() })
+	return cc
+}
+
+// readHeaders returns the IDs of the next n streams the client opens
+func readHeaders(fr *http2.Framer, n int) []uint32 {
+	var ids []uint32
+	for len(ids) < n {
+		frame, err := fr.ReadFrame()
+		if err != nil {
+			return ids
+		}
+		if f, ok := frame.(*http2.MetaHeadersFrame); ok {
+			ids = append(ids, f.StreamID)
+		}
+	}
+	return ids
+}
+
+func writeResponse(fr *http2.Framer, streamID uint32, body string) {
+	var buf bytes.Buffer
+	enc := hpack.NewEncoder(&buf)
+	enc.WriteField(hpack.HeaderField{Name: ":status", Value: "200"})
+	fr.WriteHeaders(http2.HeadersFrameParam{StreamID: streamID, BlockFragment: buf.Bytes(), EndHeaders: true})
+	fr.WriteData(streamID, true, []byte(body))
+}
+
+func TestHTTP2GracefulGoAway(t *testing.T) {
+	opened := make(chan struct{})
+	cc := newRawHTTP2Conn(t, nil, func(fr *http2.Framer) {
+		<-opened
+		ids := readHeaders(fr, 2)
+		if len(ids) != 2 {
+			return
+		}
+		first := min(ids[0], ids[1])
+		fr.WriteGoAway(first, http2.ErrCodeNo, nil)
+		writeResponse(fr, first, "ok")
+	})
+
+	type result struct {
+		body string
+		err  error
+	}
+	results := make(chan result, 2)
+	for range 2 {
+		go func() {
+			resp, err := cc.RoundTrip(newTestRequest(t, "example.com"))
+			if err != nil {
+				results <- result{err: err}
+				return
+			}
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			results <- result{body: string(body), err: err}
+		}()
+	}
+	close(opened)
+
+	var ok, unprocessed int
+	for range 2 {
+		select {
+		case r := <-results:
+			switch {
+			case r.err == nil && r.body == "ok":
+				ok++
+			case errors.Is(r.err, errStreamUnprocessed):
+				unprocessed++
+			default:
+				t.Errorf("unexpected result %q, %v", r.body, r.err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("a stream above the GOAWAY's last stream ID was never failed")
+		}
+	}
+	if ok != 1 || unprocessed != 1 {
+		t.Errorf("%d streams finished and %d were unprocessed, want 1 and 1", ok, unprocessed)
+	}
+	if cc.canTakeNewRequest() {
+		t.Error("the connection accepts new streams after GOAWAY")
+	}
+}
+
+func TestHTTP2WriteBodyStops(t *testing.T) {
+	// The server never opens its flow control window, so the body cannot be sent
+	noWindow := []http2.Setting{{ID: http2.SettingInitialWindowSize, Val: 0}}
+	newUpload := func(ctx context.Context) *http.Request {
+		req, err := http.NewRequestWithContext(ctx, "POST", "http://example.com/", strings.NewReader("body"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return req
+	}
+
+	t.Run("reset by server", func(t *testing.T) {
+		cc := newRawHTTP2Conn(t, noWindow, func(fr *http2.Framer) {
+			for _, id := range readHeaders(fr, 1) {
+				fr.WriteRSTStream(id, http2.ErrCodeRefusedStream)
+			}
+		})
+		waitForSettings(t, cc, func() bool { return cc.peerInitialWin == 0 })
+		done := make(chan error, 1)
+		go func() {
+			_, err := cc.RoundTrip(newUpload(context.Background()))
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			if err == nil || !strings.Contains(err.Error(), "REFUSED_STREAM") {
+				t.Errorf("RoundTrip() = %v, want the stream reset", err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("the body writer kept waiting on a reset stream")
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		cc := newRawHTTP2Conn(t, noWindow, func(fr *http2.Framer) {})
+		waitForSettings(t, cc, func() bool { return cc.peerInitialWin == 0 })
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		done := make(chan error, 1)
+		go func() {
+			_, err := cc.RoundTrip(newUpload(ctx))
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("RoundTrip() = %v, want the context error", err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("the body writer ignored the cancelled context")
+		}
+		if !cc.canTakeNewRequest() {
+			t.Error("cancelling one request closed the connection")
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package upstream
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+
+	"httpDebugger/pkg/clientHello"
+	"httpDebugger/pkg/http2Fingerprint"
+)
+
+// maxPoolClients bounds the fingerprinted clients kept at once; the least
+// recently used one is dropped beyond it
+const maxPoolClients = 64
+
+// Pool hands out one HTTP client per distinct ClientHello and HTTP/2 fingerprint
+// so connections opened with a given fingerprint are reused only for requests carrying it
+type Pool struct {
+	fallback *http.Client
+	dial     DialFunc
+
+	mu      sync.Mutex
+	clients map[string]*list.Element
+	lru     *list.List
+}
+
+type pooledClient struct {
+	key    string
+	client *http.Client
+}
+
+// NewPool creates a pool that falls back to the given client when a request has no
+// usable fingerprint (plain HTTP, or a ClientHello utls could not parse)
+func NewPool(fallback *http.Client) *Pool {
+	return &Pool{
+		fallback: fallback,
+		dial:     defaultDial,
+		clients:  make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// SetChain routes connections of fingerprinted clients through the upstream
+// proxies of chain; call it before serving
+func (p *Pool) SetChain(chain *Chain) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.dial = chain.DialContext
+	for elem := p.lru.Front(); elem != nil; elem = elem.Next() {
+		elem.Value.(*pooledClient).client.CloseIdleConnections()
+	}
+	p.clients = make(map[string]*list.Element)
+	p.lru.Init()
+}
+
+// Client returns the client that mimics the given fingerprints. h2Fingerprint may
+// be nil, in which case HTTP/2 upstream connections use the x/net defaults
+func (p *Pool) Client(fingerprint *clientHello.TLSFingerprint, h2Fingerprint *http2Fingerprint.HTTP2Fingerprint) *http.Client {
+	if fingerprint == nil || fingerprint.Spec == nil || len(fingerprint.Raw) == 0 {
+		return p.fallback
+	}
+
+	key := fingerprint.SpecKey()
+	if h2Fingerprint != nil {
+		key += "|" + h2Fingerprint.Akamai
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if elem, ok := p.clients[key]; ok {
+		p.lru.MoveToFront(elem)
+		return elem.Value.(*pooledClient).client
+	}
+
+	if _, err := fingerprint.NewSpec(); err != nil {
+		return p.fallback
+	}
+
+	client := &http.Client{
+		Transport:     NewFingerprintTransport(fingerprint, h2Fingerprint, p.dial),
+		Timeout:       p.fallback.Timeout,
+		CheckRedirect: p.fallback.CheckRedirect,
+	}
+	p.add(key, client)
+	return client
+}
+
+// add stores client under key, evicting the least recently used client when
+// the pool is full. Its idle connections are closed; busy ones close once
+// their requests finish and they sit idle
+func (p *Pool) add(key string, client *http.Client) {
+	p.clients[key] = p.lru.PushFront(&pooledClient{key: key, client: client})
+	if p.lru.Len() > maxPoolClients {
+		oldest := p.lru.Back()
+		p.lru.Remove(oldest)
+		evicted := oldest.Value.(*pooledClient)
+		delete(p.clients, evicted.key)
+		evicted.client.CloseIdleConnections()
+	}
+}
+
+// CloseIdleConnections closes idle connections of every pooled transport
+func (p *Pool) CloseIdleConnections() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for elem := p.lru.Front(); elem != nil; elem = elem.Next() {
+		elem.Value.(*pooledClient).client.CloseIdleConnections()
+	}
+	p.fallback.CloseIdleConnections()
+}
@@ -0,0 +1,30 @@
+package upstream
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestPoolEvictsLeastRecentlyUsed(t *testing.T) {
+	pool := NewPool(&http.Client{})
+	first := &http.Client{}
+	pool.add("first", first)
+	for i := range maxPoolClients - 1 {
+		pool.add(fmt.Sprint(i), &http.Client{})
+	}
+
+	// Using the oldest client keeps it in the pool
+	pool.lru.MoveToFront(pool.clients["first"])
+	pool.add("new", &http.Client{})
+
+	if len(pool.clients) != maxPoolClients || pool.lru.Len() != maxPoolClients {
+		t.Fatalf("pool holds %d clients, want %d", len(pool.clients), maxPoolClients)
+	}
+	if _, ok := pool.clients["first"]; !ok {
+		t.Error("a recently used client was evicted")
+	}
+	if _, ok := pool.clients["0"]; ok {
+		t.Error("the least recently used client was kept")
+	}
+}
@@ -0,0 +1,299 @@
+package upstream
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sync"
+	"time"
+
+	"httpDebugger/pkg/clientHello"
+	"httpDebugger/pkg/http2Fingerprint"
+	"httpDebugger/pkg/proxy/connections"
+
+	utls "github.com/refraction-networking/utls"
+	"golang.org/x/net/http2"
+)
+
+const (
+	dialTimeout     = 30 * time.Second
+	idleConnTimeout = 90 * time.Second
+	// spareIdleTimeout is how long the connection that discovered a server's
+	// protocol waits for the request it was opened for before it is dropped
+	spareIdleTimeout = 5 * time.Second
+)
+
+// DialFunc opens the raw TCP connection used for upstream traffic
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// FingerprintTransport is an http.RoundTripper that performs the upstream TLS
+// handshake with the ClientHello captured from the intercepted client, so the
+// server sees the same cipher suites, extensions, GREASE and extension order.
+// When an HTTP/2 fingerprint is known, HTTP/2 connections replay it as well
+type FingerprintTransport struct {
+	fingerprint   *clientHello.TLSFingerprint
+	h2Fingerprint *http2Fingerprint.HTTP2Fingerprint
+	dial          DialFunc
+	h1            *http.Transport
+	h2            *http2.Transport
+
+	mu        sync.Mutex
+	protocols map[string]string
+	spare     map[string]net.Conn
+	h2Conns   map[string]*http2Conn
+}
+
+func NewFingerprintTransport(fingerprint *clientHello.TLSFingerprint, h2Fingerprint *http2Fingerprint.HTTP2Fingerprint, dial DialFunc) *FingerprintTransport {
+	t := &FingerprintTransport{
+		fingerprint:   fingerprint,
+		h2Fingerprint: h2Fingerprint,
+		dial:          dial,
+		protocols:     make(map[string]string),
+		spare:         make(map[string]net.Conn),
+		h2Conns:       make(map[string]*http2Conn),
+	}
+
+	t.h1 = &http.Transport{
+		DialContext:         dial,
+		DialTLSContext:      t.dialTLSConn,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     idleConnTimeout,
+		DisableCompression:  true,
+	}
+
+	t.h2 = &http2.Transport{
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return t.dialTLSConn(ctx, network, addr)
+		},
+		DisableCompression: true,
+		IdleConnTimeout:    idleConnTimeout,
+	}
+
+	return t
+}
+
+// RoundTrip picks HTTP/1.1 or HTTP/2 based on what the server negotiated via ALPN
+// when first contacted with the mimicked ClientHello
+func (t *FingerprintTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "https" {
+		return t.h1.RoundTrip(req)
+	}
+
+	addr := canonicalAddr(req.URL)
+	proto, err := t.protocolFor(req.Context(), addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if proto == http2.NextProtoTLS {
+		if t.h2Fingerprint != nil {
+			return t.roundTripHTTP2(req, addr)
+		}
+		return t.h2.RoundTrip(req)
+	}
+	return t.h1.RoundTrip(req)
+}
+
+// roundTripHTTP2 sends the request over a connection that replays the client's
+// HTTP/2 fingerprint, retrying once if a pooled connection went away meanwhile
+// or did not process the request before GOAWAY
+func (t *FingerprintTransport) roundTripHTTP2(req *http.Request, addr string) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		cc, err := t.http2ConnFor(req.Context(), addr)
+		if err != nil {
+			return nil, err
+		}
+		// Report the connection like net/http does, so callers can inspect its TLS state
+		if trace := httptrace.ContextClientTrace(req.Context()); trace != nil && trace.GotConn != nil {
+			trace.GotConn(httptrace.GotConnInfo{Conn: cc.conn})
+		}
+
+		resp, err := cc.RoundTrip(req)
+		retryable := errors.Is(err, errConnClosed) || errors.Is(err, errStreamUnprocessed)
+		if err == nil || !retryable || attempt > 0 {
+			return resp, err
+		}
+
+		if req.GetBody != nil {
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+	}
+}
+
+func (t *FingerprintTransport) http2ConnFor(ctx context.Context, addr string) (*http2Conn, error) {
+	t.mu.Lock()
+	cc, ok := t.h2Conns[addr]
+	t.mu.Unlock()
+	if ok && cc.canTakeNewRequest() {
+		return cc, nil
+	}
+
+	conn, err := t.dialTLSConn(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	cc, err = newHTTP2Conn(conn, t.h2Fingerprint)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	if old, ok := t.h2Conns[addr]; ok && !old.canTakeNewRequest() {
+		old.Close()
+	}
+	t.h2Conns[addr] = cc
+	t.mu.Unlock()
+
+	return cc, nil
+}
+
+func (t *FingerprintTransport) CloseIdleConnections() {
+	t.mu.Lock()
+	for addr, conn := range t.spare {
+		conn.Close()
+		delete(t.spare, addr)
+	}
+	for addr, cc := range t.h2Conns {
+		if cc.closeIfIdle() || !cc.canTakeNewRequest() {
+			delete(t.h2Conns, addr)
+		}
+	}
+	t.mu.Unlock()
+
+	t.h1.CloseIdleConnections()
+	t.h2.CloseIdleConnections()
+}
+
+// protocolFor returns the ALPN protocol negotiated with addr. The connection used
+// to discover it is kept as a spare so the first request does not pay for two handshakes
+func (t *FingerprintTransport) protocolFor(ctx context.Context, addr string) (string, error) {
+	t.mu.Lock()
+	proto, known := t.protocols[addr]
+	t.mu.Unlock()
+	if known {
+		return proto, nil
+	}
+
+	conn, err := t.dialTLS(ctx, "tcp", addr)
+	if err != nil {
+		return "", err
+	}
+	proto = conn.ConnectionState().NegotiatedProtocol
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.protocols[addr] = proto
+	if old, ok := t.spare[addr]; ok {
+		old.Close()
+	}
+	t.spare[addr] = conn
+	// Servers close idle connections, so a spare is only trusted briefly
+	time.AfterFunc(spareIdleTimeout, func() { t.dropSpare(addr, conn) })
+
+	return proto, nil
+}
+
+// dropSpare closes conn if it is still the unused spare for addr
+func (t *FingerprintTransport) dropSpare(addr string, conn net.Conn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.spare[addr] == conn {
+		conn.Close()
+		delete(t.spare, addr)
+	}
+}
+
+func (t *FingerprintTransport) takeSpare(addr string) net.Conn {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	conn, ok := t.spare[addr]
+	if !ok {
+		return nil
+	}
+	delete(t.spare, addr)
+	return conn
+}
+
+func (t *FingerprintTransport) dialTLSConn(ctx context.Context, network, addr string) (net.Conn, error) {
+	if conn := t.takeSpare(addr); conn != nil {
+		return conn, nil
+	}
+	return t.dialTLS(ctx, network, addr)
+}
+
+// TLSConn is an upstream TLS connection that remembers the server's ServerHello
+type TLSConn struct {
+	*utls.UConn
+	serverHello *clientHello.ServerHelloFingerprint
+}
+
+// ServerHello returns the fingerprint of the server's ServerHello, nil when it
+// could not be parsed
+func (c *TLSConn) ServerHello() *clientHello.ServerHelloFingerprint {
+	return c.serverHello
+}
+
+// dialTLS opens a TLS connection whose ClientHello is rebuilt from the captured fingerprint
+func (t *FingerprintTransport) dialTLS(ctx context.Context, network, addr string) (*TLSConn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upstream address %s: %w", addr, err)
+	}
+
+	spec, err := t.fingerprint.NewSpec()
+	if err != nil {
+		return nil, err
+	}
+
+	rawConn, err := t.dial(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	recorder := connections.NewHelloRecorder(rawConn)
+	uconn := utls.UClient(recorder, &utls.Config{ServerName: host}, utls.HelloCustom)
+	if err := uconn.ApplyPreset(spec); err != nil {
+		rawConn.Close()
+		return nil, fmt.Errorf("applying client hello spec: %w", err)
+	}
+
+	if err := uconn.HandshakeContext(ctx); err != nil {
+		rawConn.Close()
+		return nil, fmt.Errorf("upstream TLS handshake with %s: %w", addr, err)
+	}
+
+	serverHello, _ := clientHello.ParseServerHello(recorder.Record())
+	return &TLSConn{UConn: uconn, serverHello: serverHello}, nil
+}
+
+func canonicalAddr(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+func defaultDial(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{
+		Timeout:   dialTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	return dialer.DialContext(ctx, network, addr)
+}
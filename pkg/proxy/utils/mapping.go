@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"net/http"
+
+	"httpDebugger/pkg/mapping"
+	"httpDebugger/pkg/proxy/types"
+	"httpDebugger/pkg/sessiondata"
+)
+
+// applyMapping answers forwardedReq from a Map Local rule, or points it at the
+// target of a Map Remote rule. A nil response means the request must still be sent
+func applyMapping(forwardedReq *http.Request, session *sessiondata.Session, config *types.Config) (*http.Response, error) {
+	rule := config.Mappings.Match(session)
+	if rule == nil {
+		return nil, nil
+	}
+
+	if rule.Kind == mapping.KindLocal {
+		data, err := rule.LocalResponse()
+		if err != nil {
+			return nil, err
+		}
+		session.Mapping = &sessiondata.Mapping{Kind: rule.Kind.String(), Rule: rule.String(), Target: rule.Source()}
+		return ResponseFromData(data), nil
+	}
+
+	target, err := rule.RemoteURL(forwardedReq.URL.String())
+	if err != nil {
+		return nil, err
+	}
+	// The session keeps the original URL; only the forwarded request moves
+	forwardedReq.URL = target
+	forwardedReq.Host = target.Host
+	session.Mapping = &sessiondata.Mapping{Kind: rule.Kind.String(), Rule: rule.String(), Target: target.String()}
+	return nil, nil
+}
@@ -2,13 +2,19 @@ package utils
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"httpDebugger/pkg/bodyParser"
+	"httpDebugger/pkg/breakpoints"
 	"httpDebugger/pkg/proxy/types"
+	"httpDebugger/pkg/proxy/upstream"
 	"httpDebugger/pkg/sessiondata"
 	"httpDebugger/pkg/sortedMap"
 )
@@ -17,11 +23,50 @@ const (
 	maxBodySize = 10 * 1024 * 1024
 )
 
+var errDroppedByBreakpoint = errors.New("dropped at breakpoint")
+
 // ProcessAndStoreHTTPSession processes an HTTP request, forwards it, and stores the session data
 func ProcessAndStoreHTTPSession(w io.Writer, r *http.Request, session *sessiondata.Session, bodyBytes []byte, config *types.Config) {
+	session.TakeReplayHeaders(r.Header)
+	config.Fingerprints.Annotate(session)
 	config.Logger.LogRequest(session)
 
-	forwardedReq, err := http.NewRequestWithContext(r.Context(), r.Method, r.URL.String(), bytes.NewBuffer(bodyBytes))
+	bodyBytes, err := rewriteRequest(r, session, bodyBytes, config)
+	if err != nil {
+		HandleProxyError(w, r, err, "Bad Request", http.StatusBadRequest, session, config)
+		return
+	}
+
+	rule := config.Breakpoints.Match(session)
+	if rule != nil {
+		decision := holdSession(w, r, session, breakpoints.StageRequest, rule, config)
+		switch decision.Action {
+		case breakpoints.ActionDrop:
+			dropSession(w, session, config)
+			return
+		case breakpoints.ActionRespond:
+			session.Response = decision.Response
+			config.Logger.LogResponse(session)
+			config.SessionStore.Store(session)
+			writeResponse(w, ResponseFromData(decision.Response), config)
+			return
+		}
+		if decision.Edited {
+			if bodyBytes, err = ApplyRequestEdits(r, session.Request); err != nil {
+				HandleProxyError(w, r, err, "Bad Request", http.StatusBadRequest, session, config)
+				return
+			}
+		}
+	}
+
+	ctx := r.Context()
+	if session.Request.Headers != nil {
+		ctx = upstream.WithHeaderOrder(ctx, session.Request.Headers.Keys())
+	}
+
+	ctx, upstreamConn := withConnRecorder(ctx)
+
+	forwardedReq, err := http.NewRequestWithContext(ctx, r.Method, r.URL.String(), bytes.NewBuffer(bodyBytes))
 	if err != nil {
 		HandleProxyError(w, r, err, "Bad Gateway", http.StatusBadGateway, session, config)
 		return
@@ -30,7 +75,11 @@ func ProcessAndStoreHTTPSession(w io.Writer, r *http.Request, session *sessionda
 	CleanHeader(forwardedReq.Header, r.Header)
 
 	start := time.Now()
-	resp, err := config.HTTPClient.Do(forwardedReq)
+	resp, err := applyMapping(forwardedReq, session, config)
+	if err == nil && resp == nil {
+		resp, err = config.UpstreamClient(session).Do(forwardedReq)
+		session.UpstreamTLS = upstreamTLSFromExchange(upstreamConn, resp, err)
+	}
 	if err != nil {
 		session.Error = err
 		session.Duration = time.Since(start)
@@ -40,9 +89,55 @@ func ProcessAndStoreHTTPSession(w io.Writer, r *http.Request, session *sessionda
 
 	session.Duration = time.Since(start)
 	session.Response = ExtractResponseData(resp, config)
+	rewriteResponse(resp, session, config)
+
+	if rule != nil && rule.Response {
+		decision := holdSession(w, r, session, breakpoints.StageResponse, rule, config)
+		switch decision.Action {
+		case breakpoints.ActionDrop:
+			dropSession(w, session, config)
+			return
+		case breakpoints.ActionRespond:
+			session.Response = decision.Response
+			resp = ResponseFromData(decision.Response)
+		default:
+			if decision.Edited {
+				resp = ResponseFromData(session.Response)
+			}
+		}
+	}
+
 	config.Logger.LogResponse(session)
 	config.SessionStore.Store(session)
 
+	writeResponse(w, resp, config)
+}
+
+// holdSession pauses the exchange on a breakpoint until the user decides what to do with it
+func holdSession(w io.Writer, r *http.Request, session *sessiondata.Session, stage breakpoints.Stage, rule *breakpoints.Rule, config *types.Config) breakpoints.Decision {
+	// The HTTP/2 server resets streams that exceed its write timeout
+	if rw, ok := w.(http.ResponseWriter); ok {
+		http.NewResponseController(rw).SetWriteDeadline(time.Time{})
+	}
+
+	config.Logger.LogInfo(fmt.Sprintf("Breakpoint: holding %s of %s %s", stage, session.Request.Method, session.Request.URL))
+	return config.Breakpoints.Hold(r.Context(), session, stage, rule)
+}
+
+// dropSession aborts the exchange without sending a response to the client
+func dropSession(w io.Writer, session *sessiondata.Session, config *types.Config) {
+	session.Error = errDroppedByBreakpoint
+	config.SessionStore.Store(session)
+
+	if _, ok := w.(http.ResponseWriter); ok {
+		panic(http.ErrAbortHandler)
+	}
+	if closer, ok := w.(io.Closer); ok {
+		closer.Close()
+	}
+}
+
+func writeResponse(w io.Writer, resp *http.Response, config *types.Config) {
 	if httpWriter, ok := w.(http.ResponseWriter); ok {
 		CopyResponse(httpWriter, resp, config)
 	} else {
@@ -50,6 +145,56 @@ func ProcessAndStoreHTTPSession(w io.Writer, r *http.Request, session *sessionda
 	}
 }
 
+// ApplyRequestEdits rewrites r with the method, URL, headers and body of an edited
+// request and returns the new body
+func ApplyRequestEdits(r *http.Request, edited *sessiondata.RequestData) ([]byte, error) {
+	target, err := url.Parse(edited.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %s: %w", edited.URL, err)
+	}
+	if target.Scheme == "" || target.Host == "" {
+		return nil, fmt.Errorf("URL must be absolute: %s", edited.URL)
+	}
+
+	body := []byte(edited.Body)
+
+	r.Method = edited.Method
+	r.URL = target
+	r.Host = target.Host
+	r.Header = sessiondata.HTTPHeader(edited.Headers)
+	r.Header.Del("Host")
+	if len(body) > 0 {
+		r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	} else {
+		r.Header.Del("Content-Length")
+	}
+	r.ContentLength = int64(len(body))
+
+	return body, nil
+}
+
+// ResponseFromData builds a response to send to the client from stored response data.
+// The stored body is already decoded, so any Content-Encoding is dropped
+func ResponseFromData(data *sessiondata.ResponseData) *http.Response {
+	header := sessiondata.HTTPHeader(data.Headers)
+	header.Del("Content-Encoding")
+	header.Del("Transfer-Encoding")
+	header.Set("Content-Length", strconv.Itoa(len(data.Body)))
+
+	statusCode := data.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+
+	return &http.Response{
+		StatusCode:    statusCode,
+		Status:        data.Status,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(data.Body)),
+		ContentLength: int64(len(data.Body)),
+	}
+}
+
 // ExtractResponseData extracts relevant data from an HTTP response
 func ExtractResponseData(resp *http.Response, config *types.Config) *sessiondata.ResponseData {
 	// Read and close the response body
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"httpDebugger/pkg/proxy/types"
+	"httpDebugger/pkg/sessiondata"
+)
+
+// rewriteRequest applies the request rewrite rules to the session and to r and
+// returns the body to forward. The request as sent by the client is kept in
+// session.OriginalRequest
+func rewriteRequest(r *http.Request, session *sessiondata.Session, bodyBytes []byte, config *types.Config) ([]byte, error) {
+	if config.Rewrite == nil {
+		return bodyBytes, nil
+	}
+
+	rewritten, mods, err := config.Rewrite.RewriteRequest(session.Request)
+	if err != nil {
+		config.Logger.LogError(err, "applying rewrite rules")
+	}
+	if rewritten == nil {
+		return bodyBytes, nil
+	}
+
+	session.OriginalRequest = session.Request
+	session.Request = rewritten
+	session.Modifications = append(session.Modifications, mods...)
+
+	return ApplyRequestEdits(r, rewritten)
+}
+
+// rewriteResponse applies the response rewrite rules to resp and the session. The
+// response as sent by the server is kept in session.OriginalResponse
+func rewriteResponse(resp *http.Response, session *sessiondata.Session, config *types.Config) {
+	if config.Rewrite == nil || session.Response == nil {
+		return
+	}
+
+	// ExtractResponseData leaves the raw, still encoded body in resp
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	resp.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+
+	req := session.OriginalRequest
+	if req == nil {
+		req = session.Request
+	}
+
+	raw := *session.Response
+	raw.Body = string(bodyBytes)
+
+	rewritten, mods, err := config.Rewrite.RewriteResponse(req, &raw)
+	if err != nil {
+		config.Logger.LogError(err, "applying rewrite rules")
+	}
+	if rewritten == nil {
+		return
+	}
+
+	resp.StatusCode = rewritten.StatusCode
+	resp.Status = rewritten.Status
+	resp.Header = sessiondata.HTTPHeader(rewritten.Headers)
+	resp.Header.Del("Transfer-Encoding")
+	resp.Body = io.NopCloser(bytes.NewReader([]byte(rewritten.Body)))
+	resp.ContentLength = int64(len(rewritten.Body))
+
+	session.OriginalResponse = session.Response
+	session.Response = ExtractResponseData(resp, config)
+	session.Modifications = append(session.Modifications, mods...)
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"httpDebugger/pkg/proxy/types"
+	"httpDebugger/pkg/proxy/upstream"
+	"httpDebugger/pkg/sessiondata"
+)
+
+// SendRequest sends the request of a composed session upstream with the
+// session's fingerprints, and stores the session with its response. Rewrite
+// rules, mappings and breakpoints only apply to proxied traffic and are skipped
+func SendRequest(ctx context.Context, session *sessiondata.Session, config *types.Config) error {
+	config.Fingerprints.Annotate(session)
+	config.Logger.LogRequest(session)
+
+	err := sendRequest(ctx, session, config)
+	if err != nil {
+		session.Error = err
+		config.Logger.LogError(err, "sending composed request")
+	} else {
+		config.Logger.LogResponse(session)
+	}
+	config.SessionStore.Store(session)
+	return err
+}
+
+func sendRequest(ctx context.Context, session *sessiondata.Session, config *types.Config) error {
+	data := session.Request
+	if data.Headers != nil {
+		ctx = upstream.WithHeaderOrder(ctx, data.Headers.Keys())
+	}
+	ctx, upstreamConn := withConnRecorder(ctx)
+
+	req, err := http.NewRequestWithContext(ctx, data.Method, "", nil)
+	if err != nil {
+		return err
+	}
+	// ApplyRequestEdits checks the URL and sets the body and Content-Length
+	body, err := ApplyRequestEdits(req, data)
+	if err != nil {
+		return err
+	}
+	if host := sessiondata.HTTPHeader(data.Headers).Get("Host"); host != "" {
+		req.Host = host
+	}
+	for name := range req.Header {
+		if strings.HasPrefix(name, ":") {
+			req.Header.Del(name)
+		}
+	}
+	if req.Header.Get("Cookie") == "" {
+		for name, value := range data.Cookies {
+			req.AddCookie(&http.Cookie{Name: name, Value: value})
+		}
+	}
+	req.Body = http.NoBody
+	if len(body) > 0 {
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	start := time.Now()
+	resp, err := config.UpstreamClient(session).Do(req)
+	session.Duration = time.Since(start)
+	session.UpstreamTLS = upstreamTLSFromExchange(upstreamConn, resp, err)
+	if err != nil {
+		return err
+	}
+
+	if resp.ProtoMajor == 2 {
+		session.Protocol = sessiondata.HTTP2Protocol
+	} else {
+		session.Protocol = sessiondata.HTTP11Protocol
+	}
+	session.Response = ExtractResponseData(resp, config)
+	return nil
+}
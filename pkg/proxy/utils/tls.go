@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+
+	"httpDebugger/pkg/proxy/upstream"
+	"httpDebugger/pkg/sessiondata"
+
+	utls "github.com/refraction-networking/utls"
+)
+
+// connRecorder remembers the connection a request was sent on
+type connRecorder struct {
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// withConnRecorder returns a context whose requests report the connection
+// they got to the recorder
+func withConnRecorder(ctx context.Context) (context.Context, *connRecorder) {
+	recorder := &connRecorder{}
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			recorder.mu.Lock()
+			defer recorder.mu.Unlock()
+			recorder.conn = info.Conn
+		},
+	})
+	return ctx, recorder
+}
+
+func (r *connRecorder) Conn() net.Conn {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.conn
+}
+
+// upstreamTLSFromExchange describes the TLS connection a request went out on,
+// or why the server's certificate was rejected when the request failed
+func upstreamTLSFromExchange(recorder *connRecorder, resp *http.Response, err error) *sessiondata.UpstreamTLS {
+	if err != nil {
+		return UpstreamTLSFromError(err)
+	}
+	if state := UpstreamTLSFromConn(recorder.Conn()); state != nil {
+		return state
+	}
+	if resp != nil && resp.TLS != nil {
+		return upstreamTLS(resp.TLS.Version, resp.TLS.CipherSuite, resp.TLS.NegotiatedProtocol,
+			resp.TLS.ServerName, resp.TLS.DidResume, resp.TLS.OCSPResponse, resp.TLS.PeerCertificates)
+	}
+	return nil
+}
+
+// UpstreamTLSFromConn describes an upstream connection opened with crypto/tls
+// or utls, returning nil for plaintext ones
+func UpstreamTLSFromConn(conn net.Conn) *sessiondata.UpstreamTLS {
+	switch c := conn.(type) {
+	case *tls.Conn:
+		state := c.ConnectionState()
+		return upstreamTLS(state.Version, state.CipherSuite, state.NegotiatedProtocol,
+			state.ServerName, state.DidResume, state.OCSPResponse, state.PeerCertificates)
+	case *utls.UConn:
+		state := c.ConnectionState()
+		return upstreamTLS(state.Version, state.CipherSuite, state.NegotiatedProtocol,
+			state.ServerName, state.DidResume, state.OCSPResponse, state.PeerCertificates)
+	case *upstream.TLSConn:
+		state := UpstreamTLSFromConn(c.UConn)
+		state.ServerHello = c.ServerHello()
+		return state
+	default:
+		return nil
+	}
+}
+
+// UpstreamTLSFromError describes a handshake that failed because the server's
+// certificate did not verify, returning nil for any other error
+func UpstreamTLSFromError(err error) *sessiondata.UpstreamTLS {
+	var certs []*x509.Certificate
+	var tlsErr *tls.CertificateVerificationError
+	var utlsErr *utls.CertificateVerificationError
+	switch {
+	case errors.As(err, &tlsErr):
+		certs = tlsErr.UnverifiedCertificates
+		err = tlsErr.Err
+	case errors.As(err, &utlsErr):
+		certs = utlsErr.UnverifiedCertificates
+		err = utlsErr.Err
+	default:
+		return nil
+	}
+
+	return &sessiondata.UpstreamTLS{
+		Certificates:      sessiondata.NewCertificateChain(certs),
+		VerificationError: err.Error(),
+	}
+}
+
+func upstreamTLS(version, cipherSuite uint16, alpn, serverName string, resumed bool, ocspResponse []byte, certs []*x509.Certificate) *sessiondata.UpstreamTLS {
+	state := &sessiondata.UpstreamTLS{
+		Version:      version,
+		CipherSuite:  cipherSuite,
+		ALPN:         alpn,
+		ServerName:   serverName,
+		Resumed:      resumed,
+		Certificates: sessiondata.NewCertificateChain(certs),
+	}
+
+	var issuer *x509.Certificate
+	if len(certs) > 1 {
+		issuer = certs[1]
+	}
+	state.OCSP = sessiondata.NewOCSPInfo(ocspResponse, issuer)
+	return state
+}
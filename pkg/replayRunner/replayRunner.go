@@ -0,0 +1,425 @@
+package replayRunner
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"httpDebugger/pkg/sessiondata"
+)
+
+// Timing decides when each request of a run starts
+type Timing int
+
+const (
+	// TimingFast starts requests as soon as the concurrency and rate allow
+	TimingFast Timing = iota
+	// TimingOriginal keeps the gaps between the original requests, divided by Speed
+	TimingOriginal
+)
+
+func (t Timing) String() string {
+	if t == TimingOriginal {
+		return "original"
+	}
+	return "fast"
+}
+
+// Options configures a run
+type Options struct {
+	// Concurrency is how many requests may be in flight at once
+	Concurrency int
+	// Rate caps the requests started per second; 0 is unlimited
+	Rate float64
+	// Repeat is how many times the whole set is replayed
+	Repeat int
+	Timing Timing
+	// Speed divides the original gaps with TimingOriginal; 2 replays twice as fast
+	Speed float64
+}
+
+// DefaultOptions replays each session once, one at a time, with its original timing
+func DefaultOptions() Options {
+	return Options{Concurrency: 1, Repeat: 1, Timing: TimingOriginal, Speed: 1}
+}
+
+// Selection chooses the sessions to run instead of the listed ones
+type Selection struct {
+	// Host keeps only the sessions to this host
+	Host string
+	// File loads the sessions from a saved HAR capture
+	File string
+}
+
+// ParseSpec parses space separated terms such as
+// "concurrency=4 rate=10 repeat=3 timing=fast speed=2 host=api.example.com file=capture.har"
+func ParseSpec(spec string) (Options, Selection, error) {
+	opts := DefaultOptions()
+	var selection Selection
+
+	for _, term := range strings.Fields(spec) {
+		name, value, found := strings.Cut(term, "=")
+		if !found {
+			return opts, selection, fmt.Errorf("expected name=value, got %q", term)
+		}
+
+		var err error
+		switch strings.ToLower(name) {
+		case "concurrency":
+			opts.Concurrency, err = strconv.Atoi(value)
+			if err == nil && opts.Concurrency < 1 {
+				err = fmt.Errorf("must be at least 1")
+			}
+		case "rate":
+			opts.Rate, err = strconv.ParseFloat(value, 64)
+			if err == nil && opts.Rate < 0 {
+				err = fmt.Errorf("must not be negative")
+			}
+		case "repeat":
+			opts.Repeat, err = strconv.Atoi(value)
+			if err == nil && opts.Repeat < 1 {
+				err = fmt.Errorf("must be at least 1")
+			}
+		case "timing":
+			switch strings.ToLower(value) {
+			case "original":
+				opts.Timing = TimingOriginal
+			case "fast":
+				opts.Timing = TimingFast
+			default:
+				err = fmt.Errorf("use original or fast")
+			}
+		case "speed":
+			opts.Speed, err = strconv.ParseFloat(value, 64)
+			if err == nil && opts.Speed <= 0 {
+				err = fmt.Errorf("must be positive")
+			}
+		case "host":
+			selection.Host = strings.ToLower(value)
+		case "file":
+			selection.File = value
+		default:
+			return opts, selection, fmt.Errorf("unknown replay term %q", name)
+		}
+		if err != nil {
+			return opts, selection, fmt.Errorf("invalid %s %q: %w", name, value, err)
+		}
+	}
+	return opts, selection, nil
+}
+
+func (o Options) String() string {
+	s := fmt.Sprintf("concurrency=%d repeat=%d timing=%s", o.Concurrency, o.Repeat, o.Timing)
+	if o.Timing == TimingOriginal && o.Speed != 1 {
+		s += fmt.Sprintf(" speed=%g", o.Speed)
+	}
+	if o.Rate > 0 {
+		s += fmt.Sprintf(" rate=%g/s", o.Rate)
+	}
+	return s
+}
+
+// Sender replays one session and returns the session it produced
+type Sender func(ctx context.Context, session *sessiondata.Session) (*sessiondata.Session, error)
+
+// Runner replays a set of sessions in their original order and collects
+// latency, status and error statistics
+type Runner struct {
+	opts Options
+	send Sender
+
+	mu        sync.Mutex
+	total     int
+	completed int
+	latencies []time.Duration
+	statuses  map[int]int
+	errors    map[string]int
+	started   time.Time
+	finished  time.Time
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New returns a runner sending each request with send
+func New(opts Options, send Sender) *Runner {
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+	if opts.Repeat < 1 {
+		opts.Repeat = 1
+	}
+	if opts.Speed <= 0 {
+		opts.Speed = 1
+	}
+	return &Runner{
+		opts:     opts,
+		send:     send,
+		statuses: make(map[int]int),
+		errors:   make(map[string]int),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start replays sessions, sorted by timestamp, in the background
+func (r *Runner) Start(ctx context.Context, sessions []*sessiondata.Session) {
+	ordered := make([]*sessiondata.Session, len(sessions))
+	copy(ordered, sessions)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Timestamp.Before(ordered[j].Timestamp)
+	})
+
+	ctx, r.cancel = context.WithCancel(ctx)
+	r.mu.Lock()
+	r.total = len(ordered) * r.opts.Repeat
+	r.started = time.Now()
+	r.mu.Unlock()
+
+	go r.run(ctx, ordered)
+}
+
+// Stop starts no further requests; the ones in flight still complete
+func (r *Runner) Stop() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+}
+
+// Wait blocks until the run has finished or was stopped
+func (r *Runner) Wait() {
+	<-r.done
+}
+
+// Done reports whether the run has finished
+func (r *Runner) Done() bool {
+	select {
+	case <-r.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (r *Runner) run(ctx context.Context, sessions []*sessiondata.Session) {
+	defer close(r.done)
+	defer func() {
+		r.mu.Lock()
+		r.finished = time.Now()
+		r.mu.Unlock()
+	}()
+
+	// Stop cancels ctx, but requests already sent get their responses
+	sendCtx := context.WithoutCancel(ctx)
+	jobs := make(chan *sessiondata.Session)
+	var wg sync.WaitGroup
+	for i := 0; i < r.opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for session := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				r.replay(sendCtx, session)
+			}
+		}()
+	}
+	defer wg.Wait()
+	defer close(jobs)
+
+	var limit <-chan time.Time
+	if r.opts.Rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / r.opts.Rate))
+		defer ticker.Stop()
+		limit = ticker.C
+	}
+
+	for round := 0; round < r.opts.Repeat; round++ {
+		roundStart := time.Now()
+		for i, session := range sessions {
+			if r.opts.Timing == TimingOriginal && i > 0 {
+				offset := time.Duration(float64(session.Timestamp.Sub(sessions[0].Timestamp)) / r.opts.Speed)
+				if !sleepUntil(ctx, roundStart.Add(offset)) {
+					return
+				}
+			}
+			if limit != nil && i+round > 0 {
+				select {
+				case <-limit:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case jobs <- session:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func (r *Runner) replay(ctx context.Context, session *sessiondata.Session) {
+	start := time.Now()
+	replay, err := r.send(ctx, session)
+	latency := time.Since(start)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.completed++
+	if err != nil {
+		r.errors[err.Error()]++
+		return
+	}
+	r.latencies = append(r.latencies, latency)
+	if replay != nil && replay.Response != nil {
+		r.statuses[replay.Response.StatusCode]++
+	}
+}
+
+// sleepUntil waits for t and reports false when ctx is done first
+func sleepUntil(ctx context.Context, t time.Time) bool {
+	wait := time.Until(t)
+	if wait <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Summary is a snapshot of a run's statistics
+type Summary struct {
+	Options   Options
+	Total     int
+	Completed int
+	Failed    int
+	Running   bool
+	Elapsed   time.Duration
+
+	// Latencies of the requests that got a response
+	Min, Mean, P50, P90, P95, P99, Max time.Duration
+
+	Statuses map[int]int
+	Errors   map[string]int
+}
+
+// Summary returns the statistics collected so far
+func (r *Runner) Summary() Summary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := Summary{
+		Options:   r.opts,
+		Total:     r.total,
+		Completed: r.completed,
+		Running:   r.finished.IsZero(),
+		Statuses:  make(map[int]int, len(r.statuses)),
+		Errors:    make(map[string]int, len(r.errors)),
+	}
+	if s.Running {
+		s.Elapsed = time.Since(r.started)
+	} else {
+		s.Elapsed = r.finished.Sub(r.started)
+	}
+	for status, n := range r.statuses {
+		s.Statuses[status] = n
+	}
+	for err, n := range r.errors {
+		s.Errors[err] = n
+		s.Failed += n
+	}
+
+	if len(r.latencies) == 0 {
+		return s
+	}
+	sorted := make([]time.Duration, len(r.latencies))
+	copy(sorted, r.latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var sum time.Duration
+	for _, latency := range sorted {
+		sum += latency
+	}
+	s.Min = sorted[0]
+	s.Max = sorted[len(sorted)-1]
+	s.Mean = sum / time.Duration(len(sorted))
+	s.P50 = percentile(sorted, 50)
+	s.P90 = percentile(sorted, 90)
+	s.P95 = percentile(sorted, 95)
+	s.P99 = percentile(sorted, 99)
+	return s
+}
+
+// percentile returns the nearest-rank percentile p of sorted latencies
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// String renders the summary as a multi-line report
+func (s Summary) String() string {
+	var sb strings.Builder
+
+	state := "Finished"
+	if s.Running {
+		state = "Running"
+	}
+	sb.WriteString(fmt.Sprintf("%s: %d/%d requests in %v (%s)\n", state, s.Completed, s.Total, s.Elapsed.Round(time.Millisecond), s.Options))
+	if s.Elapsed > 0 && s.Completed > 0 {
+		sb.WriteString(fmt.Sprintf("Throughput: %.1f requests/s\n", float64(s.Completed)/s.Elapsed.Seconds()))
+	}
+
+	sb.WriteString("\nLatency:\n")
+	if s.Completed-s.Failed == 0 {
+		sb.WriteString(" No responses yet\n")
+	} else {
+		for _, row := range []struct {
+			name  string
+			value time.Duration
+		}{
+			{"min", s.Min}, {"mean", s.Mean}, {"p50", s.P50}, {"p90", s.P90},
+			{"p95", s.P95}, {"p99", s.P99}, {"max", s.Max},
+		} {
+			sb.WriteString(fmt.Sprintf(" %-5s %v\n", row.name, row.value.Round(time.Microsecond)))
+		}
+	}
+
+	sb.WriteString("\nStatus codes:\n")
+	codes := make([]int, 0, len(s.Statuses))
+	for code := range s.Statuses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		sb.WriteString(fmt.Sprintf(" %d  %d\n", code, s.Statuses[code]))
+	}
+	if len(codes) == 0 {
+		sb.WriteString(" None\n")
+	}
+
+	if s.Failed > 0 {
+		sb.WriteString(fmt.Sprintf("\nErrors (%d):\n", s.Failed))
+		messages := make([]string, 0, len(s.Errors))
+		for message := range s.Errors {
+			messages = append(messages, message)
+		}
+		sort.Slice(messages, func(i, j int) bool { return s.Errors[messages[i]] > s.Errors[messages[j]] })
+		for _, message := range messages {
+			sb.WriteString(fmt.Sprintf(" %dx %s\n", s.Errors[message], message))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,153 @@
+package replayRunner
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"httpDebugger/pkg/sessiondata"
+)
+
+func newSession(id string, at time.Time) *sessiondata.Session {
+	return &sessiondata.Session{
+		ID:        id,
+		Timestamp: at,
+		Request:   &sessiondata.RequestData{Method: "GET", URL: "https://example.com/" + id},
+	}
+}
+
+// recorder answers with the status in the session ID's map and records when
+// each request was sent
+type recorder struct {
+	mu     sync.Mutex
+	status map[string]int
+	sent   []string
+	at     []time.Time
+}
+
+func (r *recorder) send(ctx context.Context, session *sessiondata.Session) (*sessiondata.Session, error) {
+	r.mu.Lock()
+	r.sent = append(r.sent, session.ID)
+	r.at = append(r.at, time.Now())
+	status := r.status[session.ID]
+	r.mu.Unlock()
+
+	if status == 0 {
+		return nil, errors.New("connection refused")
+	}
+	return &sessiondata.Session{ParentID: session.ID, Response: &sessiondata.ResponseData{StatusCode: status}}, nil
+}
+
+func TestRunner(t *testing.T) {
+	now := time.Now()
+	sessions := []*sessiondata.Session{
+		newSession("b", now.Add(time.Second)),
+		newSession("a", now),
+		newSession("c", now.Add(2*time.Second)),
+	}
+	rec := &recorder{status: map[string]int{"a": 200, "b": 500}}
+
+	runner := New(Options{Concurrency: 1, Repeat: 2, Timing: TimingFast}, rec.send)
+	runner.Start(context.Background(), sessions)
+	runner.Wait()
+
+	if got := strings.Join(rec.sent, ""); got != "abcabc" {
+		t.Errorf("sent %s, want the original order twice", got)
+	}
+	summary := runner.Summary()
+	if summary.Running || summary.Total != 6 || summary.Completed != 6 || summary.Failed != 2 {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+	if summary.Statuses[200] != 2 || summary.Statuses[500] != 2 || summary.Errors["connection refused"] != 2 {
+		t.Errorf("statuses %v, errors %v", summary.Statuses, summary.Errors)
+	}
+	if summary.Min > summary.P50 || summary.P50 > summary.P99 || summary.P99 > summary.Max {
+		t.Errorf("percentiles out of order: %+v", summary)
+	}
+	report := summary.String()
+	for _, want := range []string{"Finished: 6/6 requests", " 500  2", "2x connection refused", "p95"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report is missing %q:\n%s", want, report)
+		}
+	}
+}
+
+func TestRunnerTiming(t *testing.T) {
+	now := time.Now()
+	sessions := []*sessiondata.Session{newSession("a", now), newSession("b", now.Add(200*time.Millisecond))}
+	rec := &recorder{status: map[string]int{"a": 200, "b": 200}}
+
+	runner := New(Options{Concurrency: 2, Repeat: 1, Timing: TimingOriginal, Speed: 2}, rec.send)
+	runner.Start(context.Background(), sessions)
+	runner.Wait()
+	if gap := rec.at[1].Sub(rec.at[0]); gap < 90*time.Millisecond || gap > 190*time.Millisecond {
+		t.Errorf("gap at twice the speed = %v, want about 100ms", gap)
+	}
+
+	rec = &recorder{status: map[string]int{"a": 200, "b": 200}}
+	runner = New(Options{Concurrency: 4, Repeat: 2, Timing: TimingFast, Rate: 20}, rec.send)
+	runner.Start(context.Background(), sessions)
+	runner.Wait()
+	if elapsed := rec.at[3].Sub(rec.at[0]); elapsed < 140*time.Millisecond {
+		t.Errorf("4 requests at 20/s took %v, want at least 150ms", elapsed)
+	}
+
+	runner = New(Options{Timing: TimingOriginal, Speed: 1}, rec.send)
+	runner.Start(context.Background(), []*sessiondata.Session{newSession("a", now), newSession("b", now.Add(time.Hour))})
+	runner.Stop()
+	runner.Wait()
+	if summary := runner.Summary(); summary.Running || summary.Completed > 1 {
+		t.Errorf("a stopped run should not send more requests: %+v", summary)
+	}
+}
+
+func TestRunnerStopCompletesInFlight(t *testing.T) {
+	sent := make(chan struct{})
+	release := make(chan struct{})
+	send := func(ctx context.Context, session *sessiondata.Session) (*sessiondata.Session, error) {
+		close(sent)
+		<-release
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return &sessiondata.Session{Response: &sessiondata.ResponseData{StatusCode: 200}}, nil
+	}
+
+	now := time.Now()
+	runner := New(Options{Timing: TimingOriginal, Speed: 1}, send)
+	runner.Start(context.Background(), []*sessiondata.Session{newSession("a", now), newSession("b", now.Add(time.Hour))})
+	<-sent
+	runner.Stop()
+	close(release)
+	runner.Wait()
+
+	summary := runner.Summary()
+	if summary.Completed != 1 || summary.Statuses[200] != 1 || len(summary.Errors) != 0 {
+		t.Errorf("the request in flight should complete after Stop(): %+v", summary)
+	}
+}
+
+func TestParseSpec(t *testing.T) {
+	opts, selection, err := ParseSpec("concurrency=4 rate=2.5 repeat=3 timing=fast host=API.example.com file=capture.har")
+	if err != nil {
+		t.Fatalf("ParseSpec() failed: %v", err)
+	}
+	if opts.Concurrency != 4 || opts.Rate != 2.5 || opts.Repeat != 3 || opts.Timing != TimingFast || opts.Speed != 1 {
+		t.Errorf("unexpected options: %+v", opts)
+	}
+	if selection.Host != "api.example.com" || selection.File != "capture.har" {
+		t.Errorf("unexpected selection: %+v", selection)
+	}
+
+	if opts, _, _ := ParseSpec(""); opts != DefaultOptions() {
+		t.Errorf("ParseSpec(\"\") = %+v, want the defaults", opts)
+	}
+	for _, spec := range []string{"concurrency=0", "speed=-1", "timing=slow", "burst=3", "fast"} {
+		if _, _, err := ParseSpec(spec); err == nil {
+			t.Errorf("ParseSpec(%q) should fail", spec)
+		}
+	}
+}
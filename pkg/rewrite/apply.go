@@ -0,0 +1,261 @@
+package rewrite
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"httpDebugger/pkg/bodyParser"
+	"httpDebugger/pkg/sessiondata"
+	"httpDebugger/pkg/sortedMap"
+)
+
+// message is the part of a request or response the actions operate on. The body
+// is decoded on first use and encoded again with the original Content-Encoding
+type message struct {
+	headers  *sortedMap.SortedMap
+	body     string
+	decoded  bool
+	encoding string
+	changed  bool
+	mods     []sessiondata.Modification
+	errs     []error
+}
+
+func newMessage(headers *sortedMap.SortedMap, body string) *message {
+	if headers == nil {
+		headers = sortedMap.New()
+	} else {
+		headers = headers.Clone()
+	}
+
+	msg := &message{headers: headers, body: body}
+	if key, ok := findHeader(headers, "Content-Encoding"); ok {
+		msg.encoding = strings.ToLower(strings.TrimSpace(firstHeaderValue(headers, key)))
+	}
+	return msg
+}
+
+func (m *message) record(rule *Rule, stage, action, detail string) {
+	m.changed = true
+	m.mods = append(m.mods, sessiondata.Modification{Rule: rule.Name, Stage: stage, Action: action, Detail: detail})
+}
+
+// apply runs a header or body action, reporting whether it is one of them
+func (m *message) apply(rule *Rule, action *Action) bool {
+	switch action.Type {
+	case SetHeader:
+		if key, ok := findHeader(m.headers, action.Name); ok {
+			if m.headers.Entries[key] == action.Value {
+				return true
+			}
+			m.headers.Put(key, action.Value)
+		} else {
+			m.headers.Put(action.Name, action.Value)
+		}
+		m.record(rule, action.Stage, action.Type, fmt.Sprintf("%s: %s", action.Name, action.Value))
+
+	case RemoveHeader:
+		for {
+			key, ok := findHeader(m.headers, action.Name)
+			if !ok {
+				break
+			}
+			m.headers.Delete(key)
+			m.record(rule, action.Stage, action.Type, key)
+		}
+
+	case RenameHeader:
+		if key, ok := findHeader(m.headers, action.Name); ok {
+			m.headers.Rename(key, action.To)
+			m.record(rule, action.Stage, action.Type, fmt.Sprintf("%s -> %s", key, action.To))
+		}
+
+	case ReplaceBody:
+		if !m.decoded {
+			decoded, err := bodyParser.Decompress(m.body, m.encoding)
+			if err != nil {
+				m.errs = append(m.errs, fmt.Errorf("%s: %w", rule.Name, err))
+				return true
+			}
+			m.body, m.decoded = decoded, true
+		}
+		count := len(action.re.FindAllStringIndex(m.body, -1))
+		if count == 0 {
+			return true
+		}
+		m.body = action.re.ReplaceAllString(m.body, action.Replacement)
+		m.record(rule, action.Stage, action.Type, fmt.Sprintf("%d match(es) of %s", count, action.Pattern))
+
+	default:
+		return false
+	}
+	return true
+}
+
+// finish encodes a rewritten body again and keeps Content-Length in step with it
+func (m *message) finish() error {
+	if m.decoded {
+		encoded, err := bodyParser.Compress(m.body, m.encoding)
+		if err != nil {
+			return err
+		}
+		m.body = encoded
+	}
+	if key, ok := findHeader(m.headers, "Content-Length"); ok {
+		m.headers.Put(key, strconv.Itoa(len(m.body)))
+	}
+	return nil
+}
+
+func (m *message) err() error {
+	return errors.Join(m.errs...)
+}
+
+// RewriteRequest applies the request actions of every matching rule to a copy of
+// req. It returns nil when no rule changed anything
+func (e *Engine) RewriteRequest(req *sessiondata.RequestData) (*sessiondata.RequestData, []sessiondata.Modification, error) {
+	if e == nil || req == nil {
+		return nil, nil, nil
+	}
+
+	rewritten := *req
+	msg := newMessage(req.Headers, req.Body)
+
+	for _, rule := range e.Rules() {
+		if rule.Disabled || !rule.Match.Matches(req) {
+			continue
+		}
+		for i := range rule.Actions {
+			action := &rule.Actions[i]
+			if action.Stage != StageRequest || msg.apply(rule, action) {
+				continue
+			}
+			if action.Type == RewriteURL {
+				rewriteURL(&rewritten, msg, rule, action)
+			}
+		}
+	}
+
+	if !msg.changed {
+		return nil, nil, msg.err()
+	}
+	if err := msg.finish(); err != nil {
+		return nil, nil, errors.Join(msg.err(), err)
+	}
+
+	rewritten.Headers = msg.headers
+	rewritten.Body = msg.body
+	if key, ok := findHeader(msg.headers, "Content-Type"); ok {
+		rewritten.ContentType = firstHeaderValue(msg.headers, key)
+	}
+	return &rewritten, msg.mods, msg.err()
+}
+
+// RewriteResponse applies the response actions of every rule matching req to a
+// copy of resp, whose body must be the raw body as received from the server. It
+// returns nil when no rule changed anything
+func (e *Engine) RewriteResponse(req *sessiondata.RequestData, resp *sessiondata.ResponseData) (*sessiondata.ResponseData, []sessiondata.Modification, error) {
+	if e == nil || resp == nil {
+		return nil, nil, nil
+	}
+
+	rewritten := *resp
+	msg := newMessage(resp.Headers, resp.Body)
+
+	for _, rule := range e.Rules() {
+		if rule.Disabled || !rule.Match.Matches(req) {
+			continue
+		}
+		for i := range rule.Actions {
+			action := &rule.Actions[i]
+			if action.Stage != StageResponse || msg.apply(rule, action) {
+				continue
+			}
+			if action.Type == SetStatus && action.Status != rewritten.StatusCode {
+				msg.record(rule, action.Stage, action.Type, fmt.Sprintf("%d -> %d", rewritten.StatusCode, action.Status))
+				rewritten.StatusCode = action.Status
+				rewritten.Status = strings.TrimSpace(fmt.Sprintf("%d %s", action.Status, http.StatusText(action.Status)))
+			}
+		}
+	}
+
+	if !msg.changed {
+		return nil, nil, msg.err()
+	}
+	if err := msg.finish(); err != nil {
+		return nil, nil, errors.Join(msg.err(), err)
+	}
+
+	rewritten.Headers = msg.headers
+	rewritten.Body = msg.body
+	if key, ok := findHeader(msg.headers, "Content-Type"); ok {
+		rewritten.ContentType = firstHeaderValue(msg.headers, key)
+	}
+	return &rewritten, msg.mods, msg.err()
+}
+
+func rewriteURL(req *sessiondata.RequestData, msg *message, rule *Rule, action *Action) {
+	target := action.Value
+	if action.re != nil {
+		target = action.re.ReplaceAllString(req.URL, action.Replacement)
+	}
+	if target == req.URL {
+		return
+	}
+
+	parsed, err := url.Parse(target)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		msg.errs = append(msg.errs, fmt.Errorf("%s: rewritten URL is not absolute: %s", rule.Name, target))
+		return
+	}
+
+	msg.record(rule, action.Stage, action.Type, fmt.Sprintf("%s -> %s", req.URL, target))
+	req.URL = target
+	if key, ok := findHeader(msg.headers, "Host"); ok {
+		msg.headers.Put(key, parsed.Host)
+	}
+}
+
+// decodeBody returns body decoded according to the Content-Encoding in headers
+func decodeBody(body string, headers *sortedMap.SortedMap) (string, error) {
+	key, ok := findHeader(headers, "Content-Encoding")
+	if !ok {
+		return body, nil
+	}
+	return bodyParser.Decompress(body, strings.ToLower(strings.TrimSpace(firstHeaderValue(headers, key))))
+}
+
+// findHeader returns the key under which name is stored, ignoring case
+func findHeader(headers *sortedMap.SortedMap, name string) (string, bool) {
+	if headers == nil {
+		return "", false
+	}
+	for _, key := range headers.Order {
+		if strings.EqualFold(key, name) {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+func headerValues(headers *sortedMap.SortedMap, key string) []string {
+	value, ok := headers.Get(key)
+	if !ok {
+		return nil
+	}
+	if values, ok := value.([]string); ok {
+		return values
+	}
+	return []string{fmt.Sprintf("%v", value)}
+}
+
+func firstHeaderValue(headers *sortedMap.SortedMap, key string) string {
+	if values := headerValues(headers, key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
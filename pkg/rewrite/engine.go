@@ -0,0 +1,148 @@
+package rewrite
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+var ErrNotFound = errors.New("rewrite rule not found")
+
+// Engine holds the active rewrite rules and reloads them when their file changes
+type Engine struct {
+	mu      sync.RWMutex
+	rules   []*Rule
+	path    string
+	modTime time.Time
+	size    int64
+	missing bool
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// NewEngine returns an engine using rules as returned by ParseRules
+func NewEngine(rules []*Rule) *Engine {
+	return &Engine{rules: rules, stop: make(chan struct{})}
+}
+
+// LoadFile returns an engine using the rules stored in path
+func LoadFile(path string) (*Engine, error) {
+	e := NewEngine(nil)
+	e.path = path
+	if _, err := e.Reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Path returns the rules file, or "" for an engine built from NewEngine
+func (e *Engine) Path() string {
+	return e.path
+}
+
+func (e *Engine) Rules() []*Rule {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.rules
+}
+
+// SetRuleEnabled turns the rule called name on or off until the rules file is
+// next reloaded
+func (e *Engine) SetRuleEnabled(name string, enabled bool) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for i, rule := range e.rules {
+		if rule.Name != name {
+			continue
+		}
+		// Rules are read without the lock while applied, so swap in a copy
+		updated := *rule
+		updated.Disabled = !enabled
+		rules := make([]*Rule, len(e.rules))
+		copy(rules, e.rules)
+		rules[i] = &updated
+		e.rules = rules
+		return nil
+	}
+	return ErrNotFound
+}
+
+// Reload parses the rules file again if it changed since the last load. On error
+// the previous rules stay active
+func (e *Engine) Reload() (bool, error) {
+	if e.path == "" {
+		return false, nil
+	}
+
+	info, err := os.Stat(e.path)
+	e.mu.Lock()
+	if err != nil {
+		reported := e.missing
+		e.missing = true
+		e.mu.Unlock()
+		if reported {
+			return false, nil
+		}
+		return false, fmt.Errorf("reading rules: %w", err)
+	}
+	e.missing = false
+	unchanged := info.ModTime().Equal(e.modTime) && info.Size() == e.size
+	e.mu.Unlock()
+	if unchanged {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(e.path)
+	if err != nil {
+		return false, fmt.Errorf("reading rules: %w", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// Remember the attempt so a broken file is reported once, not on every poll
+	e.modTime = info.ModTime()
+	e.size = info.Size()
+
+	rules, err := ParseRules(data, e.path)
+	if err != nil {
+		return false, err
+	}
+	e.rules = rules
+	return true, nil
+}
+
+// Watch polls the rules file every interval until Close is called, calling
+// onReload after each reload attempt with its error, if any
+func (e *Engine) Watch(interval time.Duration, onReload func(error)) {
+	if e.path == "" {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-e.stop:
+				return
+			case <-ticker.C:
+				reloaded, err := e.Reload()
+				if (reloaded || err != nil) && onReload != nil {
+					onReload(err)
+				}
+			}
+		}
+	}()
+}
+
+// Close stops watching the rules file
+func (e *Engine) Close() error {
+	e.stopOnce.Do(func() { close(e.stop) })
+	return nil
+}
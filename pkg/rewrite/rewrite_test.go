@@ -0,0 +1,222 @@
+package rewrite
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"httpDebugger/pkg/bodyParser"
+	"httpDebugger/pkg/sessiondata"
+	"httpDebugger/pkg/sortedMap"
+)
+
+const testRules = `
+rules:
+  - name: api
+    match:
+      host: "*.example.com"
+      path: ^/v1/
+      method: post
+      header: "X-Client: ^app"
+    actions:
+      - type: set-header
+        name: user-agent
+        value: rewritten
+      - type: remove-header
+        name: X-Debug
+      - type: rename-header
+        name: X-Client
+        to: X-Origin-Client
+      - type: replace-body
+        pattern: '"admin":false'
+        replacement: '"admin":true'
+      - type: rewrite-url
+        pattern: ^https://api\.example\.com/v1/
+        replacement: https://staging.example.com/v2/
+      - type: replace-body
+        stage: response
+        pattern: secret
+        replacement: "***"
+      - type: set-status
+        status: 404
+  - name: disabled
+    disabled: true
+    match: {}
+    actions:
+      - type: set-header
+        name: X-Never
+        value: "1"
+`
+
+func newTestRequest() *sessiondata.RequestData {
+	headers := sortedMap.New()
+	headers.Put("Host", "api.example.com")
+	headers.Put("User-Agent", "curl/8.0")
+	headers.Put("X-Debug", "1")
+	headers.Put("X-Client", "app/1.0")
+	headers.Put("Content-Length", "15")
+	headers.Put("Accept", "*/*")
+
+	return &sessiondata.RequestData{
+		Method:  "POST",
+		URL:     "https://api.example.com/v1/users",
+		Headers: headers,
+		Body:    `{"admin":false}`,
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules([]byte(testRules), "rules.yaml")
+	if err != nil {
+		t.Fatalf("ParseRules() failed: %v", err)
+	}
+	if len(rules) != 2 || len(rules[0].Actions) != 7 {
+		t.Fatalf("unexpected rules: %+v", rules)
+	}
+	if rules[0].Actions[6].Stage != StageResponse {
+		t.Errorf("set-status should run on the response, got %q", rules[0].Actions[6].Stage)
+	}
+
+	json := `{"rules": [{"name": "j", "match": {"method": "GET"}, "actions": [{"type": "remove-header", "name": "Cookie"}]}]}`
+	if rules, err := ParseRules([]byte(json), "rules.json"); err != nil || len(rules) != 1 {
+		t.Errorf("ParseRules(json) = %v, %v", rules, err)
+	}
+
+	invalid := []string{
+		`{"rules": [{"actions": [{"type": "explode"}]}]}`,
+		`{"rules": [{"actions": [{"type": "replace-body", "pattern": "("}]}]}`,
+		`{"rules": [{"actions": [{"type": "set-header"}]}]}`,
+		`{"rules": [{"mtach": {}}]}`,
+	}
+	for _, doc := range invalid {
+		if _, err := ParseRules([]byte(doc), "rules.json"); err == nil {
+			t.Errorf("ParseRules(%s) should fail", doc)
+		}
+	}
+}
+
+func TestRewriteRequest(t *testing.T) {
+	rules, err := ParseRules([]byte(testRules), "rules.yaml")
+	if err != nil {
+		t.Fatalf("ParseRules() failed: %v", err)
+	}
+	engine := NewEngine(rules)
+
+	req := newTestRequest()
+	rewritten, mods, err := engine.RewriteRequest(req)
+	if err != nil || rewritten == nil {
+		t.Fatalf("RewriteRequest() = %v, %v", rewritten, err)
+	}
+
+	wantOrder := []string{"Host", "User-Agent", "X-Origin-Client", "Content-Length", "Accept"}
+	if !reflect.DeepEqual(rewritten.Headers.Order, wantOrder) {
+		t.Errorf("header order = %v, want %v", rewritten.Headers.Order, wantOrder)
+	}
+	if ua, _ := rewritten.Headers.Get("User-Agent"); ua != "rewritten" {
+		t.Errorf("User-Agent = %v, want rewritten", ua)
+	}
+	if host, _ := rewritten.Headers.Get("Host"); host != "staging.example.com" {
+		t.Errorf("Host = %v, want staging.example.com", host)
+	}
+	if rewritten.URL != "https://staging.example.com/v2/users" {
+		t.Errorf("URL = %s", rewritten.URL)
+	}
+	if rewritten.Body != `{"admin":true}` {
+		t.Errorf("Body = %s", rewritten.Body)
+	}
+	if cl, _ := rewritten.Headers.Get("Content-Length"); cl != "14" {
+		t.Errorf("Content-Length = %v, want 14", cl)
+	}
+	if len(mods) != 5 {
+		t.Errorf("got %d modifications, want 5: %+v", len(mods), mods)
+	}
+
+	if _, ok := req.Headers.Get("X-Debug"); !ok || req.Body != `{"admin":false}` {
+		t.Errorf("the original request must not be modified")
+	}
+
+	req.Method = "GET"
+	if rewritten, _, _ := engine.RewriteRequest(req); rewritten != nil {
+		t.Errorf("non-matching request should not be rewritten")
+	}
+}
+
+func TestRewriteResponseRecompresses(t *testing.T) {
+	rules, err := ParseRules([]byte(testRules), "rules.yaml")
+	if err != nil {
+		t.Fatalf("ParseRules() failed: %v", err)
+	}
+	engine := NewEngine(rules)
+
+	body, err := bodyParser.Compress("token=secret", bodyParser.CompressionGzip)
+	if err != nil {
+		t.Fatalf("Compress() failed: %v", err)
+	}
+	headers := sortedMap.New()
+	headers.Put("Content-Encoding", []string{"gzip"})
+	resp := &sessiondata.ResponseData{StatusCode: 200, Status: "200 OK", Headers: headers, Body: body}
+
+	rewritten, mods, err := engine.RewriteResponse(newTestRequest(), resp)
+	if err != nil || rewritten == nil {
+		t.Fatalf("RewriteResponse() = %v, %v", rewritten, err)
+	}
+	if rewritten.StatusCode != 404 || rewritten.Status != "404 Not Found" {
+		t.Errorf("status = %d %q", rewritten.StatusCode, rewritten.Status)
+	}
+
+	decoded, err := bodyParser.Decompress(rewritten.Body, bodyParser.CompressionGzip)
+	if err != nil {
+		t.Fatalf("rewritten body is not gzip: %v", err)
+	}
+	if decoded != "token=***" {
+		t.Errorf("decoded body = %q", decoded)
+	}
+	if len(mods) != 2 || mods[0].Stage != StageResponse {
+		t.Errorf("unexpected modifications: %+v", mods)
+	}
+}
+
+func TestEngineReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte(testRules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	engine, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() failed: %v", err)
+	}
+	if len(engine.Rules()) != 2 {
+		t.Fatalf("loaded %d rules, want 2", len(engine.Rules()))
+	}
+
+	if reloaded, err := engine.Reload(); reloaded || err != nil {
+		t.Errorf("unchanged file should not reload: %v, %v", reloaded, err)
+	}
+
+	later := time.Now().Add(time.Second)
+	if err := os.WriteFile(path, []byte("rules: [{actions: [{type: bogus}]}]"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, later, later)
+	if _, err := engine.Reload(); err == nil {
+		t.Errorf("broken rules file should fail to reload")
+	}
+	if len(engine.Rules()) != 2 {
+		t.Errorf("previous rules should stay active after a failed reload")
+	}
+
+	later = later.Add(time.Second)
+	if err := os.WriteFile(path, []byte("rules: []"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, later, later)
+	if reloaded, err := engine.Reload(); !reloaded || err != nil {
+		t.Errorf("Reload() = %v, %v", reloaded, err)
+	}
+	if len(engine.Rules()) != 0 {
+		t.Errorf("reloaded %d rules, want 0", len(engine.Rules()))
+	}
+}
@@ -0,0 +1,270 @@
+package rewrite
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"httpDebugger/pkg/sessiondata"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Action types
+const (
+	SetHeader    = "set-header"
+	RemoveHeader = "remove-header"
+	RenameHeader = "rename-header"
+	ReplaceBody  = "replace-body"
+	RewriteURL   = "rewrite-url"
+	SetStatus    = "set-status"
+)
+
+// Stages an action can run at
+const (
+	StageRequest  = "request"
+	StageResponse = "response"
+)
+
+// RuleSet is the document stored in a rules file
+type RuleSet struct {
+	Rules []*Rule `json:"rules" yaml:"rules"`
+}
+
+// Rule applies its actions to every exchange whose request satisfies Match
+type Rule struct {
+	Name     string   `json:"name" yaml:"name"`
+	Disabled bool     `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+	Match    Match    `json:"match" yaml:"match"`
+	Actions  []Action `json:"actions" yaml:"actions"`
+}
+
+// Match selects requests. Empty fields match anything; all set fields must match
+type Match struct {
+	// Host is a hostname, or "*.example.com" to match every subdomain
+	Host string `json:"host,omitempty" yaml:"host,omitempty"`
+	// Path is a regex matched against the URL path
+	Path   string `json:"path,omitempty" yaml:"path,omitempty"`
+	Method string `json:"method,omitempty" yaml:"method,omitempty"`
+	// Header is a header name, optionally followed by ":" and a value regex
+	Header string `json:"header,omitempty" yaml:"header,omitempty"`
+	// Body is a regex matched against the decoded request body
+	Body string `json:"body,omitempty" yaml:"body,omitempty"`
+
+	pathRe      *regexp.Regexp
+	headerName  string
+	headerValue *regexp.Regexp
+	bodyRe      *regexp.Regexp
+}
+
+// Action is a single change made by a rule
+type Action struct {
+	Type string `json:"type" yaml:"type"`
+	// Stage is "request" or "response"; rewrite-url always runs on the request and
+	// set-status on the response
+	Stage string `json:"stage,omitempty" yaml:"stage,omitempty"`
+
+	// Name and Value are used by the header actions, To is the new name for rename-header
+	Name  string `json:"name,omitempty" yaml:"name,omitempty"`
+	Value string `json:"value,omitempty" yaml:"value,omitempty"`
+	To    string `json:"to,omitempty" yaml:"to,omitempty"`
+
+	// Pattern and Replacement are used by replace-body and rewrite-url. A rewrite-url
+	// without a pattern replaces the whole URL with Value
+	Pattern     string `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	Replacement string `json:"replacement,omitempty" yaml:"replacement,omitempty"`
+
+	Status int `json:"status,omitempty" yaml:"status,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// ParseRules parses a rules document. Files ending in .json are read as JSON,
+// anything else as YAML
+func ParseRules(data []byte, filename string) ([]*Rule, error) {
+	var set RuleSet
+
+	if strings.EqualFold(filepath.Ext(filename), ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&set); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", filename, err)
+		}
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&set); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("parsing %s: %w", filename, err)
+		}
+	}
+
+	for i, rule := range set.Rules {
+		if rule == nil {
+			return nil, fmt.Errorf("rule %d is empty", i+1)
+		}
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		if err := rule.compile(); err != nil {
+			return nil, fmt.Errorf("%s: %w", rule.Name, err)
+		}
+	}
+	return set.Rules, nil
+}
+
+func (r *Rule) compile() error {
+	if err := r.Match.compile(); err != nil {
+		return err
+	}
+
+	for i := range r.Actions {
+		if err := r.Actions[i].compile(); err != nil {
+			return fmt.Errorf("action %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (m *Match) compile() error {
+	m.Method = strings.ToUpper(m.Method)
+	m.Host = strings.ToLower(m.Host)
+
+	var err error
+	if m.Path != "" {
+		if m.pathRe, err = regexp.Compile(m.Path); err != nil {
+			return fmt.Errorf("invalid path regex: %w", err)
+		}
+	}
+
+	if m.Header != "" {
+		name, value, found := strings.Cut(m.Header, ":")
+		m.headerName = strings.TrimSpace(name)
+		if found {
+			if m.headerValue, err = regexp.Compile(strings.TrimSpace(value)); err != nil {
+				return fmt.Errorf("invalid header regex: %w", err)
+			}
+		}
+	}
+
+	if m.Body != "" {
+		if m.bodyRe, err = regexp.Compile(m.Body); err != nil {
+			return fmt.Errorf("invalid body regex: %w", err)
+		}
+	}
+	return nil
+}
+
+func (a *Action) compile() error {
+	a.Type = strings.ToLower(a.Type)
+	a.Stage = strings.ToLower(a.Stage)
+
+	switch a.Type {
+	case SetHeader, RemoveHeader:
+		if a.Name == "" {
+			return fmt.Errorf("%s needs a name", a.Type)
+		}
+	case RenameHeader:
+		if a.Name == "" || a.To == "" {
+			return fmt.Errorf("%s needs a name and a to", a.Type)
+		}
+	case ReplaceBody:
+		if a.Pattern == "" {
+			return fmt.Errorf("%s needs a pattern", a.Type)
+		}
+	case RewriteURL:
+		if a.Pattern == "" && a.Value == "" {
+			return fmt.Errorf("%s needs a pattern or a value", a.Type)
+		}
+		a.Stage = StageRequest
+	case SetStatus:
+		if a.Status < 100 || a.Status > 999 {
+			return fmt.Errorf("%s needs a status between 100 and 999", a.Type)
+		}
+		a.Stage = StageResponse
+	default:
+		return fmt.Errorf("unknown action type %q", a.Type)
+	}
+
+	switch a.Stage {
+	case "":
+		a.Stage = StageRequest
+	case StageRequest, StageResponse:
+	default:
+		return fmt.Errorf("unknown stage %q", a.Stage)
+	}
+
+	if a.Pattern != "" {
+		re, err := regexp.Compile(a.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+		a.re = re
+	}
+	return nil
+}
+
+// Matches reports whether req satisfies every condition of the match
+func (m *Match) Matches(req *sessiondata.RequestData) bool {
+	if req == nil {
+		return false
+	}
+
+	if m.Method != "" && !strings.EqualFold(req.Method, m.Method) {
+		return false
+	}
+
+	if m.Host != "" || m.pathRe != nil {
+		parsed, err := url.Parse(req.URL)
+		if err != nil {
+			return false
+		}
+		if m.Host != "" && !matchesHost(strings.ToLower(parsed.Hostname()), m.Host) {
+			return false
+		}
+		if m.pathRe != nil && !m.pathRe.MatchString(parsed.Path) {
+			return false
+		}
+	}
+
+	if m.headerName != "" && !m.matchesHeader(req) {
+		return false
+	}
+
+	if m.bodyRe != nil {
+		body, err := decodeBody(req.Body, req.Headers)
+		if err != nil || !m.bodyRe.MatchString(body) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (m *Match) matchesHeader(req *sessiondata.RequestData) bool {
+	key, ok := findHeader(req.Headers, m.headerName)
+	if !ok {
+		return false
+	}
+	if m.headerValue == nil {
+		return true
+	}
+	for _, value := range headerValues(req.Headers, key) {
+		if m.headerValue.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesHost(host, pattern string) bool {
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return host == suffix || strings.HasSuffix(host, "."+suffix)
+	}
+	return host == pattern
+}
@@ -0,0 +1,590 @@
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"httpDebugger/pkg/sessiondata"
+)
+
+const (
+	indexFileName     = "index.log"
+	segmentPattern    = "segment-*.log"
+	segmentNameFormat = "segment-%06d.log"
+	maxSegmentSize    = 64 * 1024 * 1024
+	defaultCacheSize  = 256
+)
+
+// indexEntry locates the latest record of a session inside the segment log and
+// keeps a body-less summary of it so listing never touches the segments
+type indexEntry struct {
+	ID      string               `json:"id"`
+	Segment int                  `json:"segment"`
+	Offset  int64                `json:"offset"`
+	Length  int64                `json:"length"`
+	Summary *sessiondata.Session `json:"summary"`
+}
+
+// DiskStore persists sessions in an append-only log split into segments, with a
+// separate append-only index. Storing a session again (e.g. a WebSocket that
+// closed) appends a new record and moves the index entry to it. Full sessions
+// are read back on demand and kept in a small LRU cache; GetAll returns summaries
+// without bodies or WebSocket messages, use Get or GetPage for the full data
+type DiskStore struct {
+	dir   string
+	mutex sync.RWMutex
+
+	entries map[string]*indexEntry
+	order   []*indexEntry
+	live    map[string]*sessiondata.Session
+
+	segments      map[int]*os.File
+	activeSegment int
+	activeSize    int64
+	indexFile     *os.File
+
+	cache *sessionCache
+
+	subscribers  []func()
+	sessionCount int
+
+	sessionSubscribers []func(*sessiondata.Session)
+}
+
+// OpenDiskStore opens the capture directory dir, creating it if needed, and
+// loads the index of a previous capture. cacheSize bounds how many full sessions
+// are kept in memory; 0 uses the default
+func OpenDiskStore(dir string, cacheSize int) (*DiskStore, error) {
+	if cacheSize <= 0 {
+		cacheSize = defaultCacheSize
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating capture dir: %w", err)
+	}
+
+	s := &DiskStore{
+		dir:      dir,
+		entries:  make(map[string]*indexEntry),
+		live:     make(map[string]*sessiondata.Session),
+		segments: make(map[int]*os.File),
+		cache:    newSessionCache(cacheSize),
+	}
+
+	if err := s.openSegments(); err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	if err := s.loadIndex(); err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *DiskStore) Dir() string {
+	return s.dir
+}
+
+func (s *DiskStore) Subscribe(callback func()) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.subscribers = append(s.subscribers, callback)
+}
+
+func (s *DiskStore) notifySubscribers() {
+	s.mutex.RLock()
+	subs := make([]func(), len(s.subscribers))
+	copy(subs, s.subscribers)
+	s.mutex.RUnlock()
+
+	for _, callback := range subs {
+		go callback()
+	}
+}
+
+// SubscribeSessions registers a callback run with a summary of every stored session
+func (s *DiskStore) SubscribeSessions(callback func(*sessiondata.Session)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sessionSubscribers = append(s.sessionSubscribers, callback)
+}
+
+// notifySessionSubscribers must be called with the mutex held
+func (s *DiskStore) notifySessionSubscribers(session *sessiondata.Session) {
+	if len(s.sessionSubscribers) == 0 {
+		return
+	}
+	summary := session.Summary()
+	for _, callback := range s.sessionSubscribers {
+		go callback(summary)
+	}
+}
+
+func (s *DiskStore) Store(session *sessiondata.Session) error {
+	record, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("encoding session %s: %w", session.ID, err)
+	}
+	record = append(record, '\n')
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.activeSize > 0 && s.activeSize+int64(len(record)) > maxSegmentSize {
+		if err := s.rotateSegment(); err != nil {
+			return err
+		}
+	}
+
+	active, ok := s.segments[s.activeSegment]
+	if !ok || s.indexFile == nil {
+		return fmt.Errorf("storing session %s: capture in %s is not open", session.ID, s.dir)
+	}
+	if _, err := active.WriteAt(record, s.activeSize); err != nil {
+		return fmt.Errorf("writing session %s: %w", session.ID, err)
+	}
+
+	entry := &indexEntry{
+		ID:      session.ID,
+		Segment: s.activeSegment,
+		Offset:  s.activeSize,
+		Length:  int64(len(record)),
+		Summary: session.Summary(),
+	}
+	s.activeSize += entry.Length
+
+	if err := s.appendIndex(entry); err != nil {
+		return err
+	}
+	s.addEntry(entry)
+
+	if isLive(session) {
+		s.live[session.ID] = session
+	} else {
+		delete(s.live, session.ID)
+	}
+	s.cache.put(session)
+
+	s.sessionCount++
+	s.notifySessionSubscribers(session)
+	go s.notifySubscribers()
+	return nil
+}
+
+// GetAll returns a summary of every stored session in capture order. Sessions
+// still being captured (open WebSockets) are returned in full
+func (s *DiskStore) GetAll() []*sessiondata.Session {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	result := make([]*sessiondata.Session, len(s.order))
+	for i, entry := range s.order {
+		if session, ok := s.live[entry.ID]; ok {
+			result[i] = session
+		} else {
+			result[i] = entry.Summary
+		}
+	}
+	return result
+}
+
+func (s *DiskStore) Get(id string) (*sessiondata.Session, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	entry, exists := s.entries[id]
+	if !exists {
+		return nil, errors.New("session not found")
+	}
+	return s.load(entry)
+}
+
+func (s *DiskStore) Len() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.order)
+}
+
+// GetPage returns up to limit full sessions starting at offset in capture order
+func (s *DiskStore) GetPage(offset, limit int) []*sessiondata.Session {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	start, end := pageBounds(offset, limit, len(s.order))
+	result := make([]*sessiondata.Session, 0, end-start)
+	for _, entry := range s.order[start:end] {
+		if session, err := s.load(entry); err == nil {
+			result = append(result, session)
+		}
+	}
+	return result
+}
+
+// Search reads every stored session back from disk one at a time and returns
+// the ones matching opt
+func (s *DiskStore) Search(opt SearchOptions) ([]*sessiondata.Session, error) {
+	if opt.isEmpty() {
+		return nil, fmt.Errorf("no search criteria provided")
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var results []*sessiondata.Session
+	for _, entry := range s.order {
+		session, err := s.load(entry)
+		if err != nil {
+			continue
+		}
+		if matchesSearch(session, opt) {
+			results = append(results, session)
+		}
+	}
+
+	return results, nil
+}
+
+// Clear deletes the capture from disk and starts a new one in the same directory.
+// When the new files cannot be created, storing fails until Clear succeeds
+func (s *DiskStore) Clear() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.closeFiles()
+
+	segments, _ := filepath.Glob(filepath.Join(s.dir, segmentPattern))
+	for _, path := range segments {
+		os.Remove(path)
+	}
+	os.Remove(filepath.Join(s.dir, indexFileName))
+
+	s.entries = make(map[string]*indexEntry)
+	s.order = nil
+	s.live = make(map[string]*sessiondata.Session)
+	s.cache.clear()
+	s.segments = make(map[int]*os.File)
+	s.activeSegment = 0
+	s.activeSize = 0
+
+	s.sessionCount++
+	go s.notifySubscribers()
+
+	if err := s.openSegment(1); err != nil {
+		return err
+	}
+	s.activeSegment = 1
+	indexFile, err := os.OpenFile(filepath.Join(s.dir, indexFileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening index: %w", err)
+	}
+	s.indexFile = indexFile
+	return nil
+}
+
+func (s *DiskStore) SessionCount() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.sessionCount
+}
+
+// Close flushes and closes the segment and index files
+func (s *DiskStore) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.closeFiles()
+}
+
+func (s *DiskStore) closeFiles() error {
+	var firstErr error
+	for id, f := range s.segments {
+		if err := f.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(s.segments, id)
+	}
+	if s.indexFile != nil {
+		if err := s.indexFile.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		s.indexFile = nil
+	}
+	return firstErr
+}
+
+// load returns the full session for entry, from memory when possible. Callers hold the read lock
+func (s *DiskStore) load(entry *indexEntry) (*sessiondata.Session, error) {
+	if session, ok := s.live[entry.ID]; ok {
+		return session, nil
+	}
+	if session, ok := s.cache.get(entry.ID); ok {
+		return session, nil
+	}
+
+	session, err := s.readRecord(entry)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.put(session)
+	return session, nil
+}
+
+func (s *DiskStore) readRecord(entry *indexEntry) (*sessiondata.Session, error) {
+	f, ok := s.segments[entry.Segment]
+	if !ok {
+		return nil, fmt.Errorf("segment %d of session %s is missing", entry.Segment, entry.ID)
+	}
+
+	buf := make([]byte, entry.Length)
+	if _, err := f.ReadAt(buf, entry.Offset); err != nil {
+		return nil, fmt.Errorf("reading session %s: %w", entry.ID, err)
+	}
+
+	session := &sessiondata.Session{}
+	if err := json.Unmarshal(buf, session); err != nil {
+		return nil, fmt.Errorf("decoding session %s: %w", entry.ID, err)
+	}
+	return session, nil
+}
+
+func (s *DiskStore) addEntry(entry *indexEntry) {
+	if existing, ok := s.entries[entry.ID]; ok {
+		*existing = *entry
+		return
+	}
+	s.entries[entry.ID] = entry
+	s.order = append(s.order, entry)
+}
+
+func (s *DiskStore) appendIndex(entry *indexEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encoding index entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	if _, err := s.indexFile.Write(line); err != nil {
+		return fmt.Errorf("writing index: %w", err)
+	}
+	return nil
+}
+
+// openSegments opens every existing segment for reading and the newest one for appending
+func (s *DiskStore) openSegments() error {
+	ids, err := s.segmentIDs()
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		ids = []int{1}
+	}
+
+	for _, id := range ids {
+		if err := s.openSegment(id); err != nil {
+			return err
+		}
+	}
+
+	s.activeSegment = ids[len(ids)-1]
+	info, err := s.segments[s.activeSegment].Stat()
+	if err != nil {
+		return fmt.Errorf("reading segment size: %w", err)
+	}
+	s.activeSize = info.Size()
+	return nil
+}
+
+func (s *DiskStore) openSegment(id int) error {
+	path := filepath.Join(s.dir, fmt.Sprintf(segmentNameFormat, id))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening segment: %w", err)
+	}
+	s.segments[id] = f
+	return nil
+}
+
+func (s *DiskStore) rotateSegment() error {
+	next := s.activeSegment + 1
+	if err := s.openSegment(next); err != nil {
+		return err
+	}
+	s.activeSegment = next
+	s.activeSize = 0
+	return nil
+}
+
+func (s *DiskStore) segmentIDs() ([]int, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, segmentPattern))
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(paths))
+	for _, path := range paths {
+		var id int
+		if _, err := fmt.Sscanf(filepath.Base(path), segmentNameFormat, &id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids, nil
+}
+
+// loadIndex replays the index file. A torn last line left by a crash is cut off
+// so new entries start on a clean line. Without an index the segments are scanned
+func (s *DiskStore) loadIndex() error {
+	path := filepath.Join(s.dir, indexFileName)
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening index: %w", err)
+	}
+	s.indexFile = f
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("reading index: %w", err)
+	}
+	if info.Size() == 0 {
+		if err := s.rebuildIndex(); err != nil {
+			return err
+		}
+		_, err := f.Seek(0, io.SeekEnd)
+		return err
+	}
+
+	reader := bufio.NewReader(f)
+	var good int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			break
+		}
+
+		entry := &indexEntry{}
+		if json.Unmarshal(line, entry) != nil || entry.Summary == nil {
+			break
+		}
+		s.addEntry(entry)
+		good += int64(len(line))
+	}
+
+	if good < info.Size() {
+		if err := f.Truncate(good); err != nil {
+			return fmt.Errorf("repairing index: %w", err)
+		}
+	}
+	_, err = f.Seek(good, io.SeekStart)
+	return err
+}
+
+// rebuildIndex recreates the index from the segments, e.g. when it was deleted
+func (s *DiskStore) rebuildIndex() error {
+	ids := make([]int, 0, len(s.segments))
+	for id := range s.segments {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	for _, id := range ids {
+		reader := bufio.NewReaderSize(io.NewSectionReader(s.segments[id], 0, 1<<62), 64*1024)
+
+		var offset int64
+		for {
+			line, err := reader.ReadBytes('\n')
+			if err != nil {
+				break
+			}
+
+			session := &sessiondata.Session{}
+			if json.Unmarshal(bytes.TrimSpace(line), session) == nil && session.ID != "" {
+				entry := &indexEntry{
+					ID:      session.ID,
+					Segment: id,
+					Offset:  offset,
+					Length:  int64(len(line)),
+					Summary: session.Summary(),
+				}
+				if err := s.appendIndex(entry); err != nil {
+					return err
+				}
+				s.addEntry(entry)
+			}
+			offset += int64(len(line))
+		}
+	}
+	return nil
+}
+
+func isLive(session *sessiondata.Session) bool {
+	if session.WebSocket == nil {
+		return false
+	}
+	return session.WebSocket.State == sessiondata.WSConnecting || session.WebSocket.State == sessiondata.WSOpen
+}
+
+// sessionCache is a fixed-size LRU of fully loaded sessions
+type sessionCache struct {
+	mutex    sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	lru      *list.List
+}
+
+func newSessionCache(capacity int) *sessionCache {
+	return &sessionCache{
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+func (c *sessionCache) get(id string) (*sessiondata.Session, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	elem, ok := c.items[id]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*sessiondata.Session), true
+}
+
+func (c *sessionCache) put(session *sessiondata.Session) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if elem, ok := c.items[session.ID]; ok {
+		elem.Value = session
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	c.items[session.ID] = c.lru.PushFront(session)
+	if c.lru.Len() > c.capacity {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.items, oldest.Value.(*sessiondata.Session).ID)
+	}
+}
+
+func (c *sessionCache) clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.items = make(map[string]*list.Element)
+	c.lru.Init()
+}
@@ -0,0 +1,167 @@
+package session
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"httpDebugger/pkg/sessiondata"
+	"httpDebugger/pkg/sortedMap"
+)
+
+func createTestDiskSession(id string) *sessiondata.Session {
+	headers := sortedMap.New()
+	headers.Put("Accept", "application/json")
+	headers.Put("Set-Cookie", []string{"a=1", "b=2"})
+
+	s := createTestSession(id)
+	s.Request.Method = "POST"
+	s.Request.URL = "https://example.com/" + id
+	s.Request.Body = "request body " + id
+	s.Request.Headers = headers
+	s.Response = &sessiondata.ResponseData{StatusCode: 200, Body: "response body " + id}
+	return s
+}
+
+func TestDiskStoreReopen(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := OpenDiskStore(dir, 1)
+	if err != nil {
+		t.Fatalf("OpenDiskStore() failed: %v", err)
+	}
+	failed := createTestDiskSession("2")
+	failed.Error = errors.New("connection refused")
+	store.Store(createTestDiskSession("1"))
+	store.Store(failed)
+	store.Close()
+
+	store, err = OpenDiskStore(dir, 1)
+	if err != nil {
+		t.Fatalf("reopening store failed: %v", err)
+	}
+	defer store.Close()
+
+	if store.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", store.Len())
+	}
+
+	all := store.GetAll()
+	if all[0].ID != "1" || all[1].ID != "2" {
+		t.Errorf("GetAll() returned wrong order: %s, %s", all[0].ID, all[1].ID)
+	}
+	if all[0].Request.Body != "" || all[0].Response.Body != "" {
+		t.Errorf("GetAll() should return summaries without bodies")
+	}
+
+	full, err := store.Get("2")
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if full.Request.Body != "request body 2" || full.Response.Body != "response body 2" {
+		t.Errorf("Get() returned wrong bodies: %q, %q", full.Request.Body, full.Response.Body)
+	}
+	if full.Error == nil || full.Error.Error() != "connection refused" {
+		t.Errorf("Get() lost the session error: %v", full.Error)
+	}
+	if v, _ := full.Request.Headers.Get("Set-Cookie"); len(v.([]string)) != 2 {
+		t.Errorf("multi-value header not restored: %v", v)
+	}
+
+	page := store.GetPage(1, 10)
+	if len(page) != 1 || page[0].ID != "2" {
+		t.Errorf("GetPage(1, 10) returned %d sessions", len(page))
+	}
+}
+
+func TestDiskStoreUpdate(t *testing.T) {
+	store, err := OpenDiskStore(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("OpenDiskStore() failed: %v", err)
+	}
+	defer store.Close()
+
+	s := createTestDiskSession("1")
+	store.Store(s)
+	store.Store(createTestDiskSession("2"))
+
+	s.Response.StatusCode = 500
+	store.Store(s)
+
+	all := store.GetAll()
+	if len(all) != 2 {
+		t.Fatalf("storing a session twice should not duplicate it, got %d sessions", len(all))
+	}
+	if all[0].ID != "1" || all[0].Response.StatusCode != 500 {
+		t.Errorf("updated session should keep its position with the new data")
+	}
+}
+
+func TestDiskStoreRecovery(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := OpenDiskStore(dir, 0)
+	if err != nil {
+		t.Fatalf("OpenDiskStore() failed: %v", err)
+	}
+	store.Store(createTestDiskSession("1"))
+	store.Store(createTestDiskSession("2"))
+	store.Close()
+
+	indexPath := filepath.Join(dir, indexFileName)
+	f, err := os.OpenFile(indexPath, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"id":"3","segm`)
+	f.Close()
+
+	store, err = OpenDiskStore(dir, 0)
+	if err != nil {
+		t.Fatalf("reopening with a torn index failed: %v", err)
+	}
+	store.Store(createTestDiskSession("3"))
+	store.Close()
+
+	os.Remove(indexPath)
+
+	store, err = OpenDiskStore(dir, 0)
+	if err != nil {
+		t.Fatalf("reopening without an index failed: %v", err)
+	}
+	defer store.Close()
+
+	if store.Len() != 3 {
+		t.Fatalf("Len() after rebuild = %d, want 3", store.Len())
+	}
+	if s, err := store.Get("3"); err != nil || s.Request.Body != "request body 3" {
+		t.Errorf("Get() after rebuild failed: %v", err)
+	}
+}
+
+func TestDiskStoreClearFailure(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "capture")
+	store, err := OpenDiskStore(dir, 1)
+	if err != nil {
+		t.Fatalf("OpenDiskStore() failed: %v", err)
+	}
+	defer store.Close()
+
+	// Clear cannot recreate the files of a capture whose directory is gone
+	os.RemoveAll(dir)
+	if err := store.Clear(); err == nil {
+		t.Fatal("Clear() should fail when the capture directory is gone")
+	}
+	if err := store.Store(createTestDiskSession("1")); err == nil {
+		t.Error("Store() should fail after a failed Clear()")
+	}
+
+	os.MkdirAll(dir, 0o755)
+	if err := store.Clear(); err != nil {
+		t.Fatalf("Clear() failed: %v", err)
+	}
+	if err := store.Store(createTestDiskSession("1")); err != nil || store.Len() != 1 {
+		t.Errorf("Store() after Clear() failed: %v", err)
+	}
+}
@@ -13,10 +13,16 @@ type SearchOptions struct {
 	CookiesKey string
 	CookiesVal string
 	Body       string
+	// Fingerprint matches the session's JA3, JA4, JA4H, JA3S or JA4S
+	Fingerprint string
+}
+
+func (opt SearchOptions) isEmpty() bool {
+	return opt.URL == "" && opt.HeadersKey == "" && opt.HeadersVal == "" && opt.CookiesKey == "" && opt.CookiesVal == "" && opt.Body == "" && opt.Fingerprint == ""
 }
 
 func (s *InMemoryStore) Search(opt SearchOptions) ([]*sessiondata.Session, error) {
-	if opt.URL == "" && opt.HeadersKey == "" && opt.HeadersVal == "" && opt.CookiesKey == "" && opt.CookiesVal == "" && opt.Body == "" {
+	if opt.isEmpty() {
 		return nil, fmt.Errorf("no search criteria provided")
 	}
 
@@ -25,7 +31,7 @@ func (s *InMemoryStore) Search(opt SearchOptions) ([]*sessiondata.Session, error
 
 	var results []*sessiondata.Session
 	for _, session := range s.order {
-		if s.checkIfMatch(session, opt) {
+		if matchesSearch(session, opt) {
 			results = append(results, session)
 		}
 	}
@@ -33,7 +39,7 @@ func (s *InMemoryStore) Search(opt SearchOptions) ([]*sessiondata.Session, error
 	return results, nil
 }
 
-func (s *InMemoryStore) checkIfMatch(ses *sessiondata.Session, opt SearchOptions) bool {
+func matchesSearch(ses *sessiondata.Session, opt SearchOptions) bool {
 	if opt.URL != "" {
 		if !matchString(ses.Request.URL, opt.URL) {
 			return false
@@ -41,13 +47,13 @@ func (s *InMemoryStore) checkIfMatch(ses *sessiondata.Session, opt SearchOptions
 	}
 
 	if opt.HeadersKey != "" || opt.HeadersVal != "" {
-		if !s.checkHeaders(ses, opt) {
+		if !checkHeaders(ses, opt) {
 			return false
 		}
 	}
 
 	if opt.CookiesKey != "" || opt.CookiesVal != "" {
-		if !s.checkCookies(ses, opt) {
+		if !checkCookies(ses, opt) {
 			return false
 		}
 	}
@@ -60,10 +66,34 @@ func (s *InMemoryStore) checkIfMatch(ses *sessiondata.Session, opt SearchOptions
 		}
 	}
 
+	if opt.Fingerprint != "" && !checkFingerprints(ses, opt.Fingerprint) {
+		return false
+	}
+
 	return true
 }
 
-func (s *InMemoryStore) checkHeaders(ses *sessiondata.Session, opt SearchOptions) bool {
+// checkFingerprints matches pattern against every fingerprint the session
+// carries, both as strings and as hashes
+func checkFingerprints(ses *sessiondata.Session, pattern string) bool {
+	fingerprints := []string{ses.JA4H}
+	if fp := ses.TLSFingerprint; fp != nil {
+		fingerprints = append(fingerprints, fp.JA3, fp.JA3Hash, fp.JA4, fp.JA4Raw)
+	}
+	if ses.UpstreamTLS != nil && ses.UpstreamTLS.ServerHello != nil {
+		hello := ses.UpstreamTLS.ServerHello
+		fingerprints = append(fingerprints, hello.JA3S, hello.JA3SHash, hello.JA4S)
+	}
+
+	for _, fingerprint := range fingerprints {
+		if fingerprint != "" && matchString(fingerprint, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkHeaders(ses *sessiondata.Session, opt SearchOptions) bool {
 	foundKey := opt.HeadersKey == ""
 	foundVal := opt.HeadersVal == ""
 
@@ -87,7 +117,7 @@ func (s *InMemoryStore) checkHeaders(ses *sessiondata.Session, opt SearchOptions
 	return foundKey && foundVal
 }
 
-func (s *InMemoryStore) checkCookies(ses *sessiondata.Session, opt SearchOptions) bool {
+func checkCookies(ses *sessiondata.Session, opt SearchOptions) bool {
 	foundKey := opt.CookiesKey == ""
 	foundVal := opt.CookiesVal == ""
 
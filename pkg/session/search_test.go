@@ -4,6 +4,7 @@ import (
 	"sort"
 	"testing"
 
+	"httpDebugger/pkg/clientHello"
 	"httpDebugger/pkg/sessiondata"
 	"httpDebugger/pkg/sortedMap"
 )
@@ -485,6 +486,47 @@ func TestSearch_MultipleCriteria(t *testing.T) {
 	}
 }
 
+func TestSearch_FingerprintSearch(t *testing.T) {
+	store := NewInMemoryStore(10)
+	store.Store(&sessiondata.Session{
+		ID:      "client",
+		Request: &sessiondata.RequestData{URL: "https://a.example.com/"},
+		TLSFingerprint: &clientHello.TLSFingerprint{
+			JA3Hash: "cd08e31494f9531f560d64c695473da9",
+			JA4:     "t13d1516h2_8daaf6152771_02713d6af862",
+		},
+		JA4H: "ge11nn05enus_9ed1ff1f7b03_000000000000_000000000000",
+	})
+	store.Store(&sessiondata.Session{
+		ID:      "server",
+		Request: &sessiondata.RequestData{URL: "https://b.example.com/"},
+		UpstreamTLS: &sessiondata.UpstreamTLS{
+			ServerHello: &clientHello.ServerHelloFingerprint{JA3SHash: "f4febc55ea12b31ae17cfb7e614afda8", JA4S: "t130200_1301_234ea6891581"},
+		},
+	})
+
+	tests := []struct {
+		pattern  string
+		expected []string
+	}{
+		{"t13d1516h2_8daaf6152771_02713d6af862", []string{"client"}},
+		{"CD08E314", []string{"client"}},
+		{"ge11nn05", []string{"client"}},
+		{"f4febc55ea12b31ae17cfb7e614afda8", []string{"server"}},
+		{"/^t13/", []string{"client", "server"}},
+		{"no-such-fingerprint", nil},
+	}
+	for _, tt := range tests {
+		results, err := store.Search(SearchOptions{Fingerprint: tt.pattern})
+		if err != nil {
+			t.Fatalf("Search(%q) failed: %v", tt.pattern, err)
+		}
+		if ids := getIDs(results); !slicesEqual(ids, tt.expected) {
+			t.Errorf("Search(%q) = %v, want %v", tt.pattern, ids, tt.expected)
+		}
+	}
+}
+
 func BenchmarkSearch_URLOnly(b *testing.B) {
 	store := setupTestStoreWithData()
 	opt := SearchOptions{URL: "amazon"}
@@ -14,6 +14,8 @@ type InMemoryStore struct {
 	maxSize      int
 	subscribers  []func()
 	sessionCount int
+
+	sessionSubscribers []func(*sessiondata.Session)
 }
 
 func NewInMemoryStore(maxSize int) *InMemoryStore {
@@ -41,10 +43,42 @@ func (s *InMemoryStore) notifySubscribers() {
 	}
 }
 
+// SubscribeSessions registers a callback run with a summary of every stored session
+func (s *InMemoryStore) SubscribeSessions(callback func(*sessiondata.Session)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sessionSubscribers = append(s.sessionSubscribers, callback)
+}
+
+// notifySessionSubscribers must be called with the mutex held
+func (s *InMemoryStore) notifySessionSubscribers(session *sessiondata.Session) {
+	if len(s.sessionSubscribers) == 0 {
+		return
+	}
+	summary := session.Summary()
+	for _, callback := range s.sessionSubscribers {
+		go callback(summary)
+	}
+}
+
 func (s *InMemoryStore) Store(session *sessiondata.Session) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	if _, exists := s.sessions[session.ID]; exists {
+		s.sessions[session.ID] = session
+		for i, stored := range s.order {
+			if stored.ID == session.ID {
+				s.order[i] = session
+				break
+			}
+		}
+		s.sessionCount++
+		s.notifySessionSubscribers(session)
+		go s.notifySubscribers()
+		return nil
+	}
+
 	s.sessions[session.ID] = session
 	s.order = append(s.order, session)
 
@@ -55,6 +89,7 @@ func (s *InMemoryStore) Store(session *sessiondata.Session) error {
 	}
 
 	s.sessionCount++
+	s.notifySessionSubscribers(session)
 	go s.notifySubscribers()
 	return nil
 }
@@ -68,6 +103,22 @@ func (s *InMemoryStore) GetAll() []*sessiondata.Session {
 	return result
 }
 
+func (s *InMemoryStore) Len() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.order)
+}
+
+func (s *InMemoryStore) GetPage(offset, limit int) []*sessiondata.Session {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	start, end := pageBounds(offset, limit, len(s.order))
+	result := make([]*sessiondata.Session, end-start)
+	copy(result, s.order[start:end])
+	return result
+}
+
 func (s *InMemoryStore) Get(id string) (*sessiondata.Session, error) {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
@@ -79,13 +130,14 @@ func (s *InMemoryStore) Get(id string) (*sessiondata.Session, error) {
 	return session, nil
 }
 
-func (s *InMemoryStore) Clear() {
+func (s *InMemoryStore) Clear() error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 	s.sessions = make(map[string]*sessiondata.Session)
 	s.order = make([]*sessiondata.Session, 0)
 	s.sessionCount++
 	go s.notifySubscribers()
+	return nil
 }
 
 func (s *InMemoryStore) SessionCount() int {
@@ -96,3 +96,21 @@ func TestStoreMaxSize(t *testing.T) {
 		t.Errorf("Get() for newest session '4' failed")
 	}
 }
+
+func TestStoreSameSessionTwice(t *testing.T) {
+	store := NewInMemoryStore(10)
+	store.Store(createTestSession("1"))
+	store.Store(createTestSession("2"))
+
+	updated := createTestSession("1")
+	updated.Protocol = "HTTP/2"
+	store.Store(updated)
+
+	sessions := store.GetAll()
+	if len(sessions) != 2 {
+		t.Fatalf("storing a session twice should update it, got %d sessions", len(sessions))
+	}
+	if sessions[0].Protocol != "HTTP/2" {
+		t.Errorf("stored session was not replaced in place")
+	}
+}
@@ -0,0 +1,57 @@
+package session
+
+import (
+	"httpDebugger/pkg/proxy/interfaces"
+	"httpDebugger/pkg/sessiondata"
+)
+
+// Store is a SessionStore the TUI can browse: it pages through captured
+// sessions, searches them and signals every change
+type Store interface {
+	interfaces.SessionStore
+	Len() int
+	GetPage(offset, limit int) []*sessiondata.Session
+	Search(opt SearchOptions) ([]*sessiondata.Session, error)
+	Subscribe(callback func())
+	SubscribeSessions(callback func(*sessiondata.Session))
+	SessionCount() int
+	Clear() error
+}
+
+// DefaultMaxSessions is how many sessions an in-memory store keeps by default
+const DefaultMaxSessions = 1000
+
+// Open returns a disk store in dir when it is set, otherwise an in-memory store
+// keeping the latest maxSessions sessions
+func Open(dir string, maxSessions int) (Store, error) {
+	if dir != "" {
+		store, err := OpenDiskStore(dir, 0)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	}
+	if maxSessions <= 0 {
+		maxSessions = DefaultMaxSessions
+	}
+	return NewInMemoryStore(maxSessions), nil
+}
+
+var (
+	_ Store = (*InMemoryStore)(nil)
+	_ Store = (*DiskStore)(nil)
+)
+
+func pageBounds(offset, limit, total int) (int, int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > total {
+		offset = total
+	}
+	end := total
+	if limit >= 0 && offset+limit < total {
+		end = offset + limit
+	}
+	return offset, end
+}
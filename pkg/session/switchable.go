@@ -0,0 +1,147 @@
+package session
+
+import (
+	"io"
+	"sync"
+
+	"httpDebugger/pkg/sessiondata"
+)
+
+// SwitchableStore is a Store whose backing store can be replaced while the
+// proxy keeps storing sessions in it, so another capture can be opened
+// without restarting. Subscribers stay registered across switches
+type SwitchableStore struct {
+	mutex sync.RWMutex
+	store Store
+	// countBase keeps SessionCount growing across switches so pollers always
+	// see a switch as a change
+	countBase int
+
+	subscribers        []func()
+	sessionSubscribers []func(*sessiondata.Session)
+}
+
+// NewSwitchableStore wraps store
+func NewSwitchableStore(store Store) *SwitchableStore {
+	s := &SwitchableStore{store: store}
+	s.forward(store)
+	return s
+}
+
+// Switch replaces the backing store and returns the previous one, which the
+// caller closes. Subscribers are notified as if every session changed
+func (s *SwitchableStore) Switch(store Store) Store {
+	s.forward(store)
+
+	s.mutex.Lock()
+	old := s.store
+	s.countBase += old.SessionCount() + 1
+	s.store = store
+	subs := make([]func(), len(s.subscribers))
+	copy(subs, s.subscribers)
+	s.mutex.Unlock()
+
+	for _, callback := range subs {
+		go callback()
+	}
+	return old
+}
+
+// forward relays the notifications of store to the subscribers while it is
+// the backing store
+func (s *SwitchableStore) forward(store Store) {
+	store.Subscribe(func() {
+		s.mutex.RLock()
+		if s.store != store {
+			s.mutex.RUnlock()
+			return
+		}
+		subs := make([]func(), len(s.subscribers))
+		copy(subs, s.subscribers)
+		s.mutex.RUnlock()
+
+		for _, callback := range subs {
+			callback()
+		}
+	})
+	store.SubscribeSessions(func(session *sessiondata.Session) {
+		s.mutex.RLock()
+		if s.store != store {
+			s.mutex.RUnlock()
+			return
+		}
+		subs := make([]func(*sessiondata.Session), len(s.sessionSubscribers))
+		copy(subs, s.sessionSubscribers)
+		s.mutex.RUnlock()
+
+		for _, callback := range subs {
+			callback(session)
+		}
+	})
+}
+
+// Store holds the read lock while storing, so a switch waits for it and the
+// previous store can be closed safely
+func (s *SwitchableStore) Store(session *sessiondata.Session) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.store.Store(session)
+}
+
+func (s *SwitchableStore) GetAll() []*sessiondata.Session {
+	return s.current().GetAll()
+}
+
+func (s *SwitchableStore) Get(id string) (*sessiondata.Session, error) {
+	return s.current().Get(id)
+}
+
+func (s *SwitchableStore) Len() int {
+	return s.current().Len()
+}
+
+func (s *SwitchableStore) GetPage(offset, limit int) []*sessiondata.Session {
+	return s.current().GetPage(offset, limit)
+}
+
+func (s *SwitchableStore) Search(opt SearchOptions) ([]*sessiondata.Session, error) {
+	return s.current().Search(opt)
+}
+
+func (s *SwitchableStore) Subscribe(callback func()) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.subscribers = append(s.subscribers, callback)
+}
+
+func (s *SwitchableStore) SubscribeSessions(callback func(*sessiondata.Session)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sessionSubscribers = append(s.sessionSubscribers, callback)
+}
+
+func (s *SwitchableStore) SessionCount() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.countBase + s.store.SessionCount()
+}
+
+func (s *SwitchableStore) Clear() error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.store.Clear()
+}
+
+// Close closes the backing store when it holds files
+func (s *SwitchableStore) Close() error {
+	if closer, ok := s.current().(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (s *SwitchableStore) current() Store {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.store
+}
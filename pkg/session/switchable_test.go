@@ -0,0 +1,54 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"httpDebugger/pkg/sessiondata"
+)
+
+func TestSwitchableStore(t *testing.T) {
+	first := NewInMemoryStore(10)
+	first.Store(createTestSession("1"))
+	store := NewSwitchableStore(first)
+
+	stored := make(chan string, 4)
+	store.SubscribeSessions(func(s *sessiondata.Session) { stored <- s.ID })
+
+	disk, err := OpenDiskStore(t.TempDir(), 1)
+	if err != nil {
+		t.Fatalf("OpenDiskStore() failed: %v", err)
+	}
+	defer disk.Close()
+
+	count := store.SessionCount()
+	if old := store.Switch(disk); old != first {
+		t.Errorf("Switch() returned %v, want the previous store", old)
+	}
+	if store.SessionCount() <= count {
+		t.Errorf("SessionCount() did not grow across a switch")
+	}
+	if store.Len() != 0 {
+		t.Errorf("Len() = %d after switching to an empty capture", store.Len())
+	}
+
+	// Sessions go to the new store and notify the subscribers registered before
+	first.Store(createTestSession("old"))
+	store.Store(createTestSession("2"))
+	if _, err := disk.Get("2"); err != nil {
+		t.Errorf("session was not stored in the new store: %v", err)
+	}
+	select {
+	case id := <-stored:
+		if id != "2" {
+			t.Errorf("subscriber notified of %s, want 2", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("subscriber was not notified after the switch")
+	}
+	select {
+	case id := <-stored:
+		t.Errorf("subscriber notified of %s stored in the previous store", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
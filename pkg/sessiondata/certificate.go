@@ -0,0 +1,99 @@
+package sessiondata
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// NewCertificateChain describes the certificates a server sent, leaf first
+func NewCertificateChain(certs []*x509.Certificate) []CertificateInfo {
+	chain := make([]CertificateInfo, 0, len(certs))
+	for _, cert := range certs {
+		chain = append(chain, NewCertificateInfo(cert))
+	}
+	return chain
+}
+
+// NewCertificateInfo describes one certificate
+func NewCertificateInfo(cert *x509.Certificate) CertificateInfo {
+	info := CertificateInfo{
+		Subject:            cert.Subject.String(),
+		Issuer:             cert.Issuer.String(),
+		SerialNumber:       formatFingerprint(cert.SerialNumber.Bytes()),
+		DNSNames:           cert.DNSNames,
+		NotBefore:          cert.NotBefore,
+		NotAfter:           cert.NotAfter,
+		KeyType:            keyType(cert),
+		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+		IsCA:               cert.IsCA,
+	}
+	for _, ip := range cert.IPAddresses {
+		info.IPAddresses = append(info.IPAddresses, ip.String())
+	}
+
+	sha256Sum := sha256.Sum256(cert.Raw)
+	info.SHA256 = formatFingerprint(sha256Sum[:])
+	sha1Sum := sha1.Sum(cert.Raw)
+	info.SHA1 = formatFingerprint(sha1Sum[:])
+	return info
+}
+
+func keyType(cert *x509.Certificate) string {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", key.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA " + key.Curve.Params().Name
+	case ed25519.PublicKey:
+		return "Ed25519"
+	default:
+		return cert.PublicKeyAlgorithm.String()
+	}
+}
+
+// formatFingerprint writes bytes as colon separated upper case hex, as
+// browsers show certificate fingerprints
+func formatFingerprint(data []byte) string {
+	parts := make([]string, len(data))
+	for i, b := range data {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+// NewOCSPInfo parses a stapled OCSP response, checking its signature against
+// issuer when known. It returns nil when nothing was stapled
+func NewOCSPInfo(raw []byte, issuer *x509.Certificate) *OCSPInfo {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	resp, err := ocsp.ParseResponse(raw, issuer)
+	if err != nil {
+		return &OCSPInfo{Error: err.Error()}
+	}
+
+	info := &OCSPInfo{
+		ProducedAt: resp.ProducedAt,
+		ThisUpdate: resp.ThisUpdate,
+		NextUpdate: resp.NextUpdate,
+	}
+	switch resp.Status {
+	case ocsp.Good:
+		info.Status = "good"
+	case ocsp.Revoked:
+		info.Status = "revoked"
+		info.RevokedAt = resp.RevokedAt
+	default:
+		info.Status = "unknown"
+	}
+	return info
+}
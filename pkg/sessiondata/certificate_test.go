@@ -0,0 +1,71 @@
+package sessiondata
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewCertificateInfo(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() failed: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(0x0102),
+		Subject:      pkix.Name{CommonName: "api.example.com"},
+		DNSNames:     []string{"api.example.com", "www.example.com"},
+		IPAddresses:  []net.IP{net.ParseIP("192.0.2.1")},
+		NotBefore:    time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate() failed: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate() failed: %v", err)
+	}
+
+	chain := NewCertificateChain([]*x509.Certificate{cert})
+	if len(chain) != 1 {
+		t.Fatalf("NewCertificateChain() returned %d certificates, want 1", len(chain))
+	}
+	info := chain[0]
+
+	if info.Subject != "CN=api.example.com" || info.Issuer != "CN=api.example.com" {
+		t.Errorf("subject %q, issuer %q", info.Subject, info.Issuer)
+	}
+	if info.SerialNumber != "01:02" {
+		t.Errorf("SerialNumber = %q, want 01:02", info.SerialNumber)
+	}
+	if len(info.DNSNames) != 2 || len(info.IPAddresses) != 1 || info.IPAddresses[0] != "192.0.2.1" {
+		t.Errorf("unexpected SANs: %v %v", info.DNSNames, info.IPAddresses)
+	}
+	if info.KeyType != "ECDSA P-256" || info.SignatureAlgorithm != "ECDSA-SHA256" {
+		t.Errorf("KeyType = %q, SignatureAlgorithm = %q", info.KeyType, info.SignatureAlgorithm)
+	}
+	if !info.NotAfter.Equal(template.NotAfter) {
+		t.Errorf("NotAfter = %v, want %v", info.NotAfter, template.NotAfter)
+	}
+	if len(info.SHA256) != 32*3-1 || len(info.SHA1) != 20*3-1 || strings.ToUpper(info.SHA256) != info.SHA256 {
+		t.Errorf("unexpected fingerprints %q %q", info.SHA256, info.SHA1)
+	}
+}
+
+func TestNewOCSPInfo(t *testing.T) {
+	if NewOCSPInfo(nil, nil) != nil {
+		t.Errorf("NewOCSPInfo() should return nil when nothing was stapled")
+	}
+	if info := NewOCSPInfo([]byte("not ocsp"), nil); info == nil || info.Error == "" {
+		t.Errorf("NewOCSPInfo() should report an unparsable response, got %+v", info)
+	}
+}
@@ -3,6 +3,9 @@ package sessiondata
 const (
 	HTTPSession SessionType = iota
 	WebSocketSession
+	// TunnelSession is a TCP stream relayed as is, because it was neither TLS nor
+	// HTTP or because TLS interception was skipped for its host
+	TunnelSession
 )
 
 const (
@@ -34,4 +37,9 @@ const (
 	HTTP2Protocol  = "HTTP/2"
 	HTTP11Protocol = "HTTP/1.1"
 	HTTP10Protocol = "HTTP/1.0"
+	TCPProtocol    = "TCP"
+	TLSProtocol    = "TLS"
 )
+
+// TunnelPreviewSize is how many leading bytes of each direction a tunnel session keeps
+const TunnelPreviewSize = 1024
@@ -0,0 +1,103 @@
+package sessiondata
+
+import "strings"
+
+// LineOp says how a row of a side-by-side diff changed
+type LineOp int
+
+const (
+	LineEqual LineOp = iota
+	LineRemoved
+	LineAdded
+	LineChanged
+)
+
+// LineDiff is a row of a side-by-side diff. Original is empty for added lines
+// and Other for removed ones
+type LineDiff struct {
+	Op       LineOp
+	Original string
+	Other    string
+}
+
+// maxDiffCells bounds the table of the line diff; larger texts are compared
+// line by line at the same positions
+const maxDiffCells = 4_000_000
+
+// DiffLines compares two texts line by line for side-by-side display. Removed
+// lines followed by added ones are paired up as changed rows
+func DiffLines(original, other string) []LineDiff {
+	a, b := splitLines(original), splitLines(other)
+	if len(a)*len(b) > maxDiffCells {
+		return positionalDiff(a, b)
+	}
+
+	// common[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	var rows, removed, added []LineDiff
+	flush := func() {
+		for len(removed) > 0 && len(added) > 0 {
+			rows = append(rows, LineDiff{Op: LineChanged, Original: removed[0].Original, Other: added[0].Other})
+			removed, added = removed[1:], added[1:]
+		}
+		rows = append(rows, removed...)
+		rows = append(rows, added...)
+		removed, added = nil, nil
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			flush()
+			rows = append(rows, LineDiff{Op: LineEqual, Original: a[i], Other: b[j]})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && common[i+1][j] >= common[i][j+1]):
+			removed = append(removed, LineDiff{Op: LineRemoved, Original: a[i]})
+			i++
+		default:
+			added = append(added, LineDiff{Op: LineAdded, Other: b[j]})
+			j++
+		}
+	}
+	flush()
+	return rows
+}
+
+func positionalDiff(a, b []string) []LineDiff {
+	rows := make([]LineDiff, 0, max(len(a), len(b)))
+	for i := 0; i < len(a) || i < len(b); i++ {
+		switch {
+		case i >= len(a):
+			rows = append(rows, LineDiff{Op: LineAdded, Other: b[i]})
+		case i >= len(b):
+			rows = append(rows, LineDiff{Op: LineRemoved, Original: a[i]})
+		case a[i] == b[i]:
+			rows = append(rows, LineDiff{Op: LineEqual, Original: a[i], Other: b[i]})
+		default:
+			rows = append(rows, LineDiff{Op: LineChanged, Original: a[i], Other: b[i]})
+		}
+	}
+	return rows
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
@@ -0,0 +1,30 @@
+package sessiondata
+
+import "testing"
+
+func TestDiffLines(t *testing.T) {
+	rows := DiffLines("a\nb\nc\nd\n", "a\nB\nc\nd\ne")
+	want := []LineDiff{
+		{Op: LineEqual, Original: "a", Other: "a"},
+		{Op: LineChanged, Original: "b", Other: "B"},
+		{Op: LineEqual, Original: "c", Other: "c"},
+		{Op: LineEqual, Original: "d", Other: "d"},
+		{Op: LineAdded, Other: "e"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("DiffLines() = %+v, want %+v", rows, want)
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Errorf("row %d = %+v, want %+v", i, rows[i], want[i])
+		}
+	}
+
+	rows = DiffLines("x\ny", "")
+	if len(rows) != 2 || rows[0].Op != LineRemoved || rows[1].Original != "y" {
+		t.Errorf("DiffLines() against an empty text = %+v", rows)
+	}
+	if rows := DiffLines("", ""); len(rows) != 0 {
+		t.Errorf("DiffLines() of empty texts = %+v", rows)
+	}
+}
@@ -0,0 +1,553 @@
+package sessiondata
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"httpDebugger/pkg/sortedMap"
+
+	"github.com/google/uuid"
+)
+
+const (
+	harVersion       = "1.2"
+	harCreator       = "MITM-go"
+	harBase64        = "base64"
+	harWebSocketType = "websocket"
+)
+
+// HAR is the root of an HTTP Archive 1.2 document
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Entries []HAREntry `json:"entries"`
+}
+
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type HAREntry struct {
+	StartedDateTime   time.Time             `json:"startedDateTime"`
+	Time              float64               `json:"time"`
+	Request           HARRequest            `json:"request"`
+	Response          HARResponse           `json:"response"`
+	Cache             struct{}              `json:"cache"`
+	Timings           HARTimings            `json:"timings"`
+	ResourceType      string                `json:"_resourceType,omitempty"`
+	WebSocketMessages []HARWebSocketMessage `json:"_webSocketMessages,omitempty"`
+	Error             string                `json:"_error,omitempty"`
+}
+
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARCookie    `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARCookie    `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type HARCookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARPostData carries the request body. HAR has no encoding field for request
+// bodies, so binary ones use the _encoding extension like the response content
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Encoding string `json:"_encoding,omitempty"`
+}
+
+type HARContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// HARWebSocketMessage follows the _webSocketMessages extension written by Chrome DevTools
+type HARWebSocketMessage struct {
+	Type   string  `json:"type"`
+	Time   float64 `json:"time"`
+	Opcode uint8   `json:"opcode"`
+	Data   string  `json:"data"`
+}
+
+// ExportHAR writes sessions as an indented HAR 1.2 document
+func ExportHAR(w io.Writer, sessions []*Session) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(ToHAR(sessions))
+}
+
+// ImportHAR reads a HAR document and converts its entries to sessions
+func ImportHAR(r io.Reader) ([]*Session, error) {
+	var har HAR
+	if err := json.NewDecoder(r).Decode(&har); err != nil {
+		return nil, fmt.Errorf("decoding HAR: %w", err)
+	}
+	return FromHAR(&har)
+}
+
+// ToHAR converts sessions to a HAR document, keeping headers in their captured order
+func ToHAR(sessions []*Session) *HAR {
+	har := &HAR{
+		Log: HARLog{
+			Version: harVersion,
+			Creator: HARCreator{Name: harCreator, Version: harVersion},
+			Entries: make([]HAREntry, 0, len(sessions)),
+		},
+	}
+
+	for _, s := range sessions {
+		// HAR has no representation for raw TCP streams
+		if s == nil || s.Request == nil || s.Type == TunnelSession {
+			continue
+		}
+		har.Log.Entries = append(har.Log.Entries, s.toHAREntry())
+	}
+	return har
+}
+
+// FromHAR converts HAR entries to sessions with fresh IDs
+func FromHAR(har *HAR) ([]*Session, error) {
+	sessions := make([]*Session, 0, len(har.Log.Entries))
+	for i, entry := range har.Log.Entries {
+		session, err := sessionFromHAREntry(entry)
+		if err != nil {
+			return nil, fmt.Errorf("HAR entry %d: %w", i, err)
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+func (s *Session) toHAREntry() HAREntry {
+	ms := float64(s.Duration) / float64(time.Millisecond)
+	version := harHTTPVersion(s.Protocol)
+
+	entry := HAREntry{
+		StartedDateTime: s.Timestamp,
+		Time:            ms,
+		Request: HARRequest{
+			Method:      s.Request.Method,
+			URL:         s.Request.URL,
+			HTTPVersion: version,
+			Cookies:     harCookies(s.Request.Cookies),
+			Headers:     harHeaders(s.Request.Headers),
+			QueryString: harQueryString(s.Request.URL),
+			HeadersSize: -1,
+			BodySize:    len(s.Request.Body),
+		},
+		Response: HARResponse{
+			Cookies:     []HARCookie{},
+			Headers:     []HARNameValue{},
+			HTTPVersion: version,
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Timings: HARTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Wait: ms},
+	}
+
+	if s.Request.Body != "" {
+		text, encoding := harEncodeBody(s.Request.Body)
+		entry.Request.PostData = &HARPostData{
+			MimeType: s.Request.ContentType,
+			Text:     text,
+			Encoding: encoding,
+		}
+	}
+
+	if s.Response != nil {
+		text, encoding := harEncodeBody(s.Response.Body)
+		entry.Response.Status = s.Response.StatusCode
+		entry.Response.StatusText = statusText(s.Response)
+		entry.Response.Cookies = harCookies(s.Response.Cookies)
+		entry.Response.Headers = harHeaders(s.Response.Headers)
+		entry.Response.BodySize = len(s.Response.Body)
+		entry.Response.Content = HARContent{
+			Size:     len(s.Response.Body),
+			MimeType: s.Response.ContentType,
+			Text:     text,
+			Encoding: encoding,
+		}
+		if s.Response.Headers != nil {
+			if location, ok := s.Response.Headers.Get("Location"); ok {
+				entry.Response.RedirectURL = firstValue(location)
+			}
+		}
+	}
+
+	if s.Error != nil {
+		entry.Error = s.Error.Error()
+	}
+
+	if s.Type == WebSocketSession && s.WebSocket != nil {
+		entry.ResourceType = harWebSocketType
+		entry.WebSocketMessages = make([]HARWebSocketMessage, 0, len(s.WebSocket.Messages))
+		for _, msg := range s.WebSocket.Messages {
+			entry.WebSocketMessages = append(entry.WebSocketMessages, harWebSocketMessage(msg))
+		}
+	}
+
+	return entry
+}
+
+func sessionFromHAREntry(entry HAREntry) (*Session, error) {
+	reqBody := ""
+	contentType := ""
+	if entry.Request.PostData != nil {
+		body, err := harDecodeBody(entry.Request.PostData.Text, entry.Request.PostData.Encoding)
+		if err != nil {
+			return nil, fmt.Errorf("request body: %w", err)
+		}
+		reqBody = body
+		contentType = entry.Request.PostData.MimeType
+	}
+
+	reqHeaders := headersFromHAR(entry.Request.Headers)
+	if contentType == "" {
+		if ct, ok := reqHeaders.Get("Content-Type"); ok {
+			contentType = firstValue(ct)
+		}
+	}
+
+	request := &RequestData{
+		Method:      entry.Request.Method,
+		URL:         entry.Request.URL,
+		Headers:     reqHeaders,
+		Cookies:     cookiesFromHAR(entry.Request.Cookies),
+		Body:        reqBody,
+		ContentType: contentType,
+	}
+
+	session := &Session{
+		ID:        uuid.New().String(),
+		Timestamp: entry.StartedDateTime,
+		Request:   request,
+		Duration:  time.Duration(entry.Time * float64(time.Millisecond)),
+		Protocol:  protocolFromHAR(entry.Request.HTTPVersion),
+		Type:      HTTPSession,
+	}
+
+	if entry.Response.Status > 0 {
+		respBody, err := harDecodeBody(entry.Response.Content.Text, entry.Response.Content.Encoding)
+		if err != nil {
+			return nil, fmt.Errorf("response body: %w", err)
+		}
+
+		status := strconv.Itoa(entry.Response.Status)
+		if entry.Response.StatusText != "" {
+			status += " " + entry.Response.StatusText
+		}
+
+		session.Response = &ResponseData{
+			StatusCode:  entry.Response.Status,
+			Status:      status,
+			Headers:     headersFromHAR(entry.Response.Headers),
+			Cookies:     cookiesFromHAR(entry.Response.Cookies),
+			Body:        respBody,
+			ContentType: entry.Response.Content.MimeType,
+		}
+	}
+
+	if entry.Error != "" {
+		session.Error = fmt.Errorf("%s", entry.Error)
+	}
+
+	if entry.ResourceType == harWebSocketType || len(entry.WebSocketMessages) > 0 {
+		session.Type = WebSocketSession
+		session.WebSocket = webSocketFromHAR(session, entry.WebSocketMessages)
+	}
+
+	return session, nil
+}
+
+func webSocketFromHAR(session *Session, messages []HARWebSocketMessage) *WebSocketData {
+	ws := &WebSocketData{
+		UpgradeRequest:  session.Request,
+		UpgradeResponse: session.Response,
+		State:           WSClosed,
+		ConnectedAt:     session.Timestamp,
+		Messages:        make([]WebSocketMessage, 0, len(messages)),
+		MessageCount:    newMessageStats(),
+	}
+
+	for _, m := range messages {
+		msg := WebSocketMessage{
+			ID:        uuid.New().String(),
+			Timestamp: time.UnixMicro(int64(m.Time * 1e6)),
+			Direction: Inbound,
+			Opcode:    m.Opcode,
+			Type:      messageTypeForOpcode(m.Opcode),
+		}
+		if m.Type == "send" {
+			msg.Direction = Outbound
+		}
+
+		if m.Opcode == 0x1 {
+			msg.Payload = []byte(m.Data)
+			msg.PayloadText = m.Data
+		} else if payload, err := base64.StdEncoding.DecodeString(m.Data); err == nil {
+			msg.Payload = payload
+		} else {
+			msg.Payload = []byte(m.Data)
+		}
+		msg.Size = len(msg.Payload)
+
+		ws.Messages = append(ws.Messages, msg)
+		ws.MessageCount.add(msg)
+	}
+
+	if n := len(ws.Messages); n > 0 {
+		ws.DisconnectedAt = ws.Messages[n-1].Timestamp
+		ws.ConnectionDuration = ws.DisconnectedAt.Sub(ws.ConnectedAt)
+	}
+
+	return ws
+}
+
+func harWebSocketMessage(msg WebSocketMessage) HARWebSocketMessage {
+	out := HARWebSocketMessage{
+		Type:   "receive",
+		Time:   float64(msg.Timestamp.UnixMicro()) / 1e6,
+		Opcode: msg.Opcode,
+	}
+	if msg.Direction == Outbound {
+		out.Type = "send"
+	}
+
+	if msg.Opcode == 0x1 {
+		out.Data = string(msg.Payload)
+	} else {
+		out.Data = base64.StdEncoding.EncodeToString(msg.Payload)
+	}
+	return out
+}
+
+func messageTypeForOpcode(opcode uint8) MessageType {
+	switch opcode {
+	case 0x1:
+		return TextMessage
+	case 0x2:
+		return BinaryMessage
+	case 0x8:
+		return CloseMessage
+	case 0x9:
+		return PingMessage
+	case 0xA:
+		return PongMessage
+	default:
+		return ContinuationMessage
+	}
+}
+
+// add updates the statistics with a captured message
+func (m *MessageStats) add(msg WebSocketMessage) {
+	m.TotalMessages++
+	m.TotalBytes += int64(msg.Size)
+
+	switch msg.Type {
+	case TextMessage:
+		m.TextMessages++
+	case BinaryMessage:
+		m.BinaryMessages++
+	}
+	if msg.Opcode >= 0x8 && msg.Opcode <= 0xA {
+		m.ControlFrames++
+	}
+
+	if msg.Direction == Outbound {
+		m.OutboundMessages++
+		m.OutboundBytes += int64(msg.Size)
+	} else {
+		m.InboundMessages++
+		m.InboundBytes += int64(msg.Size)
+	}
+}
+
+// harHeaders flattens a header map in its original order, one entry per value
+func harHeaders(headers *sortedMap.SortedMap) []HARNameValue {
+	result := []HARNameValue{}
+	if headers == nil {
+		return result
+	}
+
+	for _, key := range headers.Order {
+		value, ok := headers.Get(key)
+		if !ok {
+			continue
+		}
+		switch v := value.(type) {
+		case []string:
+			for _, item := range v {
+				result = append(result, HARNameValue{Name: key, Value: item})
+			}
+		default:
+			result = append(result, HARNameValue{Name: key, Value: fmt.Sprintf("%v", v)})
+		}
+	}
+	return result
+}
+
+// headersFromHAR rebuilds a header map, collecting repeated names into a []string
+func headersFromHAR(headers []HARNameValue) *sortedMap.SortedMap {
+	result := sortedMap.New()
+	for _, h := range headers {
+		existing, ok := result.Get(h.Name)
+		if !ok {
+			result.Put(h.Name, h.Value)
+			continue
+		}
+		switch v := existing.(type) {
+		case []string:
+			result.Put(h.Name, append(v, h.Value))
+		default:
+			result.Put(h.Name, []string{fmt.Sprintf("%v", v), h.Value})
+		}
+	}
+	return result
+}
+
+func harCookies(cookies map[string]string) []HARCookie {
+	result := make([]HARCookie, 0, len(cookies))
+	for name, value := range cookies {
+		result = append(result, HARCookie{Name: name, Value: value})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+func cookiesFromHAR(cookies []HARCookie) map[string]string {
+	result := make(map[string]string, len(cookies))
+	for _, c := range cookies {
+		result[c.Name] = c.Value
+	}
+	return result
+}
+
+func harQueryString(rawURL string) []HARNameValue {
+	result := []HARNameValue{}
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.RawQuery == "" {
+		return result
+	}
+
+	for _, pair := range strings.Split(parsed.RawQuery, "&") {
+		name, value, _ := strings.Cut(pair, "=")
+		if n, err := url.QueryUnescape(name); err == nil {
+			name = n
+		}
+		if v, err := url.QueryUnescape(value); err == nil {
+			value = v
+		}
+		result = append(result, HARNameValue{Name: name, Value: value})
+	}
+	return result
+}
+
+// harEncodeBody returns the body as text, or base64 when it is not valid UTF-8
+func harEncodeBody(body string) (string, string) {
+	if utf8.ValidString(body) {
+		return body, ""
+	}
+	return base64.StdEncoding.EncodeToString([]byte(body)), harBase64
+}
+
+func harDecodeBody(text, encoding string) (string, error) {
+	if encoding != harBase64 {
+		return text, nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(text)
+	if err != nil {
+		return "", err
+	}
+	return string(decoded), nil
+}
+
+func harHTTPVersion(protocol string) string {
+	switch protocol {
+	case HTTP2Protocol:
+		return "HTTP/2.0"
+	case "":
+		return HTTP11Protocol
+	default:
+		return protocol
+	}
+}
+
+func protocolFromHAR(version string) string {
+	switch strings.ToUpper(version) {
+	case "HTTP/2", "HTTP/2.0", "H2":
+		return HTTP2Protocol
+	case HTTP10Protocol:
+		return HTTP10Protocol
+	default:
+		return HTTP11Protocol
+	}
+}
+
+func statusText(resp *ResponseData) string {
+	code := strconv.Itoa(resp.StatusCode)
+	if text := strings.TrimSpace(strings.TrimPrefix(resp.Status, code)); text != "" {
+		return text
+	}
+	return http.StatusText(resp.StatusCode)
+}
+
+func firstValue(value interface{}) string {
+	if values, ok := value.([]string); ok {
+		if len(values) == 0 {
+			return ""
+		}
+		return values[0]
+	}
+	return fmt.Sprintf("%v", value)
+}
@@ -0,0 +1,116 @@
+package sessiondata
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHARRoundTrip(t *testing.T) {
+	reqHeaders := createOrderedTestSortedMap()
+	respHeaders := createOrderedTestSortedMap()
+	respHeaders.Put("Set-Cookie", []string{"a=1", "b=2"})
+
+	original := createTestSession("POST", "https://example.com/api?q=go%20lang&page=2", `{"name":"test"}`, nil, map[string]string{"session": "abc123"})
+	original.Request.Headers = reqHeaders
+	original.Request.ContentType = "application/json"
+	original.Protocol = HTTP2Protocol
+	original.Duration = 1500 * time.Millisecond
+	original.Response = &ResponseData{
+		StatusCode:  201,
+		Status:      "201 Created",
+		Headers:     respHeaders,
+		Body:        "\x89PNG\x00\xff",
+		ContentType: "image/png",
+	}
+	original.Error = errors.New("upstream reset")
+
+	var buf bytes.Buffer
+	if err := ExportHAR(&buf, []*Session{original}); err != nil {
+		t.Fatalf("ExportHAR() failed: %v", err)
+	}
+
+	har := ToHAR([]*Session{original})
+	entry := har.Log.Entries[0]
+	if entry.Response.Content.Encoding != "base64" {
+		t.Errorf("binary body should be base64 encoded, got encoding %q", entry.Response.Content.Encoding)
+	}
+	if entry.Request.HTTPVersion != "HTTP/2.0" {
+		t.Errorf("HTTPVersion = %q, want HTTP/2.0", entry.Request.HTTPVersion)
+	}
+	if len(entry.Request.QueryString) != 2 || entry.Request.QueryString[0].Value != "go lang" {
+		t.Errorf("query string not decoded: %+v", entry.Request.QueryString)
+	}
+	if entry.Time != 1500 {
+		t.Errorf("Time = %v, want 1500", entry.Time)
+	}
+
+	imported, err := ImportHAR(&buf)
+	if err != nil {
+		t.Fatalf("ImportHAR() failed: %v", err)
+	}
+	if len(imported) != 1 {
+		t.Fatalf("ImportHAR() returned %d sessions, want 1", len(imported))
+	}
+
+	got := imported[0]
+	if got.ID == original.ID || got.ID == "" {
+		t.Errorf("imported session should get a fresh ID")
+	}
+	if diff := original.RequestDifferences(got); diff.HasDiffs {
+		t.Errorf("imported request differs from the original: %+v", diff)
+	}
+	if got.Response.Body != original.Response.Body || got.Response.Status != "201 Created" {
+		t.Errorf("response not restored: %q %q", got.Response.Status, got.Response.Body)
+	}
+	if cookies, _ := got.Response.Headers.Get("Set-Cookie"); len(cookies.([]string)) != 2 {
+		t.Errorf("repeated headers should be merged back, got %v", cookies)
+	}
+	if got.Duration != original.Duration || got.Protocol != HTTP2Protocol {
+		t.Errorf("duration or protocol not restored: %v %q", got.Duration, got.Protocol)
+	}
+	if got.Error == nil || got.Error.Error() != "upstream reset" {
+		t.Errorf("error not restored: %v", got.Error)
+	}
+}
+
+func TestHARWebSocketMessages(t *testing.T) {
+	session := createTestSession("GET", "wss://example.com/socket", "", nil, nil)
+	session.Type = WebSocketSession
+	session.WebSocket = &WebSocketData{
+		Messages: []WebSocketMessage{
+			{Direction: Outbound, Opcode: 0x1, Type: TextMessage, Payload: []byte("hello"), Timestamp: time.Unix(100, 0)},
+			{Direction: Inbound, Opcode: 0x2, Type: BinaryMessage, Payload: []byte{0x00, 0xff}, Timestamp: time.Unix(101, 0)},
+		},
+	}
+
+	entry := ToHAR([]*Session{session}).Log.Entries[0]
+	if entry.ResourceType != "websocket" || len(entry.WebSocketMessages) != 2 {
+		t.Fatalf("WebSocket messages not exported: %+v", entry)
+	}
+	if entry.WebSocketMessages[0].Type != "send" || entry.WebSocketMessages[0].Data != "hello" {
+		t.Errorf("unexpected text message: %+v", entry.WebSocketMessages[0])
+	}
+	if entry.WebSocketMessages[1].Data != "AP8=" {
+		t.Errorf("binary message should be base64 encoded, got %q", entry.WebSocketMessages[1].Data)
+	}
+
+	imported, err := FromHAR(ToHAR([]*Session{session}))
+	if err != nil {
+		t.Fatalf("FromHAR() failed: %v", err)
+	}
+	ws := imported[0].WebSocket
+	if imported[0].Type != WebSocketSession || ws == nil || len(ws.Messages) != 2 {
+		t.Fatalf("WebSocket session not restored")
+	}
+	if ws.Messages[0].Direction != Outbound || ws.Messages[0].PayloadText != "hello" {
+		t.Errorf("text message not restored: %+v", ws.Messages[0])
+	}
+	if !bytes.Equal(ws.Messages[1].Payload, []byte{0x00, 0xff}) {
+		t.Errorf("binary message not restored: %v", ws.Messages[1].Payload)
+	}
+	if ws.MessageCount.TotalMessages != 2 || ws.MessageCount.OutboundMessages != 1 {
+		t.Errorf("message stats not rebuilt: %+v", ws.MessageCount)
+	}
+}
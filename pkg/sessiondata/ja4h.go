@@ -0,0 +1,76 @@
+package sessiondata
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"httpDebugger/pkg/sortedMap"
+)
+
+// NewJA4H fingerprints an HTTP request from its method, version, the order
+// of its headers and its cookies. headers must keep the order the client
+// sent them in; HTTP/2 pseudo headers are ignored
+func NewJA4H(r *http.Request, headers *sortedMap.SortedMap) string {
+	method := strings.ToLower(r.Method)
+	if len(method) > 2 {
+		method = method[:2]
+	}
+
+	var names []string
+	cookie, referer := "n", "n"
+	if headers != nil {
+		for _, name := range headers.Keys() {
+			switch {
+			case strings.HasPrefix(name, ":"):
+			case strings.EqualFold(name, "Cookie"):
+				cookie = "c"
+			case strings.EqualFold(name, "Referer"):
+				referer = "r"
+			default:
+				names = append(names, name)
+			}
+		}
+	}
+
+	a := fmt.Sprintf("%s%d%d%s%s%02d%s", method, r.ProtoMajor, r.ProtoMinor,
+		cookie, referer, min(len(names), 99), ja4hLanguage(r.Header.Get("Accept-Language")))
+
+	var cookieNames, cookiePairs []string
+	for _, c := range r.Cookies() {
+		cookieNames = append(cookieNames, c.Name)
+		cookiePairs = append(cookiePairs, c.Name+"="+c.Value)
+	}
+	slices.Sort(cookieNames)
+	slices.Sort(cookiePairs)
+
+	return strings.Join([]string{
+		a,
+		truncatedHash(strings.Join(names, ",")),
+		truncatedHash(strings.Join(cookieNames, ",")),
+		truncatedHash(strings.Join(cookiePairs, ",")),
+	}, "_")
+}
+
+// ja4hLanguage keeps the first four letters of the preferred language,
+// "en-US,en;q=0.9" becoming "enus"
+func ja4hLanguage(acceptLanguage string) string {
+	first, _, _ := strings.Cut(acceptLanguage, ",")
+	first = strings.ToLower(strings.NewReplacer("-", "", ";", "", " ", "").Replace(first))
+	if len(first) > 4 {
+		first = first[:4]
+	}
+	return first + strings.Repeat("0", 4-len(first))
+}
+
+// truncatedHash returns the first 12 hex characters of the SHA-256 of value,
+// or zeros when it is empty
+func truncatedHash(value string) string {
+	if value == "" {
+		return "000000000000"
+	}
+	sum := sha256.Sum256([]byte(value))
+	return fmt.Sprintf("%x", sum[:6])
+}
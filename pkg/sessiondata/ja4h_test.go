@@ -0,0 +1,45 @@
+package sessiondata
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"httpDebugger/pkg/sortedMap"
+)
+
+func TestNewJA4H(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	req.Header.Set("Cookie", "b=2; a=1")
+	req.Header.Set("Referer", "https://example.com/start")
+
+	headers := sortedMap.New()
+	headers.Put("Host", "example.com")
+	headers.Put("User-Agent", "test")
+	headers.Put("Cookie", "b=2; a=1")
+	headers.Put("Referer", "https://example.com/start")
+	headers.Put("Accept-Language", "en-US,en;q=0.9")
+
+	want := strings.Join([]string{
+		"ge11cr03enus",
+		truncatedHash("Host,User-Agent,Accept-Language"),
+		truncatedHash("a,b"),
+		truncatedHash("a=1,b=2"),
+	}, "_")
+	if got := NewJA4H(req, headers); got != want {
+		t.Errorf("NewJA4H() = %q, want %q", got, want)
+	}
+
+	h2, _ := http.NewRequest(http.MethodPost, "https://example.com/", nil)
+	h2.ProtoMajor, h2.ProtoMinor = 2, 0
+	pseudo := sortedMap.New()
+	pseudo.Put(":method", "POST")
+	pseudo.Put(":path", "/")
+	pseudo.Put("content-type", "application/json")
+
+	want = "po20nn010000_" + truncatedHash("content-type") + "_000000000000_000000000000"
+	if got := NewJA4H(h2, pseudo); got != want {
+		t.Errorf("NewJA4H() = %q, want %q without pseudo headers", got, want)
+	}
+}
@@ -0,0 +1,84 @@
+package sessiondata
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+type sessionAlias Session
+
+type sessionJSON struct {
+	*sessionAlias
+	Error string `json:"error,omitempty"`
+}
+
+// MarshalJSON encodes the session with its error flattened to a string
+func (s *Session) MarshalJSON() ([]byte, error) {
+	out := sessionJSON{sessionAlias: (*sessionAlias)(s)}
+	if s.Error != nil {
+		out.Error = s.Error.Error()
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes a session written by MarshalJSON
+func (s *Session) UnmarshalJSON(data []byte) error {
+	in := sessionJSON{sessionAlias: (*sessionAlias)(s)}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	s.Error = nil
+	if in.Error != "" {
+		s.Error = errors.New(in.Error)
+	}
+	return nil
+}
+
+// Summary returns a copy of the session without request and response bodies,
+// WebSocket messages or tunnel previews, cheap enough to keep in memory for
+// every stored session
+func (s *Session) Summary() *Session {
+	summary := *s
+
+	if s.Request != nil {
+		req := *s.Request
+		req.Body = ""
+		summary.Request = &req
+	}
+
+	if s.Response != nil {
+		resp := *s.Response
+		resp.Body = ""
+		summary.Response = &resp
+	}
+
+	if s.OriginalRequest != nil {
+		req := *s.OriginalRequest
+		req.Body = ""
+		summary.OriginalRequest = &req
+	}
+
+	if s.OriginalResponse != nil {
+		resp := *s.OriginalResponse
+		resp.Body = ""
+		summary.OriginalResponse = &resp
+	}
+
+	if s.WebSocket != nil {
+		ws := *s.WebSocket
+		ws.Messages = nil
+		ws.UpgradeRequest = summary.Request
+		ws.UpgradeResponse = summary.Response
+		summary.WebSocket = &ws
+	}
+
+	if s.Tunnel != nil {
+		tunnel := *s.Tunnel
+		tunnel.ClientPreview = nil
+		tunnel.ServerPreview = nil
+		summary.Tunnel = &tunnel
+	}
+
+	return &summary
+}
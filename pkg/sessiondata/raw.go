@@ -0,0 +1,201 @@
+package sessiondata
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"httpDebugger/pkg/sortedMap"
+)
+
+// FormatRawRequest renders a request as editable HTTP text: a request line with
+// the absolute URL, the headers in their original order, a blank line and the body
+func FormatRawRequest(req *RequestData) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s %s %s\n", req.Method, req.URL, HTTP11Protocol))
+	writeRawHeaders(&sb, req.Headers)
+	sb.WriteString("\n")
+	sb.WriteString(req.Body)
+	return sb.String()
+}
+
+// ParseRawRequest parses text written by FormatRawRequest, keeping the header order
+func ParseRawRequest(text string) (*RequestData, error) {
+	reader := bufio.NewReader(strings.NewReader(text))
+
+	line, err := readRawLine(reader)
+	if err != nil {
+		return nil, fmt.Errorf("missing request line")
+	}
+
+	parts := strings.Fields(line)
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid request line %q", line)
+	}
+
+	headers, err := readRawHeaders(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	body, _ := io.ReadAll(reader)
+
+	req := &RequestData{
+		Method:  strings.ToUpper(parts[0]),
+		URL:     parts[1],
+		Headers: headers,
+		Cookies: make(map[string]string),
+		Body:    string(body),
+	}
+
+	if ct, ok := headers.Get("Content-Type"); ok {
+		req.ContentType = fmt.Sprintf("%v", ct)
+	}
+	if cookie, ok := headers.Get("Cookie"); ok {
+		for _, c := range (&http.Request{Header: http.Header{"Cookie": {fmt.Sprintf("%v", cookie)}}}).Cookies() {
+			req.Cookies[c.Name] = c.Value
+		}
+	}
+
+	return req, nil
+}
+
+// FormatRawResponse renders a response as editable HTTP text
+func FormatRawResponse(resp *ResponseData) string {
+	var sb strings.Builder
+	status := resp.Status
+	if status == "" {
+		status = fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	sb.WriteString(fmt.Sprintf("%s %s\n", HTTP11Protocol, status))
+	writeRawHeaders(&sb, resp.Headers)
+	sb.WriteString("\n")
+	sb.WriteString(resp.Body)
+	return sb.String()
+}
+
+// ParseRawResponse parses text written by FormatRawResponse
+func ParseRawResponse(text string) (*ResponseData, error) {
+	reader := bufio.NewReader(strings.NewReader(text))
+
+	line, err := readRawLine(reader)
+	if err != nil {
+		return nil, fmt.Errorf("missing status line")
+	}
+
+	parts := strings.SplitN(line, " ", 3)
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid status line %q", line)
+	}
+
+	code, err := strconv.Atoi(parts[1])
+	if err != nil || code < 100 || code > 999 {
+		return nil, fmt.Errorf("invalid status code %q", parts[1])
+	}
+
+	status := parts[1]
+	if len(parts) == 3 {
+		status += " " + parts[2]
+	} else if text := http.StatusText(code); text != "" {
+		status += " " + text
+	}
+
+	headers, err := readRawHeaders(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	body, _ := io.ReadAll(reader)
+
+	resp := &ResponseData{
+		StatusCode: code,
+		Status:     status,
+		Headers:    headers,
+		Cookies:    make(map[string]string),
+		Body:       string(body),
+	}
+	if ct, ok := headers.Get("Content-Type"); ok {
+		resp.ContentType = fmt.Sprintf("%v", ct)
+	}
+	return resp, nil
+}
+
+// HTTPHeader converts an ordered header map to an http.Header
+func HTTPHeader(headers *sortedMap.SortedMap) http.Header {
+	result := make(http.Header)
+	if headers == nil {
+		return result
+	}
+
+	for _, key := range headers.Order {
+		value, ok := headers.Get(key)
+		if !ok {
+			continue
+		}
+		if values, ok := value.([]string); ok {
+			for _, v := range values {
+				result.Add(key, v)
+			}
+		} else {
+			result.Add(key, fmt.Sprintf("%v", value))
+		}
+	}
+	return result
+}
+
+func writeRawHeaders(sb *strings.Builder, headers *sortedMap.SortedMap) {
+	if headers == nil {
+		return
+	}
+	for _, key := range headers.Order {
+		value, ok := headers.Get(key)
+		if !ok {
+			continue
+		}
+		if values, ok := value.([]string); ok {
+			for _, v := range values {
+				sb.WriteString(fmt.Sprintf("%s: %s\n", key, v))
+			}
+		} else {
+			sb.WriteString(fmt.Sprintf("%s: %v\n", key, value))
+		}
+	}
+}
+
+func readRawHeaders(reader *bufio.Reader) (*sortedMap.SortedMap, error) {
+	headers := sortedMap.New()
+	for {
+		line, err := readRawLine(reader)
+		if err != nil || line == "" {
+			return headers, nil
+		}
+
+		name, value, found := strings.Cut(line, ":")
+		if !found || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid header line %q", line)
+		}
+		name = strings.TrimSpace(name)
+		value = strings.TrimSpace(value)
+
+		existing, _ := headers.Get(name)
+		switch v := existing.(type) {
+		case []string:
+			headers.Put(name, append(v, value))
+		case string:
+			headers.Put(name, []string{v, value})
+		default:
+			headers.Put(name, value)
+		}
+	}
+}
+
+func readRawLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,53 @@
+package sessiondata
+
+import "testing"
+
+func TestRawRequestRoundTrip(t *testing.T) {
+	req := &RequestData{
+		Method:  "POST",
+		URL:     "https://example.com/api?x=1",
+		Headers: createOrderedTestSortedMap(),
+		Body:    "line one\nline two\n",
+	}
+	req.Headers.Put("Cookie", "session=abc; theme=dark")
+	req.Headers.Put("Accept-Language", []string{"en", "it"})
+
+	parsed, err := ParseRawRequest(FormatRawRequest(req))
+	if err != nil {
+		t.Fatalf("ParseRawRequest() failed: %v", err)
+	}
+
+	if parsed.Method != req.Method || parsed.URL != req.URL || parsed.Body != req.Body {
+		t.Errorf("request line or body changed: %s %s %q", parsed.Method, parsed.URL, parsed.Body)
+	}
+	if len(parsed.Headers.Order) != len(req.Headers.Order) {
+		t.Fatalf("got %d headers, want %d", len(parsed.Headers.Order), len(req.Headers.Order))
+	}
+	for i, key := range req.Headers.Order {
+		if parsed.Headers.Order[i] != key {
+			t.Errorf("header %d is %s, want %s", i, parsed.Headers.Order[i], key)
+		}
+	}
+	if langs, _ := parsed.Headers.Get("Accept-Language"); len(langs.([]string)) != 2 {
+		t.Errorf("repeated header not kept: %v", langs)
+	}
+	if parsed.Cookies["theme"] != "dark" || parsed.ContentType != "application/json" {
+		t.Errorf("cookies or content type not derived: %v %q", parsed.Cookies, parsed.ContentType)
+	}
+}
+
+func TestParseRawResponse(t *testing.T) {
+	resp, err := ParseRawResponse("HTTP/1.1 404\nContent-Type: text/plain\n\nnot here")
+	if err != nil {
+		t.Fatalf("ParseRawResponse() failed: %v", err)
+	}
+	if resp.StatusCode != 404 || resp.Status != "404 Not Found" || resp.Body != "not here" {
+		t.Errorf("unexpected response %d %q %q", resp.StatusCode, resp.Status, resp.Body)
+	}
+
+	for _, text := range []string{"", "HTTP/1.1", "HTTP/1.1 abc", "HTTP/1.1 200 OK\nbroken header\n\n"} {
+		if _, err := ParseRawResponse(text); err == nil {
+			t.Errorf("ParseRawResponse(%q) should fail", text)
+		}
+	}
+}
@@ -1,15 +1,21 @@
 package sessiondata
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
 	"crypto/tls"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
 
+	"httpDebugger/pkg/bodyParser"
 	"httpDebugger/pkg/clientHello"
+	"httpDebugger/pkg/http2Fingerprint"
 	"httpDebugger/pkg/sortedMap"
 
 	"github.com/google/uuid"
@@ -17,16 +23,39 @@ import (
 )
 
 type Session struct {
-	ID             string
-	Timestamp      time.Time
-	TLSFingerprint *clientHello.TLSFingerprint
-	Request        *RequestData
-	Response       *ResponseData
-	Duration       time.Duration
-	Error          error
-	Protocol       string
-	Type           SessionType
-	WebSocket      *WebSocketData
+	ID               string                             `json:"id"`
+	Timestamp        time.Time                          `json:"timestamp"`
+	TLSFingerprint   *clientHello.TLSFingerprint        `json:"tls_fingerprint,omitempty"`
+	HTTP2Fingerprint *http2Fingerprint.HTTP2Fingerprint `json:"http2_fingerprint,omitempty"`
+	JA4H             string                             `json:"ja4h,omitempty"`
+	Request          *RequestData                       `json:"request"`
+	Response         *ResponseData                      `json:"response,omitempty"`
+	Duration         time.Duration                      `json:"duration"`
+	Error            error                              `json:"-"`
+	Protocol         string                             `json:"protocol"`
+	Type             SessionType                        `json:"type"`
+	WebSocket        *WebSocketData                     `json:"websocket,omitempty"`
+
+	// OriginalRequest and OriginalResponse keep what the client and server actually
+	// sent when rewrite rules changed Request or Response
+	OriginalRequest  *RequestData   `json:"original_request,omitempty"`
+	OriginalResponse *ResponseData  `json:"original_response,omitempty"`
+	Modifications    []Modification `json:"modifications,omitempty"`
+
+	// Mapping is set when a Map Local rule answered the request or a Map Remote
+	// rule sent it to another origin
+	Mapping *Mapping `json:"mapping,omitempty"`
+
+	Tunnel *TunnelData `json:"tunnel,omitempty"`
+
+	// UpstreamTLS describes the proxy's own TLS connection to the server
+	UpstreamTLS *UpstreamTLS `json:"upstream_tls,omitempty"`
+
+	// Client is the known client whose fingerprints match best, nil if none
+	Client *ClientMatch `json:"client,omitempty"`
+
+	// ParentID is the session a composed or replayed request was made from
+	ParentID string `json:"parent_id,omitempty"`
 }
 
 func NewSessionData(r *http.Request, bodyBytes []byte, headers *sortedMap.SortedMap, tlsFingerprint *clientHello.TLSFingerprint, protocol string) *Session {
@@ -59,6 +88,7 @@ func NewSessionData(r *http.Request, bodyBytes []byte, headers *sortedMap.Sorted
 			Request:        requestData,
 			Type:           WebSocketSession,
 			TLSFingerprint: tlsFingerprint,
+			JA4H:           NewJA4H(r, headers),
 			Protocol:       protocol,
 			WebSocket: &WebSocketData{
 				State:          WSConnecting,
@@ -79,6 +109,7 @@ func NewSessionData(r *http.Request, bodyBytes []byte, headers *sortedMap.Sorted
 			Request:        requestData,
 			Type:           HTTPSession,
 			TLSFingerprint: tlsFingerprint,
+			JA4H:           NewJA4H(r, headers),
 			Protocol:       protocol,
 			WebSocket:      nil,
 			Response:       nil,
@@ -127,45 +158,144 @@ func (s *Session) RequestDifferences(other *Session) *RequestDifference {
 	return diff
 }
 
-func (s *Session) ToCurl() string {
-	if s.Type == WebSocketSession {
-		return "WebSocket sessions cannot be converted to cURL commands."
+// ResponseDifferences compares the response of s with the response of other,
+// such as a replay of s. A missing response compares as empty
+func (s *Session) ResponseDifferences(other *Session) *ResponseDifference {
+	original, changed := s.responseOrEmpty(), other.responseOrEmpty()
+	diff := &ResponseDifference{}
+
+	diff.Status = compareStringField(original.Status, changed.Status)
+	if original.Status == "" && changed.Status == "" {
+		diff.Status = compareStringField(fmt.Sprint(original.StatusCode), fmt.Sprint(changed.StatusCode))
 	}
-	curlCommand := "curl -X " + s.Request.Method + " '" + s.Request.URL + "'"
 
-	for _, key := range s.Request.Headers.Order {
-		if key == "Host" || key == "Content-Length" {
-			continue
+	diff.Body = compareStringField(original.Body, changed.Body)
+
+	diff.ContentType = compareStringField(original.ContentType, changed.ContentType)
+
+	diff.Headers = compareHeaders(original.Headers, changed.Headers)
+
+	diff.Cookies = compareCookies(original.Cookies, changed.Cookies)
+
+	diff.HasDiffs = diff.Status.Changed || diff.Body.Changed || diff.ContentType.Changed ||
+		diff.Headers.Changed || diff.Cookies.Changed
+
+	return diff
+}
+
+func (s *Session) responseOrEmpty() *ResponseData {
+	resp := &ResponseData{Headers: sortedMap.New()}
+	if s.Response != nil {
+		*resp = *s.Response
+		if resp.Headers == nil {
+			resp.Headers = sortedMap.New()
 		}
-		val, _ := s.Request.Headers.Get(key)
-		valStr := fmt.Sprintf("%v", val)
-		curlCommand += fmt.Sprintf(" -H '%s: %s'", key, valStr)
 	}
+	return resp
+}
 
-	if s.Request.Body != "" {
-		curlCommand += " -d '" + s.Request.Body + "'"
+// NewComposedSession creates the session of a request written or edited by
+// the user rather than captured from a client
+func NewComposedSession(req *RequestData, parentID string) *Session {
+	if req.Headers == nil {
+		req.Headers = sortedMap.New()
 	}
+	if req.Cookies == nil {
+		req.Cookies = make(map[string]string)
+	}
+	return &Session{
+		ID:        uuid.New().String(),
+		Timestamp: time.Now(),
+		Request:   req,
+		Type:      HTTPSession,
+		Protocol:  HTTP11Protocol,
+		ParentID:  parentID,
+	}
+}
 
-	return curlCommand
+// NewTunnelSession creates the session of a TCP stream to target (host:port)
+// relayed without interpretation
+func NewTunnelSession(target string) *Session {
+	now := time.Now()
+	return &Session{
+		ID:        uuid.New().String(),
+		Timestamp: now,
+		Request: &RequestData{
+			Method:  "TCP",
+			URL:     "tcp://" + target,
+			Headers: sortedMap.New(),
+			Cookies: make(map[string]string),
+		},
+		Type:     TunnelSession,
+		Protocol: TCPProtocol,
+		Tunnel:   &TunnelData{Target: target, OpenedAt: now},
+	}
 }
 
-func (s *Session) Replay(port int) error {
+// NewPassthroughSession creates the session of a TLS connection to target
+// relayed without interception, for the given reason. The fingerprint still
+// comes from the client's ClientHello
+func NewPassthroughSession(target string, fingerprint *clientHello.TLSFingerprint, reason string) *Session {
+	session := NewTunnelSession(target)
+	session.Request.Method = TLSProtocol
+	session.Request.URL = "tls://" + target
+	session.Protocol = TLSProtocol
+	session.TLSFingerprint = fingerprint
+	session.Tunnel.Passthrough = reason
+	return session
+}
+
+// ReplayParentHeader and ReplaySessionHeader carry the original session and
+// the ID to store a replayed request under, and ReplayTokenHeader proves they
+// were set by Replay in this process. The proxy removes them before
+// forwarding the request
+const (
+	ReplayParentHeader  = "X-Httpdebugger-Replay-Of"
+	ReplaySessionHeader = "X-Httpdebugger-Session"
+	ReplayTokenHeader   = "X-Httpdebugger-Replay-Token"
+)
+
+// replayToken is the per-process secret Replay sends, so clients of the proxy
+// cannot link their requests to sessions or overwrite them
+var replayToken = newReplayToken()
+
+func newReplayToken() string {
+	token := make([]byte, 16)
+	rand.Read(token)
+	return hex.EncodeToString(token)
+}
+
+// maxReplayBodySize caps the response body a replay keeps
+const maxReplayBodySize = 10 * 1024 * 1024
+
+// Replay sends the request again through the proxy listening on proxyAddr and
+// returns the new session, linked to s by its ParentID. The proxy stores the
+// same exchange under the returned session's ID
+func (s *Session) Replay(proxyAddr string) (*Session, error) {
 	if s.Type == WebSocketSession {
-		return fmt.Errorf("WebSocket sessions cannot be replayed")
+		return nil, fmt.Errorf("WebSocket sessions cannot be replayed")
+	}
+	if s.Type == TunnelSession {
+		return nil, fmt.Errorf("TCP tunnel sessions cannot be replayed")
 	}
 
 	parsedURL, err := url.Parse(s.Request.URL)
 	if err != nil {
-		return fmt.Errorf("failed to parse URL %s: %w", s.Request.URL, err)
+		return nil, fmt.Errorf("failed to parse URL %s: %w", s.Request.URL, err)
 	}
 
+	host, port, err := net.SplitHostPort(proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy address %s: %w", proxyAddr, err)
+	}
 	proxyURL := &url.URL{
 		Scheme: "http",
-		Host:   fmt.Sprintf("127.0.0.1:%d", port),
+		Host:   net.JoinHostPort(dialHost(host), port),
 	}
 
 	transport := &http.Transport{
-		Proxy: http.ProxyURL(proxyURL),
+		Proxy:              http.ProxyURL(proxyURL),
+		DisableCompression: true,
 	}
 
 	if parsedURL.Scheme == "https" {
@@ -186,6 +316,9 @@ func (s *Session) Replay(port int) error {
 	client := &http.Client{
 		Timeout:   30 * time.Second,
 		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
 	}
 
 	var bodyReader io.Reader
@@ -195,33 +328,118 @@ func (s *Session) Replay(port int) error {
 
 	req, err := http.NewRequest(s.Request.Method, s.Request.URL, bodyReader)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	for _, key := range s.Request.Headers.Order {
-		val, _ := s.Request.Headers.Get(key)
-		if val != nil {
-			req.Header.Set(key, fmt.Sprintf("%v", val))
+	// The URL names the target; a Host header is left for the proxy to ignore
+	req.Header = HTTPHeader(s.Request.Headers)
+	if req.Header.Get("Cookie") == "" {
+		for name, value := range s.Request.Cookies {
+			req.AddCookie(&http.Cookie{Name: name, Value: value})
 		}
 	}
 
-	if s.Request.Cookies != nil {
-		for name, value := range s.Request.Cookies {
-			req.AddCookie(&http.Cookie{
-				Name:  name,
-				Value: fmt.Sprintf("%v", value),
-			})
-		}
+	request := *s.Request
+	if request.Headers != nil {
+		request.Headers = request.Headers.Clone()
+	}
+	replay := &Session{
+		ID:             uuid.New().String(),
+		Timestamp:      time.Now(),
+		TLSFingerprint: s.TLSFingerprint,
+		Request:        &request,
+		Type:           HTTPSession,
+		Protocol:       s.Protocol,
+		ParentID:       s.ID,
 	}
+	req.Header.Set(ReplayParentHeader, replay.ParentID)
+	req.Header.Set(ReplaySessionHeader, replay.ID)
+	req.Header.Set(ReplayTokenHeader, replayToken)
 
 	resp, err := client.Do(req)
+	replay.Duration = time.Since(replay.Timestamp)
 	if err != nil {
-		return fmt.Errorf("error replaying request: %w", err)
+		replay.Error = fmt.Errorf("error replaying request: %w", err)
+		return replay, replay.Error
 	}
-	io.Copy(io.Discard, resp.Body)
 	defer resp.Body.Close()
 
-	return nil
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxReplayBodySize))
+	replay.Duration = time.Since(replay.Timestamp)
+	if err != nil {
+		replay.Error = fmt.Errorf("error reading replayed response: %w", err)
+		return replay, replay.Error
+	}
+
+	if resp.ProtoMajor == 2 {
+		replay.Protocol = HTTP2Protocol
+	} else {
+		replay.Protocol = HTTP11Protocol
+	}
+	replay.Response = newResponseData(resp, body)
+	return replay, nil
+}
+
+// newResponseData records resp with its body decoded
+func newResponseData(resp *http.Response, body []byte) *ResponseData {
+	headers := sortedMap.New()
+	for name, values := range resp.Header {
+		headers.Put(name, values)
+	}
+
+	cookies := make(map[string]string)
+	for _, cookie := range resp.Cookies() {
+		cookies[cookie.Name] = cookie.Value
+	}
+
+	opts := bodyParser.NewBodyParserOptions()
+	opts.PopulateFromHeaders(resp.Header)
+	parsed, err := bodyParser.Parse(string(body), opts)
+	if err != nil {
+		parsed = string(body)
+	}
+
+	return &ResponseData{
+		StatusCode:  resp.StatusCode,
+		Status:      resp.Status,
+		Headers:     headers,
+		Cookies:     cookies,
+		Body:        parsed,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+}
+
+// TakeReplayHeaders removes the headers added by Replay from header and the
+// recorded request, and links the session to the one it replays. Headers
+// without this process's replay token are removed but otherwise ignored
+func (s *Session) TakeReplayHeaders(header http.Header) {
+	parentID := header.Get(ReplayParentHeader)
+	sessionID := header.Get(ReplaySessionHeader)
+	token := header.Get(ReplayTokenHeader)
+	if parentID == "" && sessionID == "" && token == "" {
+		return
+	}
+
+	header.Del(ReplayParentHeader)
+	header.Del(ReplaySessionHeader)
+	header.Del(ReplayTokenHeader)
+	if s.Request != nil && s.Request.Headers != nil {
+		for _, name := range s.Request.Headers.Keys() {
+			if strings.EqualFold(name, ReplayParentHeader) || strings.EqualFold(name, ReplaySessionHeader) ||
+				strings.EqualFold(name, ReplayTokenHeader) {
+				s.Request.Headers.Delete(name)
+			}
+		}
+	}
+
+	if subtle.ConstantTimeCompare([]byte(token), []byte(replayToken)) != 1 {
+		return
+	}
+	s.ParentID = parentID
+	// The ID is only taken when it cannot clash with a session recorded by the proxy
+	if _, err := uuid.Parse(sessionID); err == nil {
+		s.ID = sessionID
+	}
 }
 
 func hasHTTP2(fp *clientHello.TLSFingerprint) bool {
@@ -235,3 +453,16 @@ func hasHTTP2(fp *clientHello.TLSFingerprint) bool {
 	}
 	return false
 }
+
+// dialHost returns the loopback address reaching a proxy listening on host
+// when it listens on every interface
+func dialHost(host string) string {
+	ip := net.ParseIP(host)
+	switch {
+	case host == "", ip != nil && ip.Equal(net.IPv4zero):
+		return "127.0.0.1"
+	case ip != nil && ip.Equal(net.IPv6unspecified):
+		return "::1"
+	}
+	return host
+}
@@ -4,11 +4,12 @@ import (
 	"bytes"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
-	"strings"
 	"testing"
 	"time"
 
+	"httpDebugger/pkg/clientHello"
 	"httpDebugger/pkg/sortedMap"
 )
 
@@ -158,221 +159,118 @@ func TestRequestDifferencesIdentical(t *testing.T) {
 	}
 }
 
-func TestToCurl(t *testing.T) {
-	tests := []struct {
-		name     string
-		method   string
-		url      string
-		body     string
-		headers  map[string]interface{}
-		cookies  map[string]string
-		expected string
-	}{
-		{
-			name:   "Simple GET request",
-			method: "GET",
-			url:    "https://api.example.com/users",
-			body:   "",
-			headers: map[string]interface{}{
-				"Accept":     "application/json",
-				"User-Agent": "TestAgent/1.0",
-			},
-			cookies:  map[string]string{},
-			expected: "curl -X GET 'https://api.example.com/users' -H 'Accept: application/json' -H 'User-Agent: TestAgent/1.0'",
-		},
-		{
-			name:   "POST request with body",
-			method: "POST",
-			url:    "https://api.example.com/users",
-			body:   `{"name":"John","email":"john@example.com"}`,
-			headers: map[string]interface{}{
-				"Content-Type":  "application/json",
-				"Authorization": "Bearer token123",
-			},
-			cookies:  map[string]string{},
-			expected: "curl -X POST 'https://api.example.com/users' -H 'Content-Type: application/json' -H 'Authorization: Bearer token123' -d '{\"name\":\"John\",\"email\":\"john@example.com\"}'",
-		},
-		{
-			name:   "Request with Host header (should be skipped)",
-			method: "GET",
-			url:    "https://api.example.com/data",
-			body:   "",
-			headers: map[string]interface{}{
-				"Host":           "api.example.com",
-				"Accept":         "application/json",
-				"Content-Length": "123",
-			},
-			cookies:  map[string]string{},
-			expected: "curl -X GET 'https://api.example.com/data' -H 'Accept: application/json'",
-		},
-		{
-			name:     "Request with empty headers",
-			method:   "GET",
-			url:      "https://api.example.com/simple",
-			body:     "",
-			headers:  map[string]interface{}{},
-			cookies:  map[string]string{},
-			expected: "curl -X GET 'https://api.example.com/simple'",
-		},
+func TestResponseDifferences(t *testing.T) {
+	original := createTestSession("GET", "https://example.com/api", "", nil, nil)
+	original.Response = &ResponseData{
+		StatusCode: 500,
+		Status:     "500 Internal Server Error",
+		Headers:    createTestSortedMap(map[string]interface{}{"Content-Type": "text/plain", "X-Trace": "1"}),
+		Body:       "boom",
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			session := createTestSession(tt.method, tt.url, tt.body, tt.headers, tt.cookies)
-			result := session.ToCurl()
-
-			if result != tt.expected {
-				t.Errorf("ToCurl() = %v, want %v", result, tt.expected)
-			}
-		})
+	replay := createTestSession("GET", "https://example.com/api", "", nil, nil)
+	replay.Response = &ResponseData{
+		StatusCode: 200,
+		Status:     "200 OK",
+		Headers:    createTestSortedMap(map[string]interface{}{"Content-Type": "text/plain"}),
+		Cookies:    map[string]string{"session": "new"},
+		Body:       "ok",
 	}
-}
 
-func TestToCurlWithInterfaceHeaders(t *testing.T) {
-	session := createTestSession("POST", "https://api.example.com/data", "test data",
-		map[string]interface{}{
-			"Content-Type":   "text/plain",
-			"Content-Length": 123,
-			"Rate-Limit":     []string{"100", "per-hour"},
-			"Custom-Bool":    true,
-		},
-		map[string]string{})
-
-	result := session.ToCurl()
-
-	expectedStart := "curl -X POST 'https://api.example.com/data'"
-	if !strings.HasPrefix(result, expectedStart) {
-		t.Errorf("Expected curl command to start with %q, got %q", expectedStart, result)
+	diff := original.ResponseDifferences(replay)
+	if !diff.HasDiffs || !diff.Status.Changed || diff.Status.Other != "200 OK" {
+		t.Errorf("status difference not detected: %+v", diff.Status)
 	}
-
-	expectedEnd := " -d 'test data'"
-	if !strings.HasSuffix(result, expectedEnd) {
-		t.Errorf("Expected curl command to end with %q, got %q", expectedEnd, result)
+	if diff.Headers.Removed["X-Trace"] != "1" || diff.ContentType.Changed {
+		t.Errorf("header differences not detected correctly: %+v", diff.Headers)
 	}
-
-	expectedHeaders := []string{
-		"-H 'Content-Type: text/plain'",
-		"-H 'Rate-Limit: [100 per-hour]'",
-		"-H 'Custom-Bool: true'",
+	if diff.Cookies.Added["session"] != "new" || !diff.Body.Changed {
+		t.Errorf("cookie or body differences not detected: %+v %+v", diff.Cookies, diff.Body)
 	}
 
-	for _, header := range expectedHeaders {
-		if !strings.Contains(result, header) {
-			t.Errorf("Expected curl command to contain %q, got %q", header, result)
-		}
+	if diff := original.ResponseDifferences(original); diff.HasDiffs {
+		t.Errorf("a response should not differ from itself: %+v", diff)
 	}
-
-	if strings.Contains(result, "Content-Length") {
-		t.Error("Content-Length header should be excluded from curl command")
+	replay.Response = nil
+	if diff := original.ResponseDifferences(replay); !diff.Status.Changed || diff.Body.Other != "" {
+		t.Errorf("a missing response should compare as empty: %+v", diff)
 	}
 }
 
-func TestToCurlEscaping(t *testing.T) {
-	tests := []struct {
-		name     string
-		body     string
-		headers  map[string]interface{}
-		expected string
-	}{
-		{
-			name: "Body with single quotes",
-			body: `{"message":"It's a test"}`,
-			headers: map[string]interface{}{
-				"Content-Type": "application/json",
-			},
-			expected: "curl -X POST 'https://api.example.com/test' -H 'Content-Type: application/json' -d '{\"message\":\"It's a test\"}'",
-		},
-		{
-			name: "Header with single quotes",
-			body: "",
-			headers: map[string]interface{}{
-				"Custom-Header": "Value with 'quotes'",
-			},
-			expected: "curl -X GET 'https://api.example.com/test' -H 'Custom-Header: Value with 'quotes''",
-		},
-		{
-			name: "Body with newlines",
-			body: "{\n  \"name\": \"test\"\n}",
-			headers: map[string]interface{}{
-				"Content-Type": "application/json",
-			},
-			expected: "curl -X POST 'https://api.example.com/test' -H 'Content-Type: application/json' -d '{\n  \"name\": \"test\"\n}'",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			method := "POST"
-			if tt.body == "" {
-				method = "GET"
-			}
+func TestReplay(t *testing.T) {
+	var received http.Header
+	// The proxy sees the absolute URL of plain HTTP requests and answers them itself
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, "%s %s %s", r.Method, r.URL, r.Host)
+	}))
+	defer proxy.Close()
 
-			session := createTestSession(method, "https://api.example.com/test", tt.body, tt.headers, map[string]string{})
-			result := session.ToCurl()
+	original := createTestSession("POST", "http://example.com/items", "data",
+		map[string]interface{}{"Host": "api.example.com", "X-Multi": []string{"a", "b"}},
+		map[string]string{"session": "abc"})
 
-			if result != tt.expected {
-				t.Errorf("ToCurl() = %v, want %v", result, tt.expected)
-			}
-		})
+	replay, err := original.Replay(proxy.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Replay() failed: %v", err)
 	}
-}
-
-func TestToCurlExcludedHeaders(t *testing.T) {
-	session := createTestSession("POST", "https://api.example.com/test", "test body",
-		map[string]interface{}{
-			"Host":           "api.example.com",
-			"Content-Length": "9",
-			"Content-Type":   "text/plain",
-			"Authorization":  "Bearer token123",
-		},
-		map[string]string{})
-
-	result := session.ToCurl()
-
-	if strings.Contains(result, "Host:") {
-		t.Error("Host header should be excluded from curl command")
+	if replay.ParentID != original.ID || replay.ID == original.ID {
+		t.Errorf("replay is not linked to the original: id %s, parent %s", replay.ID, replay.ParentID)
 	}
-	if strings.Contains(result, "Content-Length:") {
-		t.Error("Content-Length header should be excluded from curl command")
+	if replay.Response == nil || replay.Response.StatusCode != http.StatusCreated ||
+		replay.Response.Body != "POST http://example.com/items example.com" {
+		t.Fatalf("unexpected replay response: %+v", replay.Response)
 	}
-	if !strings.Contains(result, "Content-Type: text/plain") {
-		t.Error("Content-Type header should be included in curl command")
+	if got := received.Values("X-Multi"); len(got) != 2 {
+		t.Errorf("X-Multi = %v, want both values", got)
 	}
-	if !strings.Contains(result, "Authorization: Bearer token123") {
-		t.Error("Authorization header should be included in curl command")
+	if received.Get("Cookie") != "session=abc" {
+		t.Errorf("Cookie = %q", received.Get("Cookie"))
 	}
-}
-
-func TestToCurlHeaderOrder(t *testing.T) {
-	session := &Session{
-		ID:        "test-id",
-		Timestamp: time.Now(),
-		Request: &RequestData{
-			Method:      "POST",
-			URL:         "https://api.example.com/ordered",
-			Body:        "test body",
-			ContentType: "application/json",
-			Headers:     createOrderedTestSortedMap(),
-			Cookies:     map[string]string{},
-		},
+	if received.Get(ReplayParentHeader) != original.ID || received.Get(ReplaySessionHeader) != replay.ID {
+		t.Errorf("replay headers not sent: %v", received)
 	}
 
-	result := session.ToCurl()
+	recorded := createTestSession("POST", "http://example.com/items", "data",
+		map[string]interface{}{ReplayParentHeader: original.ID, ReplaySessionHeader: replay.ID}, nil)
+	recorded.TakeReplayHeaders(received)
+	if recorded.ID != replay.ID || recorded.ParentID != original.ID {
+		t.Errorf("TakeReplayHeaders() did not link the session: id %s, parent %s", recorded.ID, recorded.ParentID)
+	}
+	if received.Get(ReplayParentHeader) != "" || received.Get(ReplayTokenHeader) != "" || recorded.Request.Headers.Len() != 0 {
+		t.Errorf("TakeReplayHeaders() left the headers in place")
+	}
 
-	expectedHeaders := []string{"Authorization", "Content-Type", "Accept"}
-	lastIndex := -1
-	for _, header := range expectedHeaders {
-		headerPattern := fmt.Sprintf("-H '%s:", header)
-		headerIndex := strings.Index(result, headerPattern)
+	// A client of the proxy cannot claim to be a replay without the token
+	forged := http.Header{}
+	forged.Set(ReplayParentHeader, original.ID)
+	forged.Set(ReplaySessionHeader, original.ID)
+	forged.Set(ReplayTokenHeader, "guess")
+	spoofed := createTestSession("GET", "http://example.com/", "", nil, nil)
+	id := spoofed.ID
+	spoofed.TakeReplayHeaders(forged)
+	if spoofed.ID != id || spoofed.ParentID != "" {
+		t.Errorf("forged replay headers linked the session: id %s, parent %s", spoofed.ID, spoofed.ParentID)
+	}
+	if len(forged) != 0 {
+		t.Errorf("forged replay headers were not removed: %v", forged)
+	}
+}
 
-		if headerIndex == -1 {
-			t.Errorf("Header %s not found in curl command", header)
-			continue
-		}
-		if headerIndex <= lastIndex {
-			t.Errorf("Headers are not in expected order. %s should appear after previous header", header)
+func TestDialHost(t *testing.T) {
+	tests := map[string]string{
+		"":          "127.0.0.1",
+		"0.0.0.0":   "127.0.0.1",
+		"::":        "::1",
+		"127.0.0.2": "127.0.0.2",
+		"localhost": "localhost",
+	}
+	for host, want := range tests {
+		if got := dialHost(host); got != want {
+			t.Errorf("dialHost(%q) = %q, want %q", host, got, want)
 		}
-		lastIndex = headerIndex
 	}
 }
 
@@ -485,19 +383,34 @@ func BenchmarkRequestDifferences(b *testing.B) {
 	}
 }
 
-func BenchmarkToCurl(b *testing.B) {
-	session := createTestSession("POST", "https://api.example.com/benchmark",
-		`{"data":"benchmark test"}`,
-		map[string]interface{}{
-			"Content-Type":  "application/json",
-			"Authorization": "Bearer token123",
-			"Accept":        "application/json",
-			"User-Agent":    "BenchmarkAgent/1.0",
-		},
-		map[string]string{"session": "abc123"})
+func TestTunnelSession(t *testing.T) {
+	session := NewTunnelSession("db.internal:5432")
+	if session.Type != TunnelSession || session.Request.URL != "tcp://db.internal:5432" || !session.Tunnel.Open() {
+		t.Fatalf("unexpected tunnel session: %+v", session)
+	}
 
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		session.ToCurl()
+	session.Tunnel.ClientPreview = []byte("hello")
+	session.Tunnel.ClosedAt = time.Now()
+
+	summary := session.Summary()
+	if summary.Tunnel.ClientPreview != nil {
+		t.Errorf("Summary() should drop tunnel previews")
+	}
+	if session.Tunnel.ClientPreview == nil {
+		t.Errorf("Summary() must not modify the session")
+	}
+
+	if _, err := session.Replay(":8080"); err == nil {
+		t.Errorf("Replay() of a tunnel session should fail")
+	}
+	if entries := ToHAR([]*Session{session}).Log.Entries; len(entries) != 0 {
+		t.Errorf("tunnel sessions should not be exported to HAR")
+	}
+
+	fingerprint := &clientHello.TLSFingerprint{ServerName: "pinned.example.com"}
+	passthrough := NewPassthroughSession("pinned.example.com:443", fingerprint, "rule")
+	if passthrough.Type != TunnelSession || passthrough.Request.URL != "tls://pinned.example.com:443" ||
+		passthrough.TLSFingerprint != fingerprint || passthrough.Tunnel.Passthrough != "rule" {
+		t.Errorf("unexpected passthrough session: %+v", passthrough)
 	}
 }
@@ -3,6 +3,7 @@ package sessiondata
 import (
 	"time"
 
+	"httpDebugger/pkg/clientHello"
 	"httpDebugger/pkg/sortedMap"
 )
 
@@ -16,6 +17,15 @@ type RequestDifference struct {
 	HasDiffs    bool
 }
 
+type ResponseDifference struct {
+	Status      *FieldDiff
+	Headers     *HeadersDiff
+	Cookies     *CookiesDiff
+	Body        *FieldDiff
+	ContentType *FieldDiff
+	HasDiffs    bool
+}
+
 type FieldDiff struct {
 	Original string
 	Other    string
@@ -38,57 +48,183 @@ type CookiesDiff struct {
 
 type SessionType int
 
+// Mapping records how a Map Local or Map Remote rule handled a session
+type Mapping struct {
+	Kind   string `json:"kind"`
+	Rule   string `json:"rule"`
+	Target string `json:"target"`
+}
+
+// ClientMatch names the client that most likely produced a session, from its
+// TLS, HTTP/2 and header order fingerprints
+type ClientMatch struct {
+	Name string `json:"name"`
+	// Confidence goes from 0 to 1
+	Confidence float64  `json:"confidence"`
+	MatchedOn  []string `json:"matched_on,omitempty"`
+}
+
+// Modification records a change a rewrite rule made to a session
+type Modification struct {
+	Rule   string `json:"rule"`
+	Stage  string `json:"stage"`
+	Action string `json:"action"`
+	Detail string `json:"detail"`
+}
+
 type RequestData struct {
-	Method      string
-	URL         string
-	Headers     *sortedMap.SortedMap
-	Cookies     map[string]string
-	Body        string
-	ContentType string
-	IsUpgrade   bool
+	Method      string               `json:"method"`
+	URL         string               `json:"url"`
+	Headers     *sortedMap.SortedMap `json:"headers"`
+	Cookies     map[string]string    `json:"cookies"`
+	Body        string               `json:"body"`
+	ContentType string               `json:"content_type"`
+	IsUpgrade   bool                 `json:"is_upgrade,omitempty"`
 }
 
 type ResponseData struct {
-	StatusCode  int
-	Status      string
-	Headers     *sortedMap.SortedMap
-	Cookies     map[string]string
-	Body        string
-	ContentType string
-	IsUpgrade   bool
+	StatusCode  int                  `json:"status_code"`
+	Status      string               `json:"status"`
+	Headers     *sortedMap.SortedMap `json:"headers"`
+	Cookies     map[string]string    `json:"cookies"`
+	Body        string               `json:"body"`
+	ContentType string               `json:"content_type"`
+	IsUpgrade   bool                 `json:"is_upgrade,omitempty"`
 }
 
 type WebSocketData struct {
-	UpgradeRequest  *RequestData
-	UpgradeResponse *ResponseData
+	UpgradeRequest  *RequestData  `json:"upgrade_request"`
+	UpgradeResponse *ResponseData `json:"upgrade_response"`
 
-	State              WebSocketState
-	ConnectedAt        time.Time
-	DisconnectedAt     time.Time
-	ConnectionDuration time.Duration
+	State              WebSocketState `json:"state"`
+	ConnectedAt        time.Time      `json:"connected_at"`
+	DisconnectedAt     time.Time      `json:"disconnected_at"`
+	ConnectionDuration time.Duration  `json:"connection_duration"`
 
-	Messages     []WebSocketMessage
-	MessageCount MessageStats
+	Messages     []WebSocketMessage `json:"messages"`
+	MessageCount MessageStats       `json:"message_count"`
 
-	Subprotocol string
-	Extensions  []string
-	CloseCode   int
-	CloseReason string
+	Subprotocol string   `json:"subprotocol"`
+	Extensions  []string `json:"extensions"`
+	CloseCode   int      `json:"close_code"`
+	CloseReason string   `json:"close_reason"`
 }
 
 type WebSocketState int
 
+// TunnelData describes a TCP stream relayed without interpretation
+type TunnelData struct {
+	// Target is the host:port the client asked to connect to
+	Target string `json:"target"`
+	// Passthrough tells why a TLS connection was relayed instead of
+	// intercepted; empty for streams that are not TLS
+	Passthrough string `json:"passthrough,omitempty"`
+
+	OpenedAt time.Time `json:"opened_at"`
+	ClosedAt time.Time `json:"closed_at,omitempty"`
+
+	BytesSent     int64 `json:"bytes_sent"`
+	BytesReceived int64 `json:"bytes_received"`
+
+	// ClientPreview and ServerPreview hold the first TunnelPreviewSize bytes
+	// sent by each side
+	ClientPreview []byte `json:"client_preview,omitempty"`
+	ServerPreview []byte `json:"server_preview,omitempty"`
+}
+
+// Open reports whether the tunnel is still relaying data
+func (t *TunnelData) Open() bool {
+	return t.ClosedAt.IsZero()
+}
+
+// UpstreamTLS describes the TLS connection the proxy opened to the server
+type UpstreamTLS struct {
+	Version     uint16 `json:"version"`
+	CipherSuite uint16 `json:"cipher_suite"`
+	// ALPN is the negotiated application protocol, empty when there was none
+	ALPN       string `json:"alpn,omitempty"`
+	ServerName string `json:"server_name,omitempty"`
+	Resumed    bool   `json:"resumed,omitempty"`
+
+	// ServerHello fingerprints the server's answer with JA3S and JA4S, nil
+	// when it was not captured
+	ServerHello *clientHello.ServerHelloFingerprint `json:"server_hello,omitempty"`
+
+	// OCSP is the stapled OCSP response, nil when the server sent none
+	OCSP *OCSPInfo `json:"ocsp,omitempty"`
+
+	// Certificates is the chain the server sent, leaf first
+	Certificates []CertificateInfo `json:"certificates,omitempty"`
+	// VerificationError tells why the chain was rejected, in which case the
+	// handshake did not complete
+	VerificationError string `json:"verification_error,omitempty"`
+}
+
+// CertificateInfo holds the parts of an X.509 certificate worth looking at
+// when debugging a server's TLS setup
+type CertificateInfo struct {
+	Subject            string    `json:"subject"`
+	Issuer             string    `json:"issuer"`
+	SerialNumber       string    `json:"serial_number"`
+	DNSNames           []string  `json:"dns_names,omitempty"`
+	IPAddresses        []string  `json:"ip_addresses,omitempty"`
+	NotBefore          time.Time `json:"not_before"`
+	NotAfter           time.Time `json:"not_after"`
+	KeyType            string    `json:"key_type"`
+	SignatureAlgorithm string    `json:"signature_algorithm"`
+	IsCA               bool      `json:"is_ca,omitempty"`
+	SHA256             string    `json:"sha256"`
+	SHA1               string    `json:"sha1"`
+}
+
+// OCSPInfo is a stapled OCSP response
+type OCSPInfo struct {
+	// Status is good, revoked or unknown
+	Status     string    `json:"status,omitempty"`
+	ProducedAt time.Time `json:"produced_at,omitempty"`
+	ThisUpdate time.Time `json:"this_update,omitempty"`
+	NextUpdate time.Time `json:"next_update,omitempty"`
+	RevokedAt  time.Time `json:"revoked_at,omitempty"`
+	// Error is set when the response could not be parsed or verified
+	Error string `json:"error,omitempty"`
+}
+
 type WebSocketMessage struct {
-	ID          string
-	Timestamp   time.Time
-	Direction   MessageDirection
-	Type        MessageType
-	Opcode      uint8
-	Payload     []byte
-	PayloadText string
-	IsMasked    bool
-	IsFragment  bool
-	Size        int
+	ID          string           `json:"id"`
+	Timestamp   time.Time        `json:"timestamp"`
+	Direction   MessageDirection `json:"direction"`
+	Type        MessageType      `json:"type"`
+	Opcode      uint8            `json:"opcode"`
+	Payload     []byte           `json:"payload"`
+	PayloadText string           `json:"payload_text"`
+	IsMasked    bool             `json:"is_masked"`
+	IsFragment  bool             `json:"is_fragment"`
+	Size        int              `json:"size"`
+
+	// Injected marks messages sent by the proxy rather than either peer
+	Injected bool `json:"injected,omitempty"`
+	// Edited marks held messages forwarded with a changed payload; the
+	// payload is the one sent and OriginalPayload the one received
+	Edited          bool   `json:"edited,omitempty"`
+	OriginalPayload []byte `json:"original_payload,omitempty"`
+	// Dropped marks held messages that were never forwarded
+	Dropped bool `json:"dropped,omitempty"`
+
+	// Compressed marks messages the sender compressed with permessage-deflate;
+	// Payload is the inflated message
+	Compressed bool `json:"compressed,omitempty"`
+	// Frames holds the frames of a fragmented or compressed message as they
+	// were read, with their raw payloads
+	Frames []WebSocketFrame `json:"frames,omitempty"`
+}
+
+// WebSocketFrame is one frame of a WebSocket message as sent on the wire
+type WebSocketFrame struct {
+	Fin     bool   `json:"fin"`
+	RSV     uint8  `json:"rsv,omitempty"`
+	Opcode  uint8  `json:"opcode"`
+	Masked  bool   `json:"masked"`
+	Payload []byte `json:"payload"`
 }
 
 type MessageDirection int
@@ -110,13 +246,13 @@ const (
 )
 
 type MessageStats struct {
-	TotalMessages    int
-	InboundMessages  int
-	OutboundMessages int
-	TextMessages     int
-	BinaryMessages   int
-	ControlFrames    int
-	TotalBytes       int64
-	InboundBytes     int64
-	OutboundBytes    int64
+	TotalMessages    int   `json:"total_messages"`
+	InboundMessages  int   `json:"inbound_messages"`
+	OutboundMessages int   `json:"outbound_messages"`
+	TextMessages     int   `json:"text_messages"`
+	BinaryMessages   int   `json:"binary_messages"`
+	ControlFrames    int   `json:"control_frames"`
+	TotalBytes       int64 `json:"total_bytes"`
+	InboundBytes     int64 `json:"inbound_bytes"`
+	OutboundBytes    int64 `json:"outbound_bytes"`
 }
@@ -38,13 +38,52 @@ func (sm *SortedMap) Delete(key string) {
 
 	for i, k := range sm.Order {
 		if k == key {
-			sm.Order[i] = sm.Order[len(sm.Order)-1]
-			sm.Order = sm.Order[:len(sm.Order)-1]
+			sm.Order = append(sm.Order[:i], sm.Order[i+1:]...)
 			break
 		}
 	}
 }
 
+// Rename moves the value stored under oldKey to newKey, keeping its position
+func (sm *SortedMap) Rename(oldKey, newKey string) bool {
+	value, exists := sm.Entries[oldKey]
+	if !exists {
+		return false
+	}
+	if oldKey == newKey {
+		return true
+	}
+	if _, taken := sm.Entries[newKey]; taken {
+		sm.Delete(newKey)
+	}
+
+	delete(sm.Entries, oldKey)
+	sm.Entries[newKey] = value
+	for i, k := range sm.Order {
+		if k == oldKey {
+			sm.Order[i] = newKey
+			break
+		}
+	}
+	return true
+}
+
+// Clone returns a copy whose order and entries can be changed independently
+func (sm *SortedMap) Clone() *SortedMap {
+	clone := &SortedMap{
+		Entries: make(map[string]interface{}, len(sm.Entries)),
+		Order:   make([]string, len(sm.Order)),
+	}
+	copy(clone.Order, sm.Order)
+	for key, value := range sm.Entries {
+		if values, ok := value.([]string); ok {
+			value = append([]string(nil), values...)
+		}
+		clone.Entries[key] = value
+	}
+	return clone
+}
+
 func (sm *SortedMap) Equal(other *SortedMap) bool {
 	if len(sm.Entries) != len(other.Entries) || len(sm.Order) != len(other.Order) {
 		return false
@@ -114,5 +153,27 @@ func (sm *SortedMap) UnmarshalJSON(data []byte) error {
 
 	sm.Order = temp.Order
 	sm.Entries = temp.Entries
+	if sm.Entries == nil {
+		sm.Entries = make(map[string]interface{})
+	}
+
+	// Multi-value headers are stored as []string; JSON hands them back as []interface{}
+	for key, value := range sm.Entries {
+		if values, ok := value.([]interface{}); ok {
+			sm.Entries[key] = toStrings(values)
+		}
+	}
 	return nil
 }
+
+func toStrings(values []interface{}) interface{} {
+	strs := make([]string, 0, len(values))
+	for _, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			return values
+		}
+		strs = append(strs, s)
+	}
+	return strs
+}
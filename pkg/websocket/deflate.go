@@ -0,0 +1,205 @@
+package websocket
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"httpDebugger/pkg/sessiondata"
+)
+
+// deflateTail ends the payload of a compressed message: the sync flush marker
+// the sender stripped, then an empty final block so the reader stops cleanly
+var deflateTail = []byte{0x00, 0x00, 0xff, 0xff, 0x01, 0x00, 0x00, 0xff, 0xff}
+
+var (
+	ErrContextLost = errors.New("permessage-deflate context lost to an earlier message")
+	ErrTooLarge    = fmt.Errorf("inflated message above %d bytes", MaxFramePayload)
+)
+
+// ParseExtensions splits Sec-WebSocket-Extensions header values into one
+// entry per extension, keeping its parameters
+func ParseExtensions(values []string) []string {
+	var extensions []string
+	for _, value := range values {
+		for _, ext := range strings.Split(value, ",") {
+			var params []string
+			for _, param := range strings.Split(ext, ";") {
+				if param = strings.TrimSpace(param); param != "" {
+					params = append(params, param)
+				}
+			}
+			if len(params) > 0 {
+				extensions = append(extensions, strings.Join(params, "; "))
+			}
+		}
+	}
+	return extensions
+}
+
+// Deflate holds the permessage-deflate parameters a server accepted (RFC 7692)
+type Deflate struct {
+	ServerNoContextTakeover bool
+	ClientNoContextTakeover bool
+	// ServerMaxWindowBits and ClientMaxWindowBits bound the LZ77 window each
+	// side compresses with, 15 when not negotiated
+	ServerMaxWindowBits int
+	ClientMaxWindowBits int
+}
+
+// ParseDeflate finds permessage-deflate among the negotiated extensions;
+// it returns nil when the connection does not use it
+func ParseDeflate(extensions []string) (*Deflate, error) {
+	for _, ext := range extensions {
+		params := strings.Split(ext, ";")
+		if !strings.EqualFold(strings.TrimSpace(params[0]), "permessage-deflate") {
+			continue
+		}
+
+		d := &Deflate{ServerMaxWindowBits: 15, ClientMaxWindowBits: 15}
+		for _, param := range params[1:] {
+			name, value, hasValue := strings.Cut(strings.TrimSpace(param), "=")
+			value = strings.Trim(strings.TrimSpace(value), `"`)
+			switch strings.ToLower(strings.TrimSpace(name)) {
+			case "server_no_context_takeover":
+				d.ServerNoContextTakeover = true
+			case "client_no_context_takeover":
+				d.ClientNoContextTakeover = true
+			case "server_max_window_bits":
+				bits, err := parseWindowBits(value)
+				if err != nil {
+					return nil, err
+				}
+				d.ServerMaxWindowBits = bits
+			case "client_max_window_bits":
+				// A request may offer the parameter without a value
+				if !hasValue {
+					continue
+				}
+				bits, err := parseWindowBits(value)
+				if err != nil {
+					return nil, err
+				}
+				d.ClientMaxWindowBits = bits
+			default:
+				return nil, fmt.Errorf("unknown permessage-deflate parameter %q", name)
+			}
+		}
+		return d, nil
+	}
+	return nil, nil
+}
+
+func parseWindowBits(value string) (int, error) {
+	bits, err := strconv.Atoi(value)
+	if err != nil || bits < 8 || bits > 15 {
+		return 0, fmt.Errorf("invalid permessage-deflate window bits %q", value)
+	}
+	return bits, nil
+}
+
+// Inflater returns the inflater for the messages travelling in direction,
+// nil when d is nil
+func (d *Deflate) Inflater(direction sessiondata.MessageDirection) *Inflater {
+	if d == nil {
+		return nil
+	}
+	// Inbound messages come from the server
+	if direction == sessiondata.Inbound {
+		return &Inflater{contextTakeover: !d.ServerNoContextTakeover, windowSize: 1 << d.ServerMaxWindowBits}
+	}
+	return &Inflater{contextTakeover: !d.ClientNoContextTakeover, windowSize: 1 << d.ClientMaxWindowBits}
+}
+
+// Inflater decompresses the messages one side sends. With context takeover a
+// message may refer back to the ones before it, so the end of the inflated
+// data is kept as the dictionary of the next message
+type Inflater struct {
+	contextTakeover bool
+	windowSize      int
+	window          []byte
+	reader          io.ReadCloser
+	lost            bool
+}
+
+// ContextTakeover reports whether messages depend on the ones before them
+func (i *Inflater) ContextTakeover() bool {
+	return i != nil && i.contextTakeover
+}
+
+// Inflate decompresses the payload of a complete compressed message
+func (i *Inflater) Inflate(payload []byte) ([]byte, error) {
+	if i.lost {
+		return nil, ErrContextLost
+	}
+
+	var dict []byte
+	if i.contextTakeover {
+		dict = i.window
+	}
+	src := io.MultiReader(bytes.NewReader(payload), bytes.NewReader(deflateTail))
+	if i.reader == nil {
+		i.reader = flate.NewReaderDict(src, dict)
+	} else if err := i.reader.(flate.Resetter).Reset(src, dict); err != nil {
+		return nil, err
+	}
+
+	out, err := io.ReadAll(io.LimitReader(i.reader, MaxFramePayload+1))
+	if err == nil && len(out) > MaxFramePayload {
+		err = ErrTooLarge
+	}
+	if err != nil {
+		i.Skip()
+		return nil, err
+	}
+
+	if i.contextTakeover {
+		i.window = append(i.window, out...)
+		if len(i.window) > i.windowSize {
+			i.window = append([]byte(nil), i.window[len(i.window)-i.windowSize:]...)
+		}
+	}
+	return out, nil
+}
+
+// Skip records that a compressed message went by without being inflated;
+// with context takeover the messages after it can no longer be inflated
+func (i *Inflater) Skip() {
+	if i != nil && i.contextTakeover {
+		i.lost = true
+	}
+}
+
+// Message is a data message reassembled from its frames
+type Message struct {
+	Opcode byte
+	// Compressed reports that the sender compressed the message with
+	// permessage-deflate; Payload is then the inflated message
+	Compressed bool
+	Payload    []byte
+	// Frames are the frames as read, with their raw payloads
+	Frames []*Frame
+}
+
+// Assemble joins the frames of a data message and inflates it when it is
+// compressed. On error the message keeps the raw joined payload
+func Assemble(frames []*Frame, inflater *Inflater) (*Message, error) {
+	msg := &Message{Opcode: frames[0].Opcode, Compressed: frames[0].RSV&RSV1 != 0, Frames: frames}
+	for _, f := range frames {
+		msg.Payload = append(msg.Payload, f.Payload...)
+	}
+	if !msg.Compressed || inflater == nil {
+		return msg, nil
+	}
+
+	payload, err := inflater.Inflate(msg.Payload)
+	if err != nil {
+		return msg, fmt.Errorf("inflating message: %w", err)
+	}
+	msg.Payload = payload
+	return msg, nil
+}
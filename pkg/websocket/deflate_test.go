@@ -0,0 +1,124 @@
+package websocket
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"strings"
+	"testing"
+
+	"httpDebugger/pkg/sessiondata"
+)
+
+// compressor deflates messages the way a permessage-deflate sender does
+type compressor struct {
+	buf bytes.Buffer
+	w   *flate.Writer
+}
+
+func newCompressor() *compressor {
+	c := &compressor{}
+	c.w, _ = flate.NewWriter(&c.buf, flate.BestCompression)
+	return c
+}
+
+func (c *compressor) compress(t *testing.T, msg string) []byte {
+	c.buf.Reset()
+	if _, err := c.w.Write([]byte(msg)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.TrimSuffix(c.buf.Bytes(), []byte{0x00, 0x00, 0xff, 0xff})
+}
+
+func TestParseDeflate(t *testing.T) {
+	extensions := ParseExtensions([]string{`permessage-deflate ;server_no_context_takeover; client_max_window_bits="10", x-custom`})
+	if len(extensions) != 2 || extensions[0] != `permessage-deflate; server_no_context_takeover; client_max_window_bits="10"` {
+		t.Fatalf("ParseExtensions() = %q", extensions)
+	}
+
+	d, err := ParseDeflate(extensions)
+	if err != nil || d == nil {
+		t.Fatalf("ParseDeflate() = %v, %v", d, err)
+	}
+	if !d.ServerNoContextTakeover || d.ClientNoContextTakeover || d.ServerMaxWindowBits != 15 || d.ClientMaxWindowBits != 10 {
+		t.Errorf("unexpected parameters %+v", d)
+	}
+	if d.Inflater(sessiondata.Inbound).ContextTakeover() || !d.Inflater(sessiondata.Outbound).ContextTakeover() {
+		t.Error("only the client should keep its context")
+	}
+
+	if d, err := ParseDeflate([]string{"x-webkit-deflate-frame"}); d != nil || err != nil {
+		t.Errorf("ParseDeflate() without permessage-deflate = %v, %v", d, err)
+	}
+	for _, ext := range []string{"permessage-deflate; server_max_window_bits=16", "permessage-deflate; level=9"} {
+		if _, err := ParseDeflate([]string{ext}); err == nil {
+			t.Errorf("ParseDeflate(%q) should fail", ext)
+		}
+	}
+}
+
+func TestInflateContextTakeover(t *testing.T) {
+	d, _ := ParseDeflate([]string{"permessage-deflate"})
+	inflater := d.Inflater(sessiondata.Inbound)
+	c := newCompressor()
+
+	// The second message is mostly a back-reference into the first
+	messages := []string{strings.Repeat("hello websocket ", 20), strings.Repeat("hello websocket ", 21), "bye"}
+	for _, msg := range messages {
+		payload := c.compress(t, msg)
+		got, err := inflater.Inflate(payload)
+		if err != nil || string(got) != msg {
+			t.Fatalf("Inflate() = %q, %v, want %q", got, err, msg)
+		}
+	}
+
+	inflater.Skip()
+	if _, err := inflater.Inflate(c.compress(t, "lost")); !errors.Is(err, ErrContextLost) {
+		t.Errorf("Inflate() after Skip() = %v, want ErrContextLost", err)
+	}
+}
+
+func TestInflateNoContextTakeover(t *testing.T) {
+	d, _ := ParseDeflate([]string{"permessage-deflate; client_no_context_takeover"})
+	inflater := d.Inflater(sessiondata.Outbound)
+
+	for _, msg := range []string{"first message", "second message"} {
+		got, err := inflater.Inflate(newCompressor().compress(t, msg))
+		if err != nil || string(got) != msg {
+			t.Fatalf("Inflate() = %q, %v, want %q", got, err, msg)
+		}
+	}
+
+	// Without context takeover a skipped message does not affect the next
+	inflater.Skip()
+	if _, err := inflater.Inflate(newCompressor().compress(t, "third")); err != nil {
+		t.Errorf("Inflate() after Skip() failed: %v", err)
+	}
+}
+
+func TestAssemble(t *testing.T) {
+	frames := Fragment(OpText, 0, []byte("hello world"), 4, false)
+	msg, err := Assemble(frames, nil)
+	if err != nil || msg.Opcode != OpText || msg.Compressed || string(msg.Payload) != "hello world" || len(msg.Frames) != 3 {
+		t.Errorf("Assemble() = %+v, %v", msg, err)
+	}
+
+	d, _ := ParseDeflate([]string{"permessage-deflate"})
+	compressed := newCompressor().compress(t, `{"event":"update"}`)
+	frames = Fragment(OpText, RSV1, compressed, 3, true)
+	msg, err = Assemble(frames, d.Inflater(sessiondata.Outbound))
+	if err != nil || !msg.Compressed || string(msg.Payload) != `{"event":"update"}` {
+		t.Errorf("Assemble() = %+v, %v", msg, err)
+	}
+	if string(msg.Frames[0].Payload) != string(compressed[:3]) {
+		t.Error("the frames should keep their raw payloads")
+	}
+
+	msg, err = Assemble([]*Frame{{Fin: true, RSV: RSV1, Opcode: OpBinary, Payload: []byte{0xff, 0xff}}}, d.Inflater(sessiondata.Inbound))
+	if err == nil || !bytes.Equal(msg.Payload, []byte{0xff, 0xff}) {
+		t.Errorf("a corrupt message should fail and keep its raw payload, got %+v, %v", msg, err)
+	}
+}
@@ -0,0 +1,169 @@
+package websocket
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Opcodes defined by RFC 6455
+const (
+	OpContinuation byte = 0x0
+	OpText         byte = 0x1
+	OpBinary       byte = 0x2
+	OpClose        byte = 0x8
+	OpPing         byte = 0x9
+	OpPong         byte = 0xA
+)
+
+// RSV1 is the reserved bit permessage-deflate sets on compressed messages
+const RSV1 byte = 0x40
+
+// MaxFramePayload bounds the payload of a frame read from either side
+const MaxFramePayload = 64 * 1024 * 1024
+
+// Frame is a single WebSocket frame with its payload unmasked
+type Frame struct {
+	Fin bool
+	// RSV holds the three reserved bits in their header position
+	RSV     byte
+	Opcode  byte
+	Masked  bool
+	MaskKey [4]byte
+	Payload []byte
+}
+
+// IsControl reports whether the frame is a close, ping or pong frame
+func (f *Frame) IsControl() bool {
+	return f.Opcode&0x8 != 0
+}
+
+// ReadFrame reads the next frame from r and unmasks its payload
+func ReadFrame(r io.Reader) (*Frame, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	f := &Frame{
+		Fin:    header[0]&0x80 != 0,
+		RSV:    header[0] & 0x70,
+		Opcode: header[0] & 0x0F,
+		Masked: header[1]&0x80 != 0,
+	}
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return nil, fmt.Errorf("reading extended length: %w", err)
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return nil, fmt.Errorf("reading extended length: %w", err)
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if length > MaxFramePayload {
+		return nil, fmt.Errorf("frame payload of %d bytes exceeds the %d byte limit", length, MaxFramePayload)
+	}
+
+	if f.Masked {
+		if _, err := io.ReadFull(r, f.MaskKey[:]); err != nil {
+			return nil, fmt.Errorf("reading mask key: %w", err)
+		}
+	}
+
+	f.Payload = make([]byte, length)
+	if _, err := io.ReadFull(r, f.Payload); err != nil {
+		return nil, fmt.Errorf("reading payload: %w", err)
+	}
+	if f.Masked {
+		mask(f.Payload, f.MaskKey)
+	}
+	return f, nil
+}
+
+// WriteFrame writes f to w in one call, masking the payload with f.MaskKey
+// when f.Masked is set
+func WriteFrame(w io.Writer, f *Frame) error {
+	buf := make([]byte, 0, 14+len(f.Payload))
+
+	b0 := f.Opcode&0x0F | f.RSV&0x70
+	if f.Fin {
+		b0 |= 0x80
+	}
+	buf = append(buf, b0)
+
+	var b1 byte
+	if f.Masked {
+		b1 = 0x80
+	}
+	switch n := len(f.Payload); {
+	case n < 126:
+		buf = append(buf, b1|byte(n))
+	case n <= 0xFFFF:
+		buf = append(buf, b1|126)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(n))
+	default:
+		buf = append(buf, b1|127)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(n))
+	}
+
+	if f.Masked {
+		buf = append(buf, f.MaskKey[:]...)
+		start := len(buf)
+		buf = append(buf, f.Payload...)
+		mask(buf[start:], f.MaskKey)
+	} else {
+		buf = append(buf, f.Payload...)
+	}
+
+	_, err := w.Write(buf)
+	return err
+}
+
+// Fragment splits a message into frames of at most size payload bytes, or one
+// frame when size is 0. Only the first frame carries the opcode and rsv bits.
+// Masked frames each get a fresh random key, as clients must send them
+func Fragment(opcode, rsv byte, payload []byte, size int, masked bool) []*Frame {
+	if size <= 0 || size >= len(payload) {
+		size = len(payload)
+	}
+
+	var frames []*Frame
+	for offset := 0; ; offset += size {
+		end := min(offset+size, len(payload))
+		f := &Frame{Opcode: OpContinuation, Fin: end == len(payload), Payload: payload[offset:end]}
+		if offset == 0 {
+			f.Opcode = opcode
+			f.RSV = rsv
+		}
+		if masked {
+			f.Masked = true
+			rand.Read(f.MaskKey[:])
+		}
+		frames = append(frames, f)
+		if f.Fin {
+			return frames
+		}
+	}
+}
+
+// CloseFrame returns the code and reason of a close frame's payload
+func CloseFrame(payload []byte) (code int, reason string, ok bool) {
+	if len(payload) < 2 {
+		return 0, "", false
+	}
+	return int(binary.BigEndian.Uint16(payload[:2])), string(payload[2:]), true
+}
+
+func mask(b []byte, key [4]byte) {
+	for i := range b {
+		b[i] ^= key[i%4]
+	}
+}
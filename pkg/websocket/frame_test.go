@@ -0,0 +1,78 @@
+package websocket
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestFrameRoundTrip(t *testing.T) {
+	for _, size := range []int{0, 125, 126, 65535, 65536} {
+		for _, masked := range []bool{false, true} {
+			payload := bytes.Repeat([]byte("x"), size)
+			frame := &Frame{Fin: true, RSV: RSV1, Opcode: OpBinary, Masked: masked, MaskKey: [4]byte{1, 2, 3, 4}, Payload: payload}
+
+			var buf bytes.Buffer
+			if err := WriteFrame(&buf, frame); err != nil {
+				t.Fatalf("WriteFrame() failed: %v", err)
+			}
+			if masked && bytes.Contains(buf.Bytes(), []byte("xxxx")) {
+				t.Errorf("size %d: a masked frame carries its payload in the clear", size)
+			}
+
+			got, err := ReadFrame(&buf)
+			if err != nil {
+				t.Fatalf("size %d: ReadFrame() failed: %v", size, err)
+			}
+			if !got.Fin || got.RSV != RSV1 || got.Opcode != OpBinary || got.Masked != masked || !bytes.Equal(got.Payload, payload) {
+				t.Errorf("size %d, masked %v: read back %+v", size, masked, got)
+			}
+		}
+	}
+}
+
+func TestReadFrame(t *testing.T) {
+	// The masked "Hello" example of RFC 6455 section 5.7
+	frame, err := ReadFrame(bytes.NewReader([]byte{0x81, 0x85, 0x37, 0xfa, 0x21, 0x3d, 0x7f, 0x9f, 0x4d, 0x51, 0x58}))
+	if err != nil || frame.Opcode != OpText || string(frame.Payload) != "Hello" {
+		t.Errorf("ReadFrame() = %+v, %v", frame, err)
+	}
+
+	oversized := []byte{0x82, 127, 0, 0, 0, 0, 0x10, 0, 0, 0}
+	if _, err := ReadFrame(bytes.NewReader(oversized)); err == nil {
+		t.Error("a frame above MaxFramePayload should be rejected")
+	}
+	if _, err := ReadFrame(bytes.NewReader([]byte{0x81, 0x05, 'H'})); err == nil {
+		t.Error("a truncated frame should fail")
+	}
+}
+
+func TestFragment(t *testing.T) {
+	frames := Fragment(OpText, RSV1, []byte("hello world"), 4, true)
+	if len(frames) != 3 {
+		t.Fatalf("got %d frames, want 3", len(frames))
+	}
+
+	var payload strings.Builder
+	for i, f := range frames {
+		payload.Write(f.Payload)
+		if f.Fin != (i == 2) || !f.Masked {
+			t.Errorf("frame %d: fin %v, masked %v", i, f.Fin, f.Masked)
+		}
+		if i > 0 && (f.Opcode != OpContinuation || f.RSV != 0) {
+			t.Errorf("frame %d should be a plain continuation, got opcode %d rsv %#x", i, f.Opcode, f.RSV)
+		}
+	}
+	if frames[0].Opcode != OpText || frames[0].RSV != RSV1 || payload.String() != "hello world" {
+		t.Errorf("first frame %+v, payload %q", frames[0], payload.String())
+	}
+
+	if frames := Fragment(OpBinary, 0, nil, 0, false); len(frames) != 1 || !frames[0].Fin || frames[0].Masked {
+		t.Errorf("an empty message should be one unmasked final frame: %+v", frames)
+	}
+
+	code, reason, ok := CloseFrame([]byte{0x03, 0xe8, 'b', 'y', 'e'})
+	if !ok || code != 1000 || reason != "bye" {
+		t.Errorf("CloseFrame() = %d, %q, %v", code, reason, ok)
+	}
+}
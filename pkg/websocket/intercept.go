@@ -0,0 +1,420 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"httpDebugger/pkg/sessiondata"
+
+	"github.com/google/uuid"
+)
+
+type Action int
+
+const (
+	// ActionForward sends the message on, using Decision.Payload when edited
+	ActionForward Action = iota
+	// ActionDrop discards the message; the connection stays open
+	ActionDrop
+)
+
+// Decision is how the user resolved a held message
+type Decision struct {
+	Action Action
+	// Edited reports that Opcode and Payload replace the held message
+	Edited  bool
+	Opcode  byte
+	Payload []byte
+}
+
+var (
+	ErrNotFound     = errors.New("held message not found")
+	ErrNotConnected = errors.New("WebSocket connection is closed")
+)
+
+// Pending is a message held by a rule until Resolve is called
+type Pending struct {
+	ID        string
+	Session   *sessiondata.Session
+	Direction sessiondata.MessageDirection
+	Opcode    byte
+	Payload   []byte
+	Rule      *Rule
+
+	decision chan Decision
+}
+
+// Injector writes a new message into one side of a live connection: Outbound
+// messages go to the server, Inbound ones to the client
+type Injector interface {
+	Inject(direction sessiondata.MessageDirection, opcode byte, payload []byte) error
+}
+
+// Interceptor holds the rules that pause WebSocket messages, the messages
+// currently paused by them and the live connections messages can be injected into
+type Interceptor struct {
+	mu          sync.Mutex
+	rules       []*Rule
+	pending     []*Pending
+	conns       map[string]Injector
+	subscribers []func()
+}
+
+func NewInterceptor() *Interceptor {
+	return &Interceptor{conns: make(map[string]Injector)}
+}
+
+// Subscribe registers a callback run whenever rules or held messages change
+func (i *Interceptor) Subscribe(callback func()) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.subscribers = append(i.subscribers, callback)
+}
+
+func (i *Interceptor) notify() {
+	i.mu.Lock()
+	subs := make([]func(), len(i.subscribers))
+	copy(subs, i.subscribers)
+	i.mu.Unlock()
+
+	for _, callback := range subs {
+		go callback()
+	}
+}
+
+func (i *Interceptor) AddRule(rule *Rule) {
+	i.mu.Lock()
+	i.rules = append(i.rules, rule)
+	i.mu.Unlock()
+	i.notify()
+}
+
+// ClearRules removes every rule; messages already held stay held
+func (i *Interceptor) ClearRules() {
+	i.mu.Lock()
+	i.rules = nil
+	i.mu.Unlock()
+	i.notify()
+}
+
+func (i *Interceptor) Rules() []*Rule {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	rules := make([]*Rule, len(i.rules))
+	copy(rules, i.rules)
+	return rules
+}
+
+// Watches reports whether a rule may hold messages sent in direction on the
+// session's connection, before their payload is known
+func (i *Interceptor) Watches(session *sessiondata.Session, direction sessiondata.MessageDirection) bool {
+	if i == nil {
+		return false
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for _, rule := range i.rules {
+		if rule.Enabled && rule.matchesConnection(session, direction) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match returns the first enabled rule matching a complete message
+func (i *Interceptor) Match(session *sessiondata.Session, direction sessiondata.MessageDirection, opcode byte, payload []byte) *Rule {
+	if i == nil {
+		return nil
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for _, rule := range i.rules {
+		if rule.Enabled && rule.Matches(session, direction, opcode, payload) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// Hold pauses the caller until the user resolves the message or ctx is done,
+// in which case the message is dropped
+func (i *Interceptor) Hold(ctx context.Context, session *sessiondata.Session, direction sessiondata.MessageDirection, opcode byte, payload []byte, rule *Rule) Decision {
+	p := &Pending{
+		ID:        uuid.New().String(),
+		Session:   session,
+		Direction: direction,
+		Opcode:    opcode,
+		Payload:   payload,
+		Rule:      rule,
+		decision:  make(chan Decision, 1),
+	}
+
+	i.mu.Lock()
+	i.pending = append(i.pending, p)
+	i.mu.Unlock()
+	i.notify()
+
+	var decision Decision
+	select {
+	case decision = <-p.decision:
+	case <-ctx.Done():
+		decision = Decision{Action: ActionDrop}
+	}
+
+	i.remove(p.ID)
+	return decision
+}
+
+// Pending returns the held messages, oldest first
+func (i *Interceptor) Pending() []*Pending {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	pending := make([]*Pending, len(i.pending))
+	copy(pending, i.pending)
+	return pending
+}
+
+// Resolve releases a held message with the given decision
+func (i *Interceptor) Resolve(id string, decision Decision) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for _, p := range i.pending {
+		if p.ID == id {
+			select {
+			case p.decision <- decision:
+			default:
+			}
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// ResolveAll forwards every held message unchanged
+func (i *Interceptor) ResolveAll() {
+	for _, p := range i.Pending() {
+		i.Resolve(p.ID, Decision{Action: ActionForward})
+	}
+}
+
+func (i *Interceptor) remove(id string) {
+	i.mu.Lock()
+	for n, p := range i.pending {
+		if p.ID == id {
+			i.pending = append(i.pending[:n], i.pending[n+1:]...)
+			break
+		}
+	}
+	i.mu.Unlock()
+	i.notify()
+}
+
+// Register makes the connection of a session available for injection until
+// Unregister is called
+func (i *Interceptor) Register(sessionID string, conn Injector) {
+	if i == nil {
+		return
+	}
+	i.mu.Lock()
+	i.conns[sessionID] = conn
+	i.mu.Unlock()
+}
+
+func (i *Interceptor) Unregister(sessionID string) {
+	if i == nil {
+		return
+	}
+	i.mu.Lock()
+	delete(i.conns, sessionID)
+	i.mu.Unlock()
+}
+
+// Connected reports whether the session's connection is still open
+func (i *Interceptor) Connected(sessionID string) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	_, ok := i.conns[sessionID]
+	return ok
+}
+
+// Inject sends a new message on the session's live connection
+func (i *Interceptor) Inject(sessionID string, direction sessiondata.MessageDirection, opcode byte, payload []byte) error {
+	i.mu.Lock()
+	conn, ok := i.conns[sessionID]
+	i.mu.Unlock()
+	if !ok {
+		return ErrNotConnected
+	}
+	return conn.Inject(direction, opcode, payload)
+}
+
+// Rule selects messages to hold. Empty fields match anything; all set fields must match
+type Rule struct {
+	ID string
+	// Direction is "out" for client to server, "in" for server to client
+	// messages, empty for both
+	Direction string
+	// Type is "text" or "binary", empty for both
+	Type    string
+	Host    string
+	URL     string
+	Payload string
+	Enabled bool
+
+	urlRe     *regexp.Regexp
+	payloadRe *regexp.Regexp
+}
+
+// ParseRule parses a rule written as space separated key=value terms, e.g.
+//
+//	dir=out type=text host=chat.example.com url=/socket payload="type":"join"
+//
+// A bare term without "=" is used as the payload regex
+func ParseRule(spec string) (*Rule, error) {
+	rule := &Rule{ID: uuid.New().String(), Enabled: true}
+
+	for _, term := range strings.Fields(spec) {
+		name, value, found := strings.Cut(term, "=")
+		if !found {
+			name, value = "payload", term
+		}
+
+		switch strings.ToLower(name) {
+		case "dir", "direction":
+			switch strings.ToLower(value) {
+			case "in", "out":
+				rule.Direction = strings.ToLower(value)
+			case "both":
+				rule.Direction = ""
+			default:
+				return nil, fmt.Errorf("invalid direction %q, use in, out or both", value)
+			}
+		case "type":
+			switch strings.ToLower(value) {
+			case "text", "binary":
+				rule.Type = strings.ToLower(value)
+			default:
+				return nil, fmt.Errorf("invalid message type %q, use text or binary", value)
+			}
+		case "host":
+			rule.Host = strings.ToLower(value)
+		case "url":
+			rule.URL = value
+		case "payload":
+			rule.Payload = value
+		default:
+			return nil, fmt.Errorf("unknown WebSocket rule term %q", name)
+		}
+	}
+
+	if err := rule.compile(); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (r *Rule) compile() error {
+	if r.URL != "" {
+		re, err := regexp.Compile(r.URL)
+		if err != nil {
+			return fmt.Errorf("invalid url regex: %w", err)
+		}
+		r.urlRe = re
+	}
+	if r.Payload != "" {
+		re, err := regexp.Compile(r.Payload)
+		if err != nil {
+			return fmt.Errorf("invalid payload regex: %w", err)
+		}
+		r.payloadRe = re
+	}
+	return nil
+}
+
+// Matches reports whether a complete message satisfies every condition of the rule
+func (r *Rule) Matches(session *sessiondata.Session, direction sessiondata.MessageDirection, opcode byte, payload []byte) bool {
+	if !r.matchesConnection(session, direction) {
+		return false
+	}
+
+	switch r.Type {
+	case "text":
+		if opcode != OpText {
+			return false
+		}
+	case "binary":
+		if opcode != OpBinary {
+			return false
+		}
+	}
+
+	if r.payloadRe != nil && !r.payloadRe.Match(payload) {
+		return false
+	}
+	return true
+}
+
+func (r *Rule) matchesConnection(session *sessiondata.Session, direction sessiondata.MessageDirection) bool {
+	switch r.Direction {
+	case "out":
+		if direction != sessiondata.Outbound {
+			return false
+		}
+	case "in":
+		if direction != sessiondata.Inbound {
+			return false
+		}
+	}
+
+	if r.urlRe == nil && r.Host == "" {
+		return true
+	}
+	if session.Request == nil {
+		return false
+	}
+	if r.urlRe != nil && !r.urlRe.MatchString(session.Request.URL) {
+		return false
+	}
+	if r.Host != "" {
+		parsed, err := url.Parse(session.Request.URL)
+		if err != nil || !strings.EqualFold(parsed.Hostname(), r.Host) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Rule) String() string {
+	var terms []string
+	if r.Direction != "" {
+		terms = append(terms, "dir="+r.Direction)
+	}
+	if r.Type != "" {
+		terms = append(terms, "type="+r.Type)
+	}
+	if r.Host != "" {
+		terms = append(terms, "host="+r.Host)
+	}
+	if r.URL != "" {
+		terms = append(terms, "url="+r.URL)
+	}
+	if r.Payload != "" {
+		terms = append(terms, "payload="+r.Payload)
+	}
+	if len(terms) == 0 {
+		return "*"
+	}
+	return strings.Join(terms, " ")
+}
@@ -0,0 +1,127 @@
+package websocket
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"httpDebugger/pkg/sessiondata"
+)
+
+func createTestSession(url string) *sessiondata.Session {
+	return &sessiondata.Session{
+		ID:      "test-id",
+		Type:    sessiondata.WebSocketSession,
+		Request: &sessiondata.RequestData{Method: "GET", URL: url},
+	}
+}
+
+func TestRuleMatches(t *testing.T) {
+	session := createTestSession("wss://chat.example.com/socket")
+	payload := []byte(`{"type":"join","room":1}`)
+
+	tests := []struct {
+		spec      string
+		direction sessiondata.MessageDirection
+		opcode    byte
+		matches   bool
+	}{
+		{"", sessiondata.Inbound, OpBinary, true},
+		{"dir=out", sessiondata.Outbound, OpText, true},
+		{"dir=out", sessiondata.Inbound, OpText, false},
+		{"dir=in type=text", sessiondata.Inbound, OpText, true},
+		{"type=binary", sessiondata.Outbound, OpText, false},
+		{`"type":"join"`, sessiondata.Outbound, OpText, true},
+		{`payload="type":"leave"`, sessiondata.Outbound, OpText, false},
+		{"host=CHAT.example.com url=/socket$", sessiondata.Outbound, OpText, true},
+		{"host=example.com", sessiondata.Outbound, OpText, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			rule, err := ParseRule(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseRule(%q) failed: %v", tt.spec, err)
+			}
+			if got := rule.Matches(session, tt.direction, tt.opcode, payload); got != tt.matches {
+				t.Errorf("Matches() = %v, want %v", got, tt.matches)
+			}
+		})
+	}
+
+	for _, spec := range []string{"dir=up", "type=json", "payload=([", "status=1"} {
+		if _, err := ParseRule(spec); err == nil {
+			t.Errorf("ParseRule(%q) should fail", spec)
+		}
+	}
+	if rule, _ := ParseRule("dir=both type=text join"); rule.String() != "type=text payload=join" {
+		t.Errorf("unexpected rule %q", rule.String())
+	}
+}
+
+func TestHoldAndResolve(t *testing.T) {
+	interceptor := NewInterceptor()
+	rule, _ := ParseRule("dir=out secret")
+	interceptor.AddRule(rule)
+
+	session := createTestSession("ws://example.com/")
+	if !interceptor.Watches(session, sessiondata.Outbound) || interceptor.Watches(session, sessiondata.Inbound) {
+		t.Fatal("Watches() should only report the outbound direction")
+	}
+	if interceptor.Match(session, sessiondata.Outbound, OpText, []byte("public")) != nil {
+		t.Fatal("Match() matched a message without the payload")
+	}
+
+	done := make(chan Decision)
+	go func() {
+		done <- interceptor.Hold(context.Background(), session, sessiondata.Outbound, OpText, []byte("secret"), rule)
+	}()
+
+	var pending []*Pending
+	for deadline := time.Now().Add(time.Second); len(pending) == 0 && time.Now().Before(deadline); {
+		pending = interceptor.Pending()
+		time.Sleep(time.Millisecond)
+	}
+	if len(pending) != 1 || string(pending[0].Payload) != "secret" {
+		t.Fatalf("expected the message to be held, got %v", pending)
+	}
+
+	interceptor.Resolve(pending[0].ID, Decision{Action: ActionForward, Edited: true, Opcode: OpText, Payload: []byte("redacted")})
+	if decision := <-done; !decision.Edited || string(decision.Payload) != "redacted" {
+		t.Errorf("unexpected decision %+v", decision)
+	}
+	if len(interceptor.Pending()) != 0 || interceptor.Resolve(pending[0].ID, Decision{}) != ErrNotFound {
+		t.Error("a resolved message should no longer be held")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if decision := interceptor.Hold(ctx, session, sessiondata.Outbound, OpText, nil, rule); decision.Action != ActionDrop {
+		t.Error("a message held when its connection closes should be dropped")
+	}
+}
+
+type recordingInjector struct {
+	direction sessiondata.MessageDirection
+	payload   string
+}
+
+func (r *recordingInjector) Inject(direction sessiondata.MessageDirection, opcode byte, payload []byte) error {
+	r.direction, r.payload = direction, string(payload)
+	return nil
+}
+
+func TestInject(t *testing.T) {
+	interceptor := NewInterceptor()
+	conn := &recordingInjector{}
+
+	interceptor.Register("a", conn)
+	if err := interceptor.Inject("a", sessiondata.Inbound, OpText, []byte("hi")); err != nil || conn.payload != "hi" || conn.direction != sessiondata.Inbound {
+		t.Errorf("Inject() = %v, injector saw %+v", err, conn)
+	}
+
+	interceptor.Unregister("a")
+	if interceptor.Connected("a") || interceptor.Inject("a", sessiondata.Inbound, OpText, nil) != ErrNotConnected {
+		t.Error("an unregistered connection should not take injected messages")
+	}
+}
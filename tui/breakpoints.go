@@ -0,0 +1,103 @@
+package tui
+
+import (
+	"fmt"
+
+	"httpDebugger/pkg/breakpoints"
+
+	key "github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// addBreakpoint parses a rule typed in the prompt and starts holding matching requests
+func (m *Model) addBreakpoint(spec string) {
+	rule, err := breakpoints.ParseRule(spec)
+	if err != nil {
+		m.errorMsg = err.Error()
+		return
+	}
+	m.breakpoints.AddRule(rule)
+	m.statusMsg = fmt.Sprintf("Breakpoint added: %s", rule)
+	if m.logger != nil {
+		m.logger.LogInfo(m.statusMsg)
+	}
+}
+
+// clearBreakpoints removes every rule and lets held requests continue unchanged
+func (m *Model) clearBreakpoints() {
+	m.breakpoints.ClearRules()
+	m.breakpoints.ResolveAll()
+	m.breakpointPanel.Close()
+	m.statusMsg = "Breakpoints cleared"
+}
+
+// openBreakpointEditor shows the oldest held session in the editor
+func (m *Model) openBreakpointEditor() tea.Cmd {
+	pending := m.breakpoints.Pending()
+	if len(pending) == 0 {
+		m.errorMsg = "No requests held at a breakpoint"
+		return clearStatusCmd()
+	}
+	return m.breakpointPanel.Open(pending[0])
+}
+
+func (m *Model) updateBreakpointEditor(msg tea.KeyMsg) tea.Cmd {
+	pending := m.breakpointPanel.Pending()
+
+	switch {
+	case key.Matches(msg, key.NewBinding(key.WithKeys("ctrl+f"))):
+		decision, err := m.breakpointPanel.Decision()
+		if err != nil {
+			m.errorMsg = err.Error()
+			return clearStatusCmd()
+		}
+		return m.resolveBreakpoint(pending, decision)
+
+	case key.Matches(msg, key.NewBinding(key.WithKeys("ctrl+x"))):
+		return m.resolveBreakpoint(pending, breakpoints.Decision{Action: breakpoints.ActionDrop})
+
+	case key.Matches(msg, key.NewBinding(key.WithKeys("ctrl+t"))):
+		if pending.Stage == breakpoints.StageRequest {
+			m.breakpointPanel.ComposeResponse()
+		}
+		return nil
+
+	case key.Matches(msg, key.NewBinding(key.WithKeys("esc"))):
+		m.breakpointPanel.Close()
+		return nil
+	}
+
+	return m.breakpointPanel.Update(msg)
+}
+
+func (m *Model) resolveBreakpoint(pending *breakpoints.Pending, decision breakpoints.Decision) tea.Cmd {
+	m.breakpointPanel.Close()
+
+	if err := m.breakpoints.Resolve(pending.ID, decision); err != nil {
+		m.errorMsg = "Request is no longer held"
+		return clearStatusCmd()
+	}
+
+	switch decision.Action {
+	case breakpoints.ActionDrop:
+		m.statusMsg = "Dropped " + pending.Session.Request.URL
+	case breakpoints.ActionRespond:
+		m.statusMsg = "Responded to " + pending.Session.Request.URL
+	default:
+		m.statusMsg = "Forwarded " + pending.Session.Request.URL
+	}
+
+	// Move straight on to the next held session, if any
+	if len(m.breakpoints.Pending()) > 1 {
+		return tea.Batch(m.openBreakpointEditor(), clearStatusCmd())
+	}
+	return clearStatusCmd()
+}
+
+func (m *Model) renderBreakpointEditor() string {
+	help := HelpStyle.Render("Ctrl+F: forward • Ctrl+X: drop • Ctrl+T: canned response • Esc: close (keep held)")
+	content := ActiveStyle.Copy().Width(m.width - 2).Height(m.height - 4).Render(
+		m.renderPanelTitle(m.breakpointPanel.Title(), true) + "\n\n" + m.breakpointPanel.View(),
+	)
+	return content + "\n" + m.renderStatusBar() + "\n" + help
+}
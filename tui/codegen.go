@@ -0,0 +1,63 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+
+	"httpDebugger/pkg/codegen"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// openCopyAsPrompt asks which language to copy the highlighted request as,
+// pre-filled with the last one used
+func (m *Model) openCopyAsPrompt() tea.Cmd {
+	session := m.fullSession(m.sessionsPanel.GetSelectedSession())
+	if session == nil {
+		m.errorMsg = "No session selected"
+		return clearStatusCmd()
+	}
+
+	m.copyTarget = session
+	if m.copyFormat == "" {
+		m.copyFormat = codegen.FormatCurl
+	}
+	return m.openPrompt(PromptCopyAs, fmt.Sprintf("Copy as (%s)", codegen.FormatNames()), string(m.copyFormat))
+}
+
+// copyAs copies the request being exported as code to the clipboard. A binary
+// body sent by cURL is written next to the working directory
+func (m *Model) copyAs(name string) {
+	session := m.copyTarget
+	m.copyTarget = nil
+	if session == nil {
+		return
+	}
+
+	format, err := codegen.ParseFormat(name)
+	if err != nil {
+		m.errorMsg = err.Error()
+		return
+	}
+	m.copyFormat = format
+
+	code, err := codegen.Generate(session, format, codegen.Options{BodyFile: codegen.DefaultBodyFile})
+	if err != nil {
+		m.errorMsg = err.Error()
+		return
+	}
+	if err := clipboard.WriteAll(code); err != nil {
+		m.errorMsg = "Error " + err.Error()
+		return
+	}
+	m.statusMsg = fmt.Sprintf("Copied as %s to clipboard", format)
+
+	if format == codegen.FormatCurl && codegen.IsBinary(session.Request.Body) {
+		if err := os.WriteFile(codegen.DefaultBodyFile, []byte(session.Request.Body), 0o644); err != nil {
+			m.errorMsg = fmt.Sprintf("writing %s: %v", codegen.DefaultBodyFile, err)
+			return
+		}
+		m.statusMsg += ", body written to " + codegen.DefaultBodyFile
+	}
+}
@@ -0,0 +1,163 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+
+	"httpDebugger/pkg/proxy"
+	"httpDebugger/pkg/sessiondata"
+	"httpDebugger/tui/panels"
+
+	key "github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ComposeResultMsg carries the session a composed request produced
+type ComposeResultMsg struct {
+	Session *sessiondata.Session
+	Error   error
+}
+
+// openComposer opens the request composer, seeded from the current session
+// unless blank is set
+func (m *Model) openComposer(blank bool) tea.Cmd {
+	var parent *sessiondata.Session
+	if !blank {
+		parent = m.currentSession()
+		if parent == nil {
+			m.errorMsg = "No session selected"
+			return clearStatusCmd()
+		}
+		switch parent.Type {
+		case sessiondata.WebSocketSession:
+			m.errorMsg = "WebSocket sessions cannot be composed"
+			return clearStatusCmd()
+		case sessiondata.TunnelSession:
+			m.errorMsg = "TCP tunnel sessions cannot be composed"
+			return clearStatusCmd()
+		}
+	}
+
+	profiles, selected := m.composerProfiles(parent)
+	return m.composerPanel.Open(parent, profiles, selected)
+}
+
+// composerProfiles lists Go's own fingerprints followed by each distinct pair
+// of fingerprints seen in the captured sessions, and the index of parent's
+func (m *Model) composerProfiles(parent *sessiondata.Session) ([]panels.ComposerProfile, int) {
+	profiles := []panels.ComposerProfile{{Name: "Go default"}}
+	seen := make(map[string]int)
+
+	add := func(session *sessiondata.Session) int {
+		if session == nil || session.TLSFingerprint == nil || len(session.TLSFingerprint.Raw) == 0 {
+			return 0
+		}
+		id := session.TLSFingerprint.JA4
+		if session.HTTP2Fingerprint != nil {
+			id += " " + session.HTTP2Fingerprint.Akamai
+		}
+		if i, ok := seen[id]; ok {
+			return i
+		}
+
+		name := session.TLSFingerprint.JA4
+		if session.Client != nil {
+			name = fmt.Sprintf("%s (%s)", session.Client.Name, name)
+		}
+		if session.HTTP2Fingerprint != nil {
+			name += " + HTTP/2"
+		}
+		seen[id] = len(profiles)
+		profiles = append(profiles, panels.ComposerProfile{
+			Name:  name,
+			TLS:   session.TLSFingerprint,
+			HTTP2: session.HTTP2Fingerprint,
+		})
+		return seen[id]
+	}
+
+	selected := add(parent)
+	for _, session := range m.sessions {
+		add(session)
+	}
+	return profiles, selected
+}
+
+func (m *Model) updateComposer(msg tea.KeyMsg) tea.Cmd {
+	switch {
+	case key.Matches(msg, key.NewBinding(key.WithKeys("ctrl+f"))):
+		return m.sendComposed()
+
+	case key.Matches(msg, key.NewBinding(key.WithKeys("ctrl+p"))):
+		m.composerPanel.NextProfile()
+		return nil
+
+	case key.Matches(msg, key.NewBinding(key.WithKeys("ctrl+o"))):
+		m.composerPanel.Reset()
+		return nil
+
+	case key.Matches(msg, key.NewBinding(key.WithKeys("pgup", "pgdown"))):
+		return m.composerPanel.ScrollResponse(msg)
+
+	case key.Matches(msg, key.NewBinding(key.WithKeys("esc"))):
+		m.composerPanel.Close()
+		return nil
+	}
+
+	return m.composerPanel.Update(msg)
+}
+
+// sendComposed sends the request in the composer through the proxy's upstream
+// clients as a new session linked to the one it was made from
+func (m *Model) sendComposed() tea.Cmd {
+	if m.composerPanel.Sending() {
+		return nil
+	}
+	if m.proxy == nil {
+		m.errorMsg = "Start the proxy (Ctrl+S) to send requests"
+		return clearStatusCmd()
+	}
+
+	req, err := m.composerPanel.Request()
+	if err != nil {
+		m.errorMsg = err.Error()
+		return clearStatusCmd()
+	}
+
+	profile := m.composerPanel.Profile()
+	opts := proxy.SendOptions{
+		TLSFingerprint:   profile.TLS,
+		HTTP2Fingerprint: profile.HTTP2,
+	}
+	if parent := m.composerPanel.Parent(); parent != nil {
+		opts.ParentID = parent.ID
+	}
+
+	m.composerPanel.SetSending()
+	m.statusMsg = fmt.Sprintf("Sending %s %s...", req.Method, req.URL)
+	p := m.proxy
+	return func() tea.Msg {
+		session, err := p.Send(context.Background(), req, opts)
+		return ComposeResultMsg{Session: session, Error: err}
+	}
+}
+
+func (m *Model) handleComposeResult(msg ComposeResultMsg) tea.Cmd {
+	if m.composerPanel.IsOpen() {
+		m.composerPanel.SetResult(msg.Session, msg.Error)
+	}
+	if msg.Error != nil {
+		m.errorMsg = fmt.Sprintf("Send failed: %v", msg.Error)
+	} else {
+		m.statusMsg = fmt.Sprintf("Sent: %s in %v", msg.Session.Response.Status, msg.Session.Duration)
+	}
+	return tea.Batch(clearStatusCmd(), m.refreshSessionsCmd())
+}
+
+func (m *Model) renderComposer() string {
+	help := HelpStyle.Render("Ctrl+F: send • Ctrl+P: next fingerprint • Ctrl+O: reset • PgUp/PgDn: scroll response • Esc: close")
+	content := ActiveStyle.Copy().Width(m.width - 2).Height(m.height - 4).Render(
+		m.renderPanelTitle(m.composerPanel.Title(), true) + "\n\n" + m.composerPanel.View(),
+	)
+	return content + "\n" + m.renderStatusBar() + "\n" + help
+}
@@ -0,0 +1,179 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"httpDebugger/pkg/fingerprintExport"
+	"httpDebugger/pkg/sessiondata"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const defaultFingerprintsPath = "fingerprints.json"
+
+// openCopyFingerprintPrompt asks which format to copy the fingerprint of the
+// current session in
+func (m *Model) openCopyFingerprintPrompt() tea.Cmd {
+	session := m.currentSession()
+	if session == nil {
+		m.errorMsg = "No session selected"
+		return clearStatusCmd()
+	}
+	if session.TLSFingerprint == nil {
+		m.errorMsg = "Session has no TLS fingerprint"
+		return clearStatusCmd()
+	}
+
+	m.fingerprintTarget = session
+	formats := make([]string, len(fingerprintExport.Formats))
+	for i, format := range fingerprintExport.Formats {
+		formats[i] = string(format)
+	}
+	label := fmt.Sprintf("Copy fingerprint as (%s)", strings.Join(formats, ", "))
+	return m.openPrompt(PromptCopyFingerprint, label, string(fingerprintExport.FormatGo))
+}
+
+// copyFingerprint copies the fingerprint of the chosen session to the clipboard
+func (m *Model) copyFingerprint(name string) {
+	session := m.fingerprintTarget
+	m.fingerprintTarget = nil
+	if session == nil {
+		return
+	}
+
+	format, err := fingerprintExport.ParseFormat(name)
+	if err != nil {
+		m.errorMsg = err.Error()
+		return
+	}
+	exported, err := fingerprintExport.Export(session, format)
+	if err != nil {
+		m.errorMsg = err.Error()
+		return
+	}
+	if err := clipboard.WriteAll(exported); err != nil {
+		m.errorMsg = "Error " + err.Error()
+		return
+	}
+	m.statusMsg = fmt.Sprintf("Fingerprint copied to clipboard as %s", format)
+}
+
+// currentSession returns the highlighted session in the list, or the one
+// shown in the details
+func (m *Model) currentSession() *sessiondata.Session {
+	if m.activePanel == SessionPanel {
+		return m.fullSession(m.sessionsPanel.GetSelectedSession())
+	}
+	if m.showDetails {
+		return m.selectedSession
+	}
+	return nil
+}
+
+// openLabelPrompt asks for the name of the client that produced the current
+// session, pre-filled with its current match
+func (m *Model) openLabelPrompt() tea.Cmd {
+	session := m.currentSession()
+	if session == nil {
+		m.errorMsg = "No session selected"
+		return clearStatusCmd()
+	}
+
+	m.labelTarget = session
+	name := ""
+	if session.Client != nil {
+		name = session.Client.Name
+	}
+	return m.openPrompt(PromptLabel, "Label client as", name)
+}
+
+// labelClient adds the fingerprints of the session being labeled to the
+// database under name
+func (m *Model) labelClient(name string) {
+	session := m.labelTarget
+	m.labelTarget = nil
+	if session == nil {
+		return
+	}
+
+	if _, err := m.fingerprints.Label(name, session); err != nil {
+		m.errorMsg = err.Error()
+		return
+	}
+	m.reidentifySessions()
+
+	m.statusMsg = fmt.Sprintf("Labeled client as %s", name)
+	if path := m.fingerprints.Path(); path != "" {
+		m.statusMsg += " in " + path
+	}
+	if m.logger != nil {
+		m.logger.LogInfo(m.statusMsg)
+	}
+}
+
+// updateFingerprints runs "export <file>" or "import <file>" typed in the prompt
+func (m *Model) updateFingerprints(spec string) {
+	command, path, _ := strings.Cut(strings.TrimSpace(spec), " ")
+	path = strings.TrimSpace(path)
+	if path == "" {
+		path = defaultFingerprintsPath
+	}
+
+	switch strings.ToLower(command) {
+	case "export":
+		f, err := os.Create(path)
+		if err != nil {
+			m.errorMsg = fmt.Sprintf("creating %s: %v", path, err)
+			return
+		}
+		defer f.Close()
+		if err := m.fingerprints.Export(f); err != nil {
+			m.errorMsg = fmt.Sprintf("writing %s: %v", path, err)
+			return
+		}
+		m.statusMsg = fmt.Sprintf("Exported %d fingerprints to %s", len(m.fingerprints.Entries()), path)
+
+	case "import":
+		f, err := os.Open(path)
+		if err != nil {
+			m.errorMsg = fmt.Sprintf("opening %s: %v", path, err)
+			return
+		}
+		defer f.Close()
+		n, err := m.fingerprints.Import(f)
+		if err != nil {
+			m.errorMsg = err.Error()
+			return
+		}
+		if err := m.fingerprints.Save(); err != nil {
+			m.errorMsg = fmt.Sprintf("saving fingerprints: %v", err)
+			return
+		}
+		m.reidentifySessions()
+		m.statusMsg = fmt.Sprintf("Imported %d fingerprints from %s", n, path)
+
+	default:
+		m.errorMsg = fmt.Sprintf("unknown fingerprints command %q, use export or import", command)
+		return
+	}
+
+	if m.logger != nil {
+		m.logger.LogInfo(m.statusMsg)
+	}
+}
+
+// reidentifySessions matches the listed sessions against the database again
+// after it changed
+func (m *Model) reidentifySessions() {
+	for _, session := range m.sessions {
+		m.fingerprints.Annotate(session)
+	}
+	if m.selectedSession != nil {
+		m.fingerprints.Annotate(m.selectedSession)
+		m.updatePanelsForSession(m.selectedSession)
+	}
+	m.applyFilter()
+}
@@ -0,0 +1,182 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"httpDebugger/pkg/fuzzer"
+	"httpDebugger/pkg/sessiondata"
+
+	key "github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const defaultFuzzSpec = "mode=sniper payloads=range:1-10 concurrency=1"
+
+// FuzzTickMsg refreshes the results of a running attack
+type FuzzTickMsg struct{}
+
+// openFuzzer shows the running attack, or loads the current session into the
+// fuzzer to mark its insertion points
+func (m *Model) openFuzzer() tea.Cmd {
+	if m.fuzz != nil && !m.fuzz.Done() {
+		m.fuzzerPanel.Reopen()
+		return nil
+	}
+
+	original := m.currentSession()
+	if original == nil {
+		m.errorMsg = "No session selected"
+		return clearStatusCmd()
+	}
+	if original.Type != sessiondata.HTTPSession || original.Request == nil {
+		m.errorMsg = "Only HTTP requests can be fuzzed"
+		return clearStatusCmd()
+	}
+	return m.fuzzerPanel.Open(original)
+}
+
+// startFuzz runs the attack the spec typed in the prompt describes on the
+// marked request, replaying every attempt through the proxy
+func (m *Model) startFuzz(spec string) tea.Cmd {
+	if !m.isRunning {
+		m.errorMsg = "Start the proxy (Ctrl+S) to fuzz requests"
+		return clearStatusCmd()
+	}
+	if m.fuzz != nil && !m.fuzz.Done() {
+		m.errorMsg = "An attack is already running, stop it with Ctrl+X"
+		return clearStatusCmd()
+	}
+
+	opts, err := fuzzer.ParseSpec(spec)
+	if err != nil {
+		m.errorMsg = err.Error()
+		return clearStatusCmd()
+	}
+	attack, err := fuzzer.New(m.fuzzerPanel.Original(), m.fuzzerPanel.Template(), opts)
+	if err != nil {
+		m.errorMsg = err.Error()
+		return clearStatusCmd()
+	}
+
+	listenAddr := m.listenAddr
+	m.fuzz = attack
+	m.fuzz.Start(context.Background(), func(ctx context.Context, session *sessiondata.Session) (*sessiondata.Session, error) {
+		return session.Replay(listenAddr)
+	})
+
+	m.lastFuzzSpec = spec
+	m.fuzzerPanel.ShowResults(true)
+	m.updateFuzzResults()
+	m.statusMsg = fmt.Sprintf("Fuzzing with %d requests (%s)", m.fuzz.Summary().Total, opts.Mode)
+	if m.logger != nil {
+		m.logger.LogInfo(m.statusMsg)
+	}
+	return fuzzTickCmd()
+}
+
+func (m *Model) updateFuzzResults() {
+	summary := m.fuzz.Summary()
+	state := "Running"
+	if !summary.Running {
+		state = "Finished"
+	}
+	line := fmt.Sprintf("%s: %d/%d requests, %d failed • mode=%s", state, summary.Completed, summary.Total, summary.Failed, m.fuzz.Options().Mode)
+	if match := m.fuzz.Options().Match; match != nil {
+		line += fmt.Sprintf(" match=%s", match)
+	}
+	m.fuzzerPanel.UpdateResults(m.fuzz.Results(), line)
+}
+
+func fuzzTickCmd() tea.Cmd {
+	return tea.Tick(500*time.Millisecond, func(time.Time) tea.Msg {
+		return FuzzTickMsg{}
+	})
+}
+
+func (m *Model) handleFuzzTick() tea.Cmd {
+	if m.fuzz == nil {
+		return nil
+	}
+	m.updateFuzzResults()
+	if !m.fuzz.Done() {
+		return fuzzTickCmd()
+	}
+
+	summary := m.fuzz.Summary()
+	m.statusMsg = fmt.Sprintf("Attack finished: %d requests, %d failed", summary.Completed, summary.Failed)
+	if m.logger != nil {
+		m.logger.LogInfo(m.statusMsg)
+	}
+	return tea.Batch(clearStatusCmd(), m.refreshSessionsCmd())
+}
+
+func (m *Model) updateFuzzer(msg tea.KeyMsg) tea.Cmd {
+	// The attack spec is asked for over the panel
+	if m.promptAction != PromptNone {
+		return m.updatePrompt(msg)
+	}
+
+	switch {
+	case key.Matches(msg, key.NewBinding(key.WithKeys("esc"))):
+		m.fuzzerPanel.Close()
+		return nil
+
+	case key.Matches(msg, key.NewBinding(key.WithKeys("ctrl+x"))):
+		if m.fuzz != nil && !m.fuzz.Done() {
+			m.fuzz.Stop()
+			m.statusMsg = "Stopping attack, waiting for requests in flight"
+		}
+		return nil
+
+	case key.Matches(msg, key.NewBinding(key.WithKeys("tab"))):
+		m.fuzzerPanel.ShowResults(!m.fuzzerPanel.ShowingResults())
+		return nil
+
+	case key.Matches(msg, key.NewBinding(key.WithKeys("ctrl+f"))):
+		spec := m.lastFuzzSpec
+		if spec == "" {
+			spec = defaultFuzzSpec
+		}
+		return m.openPrompt(PromptFuzz, "Attack (mode=sniper|battering-ram|pitchfork|cluster-bomb payloads=list:|file:|range:|random: concurrency= rate= match=)", spec)
+	}
+
+	if !m.fuzzerPanel.ShowingResults() {
+		switch {
+		case key.Matches(msg, key.NewBinding(key.WithKeys("ctrl+a"))):
+			m.fuzzerPanel.InsertMarker()
+			return nil
+
+		case key.Matches(msg, key.NewBinding(key.WithKeys("ctrl+o"))):
+			m.fuzzerPanel.Reset()
+			return nil
+		}
+		return m.fuzzerPanel.Update(msg)
+	}
+
+	switch {
+	case key.Matches(msg, key.NewBinding(key.WithKeys("up", "k"))):
+		m.fuzzerPanel.MoveCursor(-1)
+	case key.Matches(msg, key.NewBinding(key.WithKeys("down", "j"))):
+		m.fuzzerPanel.MoveCursor(1)
+	case key.Matches(msg, key.NewBinding(key.WithKeys("s"))):
+		m.fuzzerPanel.NextSort()
+	case key.Matches(msg, key.NewBinding(key.WithKeys("S"))):
+		m.fuzzerPanel.ReverseSort()
+	case key.Matches(msg, key.NewBinding(key.WithKeys("pgup", "pgdown"))):
+		return m.fuzzerPanel.ScrollResponse(msg)
+	}
+	return nil
+}
+
+func (m *Model) renderFuzzer() string {
+	help := "Ctrl+A: mark insertion point (§value§) • Ctrl+O: reset • Ctrl+F: attack • Tab: results • Esc: close"
+	if m.fuzzerPanel.ShowingResults() {
+		help = "↑↓: select • s: sort by • S: reverse • PgUp/PgDn: scroll response • Ctrl+X: stop • Tab: request • Esc: close (i reopens)"
+	}
+	content := ActiveStyle.Copy().Width(m.width - 2).Height(m.height - 4).Render(
+		m.renderPanelTitle(m.fuzzerPanel.Title(), true) + "\n\n" + m.fuzzerPanel.View(),
+	)
+	return content + "\n" + m.renderStatusBar() + "\n" + HelpStyle.Render(help)
+}
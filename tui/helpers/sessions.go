@@ -45,6 +45,11 @@ func GetSessionTypeIcon(session *sessiondata.Session) string {
 		return "🌐"
 	case sessiondata.WebSocketSession:
 		return "🔌"
+	case sessiondata.TunnelSession:
+		if session.Tunnel != nil && session.Tunnel.Passthrough != "" {
+			return "🔒"
+		}
+		return "🔗"
 	default:
 		return "❓"
 	}
@@ -56,6 +61,11 @@ func GetSessionTypeText(session *sessiondata.Session) string {
 		return "HTTP"
 	case sessiondata.WebSocketSession:
 		return "WebSocket"
+	case sessiondata.TunnelSession:
+		if session.Tunnel != nil && session.Tunnel.Passthrough != "" {
+			return "TLS passthrough"
+		}
+		return "TCP"
 	default:
 		return "Unknown"
 	}
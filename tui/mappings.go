@@ -0,0 +1,40 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"httpDebugger/pkg/mapping"
+)
+
+// addMapping parses a Map Local or Map Remote rule typed in the prompt; "clear"
+// removes every rule
+func (m *Model) addMapping(spec string) {
+	if strings.EqualFold(strings.TrimSpace(spec), "clear") {
+		m.mappings.Clear()
+		m.statusMsg = "Mappings cleared"
+		return
+	}
+
+	rule, err := mapping.ParseRule(spec)
+	if err != nil {
+		m.errorMsg = err.Error()
+		return
+	}
+	m.mappings.Add(rule)
+	m.statusMsg = fmt.Sprintf("Mapping added: %s", rule)
+	if m.logger != nil {
+		m.logger.LogInfo(m.statusMsg)
+	}
+}
+
+// toggleMappings turns every mapping rule on or off
+func (m *Model) toggleMappings() {
+	enabled := !m.mappings.Enabled()
+	m.mappings.SetEnabled(enabled)
+	if enabled {
+		m.statusMsg = "Mappings enabled"
+	} else {
+		m.statusMsg = "Mappings disabled"
+	}
+}
@@ -1,12 +1,30 @@
 package tui
 
 import (
+	"fmt"
+	"net"
 	"net/http"
+	"net/url"
 	"regexp"
+	"strconv"
+	"time"
 
+	"httpDebugger/pkg/api"
+	"httpDebugger/pkg/breakpoints"
+	"httpDebugger/pkg/certs"
+	"httpDebugger/pkg/codegen"
+	"httpDebugger/pkg/fingerprintDB"
+	"httpDebugger/pkg/fuzzer"
+	"httpDebugger/pkg/logging"
+	"httpDebugger/pkg/mapping"
+	"httpDebugger/pkg/passthrough"
 	"httpDebugger/pkg/proxy"
+	"httpDebugger/pkg/proxy/upstream"
+	"httpDebugger/pkg/replayRunner"
+	"httpDebugger/pkg/rewrite"
 	"httpDebugger/pkg/session"
 	"httpDebugger/pkg/sessiondata"
+	"httpDebugger/pkg/websocket"
 	"httpDebugger/tui/panels"
 
 	"github.com/charmbracelet/bubbles/textinput"
@@ -15,13 +33,24 @@ import (
 
 type Model struct {
 	// Proxy
-	proxy     *proxy.Proxy
-	server    *http.Server
-	port      int
-	isRunning bool
+	proxy      *proxy.Proxy
+	server     *http.Server
+	listenAddr string
+	caCertFile string
+	caKeyFile  string
+	isRunning  bool
+	apiServer  *http.Server
+
+	// SOCKS5, transparent and reverse proxy listeners run next to the HTTP proxy
+	socksAddr       string
+	transparentAddr string
+	reverse         *url.URL
+	reverseAddr     string
+	listeners       []net.Listener
 
 	// Sessions
-	sessionStore    *session.InMemoryStore
+	sessionStore    *session.SwitchableStore
+	captureDir      string
 	sessions        []*sessiondata.Session
 	sessionCount    int
 	selectedSession *sessiondata.Session
@@ -32,6 +61,49 @@ type Model struct {
 	responsePanel  *panels.ResponsePanel
 	websocketPanel *panels.WebSocketPanel
 	tlsPanel       *panels.TLSPanel
+	diffPanel      *panels.DiffPanel
+
+	// Breakpoints
+	breakpoints     *breakpoints.Manager
+	breakpointPanel *panels.BreakpointPanel
+
+	// WebSocket message interception and injection
+	wsIntercept    *websocket.Interceptor
+	wsMessagePanel *panels.WSMessagePanel
+
+	// Request composer
+	composerPanel *panels.ComposerPanel
+
+	// The last bulk replay and its statistics
+	replayRun      *replayRunner.Runner
+	replayRunPanel *panels.ReplayRunPanel
+
+	// The last fuzzer attack and the spec it was started with
+	fuzz         *fuzzer.Attack
+	fuzzerPanel  *panels.FuzzerPanel
+	lastFuzzSpec string
+
+	// Rewrite rules
+	rewrite *rewrite.Engine
+
+	// Map Local / Map Remote
+	mappings *mapping.Manager
+
+	// Upstream proxies
+	chain *upstream.Chain
+
+	// TLS hosts relayed without interception
+	passthrough *passthrough.Policy
+
+	// Known client fingerprints, the session being labeled and the one whose
+	// fingerprint is being copied
+	fingerprints      *fingerprintDB.Database
+	labelTarget       *sessiondata.Session
+	fingerprintTarget *sessiondata.Session
+
+	// The request being copied as code and the last format picked
+	copyTarget *sessiondata.Session
+	copyFormat codegen.Format
 
 	// Navigation
 	activePanel ActivePanel
@@ -45,12 +117,16 @@ type Model struct {
 	filterRegex    string
 	compiledFilter *regexp.Regexp
 
+	// Prompt
+	promptInput  textinput.Model
+	promptAction PromptAction
+
 	// Status
 	statusMsg string
 	errorMsg  string
 
 	// Logger
-	logger  *Logger
+	logger  *logging.Logger
 	verbose bool
 
 	// Layout
@@ -58,27 +134,167 @@ type Model struct {
 	height int
 }
 
+// Options configures a Model beyond the defaults used by NewModel
+type Options struct {
+	// CaptureDir, when set, persists sessions to this directory and reopens
+	// the capture already stored there
+	CaptureDir string
+	// RulesFile, when set, loads rewrite rules from this YAML or JSON file and
+	// reloads them whenever it changes
+	RulesFile string
+
+	// Listen is the proxy address, ":8080" by default
+	Listen string
+	// CACertFile and CAKeyFile locate the CA used to intercept HTTPS
+	CACertFile string
+	CAKeyFile  string
+	// StoreSize is how many sessions are kept in memory without CaptureDir
+	StoreSize int
+	LogDir    string
+	// SOCKSListen, when set, also accepts SOCKS5 clients on this address
+	SOCKSListen string
+	// TransparentListen, when set, accepts connections redirected by the firewall
+	TransparentListen string
+	// Reverse, when set, also serves a reverse proxy in front of this upstream
+	// base URL on ReverseListen, DefaultReverseListen by default
+	Reverse       *url.URL
+	ReverseListen string
+	// APIListen, when set, serves the control API on this address
+	APIListen string
+	// Chain routes upstream connections through other proxies; nil connects directly
+	Chain *upstream.Chain
+	// Passthrough decides which TLS hosts are relayed without interception;
+	// nil keeps the default automatic passthrough of pinned hosts
+	Passthrough *passthrough.Policy
+	// Fingerprints identifies the client of each session; nil uses the
+	// built-in database and keeps labels in memory
+	Fingerprints *fingerprintDB.Database
+}
+
 func NewModel() Model {
-	logger, _ := NewLogger(true)
+	model, _ := NewModelWithOptions(Options{})
+	return model
+}
+
+func NewModelWithOptions(opts Options) (Model, error) {
+	var rewriteEngine *rewrite.Engine
+	if opts.RulesFile != "" {
+		engine, err := rewrite.LoadFile(opts.RulesFile)
+		if err != nil {
+			return Model{}, err
+		}
+		rewriteEngine = engine
+	}
+
+	if opts.Listen == "" {
+		opts.Listen = proxy.DefaultListen
+	}
+	_, portStr, err := net.SplitHostPort(opts.Listen)
+	if err != nil {
+		return Model{}, fmt.Errorf("invalid listen address %q: %w", opts.Listen, err)
+	}
+	if _, err := strconv.Atoi(portStr); err != nil {
+		return Model{}, fmt.Errorf("invalid listen port %q", portStr)
+	}
+	if opts.Reverse != nil && opts.ReverseListen == "" {
+		opts.ReverseListen = proxy.DefaultReverseListen
+	}
+	if opts.Passthrough == nil {
+		opts.Passthrough = passthrough.Automatic(passthrough.DefaultFailureThreshold)
+	}
+	if opts.Fingerprints == nil {
+		opts.Fingerprints = fingerprintDB.Builtin()
+	}
+	if opts.CACertFile == "" {
+		opts.CACertFile = certs.DefaultCACertFile
+	}
+	if opts.CAKeyFile == "" {
+		opts.CAKeyFile = certs.DefaultCAKeyFile
+	}
+
+	opened, err := session.Open(opts.CaptureDir, opts.StoreSize)
+	if err != nil {
+		return Model{}, err
+	}
+	// The capture can be switched from the TUI while the proxy runs
+	store := session.NewSwitchableStore(opened)
+
+	logger, _ := logging.NewLogger(opts.LogDir, true)
+
+	if rewriteEngine != nil {
+		rewriteEngine.Watch(time.Second, func(err error) {
+			if logger == nil {
+				return
+			}
+			if err != nil {
+				logger.LogError(err, "reloading rewrite rules")
+				return
+			}
+			logger.LogInfo(fmt.Sprintf("Reloaded %d rewrite rules from %s", len(rewriteEngine.Rules()), rewriteEngine.Path()))
+		})
+	}
 
 	ti := textinput.New()
 	ti.Placeholder = "Regex filter by URL..."
 	ti.Prompt = "/ "
 	ti.CharLimit = 100
 
-	return Model{
-		port:           8080,
-		sessionStore:   session.NewInMemoryStore(1000),
-		sessions:       make([]*sessiondata.Session, 0),
-		sessionsPanel:  panels.NewSessionsPanel(),
-		requestPanel:   panels.NewRequestPanel(),
-		responsePanel:  panels.NewResponsePanel(),
-		websocketPanel: panels.NewWebSocketPanel(),
-		tlsPanel:       panels.NewTLSPanel(),
-		activePanel:    SessionPanel,
-		searchInput:    ti,
-		logger:         logger,
+	model := Model{
+		listenAddr:      opts.Listen,
+		socksAddr:       opts.SOCKSListen,
+		transparentAddr: opts.TransparentListen,
+		reverse:         opts.Reverse,
+		reverseAddr:     opts.ReverseListen,
+		caCertFile:      opts.CACertFile,
+		caKeyFile:       opts.CAKeyFile,
+		sessionStore:    store,
+		captureDir:      opts.CaptureDir,
+		sessions:        store.GetAll(),
+		sessionsPanel:   panels.NewSessionsPanel(),
+		requestPanel:    panels.NewRequestPanel(),
+		responsePanel:   panels.NewResponsePanel(),
+		websocketPanel:  panels.NewWebSocketPanel(),
+		tlsPanel:        panels.NewTLSPanel(),
+		diffPanel:       panels.NewDiffPanel(),
+		breakpoints:     breakpoints.NewManager(),
+		breakpointPanel: panels.NewBreakpointPanel(),
+		wsIntercept:     websocket.NewInterceptor(),
+		wsMessagePanel:  panels.NewWSMessagePanel(),
+		composerPanel:   panels.NewComposerPanel(),
+		replayRunPanel:  panels.NewReplayRunPanel(),
+		fuzzerPanel:     panels.NewFuzzerPanel(),
+		rewrite:         rewriteEngine,
+		mappings:        mapping.NewManager(),
+		chain:           opts.Chain,
+		passthrough:     opts.Passthrough,
+		fingerprints:    opts.Fingerprints,
+		activePanel:     SessionPanel,
+		searchInput:     ti,
+		promptInput:     newPromptInput(),
+		logger:          logger,
+	}
+	model.sessionsPanel.UpdateSessions(model.sessions)
+
+	if opts.APIListen != "" {
+		apiServer, err := api.ListenAndServe(opts.APIListen, api.Config{
+			Store:       store,
+			Breakpoints: model.breakpoints,
+			Mappings:    model.mappings,
+			Rewrite:     rewriteEngine,
+			CACertFile:  opts.CACertFile,
+			ProxyAddr:   opts.Listen,
+		})
+		if err != nil {
+			model.OnShutdown()
+			return Model{}, fmt.Errorf("starting API: %w", err)
+		}
+		model.apiServer = apiServer
+		if logger != nil {
+			logger.LogInfo(fmt.Sprintf("API listening on %s", apiServer.Addr))
+		}
 	}
+
+	return model, nil
 }
 
 func (m *Model) Init() tea.Cmd {
@@ -86,7 +302,27 @@ func (m *Model) Init() tea.Cmd {
 }
 
 func (m *Model) OnShutdown() {
+	m.closeListeners()
+	if m.apiServer != nil {
+		m.apiServer.Close()
+	}
 	if m.logger != nil {
 		m.logger.Close()
 	}
+	if m.rewrite != nil {
+		m.rewrite.Close()
+	}
+	m.sessionStore.Close()
+}
+
+// fullSession returns the complete stored session, since persistent stores only
+// list summaries without bodies
+func (m *Model) fullSession(s *sessiondata.Session) *sessiondata.Session {
+	if s == nil || m.sessionStore == nil {
+		return s
+	}
+	if full, err := m.sessionStore.Get(s.ID); err == nil {
+		return full
+	}
+	return s
 }
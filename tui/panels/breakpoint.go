@@ -0,0 +1,130 @@
+package panels
+
+import (
+	"fmt"
+
+	"httpDebugger/pkg/breakpoints"
+	"httpDebugger/pkg/sessiondata"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const cannedResponseTemplate = "HTTP/1.1 200 OK\nContent-Type: text/plain\n\n"
+
+// BreakpointPanel edits a session held by a breakpoint as raw HTTP text
+type BreakpointPanel struct {
+	editor   textarea.Model
+	pending  *breakpoints.Pending
+	original string
+	canned   bool
+}
+
+func NewBreakpointPanel() *BreakpointPanel {
+	ta := textarea.New()
+	ta.ShowLineNumbers = false
+	ta.CharLimit = 0
+	ta.MaxHeight = 0
+
+	return &BreakpointPanel{editor: ta}
+}
+
+// Open loads the held request or response into the editor
+func (p *BreakpointPanel) Open(pending *breakpoints.Pending) tea.Cmd {
+	p.pending = pending
+	p.canned = false
+
+	if pending.Stage == breakpoints.StageResponse && pending.Session.Response != nil {
+		p.original = sessiondata.FormatRawResponse(pending.Session.Response)
+	} else {
+		p.original = sessiondata.FormatRawRequest(pending.Session.Request)
+	}
+
+	p.editor.SetValue(p.original)
+	p.editor.Focus()
+	return textarea.Blink
+}
+
+func (p *BreakpointPanel) Close() {
+	p.pending = nil
+	p.editor.Blur()
+}
+
+func (p *BreakpointPanel) IsOpen() bool {
+	return p.pending != nil
+}
+
+func (p *BreakpointPanel) Pending() *breakpoints.Pending {
+	return p.pending
+}
+
+// ComposeResponse replaces the editor content with a canned response to return
+// to the client instead of forwarding the request
+func (p *BreakpointPanel) ComposeResponse() {
+	p.canned = true
+	p.editor.SetValue(cannedResponseTemplate)
+}
+
+// Decision parses the editor content and applies the edits to the held session
+func (p *BreakpointPanel) Decision() (breakpoints.Decision, error) {
+	value := p.editor.Value()
+
+	if p.canned {
+		resp, err := sessiondata.ParseRawResponse(value)
+		if err != nil {
+			return breakpoints.Decision{}, err
+		}
+		return breakpoints.Decision{Action: breakpoints.ActionRespond, Response: resp}, nil
+	}
+
+	if value == p.original {
+		return breakpoints.Decision{Action: breakpoints.ActionForward}, nil
+	}
+
+	session := p.pending.Session
+	if p.pending.Stage == breakpoints.StageResponse {
+		resp, err := sessiondata.ParseRawResponse(value)
+		if err != nil {
+			return breakpoints.Decision{}, err
+		}
+		session.Response = resp
+	} else {
+		req, err := sessiondata.ParseRawRequest(value)
+		if err != nil {
+			return breakpoints.Decision{}, err
+		}
+		session.Request.Method = req.Method
+		session.Request.URL = req.URL
+		session.Request.Headers = req.Headers
+		session.Request.Cookies = req.Cookies
+		session.Request.Body = req.Body
+		session.Request.ContentType = req.ContentType
+	}
+
+	return breakpoints.Decision{Action: breakpoints.ActionForward, Edited: true}, nil
+}
+
+func (p *BreakpointPanel) Title() string {
+	if p.pending == nil {
+		return "Breakpoint"
+	}
+	if p.canned {
+		return fmt.Sprintf("Canned response for %s %s", p.pending.Session.Request.Method, p.pending.Session.Request.URL)
+	}
+	return fmt.Sprintf("Breakpoint (%s): %s %s", p.pending.Stage, p.pending.Session.Request.Method, p.pending.Session.Request.URL)
+}
+
+func (p *BreakpointPanel) Update(msg tea.Msg) tea.Cmd {
+	var cmd tea.Cmd
+	p.editor, cmd = p.editor.Update(msg)
+	return cmd
+}
+
+func (p *BreakpointPanel) View() string {
+	return p.editor.View()
+}
+
+func (p *BreakpointPanel) SetSize(width, height int) {
+	p.editor.SetWidth(width)
+	p.editor.SetHeight(height)
+}
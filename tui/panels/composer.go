@@ -0,0 +1,196 @@
+package panels
+
+import (
+	"fmt"
+
+	"httpDebugger/pkg/clientHello"
+	"httpDebugger/pkg/http2Fingerprint"
+	"httpDebugger/pkg/sessiondata"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const blankRequestTemplate = "GET https://example.com/ HTTP/1.1\nUser-Agent: httpDebugger\nAccept: */*\n\n"
+
+// ComposerProfile is a pair of fingerprints a composed request can be sent with
+type ComposerProfile struct {
+	Name  string
+	TLS   *clientHello.TLSFingerprint
+	HTTP2 *http2Fingerprint.HTTP2Fingerprint
+}
+
+// ComposerPanel edits a new request as raw HTTP text and shows its response
+// next to the response of the session it was made from
+type ComposerPanel struct {
+	editor   textarea.Model
+	sent     *ResponsePanel
+	original *ResponsePanel
+
+	open     bool
+	parent   *sessiondata.Session
+	result   *sessiondata.Session
+	err      error
+	sending  bool
+	profiles []ComposerProfile
+	profile  int
+
+	width  int
+	height int
+}
+
+func NewComposerPanel() *ComposerPanel {
+	ta := textarea.New()
+	ta.ShowLineNumbers = false
+	ta.CharLimit = 0
+	ta.MaxHeight = 0
+
+	return &ComposerPanel{
+		editor:   ta,
+		sent:     NewResponsePanel(),
+		original: NewResponsePanel(),
+	}
+}
+
+// Open loads the request of parent into the editor, or a blank request when
+// parent is nil. profile is the index in profiles selected first
+func (p *ComposerPanel) Open(parent *sessiondata.Session, profiles []ComposerProfile, profile int) tea.Cmd {
+	p.open = true
+	p.parent = parent
+	p.profiles = profiles
+	p.profile = profile
+	p.SetResult(nil, nil)
+	p.original.UpdateSession(parent)
+	p.Reset()
+	p.editor.Focus()
+	return textarea.Blink
+}
+
+func (p *ComposerPanel) Close() {
+	p.open = false
+	p.parent = nil
+	p.result = nil
+	p.editor.Blur()
+}
+
+func (p *ComposerPanel) IsOpen() bool {
+	return p.open
+}
+
+// Parent returns the session the request was made from, nil for a blank one
+func (p *ComposerPanel) Parent() *sessiondata.Session {
+	return p.parent
+}
+
+// Reset puts the unedited request back into the editor
+func (p *ComposerPanel) Reset() {
+	if p.parent != nil && p.parent.Request != nil {
+		p.editor.SetValue(sessiondata.FormatRawRequest(p.parent.Request))
+	} else {
+		p.editor.SetValue(blankRequestTemplate)
+	}
+}
+
+// Request parses the editor content
+func (p *ComposerPanel) Request() (*sessiondata.RequestData, error) {
+	return sessiondata.ParseRawRequest(p.editor.Value())
+}
+
+// Profile returns the fingerprints to send the request with
+func (p *ComposerPanel) Profile() ComposerProfile {
+	if p.profile < 0 || p.profile >= len(p.profiles) {
+		return ComposerProfile{Name: "Go default"}
+	}
+	return p.profiles[p.profile]
+}
+
+// NextProfile selects the next fingerprint profile
+func (p *ComposerPanel) NextProfile() {
+	if len(p.profiles) > 0 {
+		p.profile = (p.profile + 1) % len(p.profiles)
+	}
+}
+
+// Sending reports whether a request is on its way
+func (p *ComposerPanel) Sending() bool {
+	return p.sending
+}
+
+func (p *ComposerPanel) SetSending() {
+	p.sending = true
+}
+
+// SetResult shows the session the last send produced
+func (p *ComposerPanel) SetResult(session *sessiondata.Session, err error) {
+	p.sending = false
+	p.result = session
+	p.err = err
+	p.sent.UpdateSession(session)
+	p.SetSize(p.width, p.height)
+}
+
+func (p *ComposerPanel) Title() string {
+	title := "Compose request"
+	if p.parent != nil && p.parent.Request != nil {
+		title = fmt.Sprintf("Compose from %s %s", p.parent.Request.Method, p.parent.Request.URL)
+	}
+	return fmt.Sprintf("%s • Fingerprint: %s", title, p.Profile().Name)
+}
+
+// ScrollResponse passes a scroll key to the response of the sent request
+func (p *ComposerPanel) ScrollResponse(msg tea.Msg) tea.Cmd {
+	return p.sent.Update(msg)
+}
+
+func (p *ComposerPanel) Update(msg tea.Msg) tea.Cmd {
+	var cmd tea.Cmd
+	p.editor, cmd = p.editor.Update(msg)
+	return cmd
+}
+
+func (p *ComposerPanel) View() string {
+	label := lipgloss.NewStyle().Bold(true)
+	muted := lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+
+	// Messages take the place of a response so the original stays put
+	box := lipgloss.NewStyle().Width(p.sent.viewport.Width).Height(p.sent.viewport.Height)
+
+	var sent string
+	switch {
+	case p.sending:
+		sent = box.Render(muted.Render("Sending..."))
+	case p.err != nil:
+		sent = box.Render("Error: " + p.err.Error())
+	case p.result == nil:
+		sent = box.Render(muted.Render("Ctrl+F sends the request"))
+	default:
+		sent = p.sent.View()
+	}
+
+	original := muted.Render("No original request")
+	if p.parent != nil {
+		original = p.original.View()
+	}
+
+	responses := lipgloss.JoinVertical(lipgloss.Left,
+		label.Render("Response"), sent, "",
+		label.Render("Original response"), original,
+	)
+	return lipgloss.JoinHorizontal(lipgloss.Top, p.editor.View(), "  ", responses)
+}
+
+func (p *ComposerPanel) SetSize(width, height int) {
+	p.width = width
+	p.height = height
+
+	editorW := width / 2
+	responseW := width - editorW - 2
+	// Each response sits under a title line, with a blank line between them
+	responseH := (height - 3) / 2
+
+	p.editor.SetWidth(editorW)
+	p.editor.SetHeight(height)
+	p.sent.SetSize(max(responseW, 0), max(responseH, 0))
+	p.original.SetSize(max(responseW, 0), max(height-3-responseH, 0))
+}
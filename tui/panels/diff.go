@@ -0,0 +1,221 @@
+package panels
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"httpDebugger/pkg/sessiondata"
+	"httpDebugger/pkg/sortedMap"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	diffRemovedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	diffAddedStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("46"))
+	diffChangedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("226"))
+)
+
+// DiffPanel compares a replayed or composed session with the session it was
+// made from: the request differences, then both responses side by side
+type DiffPanel struct {
+	viewport viewport.Model
+	session  *sessiondata.Session
+	parent   *sessiondata.Session
+}
+
+func NewDiffPanel() *DiffPanel {
+	return &DiffPanel{viewport: viewport.New(0, 0)}
+}
+
+func (p *DiffPanel) Update(msg tea.Msg) tea.Cmd {
+	var cmd tea.Cmd
+	p.viewport, cmd = p.viewport.Update(msg)
+	return cmd
+}
+
+func (p *DiffPanel) View() string {
+	return p.viewport.View()
+}
+
+func (p *DiffPanel) SetSize(width, height int) {
+	p.viewport.Width = width
+	p.viewport.Height = height
+	p.render()
+}
+
+// UpdateSession shows how session differs from parent, the session it was
+// made from, which is nil when it is unknown
+func (p *DiffPanel) UpdateSession(session, parent *sessiondata.Session) {
+	p.session = session
+	p.parent = parent
+	p.render()
+	p.viewport.GotoTop()
+}
+
+func (p *DiffPanel) render() {
+	var content string
+	switch {
+	case p.session == nil:
+		content = "Select a session"
+	case p.session.ParentID == "":
+		content = "Not a replayed or composed request. Replay a session with r or send one from the composer to compare them here"
+	case p.parent == nil:
+		content = fmt.Sprintf("The original session %s is no longer stored", p.session.ParentID)
+	default:
+		content = p.formatDiff()
+	}
+	p.viewport.SetContent(lipgloss.NewStyle().Width(p.viewport.Width).Render(content))
+}
+
+func (p *DiffPanel) formatDiff() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Compared with %s %s (%s)\n\n",
+		p.parent.Request.Method, p.parent.Request.URL, p.parent.Timestamp.Format("15:04:05")))
+
+	sb.WriteString("Request:\n")
+	sb.WriteString(formatRequestDiff(p.parent.RequestDifferences(p.session)))
+
+	width := (p.viewport.Width - 3) / 2
+	sb.WriteString("\nResponse:\n")
+	diff := p.parent.ResponseDifferences(p.session)
+	if !diff.HasDiffs {
+		sb.WriteString(" No differences\n")
+	}
+	sb.WriteString(sideBySide(width, sessiondata.LineEqual, "Original", "This session"))
+
+	sb.WriteString(sideBySide(width, fieldOp(diff.Status), diff.Status.Original, diff.Status.Other))
+	if p.parent.Duration > 0 || p.session.Duration > 0 {
+		sb.WriteString(sideBySide(width, sessiondata.LineEqual, p.parent.Duration.String(), p.session.Duration.String()))
+	}
+
+	sb.WriteString("\n")
+	for _, row := range headerRows(p.parent.Response, p.session.Response) {
+		sb.WriteString(sideBySide(width, row.Op, row.Original, row.Other))
+	}
+
+	sb.WriteString("\n")
+	for _, row := range sessiondata.DiffLines(diff.Body.Original, diff.Body.Other) {
+		sb.WriteString(sideBySide(width, row.Op, row.Original, row.Other))
+	}
+	return sb.String()
+}
+
+func formatRequestDiff(diff *sessiondata.RequestDifference) string {
+	if !diff.HasDiffs {
+		return " No differences\n"
+	}
+
+	var sb strings.Builder
+	for _, field := range []struct {
+		name string
+		diff *sessiondata.FieldDiff
+	}{
+		{"Method", diff.Method},
+		{"URL", diff.URL},
+		{"Content-Type", diff.ContentType},
+	} {
+		if field.diff.Changed {
+			sb.WriteString(diffChangedStyle.Render(fmt.Sprintf(" ~ %s: %s → %s", field.name, field.diff.Original, field.diff.Other)) + "\n")
+		}
+	}
+	sb.WriteString(formatChanges("Header", diff.Headers.Added, diff.Headers.Removed, diff.Headers.Modified))
+	sb.WriteString(formatChanges("Cookie", diff.Cookies.Added, diff.Cookies.Removed, diff.Cookies.Modified))
+	if diff.Body.Changed {
+		sb.WriteString(diffChangedStyle.Render(fmt.Sprintf(" ~ Body: %d → %d bytes", len(diff.Body.Original), len(diff.Body.Other))) + "\n")
+	}
+	return sb.String()
+}
+
+func formatChanges(kind string, added, removed map[string]string, modified map[string]sessiondata.FieldDiff) string {
+	var lines []string
+	for name, value := range removed {
+		lines = append(lines, diffRemovedStyle.Render(fmt.Sprintf(" - %s %s: %s", kind, name, value)))
+	}
+	for name, value := range added {
+		lines = append(lines, diffAddedStyle.Render(fmt.Sprintf(" + %s %s: %s", kind, name, value)))
+	}
+	for name, change := range modified {
+		lines = append(lines, diffChangedStyle.Render(fmt.Sprintf(" ~ %s %s: %s → %s", kind, name, change.Original, change.Other)))
+	}
+	sort.Strings(lines)
+
+	var sb strings.Builder
+	for _, line := range lines {
+		sb.WriteString(line + "\n")
+	}
+	return sb.String()
+}
+
+// headerRows pairs up the response headers of both sessions, in the order of
+// the original followed by the ones only the other has
+func headerRows(original, other *sessiondata.ResponseData) []sessiondata.LineDiff {
+	left, right := responseHeaders(original), responseHeaders(other)
+
+	var rows []sessiondata.LineDiff
+	for _, name := range left.Keys() {
+		value := headerValue(left, name)
+		_, ok := right.Get(name)
+		switch {
+		case !ok:
+			rows = append(rows, sessiondata.LineDiff{Op: sessiondata.LineRemoved, Original: name + ": " + value})
+		case headerValue(right, name) != value:
+			rows = append(rows, sessiondata.LineDiff{Op: sessiondata.LineChanged, Original: name + ": " + value, Other: name + ": " + headerValue(right, name)})
+		default:
+			rows = append(rows, sessiondata.LineDiff{Op: sessiondata.LineEqual, Original: name + ": " + value, Other: name + ": " + value})
+		}
+	}
+	for _, name := range right.Keys() {
+		if _, ok := left.Get(name); !ok {
+			rows = append(rows, sessiondata.LineDiff{Op: sessiondata.LineAdded, Other: name + ": " + headerValue(right, name)})
+		}
+	}
+	return rows
+}
+
+func responseHeaders(resp *sessiondata.ResponseData) *sortedMap.SortedMap {
+	if resp == nil || resp.Headers == nil {
+		return sortedMap.New()
+	}
+	return resp.Headers
+}
+
+func headerValue(headers *sortedMap.SortedMap, name string) string {
+	value, _ := headers.Get(name)
+	if values, ok := value.([]string); ok {
+		return strings.Join(values, ", ")
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func fieldOp(diff *sessiondata.FieldDiff) sessiondata.LineOp {
+	if diff.Changed {
+		return sessiondata.LineChanged
+	}
+	return sessiondata.LineEqual
+}
+
+// sideBySide renders one row of two columns of width, marked and colored by op
+func sideBySide(width int, op sessiondata.LineOp, left, right string) string {
+	marker, style := " ", lipgloss.NewStyle()
+	switch op {
+	case sessiondata.LineRemoved:
+		marker, style = "-", diffRemovedStyle
+	case sessiondata.LineAdded:
+		marker, style = "+", diffAddedStyle
+	case sessiondata.LineChanged:
+		marker, style = "~", diffChangedStyle
+	}
+	return style.Render(marker+column(left, width)+" "+column(right, width)) + "\n"
+}
+
+func column(text string, width int) string {
+	text = truncateString(strings.ReplaceAll(text, "\t", "    "), width)
+	if pad := width - lipgloss.Width(text); pad > 0 {
+		text += strings.Repeat(" ", pad)
+	}
+	return text
+}
@@ -0,0 +1,267 @@
+package panels
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"httpDebugger/pkg/fuzzer"
+	"httpDebugger/pkg/sessiondata"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	fuzzerAnomalyStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("208"))
+	fuzzerSelectedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("63")).Bold(true)
+)
+
+// FuzzerPanel edits a request template with marked insertion points and lists
+// the results of the attack on it, the selected one's response alongside
+type FuzzerPanel struct {
+	editor   textarea.Model
+	response *ResponsePanel
+
+	open        bool
+	showResults bool
+	original    *sessiondata.Session
+
+	results    []fuzzer.Result
+	anomalies  map[int]bool
+	summary    string
+	sortKey    fuzzer.SortKey
+	descending bool
+	cursor     int
+	offset     int
+
+	width  int
+	height int
+}
+
+func NewFuzzerPanel() *FuzzerPanel {
+	ta := textarea.New()
+	ta.ShowLineNumbers = false
+	ta.CharLimit = 0
+	ta.MaxHeight = 0
+
+	return &FuzzerPanel{
+		editor:   ta,
+		response: NewResponsePanel(),
+	}
+}
+
+// Open loads the request of original into the editor for a new attack
+func (p *FuzzerPanel) Open(original *sessiondata.Session) tea.Cmd {
+	p.open = true
+	p.original = original
+	p.showResults = false
+	p.UpdateResults(nil, "")
+	p.Reset()
+	p.editor.Focus()
+	return textarea.Blink
+}
+
+// Reopen shows the panel again as it was left
+func (p *FuzzerPanel) Reopen() {
+	p.open = true
+}
+
+func (p *FuzzerPanel) Close() {
+	p.open = false
+	p.editor.Blur()
+}
+
+func (p *FuzzerPanel) IsOpen() bool {
+	return p.open
+}
+
+// Original returns the session the attack is made from
+func (p *FuzzerPanel) Original() *sessiondata.Session {
+	return p.original
+}
+
+// Reset puts the unmarked request back into the editor
+func (p *FuzzerPanel) Reset() {
+	if p.original != nil && p.original.Request != nil {
+		p.editor.SetValue(sessiondata.FormatRawRequest(p.original.Request))
+	}
+}
+
+// Template returns the request with its insertion points
+func (p *FuzzerPanel) Template() string {
+	return p.editor.Value()
+}
+
+// InsertMarker types an insertion point marker at the cursor
+func (p *FuzzerPanel) InsertMarker() {
+	p.editor.InsertString(fuzzer.Marker)
+}
+
+// ShowingResults reports whether the results are shown instead of the editor
+func (p *FuzzerPanel) ShowingResults() bool {
+	return p.showResults
+}
+
+// ShowResults switches between the results and the editor
+func (p *FuzzerPanel) ShowResults(show bool) {
+	p.showResults = show
+	if show {
+		p.editor.Blur()
+	} else {
+		p.editor.Focus()
+	}
+}
+
+// UpdateResults shows the latest results of the attack, in the current order
+func (p *FuzzerPanel) UpdateResults(results []fuzzer.Result, summary string) {
+	selected := -1
+	if r := p.Selected(); r != nil {
+		selected = r.Attempt.Index
+	}
+
+	p.results = results
+	p.summary = summary
+	p.anomalies = fuzzer.Anomalies(results)
+	fuzzer.SortResults(p.results, p.sortKey, p.descending)
+
+	p.cursor = 0
+	for i, r := range p.results {
+		if r.Attempt.Index == selected {
+			p.cursor = i
+		}
+	}
+	p.showSelected()
+}
+
+// NextSort sorts the results by the next column
+func (p *FuzzerPanel) NextSort() {
+	p.sortKey = p.sortKey.Next()
+	// Large values stand out first, the attempt order reads top down
+	p.descending = p.sortKey != fuzzer.SortIndex
+	p.UpdateResults(p.results, p.summary)
+}
+
+// ReverseSort flips the order of the results
+func (p *FuzzerPanel) ReverseSort() {
+	p.descending = !p.descending
+	p.UpdateResults(p.results, p.summary)
+}
+
+// Selected returns the highlighted result
+func (p *FuzzerPanel) Selected() *fuzzer.Result {
+	if p.cursor < 0 || p.cursor >= len(p.results) {
+		return nil
+	}
+	return &p.results[p.cursor]
+}
+
+// MoveCursor moves the highlight by delta rows
+func (p *FuzzerPanel) MoveCursor(delta int) {
+	p.cursor = max(0, min(p.cursor+delta, len(p.results)-1))
+	p.showSelected()
+}
+
+// ScrollResponse passes a scroll key to the response of the selected result
+func (p *FuzzerPanel) ScrollResponse(msg tea.Msg) tea.Cmd {
+	return p.response.Update(msg)
+}
+
+func (p *FuzzerPanel) showSelected() {
+	if r := p.Selected(); r != nil && r.Session != nil {
+		p.response.UpdateSession(r.Session)
+	} else {
+		p.response.UpdateSession(nil)
+	}
+	p.response.SetSize(p.response.viewport.Width, p.response.viewport.Height)
+}
+
+func (p *FuzzerPanel) Title() string {
+	if p.original == nil || p.original.Request == nil {
+		return "Fuzzer"
+	}
+	return fmt.Sprintf("Fuzz %s %s", p.original.Request.Method, p.original.Request.URL)
+}
+
+func (p *FuzzerPanel) Update(msg tea.Msg) tea.Cmd {
+	var cmd tea.Cmd
+	p.editor, cmd = p.editor.Update(msg)
+	return cmd
+}
+
+func (p *FuzzerPanel) View() string {
+	if !p.showResults {
+		return p.editor.View()
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top, p.renderResults(), "  ", p.response.View())
+}
+
+func (p *FuzzerPanel) renderResults() string {
+	muted := lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+	listW := p.listWidth()
+
+	order := "↑"
+	if p.descending {
+		order = "↓"
+	}
+	lines := []string{
+		p.summary,
+		muted.Render(fmt.Sprintf("Sorted by %s %s • %d anomalies", p.sortKey, order, len(p.anomalies))),
+		"",
+		fmt.Sprintf("%5s  %-6s %8s %8s %7s  %s", "#", "Status", "Length", "Time", "Matches", "Payloads"),
+	}
+
+	rows := max(p.height-len(lines), 1)
+	if p.cursor < p.offset {
+		p.offset = p.cursor
+	} else if p.cursor >= p.offset+rows {
+		p.offset = p.cursor - rows + 1
+	}
+
+	for i := p.offset; i < len(p.results) && i < p.offset+rows; i++ {
+		line := formatFuzzerResult(p.results[i])
+		if runes := []rune(line); len(runes) > listW {
+			line = string(runes[:listW])
+		}
+		switch {
+		case i == p.cursor:
+			line = fuzzerSelectedStyle.Render(line)
+		case !p.results[i].Done:
+			line = muted.Render(line)
+		case p.anomalies[p.results[i].Attempt.Index]:
+			line = fuzzerAnomalyStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	if len(p.results) == 0 {
+		lines = append(lines, muted.Render("No results yet"))
+	}
+
+	return lipgloss.NewStyle().Width(listW).Height(p.height).Render(strings.Join(lines, "\n"))
+}
+
+func formatFuzzerResult(r fuzzer.Result) string {
+	payloads := strings.Join(r.Attempt.Payloads, " | ")
+	if !r.Done {
+		return fmt.Sprintf("%5d  %-6s %8s %8s %7s  %s", r.Attempt.Index+1, "...", "", "", "", payloads)
+	}
+	status := fmt.Sprintf("%d", r.Status)
+	if r.Error != "" {
+		status = "error"
+	}
+	return fmt.Sprintf("%5d  %-6s %8d %8v %7d  %s", r.Attempt.Index+1, status, r.Length, r.Duration.Round(time.Millisecond), r.Matches, payloads)
+}
+
+func (p *FuzzerPanel) listWidth() int {
+	return max(p.width/2, 0)
+}
+
+func (p *FuzzerPanel) SetSize(width, height int) {
+	p.width = width
+	p.height = height
+
+	p.editor.SetWidth(width)
+	p.editor.SetHeight(height)
+	p.response.SetSize(max(width-p.listWidth()-2, 0), max(height, 0))
+}
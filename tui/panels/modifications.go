@@ -0,0 +1,41 @@
+package panels
+
+import (
+	"fmt"
+	"strings"
+
+	"httpDebugger/pkg/sessiondata"
+	"httpDebugger/pkg/sortedMap"
+)
+
+// formatModifications lists the rewrite rule changes made at stage, followed by the
+// headers and body as they were before the rules ran
+func formatModifications(mods []sessiondata.Modification, stage string, headers *sortedMap.SortedMap, body string) string {
+	var sb strings.Builder
+	sb.WriteString("\n\nModified by rewrite rules:\n")
+	for _, mod := range mods {
+		if mod.Stage == stage {
+			sb.WriteString(fmt.Sprintf(" [%s] %s %s\n", mod.Rule, mod.Action, mod.Detail))
+		}
+	}
+
+	sb.WriteString("\nOriginal headers:\n")
+	if headers != nil {
+		for _, key := range headers.Order {
+			if value, ok := headers.Entries[key]; ok {
+				if slice, ok := value.([]string); ok {
+					sb.WriteString(fmt.Sprintf(" %s: %s\n", key, strings.Join(slice, ", ")))
+				} else {
+					sb.WriteString(fmt.Sprintf(" %s: %s\n", key, value))
+				}
+			}
+		}
+	}
+
+	if len(body) > 0 {
+		sb.WriteString(fmt.Sprintf("\nOriginal body (%d bytes):\n%s", len(body), body))
+	} else {
+		sb.WriteString("\nNo original body")
+	}
+	return sb.String()
+}
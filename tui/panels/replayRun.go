@@ -0,0 +1,54 @@
+package panels
+
+import (
+	"httpDebugger/pkg/replayRunner"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ReplayRunPanel shows the progress and statistics of a bulk replay
+type ReplayRunPanel struct {
+	viewport viewport.Model
+	open     bool
+	summary  string
+}
+
+func NewReplayRunPanel() *ReplayRunPanel {
+	return &ReplayRunPanel{viewport: viewport.New(0, 0)}
+}
+
+func (p *ReplayRunPanel) Open() {
+	p.open = true
+}
+
+func (p *ReplayRunPanel) Close() {
+	p.open = false
+}
+
+func (p *ReplayRunPanel) IsOpen() bool {
+	return p.open
+}
+
+// UpdateSummary shows the latest statistics of the run
+func (p *ReplayRunPanel) UpdateSummary(summary replayRunner.Summary) {
+	p.summary = summary.String()
+	p.viewport.SetContent(lipgloss.NewStyle().Width(p.viewport.Width).Render(p.summary))
+}
+
+func (p *ReplayRunPanel) Update(msg tea.Msg) tea.Cmd {
+	var cmd tea.Cmd
+	p.viewport, cmd = p.viewport.Update(msg)
+	return cmd
+}
+
+func (p *ReplayRunPanel) View() string {
+	return p.viewport.View()
+}
+
+func (p *ReplayRunPanel) SetSize(width, height int) {
+	p.viewport.Width = width
+	p.viewport.Height = height
+	p.viewport.SetContent(lipgloss.NewStyle().Width(width).Render(p.summary))
+}
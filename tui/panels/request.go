@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"strings"
 
+	"httpDebugger/pkg/mapping"
+	"httpDebugger/pkg/rewrite"
 	"httpDebugger/pkg/sessiondata"
 
 	"github.com/charmbracelet/bubbles/viewport"
@@ -51,11 +53,28 @@ func (p *RequestPanel) UpdateSession(session *sessiondata.Session) {
 		p.viewport.SetContent(lipgloss.NewStyle().Width(p.viewport.Width).Render(p.rawContent))
 		return
 	}
+	if session.Tunnel != nil {
+		p.rawContent = formatTunnel(session, "Sent by client", session.Tunnel.BytesSent, session.Tunnel.ClientPreview)
+		p.viewport.SetContent(lipgloss.NewStyle().Width(p.viewport.Width).Render(p.rawContent))
+		return
+	}
 	details := fmt.Sprintf("Method: %s\nURL: %s\nTimestamp: %s\n\n",
 		session.Request.Method,
 		session.Request.URL,
 		session.Timestamp.Format("2006-01-02 15:04:05"))
 
+	if mapped := session.Mapping; mapped != nil {
+		if mapped.Kind == mapping.KindLocal.String() {
+			details += fmt.Sprintf("Mocked by Map Local from %s\n\n", mapped.Target)
+		} else {
+			details += fmt.Sprintf("Sent to %s by Map Remote\n\n", mapped.Target)
+		}
+	}
+
+	if session.ParentID != "" {
+		details += fmt.Sprintf("Composed from session %s\n\n", session.ParentID)
+	}
+
 	details += "Headers:\n"
 	for _, key := range session.Request.Headers.Order {
 		if value, ok := session.Request.Headers.Entries[key]; ok {
@@ -75,6 +94,13 @@ func (p *RequestPanel) UpdateSession(session *sessiondata.Session) {
 		details += "\nNo body"
 	}
 
+	if original := session.OriginalRequest; original != nil {
+		if original.Method != session.Request.Method || original.URL != session.Request.URL {
+			details += fmt.Sprintf("\n\nSent by client: %s %s", original.Method, original.URL)
+		}
+		details += formatModifications(session.Modifications, rewrite.StageRequest, original.Headers, original.Body)
+	}
+
 	p.rawContent = details
 
 	wrappedContent := lipgloss.NewStyle().Width(p.viewport.Width).Render(details)
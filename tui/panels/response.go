@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"httpDebugger/pkg/rewrite"
 	"httpDebugger/pkg/sessiondata"
 
 	"github.com/charmbracelet/bubbles/viewport"
@@ -52,6 +53,12 @@ func (p *ResponsePanel) UpdateSession(session *sessiondata.Session) {
 		return
 	}
 
+	if session.Tunnel != nil {
+		p.rawContent = formatTunnel(session, "Sent by server", session.Tunnel.BytesReceived, session.Tunnel.ServerPreview)
+		p.viewport.SetContent(lipgloss.NewStyle().Width(p.viewport.Width).Render(p.rawContent))
+		return
+	}
+
 	if session.Response == nil {
 		p.rawContent = "Response not found"
 		p.viewport.SetContent(lipgloss.NewStyle().Width(p.viewport.Width).Render(p.rawContent))
@@ -83,6 +90,13 @@ func (p *ResponsePanel) UpdateSession(session *sessiondata.Session) {
 		details += "\nNo body"
 	}
 
+	if original := session.OriginalResponse; original != nil {
+		if original.StatusCode != session.Response.StatusCode {
+			details += fmt.Sprintf("\n\nSent by server: %s", original.Status)
+		}
+		details += formatModifications(session.Modifications, rewrite.StageResponse, original.Headers, original.Body)
+	}
+
 	p.rawContent = details
 	wrappedContent := lipgloss.NewStyle().Width(p.viewport.Width).Render(details)
 	p.viewport.SetContent(wrappedContent)
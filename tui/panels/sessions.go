@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"httpDebugger/pkg/mapping"
 	"httpDebugger/pkg/sessiondata"
 
 	"github.com/charmbracelet/bubbles/list"
@@ -109,6 +110,13 @@ func (i sessionListItem) Title() string {
 	}
 
 	raw := fmt.Sprintf("%s %s%s", i.session.Request.Method, i.session.Request.URL, status)
+	if mapped := i.session.Mapping; mapped != nil {
+		if mapped.Kind == mapping.KindLocal.String() {
+			raw = "[MOCK] " + raw
+		} else {
+			raw = "[MAPPED] " + raw
+		}
+	}
 
 	safeWidth := i.width - 6
 	if safeWidth < 0 {
@@ -132,6 +140,25 @@ func (i sessionListItem) Description() string {
 		sessionType = "WSS"
 		return truncateString(fmt.Sprintf("Type: %s", sessionType), safeWidth)
 	}
+	if tunnel := i.session.Tunnel; tunnel != nil {
+		tunnelType := "TCP"
+		if tunnel.Passthrough != "" {
+			tunnelType = fmt.Sprintf("TLS passthrough (%s)", tunnel.Passthrough)
+		}
+		raw := fmt.Sprintf("Type: %s | Sent: %d B | Received: %d B | %s",
+			tunnelType,
+			tunnel.BytesSent,
+			tunnel.BytesReceived,
+			i.session.Timestamp.Format("15:04:05"))
+		return truncateString(raw, safeWidth)
+	}
+
+	if client := i.session.Client; client != nil {
+		sessionType = fmt.Sprintf("%s | Client: %s", sessionType, client.Name)
+	}
+	if i.session.ParentID != "" {
+		sessionType += " | Composed"
+	}
 
 	if i.session.Response != nil && i.session.Response.ContentType != "" {
 		raw := fmt.Sprintf("Type: %s | Content type: %s | Duration: %s | %s",
@@ -1,8 +1,10 @@
 package panels
 
 import (
+	"crypto/tls"
 	"fmt"
 	"strings"
+	"time"
 
 	"httpDebugger/pkg/sessiondata"
 
@@ -50,21 +52,65 @@ func (p *TLSPanel) UpdateSession(session *sessiondata.Session) {
 		return
 	}
 
-	if session.TLSFingerprint == nil {
+	if session.TLSFingerprint == nil && session.UpstreamTLS == nil && session.JA4H == "" && session.Client == nil {
 		p.rawContent = "No TLS data (unencrypted HTTP or WS)"
 		p.viewport.SetContent(lipgloss.NewStyle().Width(p.viewport.Width).Render(p.rawContent))
 		return
 	}
 
-	fp := session.TLSFingerprint
 	var content strings.Builder
+	if session.TLSFingerprint != nil {
+		writeClientTLS(&content, session)
+	}
+	if session.JA4H != "" {
+		if content.Len() > 0 {
+			content.WriteString("\n")
+		}
+		content.WriteString("HTTP Fingerprint\n")
+		content.WriteString("────────────────────────────────────────\n\n")
+		content.WriteString(fmt.Sprintf("JA4H: %s\n", session.JA4H))
+	}
+	if client := session.Client; client != nil {
+		if content.Len() > 0 {
+			content.WriteString("\n")
+		}
+		content.WriteString("Client\n")
+		content.WriteString("────────────────────────────────────────\n\n")
+		content.WriteString(fmt.Sprintf("Identified as: %s (%.0f%%)\n", client.Name, client.Confidence*100))
+		if len(client.MatchedOn) > 0 {
+			content.WriteString(fmt.Sprintf("Matched on: %s\n", strings.Join(client.MatchedOn, ", ")))
+		}
+	}
+	if session.UpstreamTLS != nil {
+		if content.Len() > 0 {
+			content.WriteString("\n")
+		}
+		writeUpstreamTLS(&content, session.UpstreamTLS)
+	}
+
+	p.rawContent = content.String()
+	wrappedContent := lipgloss.NewStyle().Width(p.viewport.Width).Render(p.rawContent)
+	p.viewport.SetContent(wrappedContent)
+}
+
+// writeClientTLS describes the client's ClientHello and HTTP/2 fingerprint
+func writeClientTLS(content *strings.Builder, session *sessiondata.Session) {
+	fp := session.TLSFingerprint
 
 	content.WriteString(fmt.Sprintf("TLS Fingerprint: %s\n", session.Request.URL))
 	content.WriteString("────────────────────────────────────────\n\n")
 
 	content.WriteString(fmt.Sprintf("JA3 Hash:   %s\n", fp.JA3Hash))
-	content.WriteString(fmt.Sprintf("JA3 String: %s\n\n", fp.JA3))
+	content.WriteString(fmt.Sprintf("JA3 String: %s\n", fp.JA3))
+	if fp.JA4 != "" {
+		content.WriteString(fmt.Sprintf("JA4:        %s\n", fp.JA4))
+		content.WriteString(fmt.Sprintf("JA4 Raw:    %s\n", fp.JA4Raw))
+	}
+	content.WriteString("\n")
 
+	if fp.ServerName != "" {
+		content.WriteString(fmt.Sprintf("SNI: %s\n", fp.ServerName))
+	}
 	content.WriteString(fmt.Sprintf("TLS Version: 0x%04X (%s)\n", fp.TLSVersion, tlsVersionName(fp.TLSVersion)))
 
 	if len(fp.SupportedVersions) > 0 {
@@ -153,9 +199,126 @@ func (p *TLSPanel) UpdateSession(session *sessiondata.Session) {
 		content.WriteString(fmt.Sprintf("Record Size Limit: %d\n", fp.RecordSizeLimit))
 	}
 
-	p.rawContent = content.String()
-	wrappedContent := lipgloss.NewStyle().Width(p.viewport.Width).Render(p.rawContent)
-	p.viewport.SetContent(wrappedContent)
+	if h2 := session.HTTP2Fingerprint; h2 != nil {
+		content.WriteString("\nHTTP/2 Fingerprint\n")
+		content.WriteString("────────────────────────────────────────\n\n")
+		content.WriteString(fmt.Sprintf("Akamai Hash:   %s\n", h2.AkamaiHash))
+		content.WriteString(fmt.Sprintf("Akamai String: %s\n\n", h2.Akamai))
+
+		if len(h2.Settings) > 0 {
+			content.WriteString(fmt.Sprintf("Settings (%d):\n", len(h2.Settings)))
+			for _, setting := range h2.Settings {
+				content.WriteString(fmt.Sprintf("  %-28s %d\n", http2SettingName(setting.ID), setting.Value))
+			}
+			content.WriteString("\n")
+		}
+
+		content.WriteString(fmt.Sprintf("Window Update: %d\n", h2.WindowUpdate))
+		content.WriteString(fmt.Sprintf("Pseudo-Header Order: %s\n", strings.Join(h2.PseudoHeaderOrder, ", ")))
+		if h2.HeaderPriority != nil {
+			content.WriteString(fmt.Sprintf("Headers Priority: exclusive=%t depends_on=%d weight=%d\n",
+				h2.HeaderPriority.Exclusive, h2.HeaderPriority.DependsOn, int(h2.HeaderPriority.Weight)+1))
+		}
+		if len(h2.Priorities) > 0 {
+			content.WriteString(fmt.Sprintf("Priority Frames (%d):\n", len(h2.Priorities)))
+			for _, p := range h2.Priorities {
+				content.WriteString(fmt.Sprintf("  stream=%d exclusive=%t depends_on=%d weight=%d\n",
+					p.StreamID, p.Exclusive, p.DependsOn, int(p.Weight)+1))
+			}
+		}
+	}
+}
+
+// writeUpstreamTLS describes the proxy's connection to the server and the
+// certificate chain the server sent
+func writeUpstreamTLS(content *strings.Builder, upstream *sessiondata.UpstreamTLS) {
+	content.WriteString("Server TLS\n")
+	content.WriteString("────────────────────────────────────────\n\n")
+
+	if upstream.VerificationError != "" {
+		content.WriteString(fmt.Sprintf("Verification failed: %s\n", upstream.VerificationError))
+	} else {
+		content.WriteString(fmt.Sprintf("TLS Version: 0x%04X (%s)\n", upstream.Version, tlsVersionName(upstream.Version)))
+		content.WriteString(fmt.Sprintf("Cipher Suite: 0x%04X  %s\n", upstream.CipherSuite, tls.CipherSuiteName(upstream.CipherSuite)))
+		alpn := upstream.ALPN
+		if alpn == "" {
+			alpn = "none"
+		}
+		content.WriteString(fmt.Sprintf("ALPN: %s\n", alpn))
+		if upstream.ServerName != "" {
+			content.WriteString(fmt.Sprintf("SNI: %s\n", upstream.ServerName))
+		}
+		if upstream.Resumed {
+			content.WriteString("Session resumed\n")
+		}
+	}
+
+	if hello := upstream.ServerHello; hello != nil {
+		content.WriteString(fmt.Sprintf("\nJA3S Hash:   %s\n", hello.JA3SHash))
+		content.WriteString(fmt.Sprintf("JA3S String: %s\n", hello.JA3S))
+		content.WriteString(fmt.Sprintf("JA4S:        %s\n", hello.JA4S))
+		if len(hello.Extensions) > 0 {
+			names := make([]string, 0, len(hello.Extensions))
+			for _, ext := range hello.Extensions {
+				if name := extensionName(ext); name != "" {
+					names = append(names, name)
+				} else {
+					names = append(names, fmt.Sprintf("0x%04X", ext))
+				}
+			}
+			content.WriteString(fmt.Sprintf("Extensions (%d): %s\n", len(hello.Extensions), strings.Join(names, ", ")))
+		}
+		content.WriteString("\n")
+	}
+
+	switch ocsp := upstream.OCSP; {
+	case ocsp == nil:
+		content.WriteString("OCSP Stapling: none\n")
+	case ocsp.Error != "":
+		content.WriteString(fmt.Sprintf("OCSP Stapling: invalid response (%s)\n", ocsp.Error))
+	default:
+		content.WriteString(fmt.Sprintf("OCSP Stapling: %s, next update %s\n", ocsp.Status, formatCertTime(ocsp.NextUpdate)))
+		if !ocsp.RevokedAt.IsZero() {
+			content.WriteString(fmt.Sprintf("  Revoked at: %s\n", formatCertTime(ocsp.RevokedAt)))
+		}
+	}
+
+	if len(upstream.Certificates) == 0 {
+		return
+	}
+	content.WriteString(fmt.Sprintf("\nCertificate Chain (%d):\n", len(upstream.Certificates)))
+	now := time.Now()
+	for i, cert := range upstream.Certificates {
+		validity := "valid"
+		switch {
+		case now.Before(cert.NotBefore):
+			validity = "NOT YET VALID"
+		case now.After(cert.NotAfter):
+			validity = "EXPIRED"
+		}
+
+		content.WriteString(fmt.Sprintf("\n  [%d] %s\n", i, cert.Subject))
+		content.WriteString(fmt.Sprintf("      Issuer:     %s\n", cert.Issuer))
+		if names := append(append([]string{}, cert.DNSNames...), cert.IPAddresses...); len(names) > 0 {
+			content.WriteString(fmt.Sprintf("      SANs:       %s\n", strings.Join(names, ", ")))
+		}
+		content.WriteString(fmt.Sprintf("      Validity:   %s to %s (%s)\n",
+			formatCertTime(cert.NotBefore), formatCertTime(cert.NotAfter), validity))
+		content.WriteString(fmt.Sprintf("      Key:        %s, signed with %s\n", cert.KeyType, cert.SignatureAlgorithm))
+		if cert.IsCA {
+			content.WriteString("      CA:         yes\n")
+		}
+		content.WriteString(fmt.Sprintf("      Serial:     %s\n", cert.SerialNumber))
+		content.WriteString(fmt.Sprintf("      SHA-256:    %s\n", cert.SHA256))
+		content.WriteString(fmt.Sprintf("      SHA-1:      %s\n", cert.SHA1))
+	}
+}
+
+func formatCertTime(t time.Time) string {
+	if t.IsZero() {
+		return "unknown"
+	}
+	return t.UTC().Format("2006-01-02 15:04 MST")
 }
 
 func tlsVersionName(v uint16) string {
@@ -277,6 +440,29 @@ func signatureAlgName(id uint16) string {
 	return ""
 }
 
+func http2SettingName(id uint16) string {
+	switch id {
+	case 0x1:
+		return "HEADER_TABLE_SIZE"
+	case 0x2:
+		return "ENABLE_PUSH"
+	case 0x3:
+		return "MAX_CONCURRENT_STREAMS"
+	case 0x4:
+		return "INITIAL_WINDOW_SIZE"
+	case 0x5:
+		return "MAX_FRAME_SIZE"
+	case 0x6:
+		return "MAX_HEADER_LIST_SIZE"
+	case 0x8:
+		return "ENABLE_CONNECT_PROTOCOL"
+	case 0x9:
+		return "NO_RFC7540_PRIORITIES"
+	default:
+		return fmt.Sprintf("0x%X", id)
+	}
+}
+
 func certCompName(id uint16) string {
 	switch id {
 	case 1:
@@ -0,0 +1,56 @@
+package panels
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"httpDebugger/pkg/sessiondata"
+)
+
+// formatTunnel describes one direction of a relayed TCP stream with a preview
+// of its first bytes, as text when printable and as a hex dump otherwise
+func formatTunnel(session *sessiondata.Session, label string, total int64, preview []byte) string {
+	tunnel := session.Tunnel
+
+	state := "open"
+	if !tunnel.Open() {
+		state = fmt.Sprintf("closed after %v", session.Duration)
+	}
+	details := fmt.Sprintf("TCP tunnel to %s (%s)\nTimestamp: %s\n", tunnel.Target, state,
+		session.Timestamp.Format("2006-01-02 15:04:05"))
+	if tunnel.Passthrough != "" {
+		details = fmt.Sprintf("TLS passthrough to %s (%s)\nReason: %s\nTimestamp: %s\n", tunnel.Target, state,
+			tunnel.Passthrough, session.Timestamp.Format("2006-01-02 15:04:05"))
+		if fp := session.TLSFingerprint; fp != nil && fp.ServerName != "" {
+			details += fmt.Sprintf("SNI: %s\n", fp.ServerName)
+		}
+	}
+	if session.Error != nil {
+		details += fmt.Sprintf("Error: %v\n", session.Error)
+	}
+	details += fmt.Sprintf("\n%s: %d bytes\n", label, total)
+
+	switch {
+	case tunnel.Open():
+		details += "\nPreview available once the tunnel closes"
+	case len(preview) == 0:
+		details += "\nNo data"
+	case isPrintable(preview):
+		details += fmt.Sprintf("\nFirst %d bytes:\n%s", len(preview), string(preview))
+	default:
+		details += fmt.Sprintf("\nFirst %d bytes:\n%s", len(preview), hex.Dump(preview))
+	}
+	return details
+}
+
+func isPrintable(data []byte) bool {
+	if !utf8.Valid(data) {
+		return false
+	}
+	return strings.IndexFunc(string(data), func(r rune) bool {
+		return !unicode.IsPrint(r) && !unicode.IsSpace(r)
+	}) < 0
+}
@@ -76,6 +76,12 @@ func (p *WebSocketPanel) UpdateSession(session *sessiondata.Session) {
 	p.headerContent = fmt.Sprintf("WebSocket: %s\nConnected: %s",
 		session.Request.URL,
 		session.Timestamp.Format("15:04:05"))
+	if session.WebSocket.Subprotocol != "" {
+		p.headerContent += "\nSubprotocol: " + session.WebSocket.Subprotocol
+	}
+	if len(session.WebSocket.Extensions) > 0 {
+		p.headerContent += "\nExtensions: " + strings.Join(session.WebSocket.Extensions, ", ")
+	}
 
 	var content strings.Builder
 
@@ -94,7 +100,7 @@ func (p *WebSocketPanel) UpdateSession(session *sessiondata.Session) {
 			}
 
 			styledDir := lipgloss.NewStyle().Foreground(lipgloss.Color(dirColor)).Render(direction)
-			content.WriteString(fmt.Sprintf("%s %s %s\n", timestamp, styledDir, msg.PayloadText))
+			content.WriteString(fmt.Sprintf("%s %s %s\n", timestamp, styledDir, formatWSMessage(msg)))
 		}
 	}
 
@@ -0,0 +1,249 @@
+package panels
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"httpDebugger/pkg/sessiondata"
+	"httpDebugger/pkg/websocket"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// WSMessagePanel edits a WebSocket message held by an intercept rule, or a new
+// one to inject into a live connection. Binary payloads are edited as hex
+type WSMessagePanel struct {
+	editor textarea.Model
+
+	pending   *websocket.Pending
+	session   *sessiondata.Session
+	direction sessiondata.MessageDirection
+	opcode    byte
+	original  string
+}
+
+func NewWSMessagePanel() *WSMessagePanel {
+	ta := textarea.New()
+	ta.ShowLineNumbers = false
+	ta.CharLimit = 0
+	ta.MaxHeight = 0
+
+	return &WSMessagePanel{editor: ta}
+}
+
+// OpenHeld loads a held message into the editor
+func (p *WSMessagePanel) OpenHeld(pending *websocket.Pending) tea.Cmd {
+	p.pending = pending
+	p.session = pending.Session
+	p.direction = pending.Direction
+	p.opcode = pending.Opcode
+	p.original = formatWSPayload(pending.Opcode, pending.Payload)
+	p.editor.SetValue(p.original)
+	p.editor.Focus()
+	return textarea.Blink
+}
+
+// OpenInject starts a new text message to the server of session
+func (p *WSMessagePanel) OpenInject(session *sessiondata.Session) tea.Cmd {
+	p.pending = nil
+	p.session = session
+	p.direction = sessiondata.Outbound
+	p.opcode = websocket.OpText
+	p.original = ""
+	p.editor.SetValue("")
+	p.editor.Focus()
+	return textarea.Blink
+}
+
+func (p *WSMessagePanel) Close() {
+	p.pending = nil
+	p.session = nil
+	p.editor.Blur()
+}
+
+func (p *WSMessagePanel) IsOpen() bool {
+	return p.session != nil
+}
+
+// Pending returns the held message being edited, nil when injecting
+func (p *WSMessagePanel) Pending() *websocket.Pending {
+	return p.pending
+}
+
+func (p *WSMessagePanel) Session() *sessiondata.Session {
+	return p.session
+}
+
+func (p *WSMessagePanel) Direction() sessiondata.MessageDirection {
+	return p.direction
+}
+
+// ToggleDirection switches an injected message between the server and the client
+func (p *WSMessagePanel) ToggleDirection() {
+	if p.pending != nil {
+		return
+	}
+	if p.direction == sessiondata.Outbound {
+		p.direction = sessiondata.Inbound
+	} else {
+		p.direction = sessiondata.Outbound
+	}
+}
+
+// ToggleType switches between a text and a binary message, converting the
+// editor content between text and hex
+func (p *WSMessagePanel) ToggleType() error {
+	payload, err := p.Payload()
+	if err != nil {
+		return err
+	}
+	if p.opcode == websocket.OpBinary {
+		p.opcode = websocket.OpText
+	} else {
+		p.opcode = websocket.OpBinary
+	}
+	p.editor.SetValue(formatWSPayload(p.opcode, payload))
+	return nil
+}
+
+// Opcode returns the type of message the editor holds
+func (p *WSMessagePanel) Opcode() byte {
+	return p.opcode
+}
+
+// Payload parses the editor content
+func (p *WSMessagePanel) Payload() ([]byte, error) {
+	value := p.editor.Value()
+	if p.opcode != websocket.OpBinary {
+		return []byte(value), nil
+	}
+	payload, err := hex.DecodeString(strings.Join(strings.Fields(value), ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex payload: %w", err)
+	}
+	return payload, nil
+}
+
+// Decision returns how to forward the held message, edited when the editor
+// content or message type changed
+func (p *WSMessagePanel) Decision() (websocket.Decision, error) {
+	if p.opcode == p.pending.Opcode && p.editor.Value() == p.original {
+		return websocket.Decision{Action: websocket.ActionForward}, nil
+	}
+	payload, err := p.Payload()
+	if err != nil {
+		return websocket.Decision{}, err
+	}
+	return websocket.Decision{Action: websocket.ActionForward, Edited: true, Opcode: p.opcode, Payload: payload}, nil
+}
+
+func (p *WSMessagePanel) Title() string {
+	if p.session == nil {
+		return "WebSocket message"
+	}
+	kind := "text"
+	if p.opcode == websocket.OpBinary {
+		kind = "binary, hex"
+	}
+	to := "server"
+	if p.direction == sessiondata.Inbound {
+		to = "client"
+	}
+	if p.pending != nil {
+		return fmt.Sprintf("Held %s message to the %s: %s", kind, to, p.session.Request.URL)
+	}
+	return fmt.Sprintf("Inject %s message to the %s: %s", kind, to, p.session.Request.URL)
+}
+
+func (p *WSMessagePanel) Update(msg tea.Msg) tea.Cmd {
+	var cmd tea.Cmd
+	p.editor, cmd = p.editor.Update(msg)
+	return cmd
+}
+
+func (p *WSMessagePanel) View() string {
+	return p.editor.View()
+}
+
+func (p *WSMessagePanel) SetSize(width, height int) {
+	p.editor.SetWidth(width)
+	p.editor.SetHeight(height)
+}
+
+// formatWSPayload renders a payload for editing: text as is, anything else as
+// 16 hex bytes per line
+func formatWSPayload(opcode byte, payload []byte) string {
+	if opcode != websocket.OpBinary {
+		return string(payload)
+	}
+	var lines []string
+	for i := 0; i < len(payload); i += 16 {
+		chunk := payload[i:min(i+16, len(payload))]
+		var bytes []string
+		for _, b := range chunk {
+			bytes = append(bytes, fmt.Sprintf("%02x", b))
+		}
+		lines = append(lines, strings.Join(bytes, " "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+var wsMarkerStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("208"))
+
+// formatWSMessage renders a message for the message list of a WebSocket session
+func formatWSMessage(msg sessiondata.WebSocketMessage) string {
+	var text string
+	switch msg.Type {
+	case sessiondata.TextMessage:
+		text = msg.PayloadText
+	case sessiondata.ContinuationMessage:
+		if utf8.Valid(msg.Payload) {
+			text = string(msg.Payload)
+			break
+		}
+		fallthrough
+	case sessiondata.BinaryMessage:
+		preview := msg.Payload[:min(len(msg.Payload), 32)]
+		text = fmt.Sprintf("[binary %d bytes] %x", msg.Size, preview)
+		if len(preview) < len(msg.Payload) {
+			text += "…"
+		}
+	case sessiondata.CloseMessage:
+		text = "[close]"
+		if code, reason, ok := websocket.CloseFrame(msg.Payload); ok {
+			text = fmt.Sprintf("[close %d] %s", code, reason)
+		}
+	case sessiondata.PingMessage:
+		text = "[ping] " + string(msg.Payload)
+	case sessiondata.PongMessage:
+		text = "[pong] " + string(msg.Payload)
+	}
+
+	var markers []string
+	if msg.Injected {
+		markers = append(markers, "injected")
+	}
+	if msg.Edited {
+		markers = append(markers, "edited")
+	}
+	if msg.Dropped {
+		markers = append(markers, "dropped")
+	}
+	if msg.IsFragment {
+		markers = append(markers, "fragment")
+	}
+	if msg.Compressed {
+		markers = append(markers, "deflate")
+	}
+	if len(msg.Frames) > 1 {
+		markers = append(markers, fmt.Sprintf("%d frames", len(msg.Frames)))
+	}
+	if len(markers) > 0 {
+		text = wsMarkerStyle.Render("["+strings.Join(markers, ", ")+"]") + " " + text
+	}
+	return text
+}
@@ -0,0 +1,185 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"httpDebugger/pkg/session"
+	"httpDebugger/pkg/sessiondata"
+
+	key "github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// PromptAction identifies what the single-line prompt in the status bar is asking for
+type PromptAction int
+
+const (
+	PromptNone PromptAction = iota
+	PromptExportHAR
+	PromptImportHAR
+	PromptBreakpoint
+	PromptMapping
+	PromptLabel
+	PromptFingerprints
+	PromptCopyFingerprint
+	PromptCopyAs
+	PromptReplayRun
+	PromptFuzz
+	PromptWSRule
+	PromptOpenCapture
+)
+
+const defaultHARPath = "capture.har"
+
+type FileResultMsg struct {
+	Message string
+	Error   error
+}
+
+func newPromptInput() textinput.Model {
+	ti := textinput.New()
+	ti.CharLimit = 512
+	return ti
+}
+
+// openPrompt shows the prompt for action, pre-filled with value
+func (m *Model) openPrompt(action PromptAction, label, value string) tea.Cmd {
+	m.promptAction = action
+	m.promptInput.Prompt = label + ": "
+	m.promptInput.SetValue(value)
+	m.promptInput.CursorEnd()
+	m.promptInput.Focus()
+	return textinput.Blink
+}
+
+func (m *Model) closePrompt() {
+	m.promptAction = PromptNone
+	m.promptInput.Blur()
+}
+
+func (m *Model) updatePrompt(msg tea.KeyMsg) tea.Cmd {
+	switch {
+	case key.Matches(msg, key.NewBinding(key.WithKeys("enter"))):
+		action := m.promptAction
+		value := m.promptInput.Value()
+		m.closePrompt()
+		return m.runPrompt(action, value)
+
+	case key.Matches(msg, key.NewBinding(key.WithKeys("esc"))):
+		m.closePrompt()
+		return nil
+	}
+
+	var cmd tea.Cmd
+	m.promptInput, cmd = m.promptInput.Update(msg)
+	return cmd
+}
+
+func (m *Model) runPrompt(action PromptAction, value string) tea.Cmd {
+	if value == "" {
+		return nil
+	}
+
+	switch action {
+	case PromptExportHAR:
+		m.statusMsg = "Exporting HAR..."
+		return m.exportHARCmd(value)
+	case PromptImportHAR:
+		m.statusMsg = "Importing HAR..."
+		return m.importHARCmd(value)
+	case PromptBreakpoint:
+		m.addBreakpoint(value)
+		return clearStatusCmd()
+	case PromptMapping:
+		m.addMapping(value)
+		return clearStatusCmd()
+	case PromptLabel:
+		m.labelClient(value)
+		return clearStatusCmd()
+	case PromptFingerprints:
+		m.updateFingerprints(value)
+		return clearStatusCmd()
+	case PromptCopyFingerprint:
+		m.copyFingerprint(value)
+		return clearStatusCmd()
+	case PromptCopyAs:
+		m.copyAs(value)
+		return clearStatusCmd()
+	case PromptReplayRun:
+		return m.startReplayRun(value)
+	case PromptFuzz:
+		return m.startFuzz(value)
+	case PromptWSRule:
+		m.addWSRule(value)
+		return clearStatusCmd()
+	case PromptOpenCapture:
+		cmd := m.openCaptureCmd(value)
+		m.statusMsg = "Opening capture..."
+		return cmd
+	}
+	return nil
+}
+
+func (m *Model) exportHARCmd(path string) tea.Cmd {
+	store := m.sessionStore
+	return func() tea.Msg {
+		sessions := store.GetPage(0, store.Len())
+
+		f, err := os.Create(path)
+		if err != nil {
+			return FileResultMsg{Error: fmt.Errorf("creating %s: %w", path, err)}
+		}
+		defer f.Close()
+
+		if err := sessiondata.ExportHAR(f, sessions); err != nil {
+			return FileResultMsg{Error: fmt.Errorf("writing %s: %w", path, err)}
+		}
+		return FileResultMsg{Message: fmt.Sprintf("Exported %d sessions to %s", len(sessions), path)}
+	}
+}
+
+func (m *Model) importHARCmd(path string) tea.Cmd {
+	store := m.sessionStore
+	return func() tea.Msg {
+		f, err := os.Open(path)
+		if err != nil {
+			return FileResultMsg{Error: fmt.Errorf("opening %s: %w", path, err)}
+		}
+		defer f.Close()
+
+		sessions, err := sessiondata.ImportHAR(f)
+		if err != nil {
+			return FileResultMsg{Error: err}
+		}
+
+		for _, s := range sessions {
+			if err := store.Store(s); err != nil {
+				return FileResultMsg{Error: fmt.Errorf("storing imported session: %w", err)}
+			}
+		}
+		return FileResultMsg{Message: fmt.Sprintf("Imported %d sessions from %s", len(sessions), path)}
+	}
+}
+
+// openCaptureCmd switches to the capture persisted in dir, creating it when
+// empty. Sessions captured from then on are stored there
+func (m *Model) openCaptureCmd(dir string) tea.Cmd {
+	m.captureDir = dir
+	m.resetSelection()
+	m.showDetails = false
+	store := m.sessionStore
+	return func() tea.Msg {
+		opened, err := session.OpenDiskStore(dir, 0)
+		if err != nil {
+			return FileResultMsg{Error: fmt.Errorf("opening capture %s: %w", dir, err)}
+		}
+
+		if closer, ok := store.Switch(opened).(io.Closer); ok {
+			closer.Close()
+		}
+		return FileResultMsg{Message: fmt.Sprintf("Opened capture %s with %d sessions", dir, opened.Len())}
+	}
+}
@@ -0,0 +1,160 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"httpDebugger/pkg/replayRunner"
+	"httpDebugger/pkg/sessiondata"
+	"httpDebugger/tui/helpers"
+
+	key "github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const defaultReplayRunSpec = "concurrency=1 repeat=1 timing=original speed=1"
+
+// ReplayRunTickMsg refreshes the statistics of a running bulk replay
+type ReplayRunTickMsg struct{}
+
+// openReplayRun shows the running bulk replay, or asks how to start one
+func (m *Model) openReplayRun() tea.Cmd {
+	if m.replayRun != nil && !m.replayRun.Done() {
+		m.replayRunPanel.Open()
+		return nil
+	}
+	return m.openPrompt(PromptReplayRun, "Replay listed sessions (concurrency= rate= repeat= timing=original|fast speed= host= file=)", defaultReplayRunSpec)
+}
+
+// startReplayRun replays the listed sessions, a host group of them or a saved
+// capture as the spec typed in the prompt says
+func (m *Model) startReplayRun(spec string) tea.Cmd {
+	if !m.isRunning {
+		m.errorMsg = "Start the proxy (Ctrl+S) to replay sessions"
+		return clearStatusCmd()
+	}
+
+	opts, selection, err := replayRunner.ParseSpec(spec)
+	if err != nil {
+		m.errorMsg = err.Error()
+		return clearStatusCmd()
+	}
+
+	sessions, err := m.replayRunSessions(selection)
+	if err != nil {
+		m.errorMsg = err.Error()
+		return clearStatusCmd()
+	}
+	if len(sessions) == 0 {
+		m.errorMsg = "No sessions to replay"
+		return clearStatusCmd()
+	}
+
+	listenAddr := m.listenAddr
+	m.replayRun = replayRunner.New(opts, func(ctx context.Context, session *sessiondata.Session) (*sessiondata.Session, error) {
+		return session.Replay(listenAddr)
+	})
+	m.replayRun.Start(context.Background(), sessions)
+
+	m.replayRunPanel.UpdateSummary(m.replayRun.Summary())
+	m.replayRunPanel.Open()
+	m.statusMsg = fmt.Sprintf("Replaying %d sessions (%s)", len(sessions), opts)
+	if m.logger != nil {
+		m.logger.LogInfo(m.statusMsg)
+	}
+	return replayRunTickCmd()
+}
+
+// replayRunSessions returns the full HTTP sessions a bulk replay sends
+func (m *Model) replayRunSessions(selection replayRunner.Selection) ([]*sessiondata.Session, error) {
+	var sessions []*sessiondata.Session
+	if selection.File != "" {
+		f, err := os.Open(selection.File)
+		if err != nil {
+			return nil, fmt.Errorf("opening %s: %w", selection.File, err)
+		}
+		defer f.Close()
+		if sessions, err = sessiondata.ImportHAR(f); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, session := range m.filteredSessions() {
+			sessions = append(sessions, m.fullSession(session))
+		}
+	}
+
+	if selection.Host != "" {
+		sessions = helpers.GroupSessionsByHost(sessions)[selection.Host]
+	}
+
+	var replayable []*sessiondata.Session
+	for _, session := range sessions {
+		if session.Type == sessiondata.HTTPSession && session.Request != nil {
+			replayable = append(replayable, session)
+		}
+	}
+	return replayable, nil
+}
+
+// filteredSessions returns the sessions the list shows
+func (m *Model) filteredSessions() []*sessiondata.Session {
+	if m.compiledFilter == nil {
+		return m.sessions
+	}
+	var filtered []*sessiondata.Session
+	for _, s := range m.sessions {
+		if m.compiledFilter.MatchString(s.Request.URL) {
+			filtered = append(filtered, s)
+		}
+	}
+	return filtered
+}
+
+func replayRunTickCmd() tea.Cmd {
+	return tea.Tick(500*time.Millisecond, func(time.Time) tea.Msg {
+		return ReplayRunTickMsg{}
+	})
+}
+
+func (m *Model) handleReplayRunTick() tea.Cmd {
+	if m.replayRun == nil {
+		return nil
+	}
+	summary := m.replayRun.Summary()
+	m.replayRunPanel.UpdateSummary(summary)
+	if summary.Running {
+		return replayRunTickCmd()
+	}
+
+	m.statusMsg = fmt.Sprintf("Replay run finished: %d requests, %d failed, p50 %v", summary.Completed, summary.Failed, summary.P50.Round(time.Millisecond))
+	if m.logger != nil {
+		m.logger.LogInfo(m.statusMsg)
+	}
+	return tea.Batch(clearStatusCmd(), m.refreshSessionsCmd())
+}
+
+func (m *Model) updateReplayRun(msg tea.KeyMsg) tea.Cmd {
+	switch {
+	case key.Matches(msg, key.NewBinding(key.WithKeys("ctrl+x"))):
+		if m.replayRun != nil && !m.replayRun.Done() {
+			m.replayRun.Stop()
+			m.statusMsg = "Stopping replay run, waiting for requests in flight"
+		}
+		return nil
+
+	case key.Matches(msg, key.NewBinding(key.WithKeys("esc"))):
+		m.replayRunPanel.Close()
+		return nil
+	}
+	return m.replayRunPanel.Update(msg)
+}
+
+func (m *Model) renderReplayRun() string {
+	help := HelpStyle.Render("Ctrl+X: stop • ↑↓: scroll • Esc: close (keeps running, R reopens)")
+	content := ActiveStyle.Copy().Width(m.width - 2).Height(m.height - 4).Render(
+		m.renderPanelTitle("Replay run", true) + "\n\n" + m.replayRunPanel.View(),
+	)
+	return content + "\n" + m.renderStatusBar() + "\n" + help
+}
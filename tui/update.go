@@ -5,8 +5,8 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
-	"os"
 	"regexp"
 	"time"
 
@@ -15,7 +15,6 @@ import (
 	"httpDebugger/pkg/sessiondata"
 	"httpDebugger/tui/helpers"
 
-	"github.com/atotto/clipboard"
 	key "github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
@@ -26,6 +25,42 @@ type TickMsg struct{}
 func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
 
+	if m.breakpointPanel.IsOpen() {
+		if keyMsg, ok := msg.(tea.KeyMsg); ok {
+			return m, m.updateBreakpointEditor(keyMsg)
+		}
+	}
+
+	if m.wsMessagePanel.IsOpen() {
+		if keyMsg, ok := msg.(tea.KeyMsg); ok {
+			return m, m.updateWSMessageEditor(keyMsg)
+		}
+	}
+
+	if m.composerPanel.IsOpen() {
+		if keyMsg, ok := msg.(tea.KeyMsg); ok {
+			return m, m.updateComposer(keyMsg)
+		}
+	}
+
+	if m.replayRunPanel.IsOpen() {
+		if keyMsg, ok := msg.(tea.KeyMsg); ok {
+			return m, m.updateReplayRun(keyMsg)
+		}
+	}
+
+	if m.fuzzerPanel.IsOpen() {
+		if keyMsg, ok := msg.(tea.KeyMsg); ok {
+			return m, m.updateFuzzer(keyMsg)
+		}
+	}
+
+	if m.promptAction != PromptNone {
+		if keyMsg, ok := msg.(tea.KeyMsg); ok {
+			return m, m.updatePrompt(keyMsg)
+		}
+	}
+
 	if m.isSearching {
 		switch msg := msg.(type) {
 		case tea.KeyMsg:
@@ -55,6 +90,12 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		case TickMsg:
 			return m, m.tickCmd()
+
+		case ReplayRunTickMsg:
+			return m, m.handleReplayRunTick()
+
+		case FuzzTickMsg:
+			return m, m.handleFuzzTick()
 		}
 
 		return m, nil
@@ -74,8 +115,8 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.showDetails && m.selectedSession != nil {
 			for _, session := range m.sessions {
 				if session.ID == m.selectedSession.ID {
-					m.selectedSession = session
-					m.updatePanelsForSession(session)
+					m.selectedSession = m.fullSession(session)
+					m.updatePanelsForSession(m.selectedSession)
 					break
 				}
 			}
@@ -106,12 +147,35 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.logger.LogError(msg.Error, "Replay failed")
 			}
 		} else {
-			m.statusMsg = "Replay sent"
+			m.statusMsg = fmt.Sprintf("Replayed: %s, compare it in the Diff tab", msg.Session.Response.Status)
+			if m.logger != nil {
+				m.logger.LogInfo(fmt.Sprintf("Replay: %s -> %s", msg.Session.ParentID, msg.Session.ID))
+			}
+		}
+		return m, tea.Batch(clearStatusCmd(), m.refreshSessionsCmd())
+
+	case ReplayRunTickMsg:
+		return m, m.handleReplayRunTick()
+
+	case FuzzTickMsg:
+		return m, m.handleFuzzTick()
+
+	case ComposeResultMsg:
+		return m, m.handleComposeResult(msg)
+
+	case FileResultMsg:
+		if msg.Error != nil {
+			m.errorMsg = msg.Error.Error()
+			if m.logger != nil {
+				m.logger.LogError(msg.Error, "file")
+			}
+		} else {
+			m.statusMsg = msg.Message
 			if m.logger != nil {
-				m.logger.LogInfo("Replay: sent successfully")
+				m.logger.LogInfo(msg.Message)
 			}
 		}
-		return m, clearStatusCmd()
+		return m, tea.Batch(clearStatusCmd(), m.refreshSessionsCmd())
 
 	case TickMsg:
 		return m, m.tickCmd()
@@ -131,14 +195,14 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		case key.Matches(msg, key.NewBinding(key.WithKeys("right"))):
 			if m.showDetails && m.activePanel != SessionPanel {
-				m.activeTab = (m.activeTab + 1) % 3
+				m.activeTab = (m.activeTab + 1) % len(detailTabs)
 			}
 
 		case key.Matches(msg, key.NewBinding(key.WithKeys("left"))):
 			if m.showDetails && m.activePanel != SessionPanel {
 				m.activeTab--
 				if m.activeTab < 0 {
-					m.activeTab = 2
+					m.activeTab = len(detailTabs) - 1
 				}
 			}
 
@@ -173,7 +237,7 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			var session *sessiondata.Session
 
 			if m.activePanel == SessionPanel {
-				session = m.sessionsPanel.GetSelectedSession()
+				session = m.fullSession(m.sessionsPanel.GetSelectedSession())
 			} else if m.showDetails && m.selectedSession != nil {
 				session = m.selectedSession
 			}
@@ -185,7 +249,7 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			m.statusMsg = fmt.Sprintf("Replaying %s %s...", session.Request.Method, session.Request.URL)
 			if m.logger != nil {
-				m.logger.LogInfo(fmt.Sprintf("Replay: %s %s -> :%d", session.Request.Method, session.Request.URL, m.port))
+				m.logger.LogInfo(fmt.Sprintf("Replay: %s %s -> %s", session.Request.Method, session.Request.URL, m.listenAddr))
 			}
 
 			return m, m.replayCmd(session)
@@ -203,27 +267,77 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		case key.Matches(msg, key.NewBinding(key.WithKeys("c"))):
 			if m.activePanel == SessionPanel {
-				highlighted := m.sessionsPanel.GetSelectedSession()
-				if highlighted != nil {
-					err := clipboard.WriteAll(highlighted.ToCurl())
-					if err != nil {
-						m.errorMsg = "Error " + err.Error()
-					} else {
-						m.statusMsg = "cURL copied to clipboard"
-					}
-				} else {
-					m.errorMsg = "No session selected"
-				}
+				return m, m.openCopyAsPrompt()
+			}
+			return m, nil
+
+		case key.Matches(msg, key.NewBinding(key.WithKeys("R"))):
+			return m, m.openReplayRun()
+
+		case key.Matches(msg, key.NewBinding(key.WithKeys("e"))):
+			return m, m.openComposer(false)
+
+		case key.Matches(msg, key.NewBinding(key.WithKeys("n"))):
+			return m, m.openComposer(true)
+
+		case key.Matches(msg, key.NewBinding(key.WithKeys("i"))):
+			return m, m.openFuzzer()
+
+		case key.Matches(msg, key.NewBinding(key.WithKeys("u"))):
+			return m, m.openCopyFingerprintPrompt()
+
+		case key.Matches(msg, key.NewBinding(key.WithKeys("E"))):
+			return m, m.openPrompt(PromptExportHAR, "Export HAR to", defaultHARPath)
+
+		case key.Matches(msg, key.NewBinding(key.WithKeys("I"))):
+			return m, m.openPrompt(PromptImportHAR, "Import HAR from", defaultHARPath)
+
+		case key.Matches(msg, key.NewBinding(key.WithKeys("O"))):
+			return m, m.openPrompt(PromptOpenCapture, "Open capture directory", m.captureDir)
+
+		case key.Matches(msg, key.NewBinding(key.WithKeys("b"))):
+			return m, m.openPrompt(PromptBreakpoint, "Break on (method= host= url= header= response)", "")
+
+		case key.Matches(msg, key.NewBinding(key.WithKeys("B"))):
+			m.clearBreakpoints()
+			return m, clearStatusCmd()
+
+		case key.Matches(msg, key.NewBinding(key.WithKeys("p"))):
+			if len(m.breakpoints.Pending()) == 0 && len(m.wsIntercept.Pending()) > 0 {
+				return m, m.openWSMessageEditor()
 			}
+			return m, m.openBreakpointEditor()
+
+		case key.Matches(msg, key.NewBinding(key.WithKeys("w"))):
+			return m, m.openPrompt(PromptWSRule, "Hold WebSocket messages (dir=in|out type=text|binary host= url= payload=)", "")
+
+		case key.Matches(msg, key.NewBinding(key.WithKeys("W"))):
+			m.clearWSRules()
+			return m, clearStatusCmd()
+
+		case key.Matches(msg, key.NewBinding(key.WithKeys("x"))):
+			return m, m.openWSInject()
+
+		case key.Matches(msg, key.NewBinding(key.WithKeys("m"))):
+			return m, m.openPrompt(PromptMapping, "Map (local <url-regex> <file>|body=... / remote <url-regex> <target> / clear)", "")
+
+		case key.Matches(msg, key.NewBinding(key.WithKeys("M"))):
+			m.toggleMappings()
 			return m, clearStatusCmd()
 
+		case key.Matches(msg, key.NewBinding(key.WithKeys("L"))):
+			return m, m.openLabelPrompt()
+
+		case key.Matches(msg, key.NewBinding(key.WithKeys("F"))):
+			return m, m.openPrompt(PromptFingerprints, "Fingerprints (export <file> / import <file>)", "export "+defaultFingerprintsPath)
+
 		case key.Matches(msg, key.NewBinding(key.WithKeys("f1"))):
 			m.showHelp = !m.showHelp
 
 		case key.Matches(msg, key.NewBinding(key.WithKeys("f2"))):
 			m.verbose = !m.verbose
 			if m.logger != nil {
-				m.logger.verbose = m.verbose
+				m.logger.SetVerbose(m.verbose)
 			}
 		}
 	}
@@ -249,6 +363,8 @@ func (m *Model) propagateToActivePanel(msg tea.Msg) tea.Cmd {
 			if m.tlsPanel != nil {
 				return m.tlsPanel.Update(msg)
 			}
+		case 3:
+			return m.diffPanel.Update(msg)
 		}
 	}
 	return nil
@@ -264,6 +380,20 @@ func (m *Model) updatePanelsForSession(session *sessiondata.Session) {
 	if m.tlsPanel != nil {
 		m.tlsPanel.UpdateSession(session)
 	}
+	m.diffPanel.UpdateSession(session, m.parentSession(session))
+}
+
+// parentSession returns the session a replayed or composed session was made
+// from, nil if it has none or it is no longer stored
+func (m *Model) parentSession(session *sessiondata.Session) *sessiondata.Session {
+	if session.ParentID == "" || m.sessionStore == nil {
+		return nil
+	}
+	parent, err := m.sessionStore.Get(session.ParentID)
+	if err != nil {
+		return nil
+	}
+	return parent
 }
 
 func (m *Model) switchPanel() {
@@ -278,7 +408,7 @@ func (m *Model) switchPanel() {
 }
 
 func (m *Model) updateSelectedSession() {
-	session := m.sessionsPanel.GetSelectedSession()
+	session := m.fullSession(m.sessionsPanel.GetSelectedSession())
 	if session == nil {
 		return
 	}
@@ -294,7 +424,12 @@ func (m *Model) updateSelectedSession() {
 
 func (m *Model) clearSessions() {
 	if m.sessionStore != nil {
-		m.sessionStore.Clear()
+		if err := m.sessionStore.Clear(); err != nil {
+			m.errorMsg = err.Error()
+			if m.logger != nil {
+				m.logger.LogError(err, "clearing sessions")
+			}
+		}
 		m.showDetails = false
 		m.sessions = []*sessiondata.Session{}
 		m.sessionsPanel.UpdateSessions(m.sessions)
@@ -304,6 +439,7 @@ func (m *Model) clearSessions() {
 		if m.tlsPanel != nil {
 			m.tlsPanel.UpdateSession(nil)
 		}
+		m.diffPanel.UpdateSession(nil, nil)
 		if m.websocketPanel != nil {
 			m.websocketPanel.UpdateSession(nil)
 		}
@@ -318,6 +454,7 @@ func (m *Model) resetSelection() {
 	if m.tlsPanel != nil {
 		m.tlsPanel.UpdateSession(nil)
 	}
+	m.diffPanel.UpdateSession(nil, nil)
 	if m.websocketPanel != nil {
 		m.websocketPanel.UpdateSession(nil)
 	}
@@ -332,6 +469,12 @@ func (m *Model) updatePanelSizes() {
 	availW := m.width
 	availH := m.height - 2
 
+	m.breakpointPanel.SetSize(helpers.SafeInt(availW-4), helpers.SafeInt(availH-4))
+	m.wsMessagePanel.SetSize(helpers.SafeInt(availW-4), helpers.SafeInt(availH-4))
+	m.composerPanel.SetSize(helpers.SafeInt(availW-4), helpers.SafeInt(availH-4))
+	m.replayRunPanel.SetSize(helpers.SafeInt(availW-4), helpers.SafeInt(availH-4))
+	m.fuzzerPanel.SetSize(helpers.SafeInt(availW-4), helpers.SafeInt(availH-4))
+
 	if !m.showDetails {
 		m.sessionsPanel.SetSize(helpers.SafeInt(availW-2), helpers.SafeInt(availH-4))
 		return
@@ -351,6 +494,7 @@ func (m *Model) updatePanelSizes() {
 	if m.tlsPanel != nil {
 		m.tlsPanel.SetSize(helpers.SafeInt(detailsW-2), helpers.SafeInt(availH-4))
 	}
+	m.diffPanel.SetSize(helpers.SafeInt(detailsW-2), helpers.SafeInt(availH-4))
 	if m.websocketPanel != nil {
 		m.websocketPanel.SetSize(helpers.SafeInt(detailsW-2), helpers.SafeInt(availH-4))
 	}
@@ -397,6 +541,7 @@ func (m *Model) tickCmd() tea.Cmd {
 func (m *Model) toggleProxyCmd() tea.Cmd {
 	if m.isRunning {
 		server := m.server
+		m.closeListeners()
 		return func() tea.Msg {
 			if server != nil {
 				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -410,34 +555,56 @@ func (m *Model) toggleProxyCmd() tea.Cmd {
 	}
 
 	if m.proxy == nil {
-		if err := os.MkdirAll("certs", 0o755); err != nil {
-			m.errorMsg = fmt.Sprintf("Failed to create certs dir: %v", err)
-			return nil
-		}
-
-		caCache := certs.NewCertCache()
-		err := caCache.LoadOrGenerateCA("certs", "certs/httpCA.crt", "certs/httpCA.key")
+		caCache, err := certs.LoadCA(m.caCertFile, m.caKeyFile)
 		if err != nil {
 			m.errorMsg = fmt.Sprintf("CA error: %v", err)
 			return nil
 		}
 
 		m.proxy = proxy.NewProxy(m.sessionStore, m.logger, caCache)
+		m.proxy.SetBreakpoints(m.breakpoints)
+		m.proxy.SetWebSockets(m.wsIntercept)
+		m.proxy.SetRewrite(m.rewrite)
+		m.proxy.SetMappings(m.mappings)
+		m.proxy.SetChain(m.chain)
+		m.proxy.SetPassthrough(m.passthrough)
+		m.proxy.SetFingerprints(m.fingerprints)
+	}
+
+	if err := m.listen("SOCKS5 proxy", m.socksAddr, listenTCP, m.proxy.ServeSOCKS); err != nil {
+		m.errorMsg = fmt.Sprintf("SOCKS5 error: %v", err)
+		return nil
+	}
+	if err := m.listen("Transparent proxy", m.transparentAddr, m.listenTransparent, m.proxy.ServeTransparent); err != nil {
+		m.closeListeners()
+		m.errorMsg = fmt.Sprintf("Transparent proxy error: %v", err)
+		return nil
+	}
+	if m.reverse != nil {
+		reverse := m.reverse
+		err := m.listen("Reverse proxy to "+reverse.String(), m.reverseAddr, listenTCP, func(listener net.Listener) error {
+			return m.proxy.ServeReverse(listener, reverse)
+		})
+		if err != nil {
+			m.closeListeners()
+			m.errorMsg = fmt.Sprintf("Reverse proxy error: %v", err)
+			return nil
+		}
 	}
 
 	m.server = &http.Server{
-		Addr:     fmt.Sprintf(":%d", m.port),
+		Addr:     m.listenAddr,
 		Handler:  m.proxy,
 		ErrorLog: log.New(io.Discard, "", 0),
 	}
 
 	server := m.server
 	logger := m.logger
-	port := m.port
+	listenAddr := m.listenAddr
 
 	return func() tea.Msg {
 		if logger != nil {
-			logger.LogInfo(fmt.Sprintf("Proxy listening on http://127.0.0.1:%d", port))
+			logger.LogInfo(fmt.Sprintf("Proxy listening on %s", listenAddr))
 		}
 		go func() {
 			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -450,6 +617,45 @@ func (m *Model) toggleProxyCmd() tea.Cmd {
 	}
 }
 
+// listen serves one of the proxy's extra front-ends on addr when it is set
+func (m *Model) listen(name, addr string, listen func(string) (net.Listener, error), serve func(net.Listener) error) error {
+	if addr == "" {
+		return nil
+	}
+	listener, err := listen(addr)
+	if err != nil {
+		return err
+	}
+	m.listeners = append(m.listeners, listener)
+	go serve(listener)
+
+	if m.logger != nil {
+		m.logger.LogInfo(fmt.Sprintf("%s listening on %s", name, listener.Addr()))
+	}
+	return nil
+}
+
+func listenTCP(addr string) (net.Listener, error) {
+	return net.Listen("tcp", addr)
+}
+
+// listenTransparent opens the transparent listener, logging when it cannot
+// take TPROXY connections
+func (m *Model) listenTransparent(addr string) (net.Listener, error) {
+	listener, tproxy, err := proxy.ListenTransparent(addr)
+	if err == nil && !tproxy && m.logger != nil {
+		m.logger.LogInfo(proxy.NoTPROXYMessage)
+	}
+	return listener, err
+}
+
+func (m *Model) closeListeners() {
+	for _, listener := range m.listeners {
+		listener.Close()
+	}
+	m.listeners = nil
+}
+
 func (m *Model) refreshSessionsCmd() tea.Cmd {
 	return tea.Cmd(func() tea.Msg {
 		if m.sessionStore != nil {
@@ -469,13 +675,14 @@ func clearStatusCmd() tea.Cmd {
 }
 
 type ReplayResultMsg struct {
-	Error error
+	Session *sessiondata.Session
+	Error   error
 }
 
 func (m *Model) replayCmd(session *sessiondata.Session) tea.Cmd {
-	port := m.port
+	listenAddr := m.listenAddr
 	return func() tea.Msg {
-		err := session.Replay(port)
-		return ReplayResultMsg{Error: err}
+		replay, err := session.Replay(listenAddr)
+		return ReplayResultMsg{Session: replay, Error: err}
 	}
 }
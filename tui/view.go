@@ -14,6 +14,9 @@ var (
 	ActiveTabStyle = lipgloss.NewStyle().Padding(0, 1).Foreground(lipgloss.Color("63")).Bold(true).Underline(true)
 )
 
+// detailTabs are the tabs of the details panel of an HTTP session
+var detailTabs = []string{"Request", "Response", "TLS Fingerprint", "Diff"}
+
 func (m *Model) View() string {
 	if m.width == 0 {
 		return "Initializing HTTP Debugger..."
@@ -21,6 +24,21 @@ func (m *Model) View() string {
 	if m.showHelp {
 		return m.renderHelpScreen()
 	}
+	if m.breakpointPanel.IsOpen() {
+		return m.renderBreakpointEditor()
+	}
+	if m.wsMessagePanel.IsOpen() {
+		return m.renderWSMessageEditor()
+	}
+	if m.composerPanel.IsOpen() {
+		return m.renderComposer()
+	}
+	if m.replayRunPanel.IsOpen() {
+		return m.renderReplayRun()
+	}
+	if m.fuzzerPanel.IsOpen() {
+		return m.renderFuzzer()
+	}
 
 	availW := m.width
 	availH := m.height - 2
@@ -66,9 +84,8 @@ func (m *Model) View() string {
 				),
 			)
 		} else {
-			tabs := []string{"Request", "Response", "TLS Fingerprint"}
 			var tabHeaders string
-			for i, t := range tabs {
+			for i, t := range detailTabs {
 				if i == m.activeTab {
 					tabHeaders += ActiveTabStyle.Render(t) + " "
 				} else {
@@ -86,6 +103,8 @@ func (m *Model) View() string {
 				if m.tlsPanel != nil {
 					detailContent = m.tlsPanel.View()
 				}
+			case 3:
+				detailContent = m.diffPanel.View()
 			}
 
 			rightSide = detailStyle.Render(
@@ -119,6 +138,9 @@ func (m *Model) renderPanelTitle(title string, isActive bool) string {
 }
 
 func (m *Model) renderStatusBar() string {
+	if m.promptAction != PromptNone {
+		return StatusActiveStyle.Render(m.promptInput.View())
+	}
 	if m.isSearching {
 		return StatusActiveStyle.Render(m.searchInput.View())
 	}
@@ -130,6 +152,47 @@ func (m *Model) renderStatusBar() string {
 		leftStyle = StatusInactiveStyle
 	}
 
+	if m.reverse != nil && m.isRunning {
+		left += fmt.Sprintf("  ⇄ %s → %s", m.reverseAddr, m.reverse.Host)
+	}
+	if proxyURL := m.chain.Default(); proxyURL != nil {
+		left += "  ↑ via " + proxyURL.Redacted()
+	} else if routes := len(m.chain.Routes()); routes > 0 {
+		left += fmt.Sprintf("  ↑ %d upstream routes", routes)
+	}
+	if hosts := len(m.passthrough.AutoHosts()); hosts > 0 {
+		left += fmt.Sprintf("  ⇢ %d pinned hosts passed through", hosts)
+	}
+	if m.rewrite != nil {
+		left += fmt.Sprintf("  ✎ %d rewrite rules", len(m.rewrite.Rules()))
+	}
+	if rules := len(m.mappings.Rules()); rules > 0 {
+		left += fmt.Sprintf("  ⇄ %d mappings", rules)
+		if !m.mappings.Enabled() {
+			left += " (off)"
+		}
+	}
+	if rules := len(m.breakpoints.Rules()); rules > 0 {
+		left += fmt.Sprintf("  ⏸ %d breakpoints", rules)
+	}
+	if held := len(m.breakpoints.Pending()); held > 0 {
+		left += fmt.Sprintf(", %d held (p: edit)", held)
+	}
+	if rules := len(m.wsIntercept.Rules()); rules > 0 {
+		left += fmt.Sprintf("  ⏸ %d WebSocket rules", rules)
+	}
+	if held := len(m.wsIntercept.Pending()); held > 0 {
+		left += fmt.Sprintf(", %d messages held (p: edit)", held)
+	}
+	if m.replayRun != nil && !m.replayRun.Done() {
+		summary := m.replayRun.Summary()
+		left += fmt.Sprintf("  ↻ replaying %d/%d (R)", summary.Completed, summary.Total)
+	}
+	if m.fuzz != nil && !m.fuzz.Done() {
+		summary := m.fuzz.Summary()
+		left += fmt.Sprintf("  ✱ fuzzing %d/%d (i)", summary.Completed, summary.Total)
+	}
+
 	var right string
 	if m.errorMsg != "" {
 		right = "ERR: " + m.errorMsg
@@ -156,7 +219,7 @@ func (m *Model) renderStatusBar() string {
 }
 
 func (m *Model) renderHelpBar() string {
-	help := "Tab: panels • Enter: details • Ctrl+S: proxy • /: filter • r: replay • c: curl • q: quit"
+	help := "Tab: panels • Enter: details • Ctrl+S: proxy • /: filter • r: replay • e: compose • i: fuzz • c: copy as • E/I: HAR • q: quit"
 	help = helpers.TruncateString(help, m.width-1)
 	return HelpStyle.Render(help)
 }
@@ -175,13 +238,64 @@ GLOBAL COMMANDS:
   Escape            Reset selection
   /                 Search (regex filter by URL)
   r                 Replay selected request
-  c                 Copy as cURL
+  R                 Replay the listed sessions in bulk, or show the running replay
+  e                 Edit and send the selected request in the composer
+  n                 Compose a new request
+  i                 Fuzz the selected request, or show the running attack
+  c                 Copy as cURL, Go, Python, fetch or raw HTTP
+  u                 Copy TLS fingerprint as utls Go code or client JSON
+  E                 Export all sessions as HAR
+  I                 Import sessions from a HAR file
+  O                 Open or switch to a capture directory
+  b                 Add a breakpoint rule
+  B                 Clear breakpoints and release held requests
+  p                 Edit the oldest held request/response, then WebSocket messages
+  w                 Add a WebSocket message intercept rule
+  W                 Clear WebSocket rules and release held messages
+  x                 Inject a message into the selected WebSocket connection
+  m                 Add a Map Local / Map Remote rule
+  M                 Turn mappings on/off
+  L                 Label the client of the selected session
+  F                 Export or import the fingerprint database
+
+BREAKPOINT EDITOR:
+  Ctrl+F            Forward (with edits)
+  Ctrl+X            Drop
+  Ctrl+T            Reply with a canned response
+  Esc               Close editor, keep request held
   F1                Toggle this help
   F2                Toggle verbose logging
 
+WEBSOCKET MESSAGE EDITOR:
+  Ctrl+F            Forward the held message (with edits) / send the injected one
+  Ctrl+X            Drop the held message
+  Ctrl+D            Inject to the server or the client
+  Ctrl+B            Switch between text and binary (edited as hex)
+  Esc               Close editor, keep message held
+
+REQUEST COMPOSER:
+  Ctrl+F            Send as a new session
+  Ctrl+P            Cycle TLS/HTTP2 fingerprints seen so far
+  Ctrl+O            Reset to the original request
+  PgUp/PgDn         Scroll the response
+  Esc               Close composer
+
+FUZZER:
+  Ctrl+A            Insert a § marker; §value§ is an insertion point
+  Ctrl+O            Reset to the original request
+  Ctrl+F            Start an attack (mode, payloads, concurrency, rate, match)
+  Tab               Switch between the request and the results
+  s / S             Sort results by request, status, length, time, matches / reverse
+  Ctrl+X            Stop starting requests
+  Esc               Close, the attack goes on
+
+REPLAY RUN:
+  Ctrl+X            Stop starting requests
+  Esc               Close, the run goes on
+
 DETAILS PANEL:
   ↑↓                Scroll through content
-  ←→                Switch Tab (Req/Res/TLS)
+  ←→                Switch Tab (Req/Res/TLS/Diff)
   PgUp/PgDn         Page up/down
 `
 	style := lipgloss.NewStyle().
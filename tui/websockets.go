@@ -0,0 +1,158 @@
+package tui
+
+import (
+	"fmt"
+
+	"httpDebugger/pkg/sessiondata"
+	"httpDebugger/pkg/websocket"
+
+	key "github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// addWSRule parses a rule typed in the prompt and starts holding matching WebSocket messages
+func (m *Model) addWSRule(spec string) {
+	rule, err := websocket.ParseRule(spec)
+	if err != nil {
+		m.errorMsg = err.Error()
+		return
+	}
+	m.wsIntercept.AddRule(rule)
+	m.statusMsg = fmt.Sprintf("WebSocket intercept rule added: %s", rule)
+	if m.logger != nil {
+		m.logger.LogInfo(m.statusMsg)
+	}
+}
+
+// clearWSRules removes every rule and lets held messages continue unchanged
+func (m *Model) clearWSRules() {
+	m.wsIntercept.ClearRules()
+	m.wsIntercept.ResolveAll()
+	if m.wsMessagePanel.Pending() != nil {
+		m.wsMessagePanel.Close()
+	}
+	m.statusMsg = "WebSocket intercept rules cleared"
+}
+
+// openWSMessageEditor shows the oldest held WebSocket message in the editor
+func (m *Model) openWSMessageEditor() tea.Cmd {
+	pending := m.wsIntercept.Pending()
+	if len(pending) == 0 {
+		m.errorMsg = "No requests or WebSocket messages held"
+		return clearStatusCmd()
+	}
+	return m.wsMessagePanel.OpenHeld(pending[0])
+}
+
+// openWSInject starts a message to inject into the selected WebSocket connection
+func (m *Model) openWSInject() tea.Cmd {
+	session := m.currentSession()
+	if session == nil || session.Type != sessiondata.WebSocketSession {
+		m.errorMsg = "Select a WebSocket session to inject a message"
+		return clearStatusCmd()
+	}
+	if !m.wsIntercept.Connected(session.ID) {
+		m.errorMsg = "This WebSocket connection is closed"
+		return clearStatusCmd()
+	}
+	return m.wsMessagePanel.OpenInject(session)
+}
+
+func (m *Model) updateWSMessageEditor(msg tea.KeyMsg) tea.Cmd {
+	pending := m.wsMessagePanel.Pending()
+
+	switch {
+	case key.Matches(msg, key.NewBinding(key.WithKeys("ctrl+f"))):
+		if pending == nil {
+			return m.injectWSMessage()
+		}
+		decision, err := m.wsMessagePanel.Decision()
+		if err != nil {
+			m.errorMsg = err.Error()
+			return clearStatusCmd()
+		}
+		return m.resolveWSMessage(pending, decision)
+
+	case key.Matches(msg, key.NewBinding(key.WithKeys("ctrl+x"))):
+		if pending != nil {
+			return m.resolveWSMessage(pending, websocket.Decision{Action: websocket.ActionDrop})
+		}
+		return nil
+
+	case key.Matches(msg, key.NewBinding(key.WithKeys("ctrl+d"))):
+		m.wsMessagePanel.ToggleDirection()
+		return nil
+
+	case key.Matches(msg, key.NewBinding(key.WithKeys("ctrl+b"))):
+		if err := m.wsMessagePanel.ToggleType(); err != nil {
+			m.errorMsg = err.Error()
+			return clearStatusCmd()
+		}
+		return nil
+
+	case key.Matches(msg, key.NewBinding(key.WithKeys("esc"))):
+		m.wsMessagePanel.Close()
+		return nil
+	}
+
+	return m.wsMessagePanel.Update(msg)
+}
+
+func (m *Model) resolveWSMessage(pending *websocket.Pending, decision websocket.Decision) tea.Cmd {
+	m.wsMessagePanel.Close()
+
+	if err := m.wsIntercept.Resolve(pending.ID, decision); err != nil {
+		m.errorMsg = "Message is no longer held"
+		return clearStatusCmd()
+	}
+
+	switch {
+	case decision.Action == websocket.ActionDrop:
+		m.statusMsg = "Dropped WebSocket message on " + pending.Session.Request.URL
+	case decision.Edited:
+		m.statusMsg = "Forwarded edited WebSocket message on " + pending.Session.Request.URL
+	default:
+		m.statusMsg = "Forwarded WebSocket message on " + pending.Session.Request.URL
+	}
+
+	// Move straight on to the next held message, if any
+	if len(m.wsIntercept.Pending()) > 1 {
+		return tea.Batch(m.openWSMessageEditor(), clearStatusCmd())
+	}
+	return clearStatusCmd()
+}
+
+// injectWSMessage sends the message in the editor into its live connection
+func (m *Model) injectWSMessage() tea.Cmd {
+	session := m.wsMessagePanel.Session()
+	payload, err := m.wsMessagePanel.Payload()
+	if err != nil {
+		m.errorMsg = err.Error()
+		return clearStatusCmd()
+	}
+
+	direction := m.wsMessagePanel.Direction()
+	if err := m.wsIntercept.Inject(session.ID, direction, m.wsMessagePanel.Opcode(), payload); err != nil {
+		m.errorMsg = fmt.Sprintf("Inject failed: %v", err)
+		return clearStatusCmd()
+	}
+
+	to := "server"
+	if direction == sessiondata.Inbound {
+		to = "client"
+	}
+	m.wsMessagePanel.Close()
+	m.statusMsg = fmt.Sprintf("Injected %d bytes to the %s", len(payload), to)
+	return tea.Batch(clearStatusCmd(), m.refreshSessionsCmd())
+}
+
+func (m *Model) renderWSMessageEditor() string {
+	help := "Ctrl+F: forward • Ctrl+X: drop • Ctrl+B: text/binary • Esc: close (keep held)"
+	if m.wsMessagePanel.Pending() == nil {
+		help = "Ctrl+F: send • Ctrl+D: to server/client • Ctrl+B: text/binary • Esc: cancel"
+	}
+	content := ActiveStyle.Copy().Width(m.width - 2).Height(m.height - 4).Render(
+		m.renderPanelTitle(m.wsMessagePanel.Title(), true) + "\n\n" + m.wsMessagePanel.View(),
+	)
+	return content + "\n" + m.renderStatusBar() + "\n" + HelpStyle.Render(help)
+}